@@ -0,0 +1,246 @@
+// Command loadgen drives a running server with a mix of shorten and redirect
+// requests and reports throughput and latency percentiles. With -json the
+// report is machine-readable, so runs against different commits can be
+// compared.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	opShorten  = "shorten"
+	opRedirect = "redirect"
+)
+
+type options struct {
+	baseURL     string
+	apiKey      string
+	duration    time.Duration
+	requests    int64
+	concurrency int
+	shortenPct  int
+	seedLinks   int
+	timeout     time.Duration
+	insecure    bool
+	json        bool
+}
+
+// sample is one finished request.
+type sample struct {
+	op      string
+	status  int // 0 when the request itself failed
+	latency time.Duration
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.baseURL, "url", "http://localhost:8080", "base URL of the running server")
+	flag.StringVar(&opts.apiKey, "api-key", os.Getenv("LOADGEN_API_KEY"), "API key for /api/shorten (or set LOADGEN_API_KEY)")
+	flag.DurationVar(&opts.duration, "duration", 10*time.Second, "how long to run; 0 runs until -requests are sent")
+	flag.Int64Var(&opts.requests, "requests", 0, "stop after this many requests; 0 runs for -duration")
+	flag.IntVar(&opts.concurrency, "concurrency", 16, "number of concurrent workers")
+	flag.IntVar(&opts.shortenPct, "shorten-pct", 10, "percentage of requests that shorten; the rest redirect")
+	flag.IntVar(&opts.seedLinks, "seed-links", 100, "links to create before the run for redirects to hit")
+	flag.DurationVar(&opts.timeout, "timeout", 5*time.Second, "per-request timeout")
+	flag.BoolVar(&opts.insecure, "insecure", false, "skip TLS certificate verification")
+	flag.BoolVar(&opts.json, "json", false, "print the report as JSON")
+	flag.Parse()
+
+	if err := opts.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	gen := newGenerator(opts)
+	if err := gen.seed(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "seeding links: %v\n", err)
+		os.Exit(1)
+	}
+
+	started := time.Now()
+	samples := gen.run(ctx)
+	rep := newReport(opts, time.Since(started), samples)
+
+	var err error
+	if opts.json {
+		err = rep.writeJSON(os.Stdout)
+	} else {
+		err = rep.writeTable(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func (o *options) validate() error {
+	o.baseURL = strings.TrimSuffix(o.baseURL, "/")
+	switch {
+	case o.baseURL == "":
+		return errors.New("-url is required")
+	case o.duration <= 0 && o.requests <= 0:
+		return errors.New("one of -duration or -requests must be positive")
+	case o.concurrency < 1:
+		return errors.New("-concurrency must be at least 1")
+	case o.shortenPct < 0 || o.shortenPct > 100:
+		return errors.New("-shorten-pct must be between 0 and 100")
+	case o.shortenPct < 100 && o.seedLinks < 1:
+		return errors.New("-seed-links must be at least 1 when the mix has redirects")
+	}
+	return nil
+}
+
+type generator struct {
+	opts   options
+	client *http.Client
+	codes  []string
+	seq    atomic.Int64 // makes every shortened URL unique
+}
+
+func newGenerator(opts options) *generator {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = opts.concurrency
+	transport.MaxIdleConnsPerHost = opts.concurrency
+	if opts.insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &generator{
+		opts: opts,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.timeout,
+			// The redirect itself is what is measured, not the destination.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// seed creates the links that redirects pick from.
+func (g *generator) seed(ctx context.Context) error {
+	if g.opts.shortenPct == 100 {
+		return nil
+	}
+	for range g.opts.seedLinks {
+		code, err := g.shorten(ctx)
+		if err != nil {
+			return err
+		}
+		g.codes = append(g.codes, code)
+	}
+	return nil
+}
+
+// shortenRequest asks for a link to a URL no earlier request used, so every
+// shorten stores a new link.
+func (g *generator) shortenRequest(ctx context.Context) *http.Request {
+	body, _ := json.Marshal(map[string]string{
+		"url": fmt.Sprintf("https://example.com/loadgen/%d", g.seq.Add(1)),
+	})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, g.opts.baseURL+"/api/shorten", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if g.opts.apiKey != "" {
+		req.Header.Set("X-API-Key", g.opts.apiKey)
+	}
+	return req
+}
+
+func (g *generator) shorten(ctx context.Context) (string, error) {
+	resp, err := g.client.Do(g.shortenRequest(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("POST /api/shorten: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var out struct {
+		ShortCode string `json:"short_code"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("POST /api/shorten: %w", err)
+	}
+	return out.ShortCode, nil
+}
+
+// run sends requests from every worker until the duration is up, the
+// request budget is spent or ctx is cancelled. Requests in flight at the
+// deadline are allowed to finish and are counted.
+func (g *generator) run(ctx context.Context) []sample {
+	if g.opts.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.opts.duration)
+		defer cancel()
+	}
+	var (
+		sent    atomic.Int64
+		mu      sync.Mutex
+		samples []sample
+		wg      sync.WaitGroup
+	)
+	for w := range g.opts.concurrency {
+		wg.Go(func() {
+			rng := rand.New(rand.NewPCG(uint64(w), uint64(time.Now().UnixNano())))
+			var local []sample
+			for ctx.Err() == nil {
+				if g.opts.requests > 0 && sent.Add(1) > g.opts.requests {
+					break
+				}
+				if rng.IntN(100) < g.opts.shortenPct {
+					local = append(local, g.timeShorten())
+				} else {
+					local = append(local, g.timeRedirect(g.codes[rng.IntN(len(g.codes))]))
+				}
+			}
+			mu.Lock()
+			samples = append(samples, local...)
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+	return samples
+}
+
+// timeShorten and timeRedirect use a background context so the run's
+// deadline does not turn the last requests into errors.
+func (g *generator) timeShorten() sample {
+	start := time.Now()
+	return g.do(opShorten, g.shortenRequest(context.Background()), start)
+}
+
+func (g *generator) timeRedirect(code string) sample {
+	start := time.Now()
+	req, _ := http.NewRequest(http.MethodGet, g.opts.baseURL+"/"+code, nil)
+	return g.do(opRedirect, req, start)
+}
+
+func (g *generator) do(op string, req *http.Request, start time.Time) sample {
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return sample{op: op, latency: time.Since(start)}
+	}
+	// Drain the body so the connection is reused.
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return sample{op: op, status: resp.StatusCode, latency: time.Since(start)}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// expectedStatus is the status a request of each kind must get to count as
+// a success; anything else, including 429s from the rate limiter, is an
+// error.
+var expectedStatus = map[string]int{
+	opShorten:  http.StatusOK,
+	opRedirect: http.StatusFound,
+}
+
+type report struct {
+	URL         string  `json:"url"`
+	Concurrency int     `json:"concurrency"`
+	ShortenPct  int     `json:"shorten_pct"`
+	Elapsed     float64 `json:"elapsed_seconds"`
+
+	Total      opStats            `json:"total"`
+	Operations map[string]opStats `json:"operations"`
+}
+
+type opStats struct {
+	Requests   int            `json:"requests"`
+	Errors     int            `json:"errors"`
+	PerSecond  float64        `json:"requests_per_second"`
+	LatencyMS  latencies      `json:"latency_ms"`
+	StatusCode map[string]int `json:"status_codes"` // "error" for failed requests
+}
+
+type latencies struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+func newReport(opts options, elapsed time.Duration, samples []sample) report {
+	rep := report{
+		URL:         opts.baseURL,
+		Concurrency: opts.concurrency,
+		ShortenPct:  opts.shortenPct,
+		Elapsed:     elapsed.Seconds(),
+		Total:       summarize(samples, elapsed),
+		Operations:  map[string]opStats{},
+	}
+	for _, op := range []string{opShorten, opRedirect} {
+		var mine []sample
+		for _, s := range samples {
+			if s.op == op {
+				mine = append(mine, s)
+			}
+		}
+		if len(mine) > 0 {
+			rep.Operations[op] = summarize(mine, elapsed)
+		}
+	}
+	return rep
+}
+
+func summarize(samples []sample, elapsed time.Duration) opStats {
+	stats := opStats{Requests: len(samples), StatusCode: map[string]int{}}
+	if len(samples) == 0 {
+		return stats
+	}
+	durations := make([]time.Duration, len(samples))
+	var sum time.Duration
+	for i, s := range samples {
+		durations[i] = s.latency
+		sum += s.latency
+		if s.status == 0 {
+			stats.StatusCode["error"]++
+		} else {
+			stats.StatusCode[strconv.Itoa(s.status)]++
+		}
+		if s.status != expectedStatus[s.op] {
+			stats.Errors++
+		}
+	}
+	slices.Sort(durations)
+	if elapsed > 0 {
+		stats.PerSecond = float64(len(samples)) / elapsed.Seconds()
+	}
+	stats.LatencyMS = latencies{
+		Min:  ms(durations[0]),
+		Mean: ms(sum / time.Duration(len(durations))),
+		P50:  ms(percentile(durations, 50)),
+		P90:  ms(percentile(durations, 90)),
+		P99:  ms(percentile(durations, 99)),
+		Max:  ms(durations[len(durations)-1]),
+	}
+	return stats
+}
+
+// percentile uses the nearest-rank method on sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+func ms(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*1000) / 1000
+}
+
+func (r report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r report) writeTable(w io.Writer) error {
+	fmt.Fprintf(w, "%s: %d workers, %d%% shorten, %.1fs\n\n", r.URL, r.Concurrency, r.ShortenPct, r.Elapsed)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OPERATION\tREQUESTS\tERRORS\tREQ/S\tMIN\tMEAN\tP50\tP90\tP99\tMAX\tSTATUS")
+	row := func(name string, s opStats) {
+		l := s.LatencyMS
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%.2fms\t%.2fms\t%.2fms\t%.2fms\t%.2fms\t%.2fms\t%s\n",
+			name, s.Requests, s.Errors, s.PerSecond, l.Min, l.Mean, l.P50, l.P90, l.P99, l.Max, statusSummary(s.StatusCode))
+	}
+	for _, op := range []string{opShorten, opRedirect} {
+		if s, ok := r.Operations[op]; ok {
+			row(op, s)
+		}
+	}
+	row("total", r.Total)
+	return tw.Flush()
+}
+
+// statusSummary lists status counts like "302:9000 429:12".
+func statusSummary(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	out := ""
+	for i, k := range keys {
+		if i > 0 {
+			out += " "
+		}
+		out += k + ":" + strconv.Itoa(counts[k])
+	}
+	return out
+}
@@ -1,61 +1,223 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
-	"strconv"
 
 	"urlshortener/internal/api"
-	"urlshortener/internal/services/shortener"
+	"urlshortener/internal/config"
+	"urlshortener/internal/grpcapi"
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/apikey"
+	"urlshortener/internal/services/health"
+	"urlshortener/internal/services/idempotency"
+	"urlshortener/internal/services/moderation"
+	"urlshortener/internal/services/scanguard"
 	shortenerpkg "urlshortener/internal/services/shortener"
-	"urlshortener/internal/services/storage/postgres"
-
-	"github.com/joho/godotenv"
+	"urlshortener/internal/services/signing"
+	"urlshortener/internal/services/webhook"
+	"urlshortener/internal/tlscert"
+	"urlshortener/ui"
 )
 
-type appConfig struct {
-	Server struct {
-		Address string
+func main() {
+	autoMigrate := flag.Bool("auto-migrate", false, "apply pending migrations before serving (or set AUTO_MIGRATE=true)")
+	configPath := flag.String("config", "", "YAML, TOML or JSON config file (or set CONFIG_FILE); env vars override it")
+	uiDevDir := flag.String("ui-dev", "", "serve the web UI from this ui/ directory, re-read on every request (or set UI_DEV_DIR)")
+	printConfig := flag.Bool("print-config", false, "print the resolved config with secrets redacted, then exit")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage:\n  %[1]s [flags]\n  %[1]s migrate up|down|status|reset\n\nFlags:\n", os.Args[0])
+		flag.PrintDefaults()
 	}
-	ShortenerSettings shortener.ShortenerSettings
-	PostgresConfig    postgres.PostgresConfig
-}
+	flag.Parse()
 
-func main() {
-	cfg, err := loadEnvConfig()
+	cfg, err := config.Load(*configPath)
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	flags := flagOverrides{autoMigrate: *autoMigrate, uiDevDir: *uiDevDir}
+	flags.apply(&cfg)
+	if *printConfig {
+		if err := cfg.WriteRedacted(os.Stdout); err != nil {
+			panic(err)
+		}
+		return
+	}
+	cfg.LogSummary()
+	logging.SetLevel(cfg.Level())
+
+	switch flag.Arg(0) {
+	case "":
+		err = run(cfg, *configPath, flags)
+	case "migrate":
+		err = runMigrate(cfg, flag.Args()[1:])
+	default:
+		flag.Usage()
+		os.Exit(2)
 	}
-
-	err = run(cfg)
 	if err != nil {
 		panic(err)
 	}
 }
 
-func run(cfg appConfig) error {
-	// Connect to DB
-	conn, err := postgres.Open(cfg.PostgresConfig)
+// flagOverrides holds the command-line flags that take precedence over the
+// config file and environment.
+type flagOverrides struct {
+	autoMigrate bool
+	uiDevDir    string
+}
+
+// apply sets the fields the flags override. Reloads apply them again, so a
+// flag never reads as a change that needs a restart.
+func (f flagOverrides) apply(cfg *config.Config) {
+	cfg.AutoMigrate = cfg.AutoMigrate || f.autoMigrate
+	if f.uiDevDir != "" {
+		cfg.Server.UIDevDir = f.uiDevDir
+	}
+}
+
+func run(cfg config.Config, configPath string, flags flagOverrides) error {
+	if cfg.AutoMigrate && cfg.StorageDriver == config.DriverPostgres {
+		if err := migrateUp(cfg); err != nil {
+			return err
+		}
+	}
+
+	store, closeStore, err := config.OpenStore(cfg)
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
+	defer closeStore()
 
-	// store := storage.NewInMemoryStore()
-	store := postgres.NewStore(conn)
 	codeGenerator := shortenerpkg.NewRandomCodeGenerator(
 		cfg.ShortenerSettings.CodeLength,
 	)
+	// The keyring is always set so signing keys added on reload take effect.
+	keyring := signing.NewKeyring(cfg.Signing.Keys, cfg.Signing.DefaultTTL)
+	shortenerOpts := []shortenerpkg.Option{
+		shortenerpkg.WithKeyring(keyring),
+		shortenerpkg.WithHistory(store),
+	}
+	auditLog, closeAudit, err := config.OpenAuditLog(cfg, store)
+	if err != nil {
+		return err
+	}
+	defer closeAudit()
+	if auditLog != nil {
+		shortenerOpts = append(shortenerOpts, shortenerpkg.WithAuditLog(auditLog))
+	}
+	// The dispatcher runs even without endpoints so the outbox stays
+	// inspectable and earlier deliveries settle.
+	webhooks := webhook.New(store, webhook.Options{
+		Endpoints:    cfg.Webhooks.Endpoints,
+		MaxAttempts:  cfg.Webhooks.MaxAttempts,
+		PollInterval: cfg.Webhooks.PollInterval,
+	})
+	if len(cfg.Webhooks.Endpoints) > 0 {
+		shortenerOpts = append(shortenerOpts, shortenerpkg.WithWebhooks(webhooks))
+	}
 	shortenerSvc := shortenerpkg.NewShortener(
 		codeGenerator,
 		store,
 		cfg.ShortenerSettings,
+		shortenerOpts...,
 	)
-	appRouter := api.NewRouter(shortenerSvc)
+	go webhooks.Run(context.Background())
+	webhooks.WatchExpiry(context.Background(), store, cfg.Webhooks.ExpiryCheck)
+	if cfg.Health.Enabled {
+		checker := health.New(store, health.Options{
+			Interval:    cfg.Health.Interval,
+			Concurrency: cfg.Health.Concurrency,
+			HostDelay:   cfg.Health.HostDelay,
+			Timeout:     cfg.Health.Timeout,
+		})
+		go checker.Run(context.Background())
+	}
+	if cfg.Moderation.BlocklistFile != "" {
+		err := moderation.WatchBlocklist(context.Background(), cfg.Moderation.BlocklistFile,
+			cfg.Moderation.BlocklistRefresh, shortenerSvc.SetBlocklist)
+		if err != nil {
+			return err
+		}
+	}
+	keys := apikey.NewManager(store)
+	limiter := api.NewRateLimiter(cfg.RateLimit.RequestsPerMinute, cfg.RateLimit.Burst)
+	proxies, err := api.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	routerOpts := []api.Option{
+		api.WithAPIKeys(keys, cfg.RequireAPIKey),
+		api.WithRateLimiter(limiter),
+		api.WithAdmins(cfg.Moderation.AdminKeys),
+		api.WithModeration(moderation.NewService(shortenerSvc, store)),
+		api.WithWebhooks(webhooks),
+		api.WithCORS(cfg.CORS),
+		api.WithSecurityHeaders(cfg.SecurityHeaders),
+		api.WithTrustedProxies(proxies),
+	}
+	if auditLog != nil {
+		routerOpts = append(routerOpts, api.WithAudit(auditLog))
+	}
+	if cfg.Health.MarkBroken {
+		routerOpts = append(routerOpts, api.WithBrokenLinkWarnings())
+	}
+	if sg := cfg.ScanGuard; sg.Enabled {
+		routerOpts = append(routerOpts, api.WithScanGuard(scanguard.New(scanguard.Options{
+			Window:      sg.Window,
+			Threshold:   sg.Threshold,
+			Delay:       sg.Delay,
+			MaxDelay:    sg.MaxDelay,
+			BanAfter:    sg.BanAfter,
+			BanDuration: sg.BanDuration,
+		})))
+	}
+	if ttl := cfg.Idempotency.TTL; ttl > 0 {
+		replies := idempotency.New(store, idempotency.Options{TTL: ttl})
+		go replies.Run(context.Background())
+		routerOpts = append(routerOpts, api.WithIdempotency(replies))
+	}
+	if fallback := cfg.Activation.FallbackURL; fallback != "" {
+		routerOpts = append(routerOpts, api.WithActivationFallback(fallback))
+	}
+	routerOpts = append(routerOpts, api.WithSessions(cfg.Dashboard.SessionTTL, cfg.SecureCookies()))
+	if cfg.Server.UIDevDir != "" {
+		devUI, err := ui.Dev(cfg.Server.UIDevDir)
+		if err != nil {
+			return err
+		}
+		log.Printf("🛠️  Serving the UI from %s (dev mode, no caching)", cfg.Server.UIDevDir)
+		routerOpts = append(routerOpts, api.WithUI(devUI))
+	}
+	appRouter := api.NewRouter(shortenerSvc, routerOpts...)
+	if cfg.Server.GRPCAddress != "" {
+		lis, err := net.Listen("tcp", cfg.Server.GRPCAddress)
+		if err != nil {
+			return err
+		}
+		grpcServer := grpcapi.NewServer(shortenerSvc,
+			grpcapi.WithAPIKeys(keys, cfg.RequireAPIKey),
+			grpcapi.WithAdmins(cfg.Moderation.AdminKeys),
+		)
+		log.Printf("📡 gRPC listening on %s", cfg.Server.GRPCAddress)
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatalf("🚨 gRPC server stopped: %v", err)
+			}
+		}()
+	}
+	go reloadOnSIGHUP(cfg, configPath, flags, limiter, shortenerSvc, keyring)
 
 	addr := fmt.Sprintf("%s", cfg.Server.Address)
+	if cfg.Server.TLS.Enabled() {
+		return serveTLS(cfg.Server.TLS, addr, appRouter)
+	}
 	log.Printf("🚀 listening on %s 🚀", addr)    // 🪵 log message
 	err = http.ListenAndServe(addr, appRouter) // 🚀 start HTTP server
 	if err != nil {
@@ -64,77 +226,26 @@ func run(cfg appConfig) error {
 	return nil
 }
 
-func loadEnvConfig() (appConfig, error) {
-	var cfg appConfig
-
-	// Load .env file (optional - can use system env vars)
-	if err := godotenv.Load(); err != nil {
-		log.Println("⚠️  No .env file found, using system environment variables")
-	}
-
-	// Server configuration
-	port := getEnvOrDefault("PORT", "8080")
-	cfg.Server.Address = ":" + port
-
-	// Shortener configuration
-	cfg.ShortenerSettings.CodeLength = getEnvAsInt("CODE_LENGTH", 6)
-	cfg.ShortenerSettings.MaxRetries = getEnvAsInt("SHORTENER_MAX_RETRIES", 3)
-
-	maxRetries := os.Getenv("SHORTENER_MAX_RETRIES")
-	if maxRetries == "" {
-		cfg.ShortenerSettings.MaxRetries = 3
-	} else {
-		cfg.ShortenerSettings.MaxRetries, _ = strconv.Atoi(maxRetries)
-	}
-
-	// PostgreSQL configuration
-	cfg.PostgresConfig = postgres.PostgresConfig{
-		Host:     getEnvOrDefault("PSQL_HOST", "localhost"),
-		Port:     getEnvOrDefault("PSQL_PORT", "5432"),
-		User:     getEnvOrDefault("PSQL_USER", "urlshortener"),
-		Password: os.Getenv("PSQL_PASSWORD"),
-		Database: getEnvOrDefault("PSQL_DATABASE", "urlshortener"),
-		SSLMode:  getEnvOrDefault("PSQL_SSLMODE", "disable"),
-	}
-
-	// Validate required config
-	if cfg.PostgresConfig.Password == "" {
-		return cfg, fmt.Errorf("PSQL_PASSWORD environment variable is required")
-	}
-
-	// Log configuration (without sensitive data)
-	log.Printf("📋 Configuration loaded:")
-	log.Printf("   Server: %s", cfg.Server.Address)
-	log.Printf("   Code Length: %d", cfg.ShortenerSettings.CodeLength)
-	log.Printf("   Max Retries: %d", cfg.ShortenerSettings.MaxRetries)
-	log.Printf("   Database: %s@%s:%s/%s",
-		cfg.PostgresConfig.User,
-		cfg.PostgresConfig.Host,
-		cfg.PostgresConfig.Port,
-		cfg.PostgresConfig.Database)
-
-	return cfg, nil
-}
-
-// Helper functions
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// serveTLS serves handler over HTTPS on addr, picking up renewed certificate
+// files, and redirects plain HTTP on the redirect address if there is one.
+func serveTLS(cfg config.TLS, addr string, handler http.Handler) error {
+	certs, err := tlscert.Load(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return err
 	}
-	return defaultValue
-}
-
-func getEnvAsInt(key string, defaultValue int) int {
-	valueStr := os.Getenv(key)
-	if valueStr == "" {
-		return defaultValue
+	go certs.Watch(context.Background(), cfg.ReloadInterval)
+	if cfg.RedirectAddress != "" {
+		log.Printf("↪️  redirecting HTTP on %s to HTTPS", cfg.RedirectAddress)
+		go func() {
+			if err := http.ListenAndServe(cfg.RedirectAddress, tlscert.RedirectHTTPS(addr)); err != nil {
+				log.Fatalf("🚨 HTTP redirect server stopped: %v", err)
+			}
+		}()
 	}
 
-	value, err := strconv.Atoi(valueStr)
-	if err != nil {
-		log.Printf("⚠️  Invalid integer value for %s: %s, using default: %d", key, valueStr, defaultValue)
-		return defaultValue
+	log.Printf("🔒 listening on %s (HTTPS, HTTP/2) 🚀", addr)
+	if err := certs.Server(addr, handler).ListenAndServeTLS("", ""); err != nil {
+		log.Fatalf("🚨 server stopped: %v", err)
 	}
-
-	return value
+	return nil
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"urlshortener/internal/config"
+	"urlshortener/internal/services/storage/postgres"
+)
+
+// runMigrate handles `server migrate up|down|status|reset`.
+func runMigrate(cfg config.Config, args []string) error {
+	if cfg.StorageDriver != config.DriverPostgres {
+		return fmt.Errorf("migrate: only supported with STORAGE_DRIVER=%s", config.DriverPostgres)
+	}
+	if len(args) != 1 {
+		return errors.New("migrate: expected one of up, down, status, reset")
+	}
+
+	ctx := context.Background()
+	conn, err := postgres.Open(cfg.PostgresConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	migrator, err := postgres.NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		ran, err := migrator.Up(ctx)
+		for _, mig := range ran {
+			log.Printf("⬆️  applied %05d_%s", mig.Version, mig.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			log.Println("✅ database is up to date")
+		}
+	case "down":
+		mig, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("⬇️  rolled back %05d_%s", mig.Version, mig.Name)
+	case "reset":
+		for {
+			mig, err := migrator.Down(ctx)
+			if errors.Is(err, postgres.ErrNoMigrations) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			log.Printf("⬇️  rolled back %05d_%s", mig.Version, mig.Name)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			appliedAt := "pending"
+			if st.Applied() {
+				appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%05d_%-30s %s\n", st.Version, st.Name, appliedAt)
+		}
+	default:
+		return fmt.Errorf("migrate: unknown command %q (expected up, down, status, reset)", args[0])
+	}
+	return nil
+}
+
+// migrateUp applies pending migrations on boot when AUTO_MIGRATE is on.
+func migrateUp(cfg config.Config) error {
+	return runMigrate(cfg, []string{"up"})
+}
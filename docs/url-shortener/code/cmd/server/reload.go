@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"urlshortener/internal/api"
+	"urlshortener/internal/config"
+	"urlshortener/internal/logging"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/signing"
+)
+
+// reloadOnSIGHUP re-reads the config file and environment on every SIGHUP,
+// applies the command-line flags on top, and applies the fields that are safe
+// to change while serving: the rate limit, the URL denylist, the signing keys
+// and the log level. A config that fails validation is ignored, and the
+// running one stays in effect.
+func reloadOnSIGHUP(current config.Config, path string, flags flagOverrides, limiter *api.RateLimiter, svc *shortenerpkg.Shortener, keyring *signing.Keyring) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		next, err := config.Load(path)
+		if err != nil {
+			logging.Errorf("❌ Config reload failed, keeping current config:\n%v", err)
+			continue
+		}
+		flags.apply(&next)
+
+		limiter.SetLimit(next.RateLimit.RequestsPerMinute, next.RateLimit.Burst)
+		svc.SetDenylist(next.ShortenerSettings.Denylist)
+		keyring.Set(next.Signing.Keys, next.Signing.DefaultTTL)
+		logging.SetLevel(next.Level())
+		logging.Infof("🔄 Config reloaded: rate limit %d/min (burst %d), %d denied hosts, %d signing keys, log level %s",
+			next.RateLimit.RequestsPerMinute, next.RateLimit.Burst,
+			len(next.ShortenerSettings.Denylist), len(next.Signing.Keys), next.LogLevel)
+
+		if current.NeedsRestart(next) {
+			logging.Warnf("⚠️  Config has changes beyond rate_limit, shortener.denylist, signing and log_level; restart to apply them")
+		}
+		current = next
+	}
+}
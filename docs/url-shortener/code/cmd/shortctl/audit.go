@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"time"
+
+	"urlshortener/internal/services/storage"
+)
+
+func (a *app) auditCmd(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	code := fs.String("code", "", "only changes to this short code")
+	actor := fs.String("actor", "", "only changes by this actor, e.g. key:<id> or cli:<user>")
+	action := fs.String("action", "", "only this action: create, retarget, edit, rollback, delete, disable, enable or import")
+	since := fs.String("since", "", "only changes after this time, as a duration ago (24h) or an RFC3339 time")
+	limit := fs.Int("limit", 50, "maximum number of events")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if a.audit == nil {
+		return errors.New("the audit log is off (AUDIT_SINK=off)")
+	}
+
+	filter := storage.AuditFilter{ShortCode: *code, Actor: *actor, Action: *action, Limit: *limit}
+	if *since != "" {
+		if d, err := time.ParseDuration(*since); err == nil {
+			filter.Since = time.Now().Add(-d)
+		} else if filter.Since, err = time.Parse(time.RFC3339, *since); err != nil {
+			return errors.New("-since must be a duration like 24h or an RFC3339 time")
+		}
+	}
+
+	events, err := a.audit.Query(ctx, filter)
+	if err != nil {
+		return err
+	}
+	return a.out.auditEvents(events)
+}
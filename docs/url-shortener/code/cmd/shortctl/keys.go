@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+)
+
+func (a *app) keysCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: keys needs a subcommand (create, list, revoke)", errUsage)
+	}
+	sub, rest := args[0], args[1:]
+
+	switch sub {
+	case "create":
+		name, err := oneArg(flag.NewFlagSet("keys create", flag.ContinueOnError), rest, "name")
+		if err != nil {
+			return err
+		}
+		token, key, err := a.keys.Create(ctx, name)
+		if err != nil {
+			return err
+		}
+		return a.out.newKey(token, key)
+	case "list":
+		keys, err := a.keys.List(ctx)
+		if err != nil {
+			return err
+		}
+		return a.out.keys(keys)
+	case "revoke":
+		id, err := oneArg(flag.NewFlagSet("keys revoke", flag.ContinueOnError), rest, "key id")
+		if err != nil {
+			return err
+		}
+		if err := a.keys.Revoke(ctx, id); err != nil {
+			return err
+		}
+		return a.out.message("revoked %s", id)
+	default:
+		return fmt.Errorf("%w: unknown keys subcommand %q", errUsage, sub)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/storage"
+	"urlshortener/internal/services/transfer"
+)
+
+func (a *app) create(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	rawURL := fs.String("url", "", "destination URL (required)")
+	alias := fs.String("alias", "", "custom short code")
+	expires := fs.String("expires", "", "expiry as a duration from now (24h) or an RFC3339 time")
+	activates := fs.String("activates", "", "go-live time as a duration from now (2h) or an RFC3339 time; until then visitors see a coming-soon page")
+	owner := fs.String("owner", "shortctl", "recorded as the link's creator")
+	title := fs.String("title", "", "optional title")
+	description := fs.String("description", "", "optional description")
+	tags := fs.String("tags", "", "comma-separated tags; use / for folders, e.g. launch/q3")
+	signed := fs.Bool("signed", false, "only resolve with a signature; see the sign command")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *rawURL == "" {
+		return fmt.Errorf("%w: create needs -url", errUsage)
+	}
+
+	expiresAt, err := parseTimeFlag("expires", *expires, time.Now())
+	if err != nil {
+		return err
+	}
+	activatesAt, err := parseTimeFlag("activates", *activates, time.Now())
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.svc.Shorten(ctx, shortenerpkg.ShortenRequest{
+		URL:       *rawURL,
+		Alias:     *alias,
+		ExpiresAt: expiresAt,
+		CreatedBy: *owner,
+		Signed:    *signed,
+
+		ActivatesAt: activatesAt,
+
+		Title:       *title,
+		Description: *description,
+		Tags:        splitTags(*tags),
+	})
+	if err != nil {
+		return err
+	}
+
+	entry, err := a.svc.Stats(ctx, resp.ShortCode)
+	if err != nil {
+		return err
+	}
+	return a.out.entries([]storage.Entry{entry})
+}
+
+func (a *app) lookup(ctx context.Context, args []string) error {
+	code, err := oneArg(flag.NewFlagSet("lookup", flag.ContinueOnError), args, "short code")
+	if err != nil {
+		return err
+	}
+	entry, err := a.svc.Stats(ctx, code)
+	if err != nil {
+		return err
+	}
+	return a.out.entries([]storage.Entry{entry})
+}
+
+func (a *app) stats(ctx context.Context, args []string) error {
+	code, err := oneArg(flag.NewFlagSet("stats", flag.ContinueOnError), args, "short code")
+	if err != nil {
+		return err
+	}
+	entry, err := a.svc.Stats(ctx, code)
+	if err != nil {
+		return err
+	}
+	return a.out.stats(entry, time.Now())
+}
+
+func (a *app) list(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	owner := fs.String("owner", "", "only links created by this owner")
+	limit := fs.Int("limit", 50, "maximum number of links (0 for all)")
+	offset := fs.Int("offset", 0, "number of links to skip")
+	query := fs.String("q", "", "only links whose title, description, URL, code or tags contain every word")
+	tag := fs.String("tag", "", "only links with this tag or in this folder")
+	dead := fs.Bool("dead", false, "only links whose last health check failed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *tag != "" {
+		var err error
+		if *tag, err = shortenerpkg.NormalizeTag(*tag); err != nil {
+			return err
+		}
+	}
+
+	entries, err := a.svc.List(ctx, storage.ListOptions{
+		CreatedBy: *owner,
+		Limit:     *limit,
+		Offset:    *offset,
+		Query:     *query,
+		Tag:       *tag,
+		Dead:      *dead,
+	})
+	if err != nil {
+		return err
+	}
+	return a.out.entries(entries)
+}
+
+// sign prints a fresh signed path for a signed link.
+func (a *app) sign(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ContinueOnError)
+	expires := fs.String("expires", "", "signature expiry as a duration from now (24h) or an RFC3339 time; default from config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: sign expects a short code", errUsage)
+	}
+	expiresAt, err := parseTimeFlag("expires", *expires, time.Now())
+	if err != nil {
+		return err
+	}
+	sig, err := a.svc.Sign(ctx, fs.Arg(0), expiresAt)
+	if err != nil {
+		return err
+	}
+	return a.out.signature(fs.Arg(0), sig)
+}
+
+func (a *app) retarget(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("retarget", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("%w: retarget expects a short code and a url", errUsage)
+	}
+	entry, err := a.svc.Retarget(ctx, fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	return a.out.entries([]storage.Entry{entry})
+}
+
+// history lists the versions of a link, newest first.
+func (a *app) history(ctx context.Context, args []string) error {
+	code, err := oneArg(flag.NewFlagSet("history", flag.ContinueOnError), args, "short code")
+	if err != nil {
+		return err
+	}
+	versions, err := a.svc.History(ctx, code)
+	if err != nil {
+		return err
+	}
+	return a.out.versions(versions)
+}
+
+// rollback restores a link to one of its versions.
+func (a *app) rollback(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("%w: rollback expects a short code and a version", errUsage)
+	}
+	version, err := strconv.Atoi(fs.Arg(1))
+	if err != nil || version < 1 {
+		return fmt.Errorf("%w: version must be a positive integer", errUsage)
+	}
+	entry, err := a.svc.Rollback(ctx, fs.Arg(0), version)
+	if err != nil {
+		return err
+	}
+	return a.out.entries([]storage.Entry{entry})
+}
+
+// edit changes the details of a link; flags that are not given are left as
+// they are, and -tags "" clears the tags.
+func (a *app) edit(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("edit", flag.ContinueOnError)
+	title := fs.String("title", "", "new title")
+	description := fs.String("description", "", "new description")
+	tags := fs.String("tags", "", "new comma-separated tags")
+	code, err := oneArg(fs, args, "short code")
+	if err != nil {
+		return err
+	}
+
+	var changes shortenerpkg.LinkChanges
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "title":
+			changes.Title = title
+		case "description":
+			changes.Description = description
+		case "tags":
+			list := splitTags(*tags)
+			changes.Tags = &list
+		}
+	})
+	entry, err := a.svc.Edit(ctx, code, changes)
+	if err != nil {
+		return err
+	}
+	return a.out.entries([]storage.Entry{entry})
+}
+
+// splitTags splits a comma-separated -tags value, dropping empty items.
+func splitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func (a *app) delete(ctx context.Context, args []string) error {
+	code, err := oneArg(flag.NewFlagSet("delete", flag.ContinueOnError), args, "short code")
+	if err != nil {
+		return err
+	}
+	if err := a.svc.Delete(ctx, code); err != nil {
+		return err
+	}
+	return a.out.message("deleted %s", code)
+}
+
+func (a *app) export(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	path := fs.String("file", "-", "output file, - for stdout")
+	formatName := fs.String("format", "json", "csv, json or ndjson")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	format, err := transfer.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	w := a.stdout
+	if *path != "-" {
+		f, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	n, err := a.svc.Export(ctx, w, format)
+	if err != nil {
+		return err
+	}
+	if *path != "-" {
+		return a.out.message("exported %d links to %s", n, *path)
+	}
+	return nil
+}
+
+func (a *app) importLinks(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	path := fs.String("file", "-", "input file, - for stdin")
+	formatName := fs.String("format", "json", "csv, json or ndjson (csv also reads Bitly-style exports)")
+	policyName := fs.String("policy", "skip", "on existing codes: skip, overwrite or fail")
+	dryRun := fs.Bool("dry-run", false, "report what would happen without writing")
+	owner := fs.String("owner", "import", "creator recorded for rows without one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	format, err := transfer.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	policy, err := transfer.ParsePolicy(*policyName)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = a.stdin
+	if *path != "-" {
+		f, err := os.Open(*path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	report, importErr := a.svc.Import(ctx, r, transfer.ImportOptions{
+		Format:       format,
+		Policy:       policy,
+		DryRun:       *dryRun,
+		DefaultOwner: *owner,
+	})
+	if err := a.out.importReport(report); err != nil {
+		return err
+	}
+	return importErr
+}
+
+// parseTimeFlag reads the time flag name: "" (unset), a Go duration relative
+// to now, or RFC3339.
+func parseTimeFlag(name, s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: -%s must be a duration like 24h or an RFC3339 time", errUsage, name)
+	}
+	return t, nil
+}
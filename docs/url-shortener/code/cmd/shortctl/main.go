@@ -0,0 +1,193 @@
+// Command shortctl manages short links and API keys from the command line.
+// It reads the same environment, .env and config file as cmd/server and talks to
+// the configured store directly, so it works even when the server is down.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+
+	"urlshortener/internal/config"
+	"urlshortener/internal/services/apikey"
+	"urlshortener/internal/services/audit"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/signing"
+	"urlshortener/internal/services/storage"
+)
+
+const usage = `Usage: shortctl [-o table|json] [-config FILE] <command> [flags] [args]
+
+Link commands:
+  create  -url URL [-alias CODE] [-expires 24h|RFC3339] [-activates 2h|RFC3339]
+          [-owner NAME] [-title T] [-description D] [-tags a,b/c] [-signed]
+  lookup  CODE                 show a link without counting a hit
+  stats   CODE                 hit count, age and expiry of a link
+  list    [-owner NAME] [-q WORDS] [-tag TAG] [-dead] [-limit N] [-offset N]
+  sign    CODE [-expires 24h|RFC3339]  print a signed path for a signed link
+  retarget CODE URL            point a link at a new destination
+  edit    CODE [-title T] [-description D] [-tags a,b/c]
+  history CODE                 versions of a link, newest first
+  rollback CODE VERSION        restore a link's URL and details from a version
+  delete  CODE
+  export  [-file PATH] [-format csv|json|ndjson]
+  import  [-file PATH] [-format csv|json|ndjson] [-policy skip|overwrite|fail] [-dry-run]
+
+Audit commands:
+  audit   [-code CODE] [-actor ACTOR] [-action ACTION] [-since T] [-limit N]
+
+API key commands:
+  keys create NAME             prints the token once; store it safely
+  keys list
+  keys revoke ID
+
+Global flags:
+`
+
+// errUsage marks mistakes in the command line itself.
+var errUsage = errors.New("invalid usage")
+
+type app struct {
+	svc    *shortenerpkg.Shortener
+	audit  *audit.Log // nil when AUDIT_SINK=off
+	keys   *apikey.Manager
+	store  storage.Backend
+	out    printer
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+func main() {
+	global := flag.NewFlagSet("shortctl", flag.ExitOnError)
+	output := global.String("o", "table", "output format: table or json")
+	configPath := global.String("config", "", "YAML, TOML or JSON config file (or set CONFIG_FILE)")
+	global.Usage = func() {
+		fmt.Fprint(global.Output(), usage)
+		global.PrintDefaults()
+	}
+	_ = global.Parse(os.Args[1:])
+
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "unknown output format %q\n", *output)
+		os.Exit(2)
+	}
+	if global.NArg() == 0 {
+		global.Usage()
+		os.Exit(2)
+	}
+
+	err := run(global.Args(), *output, *configPath)
+	if errors.Is(err, errUsage) {
+		fmt.Fprintln(os.Stderr, err)
+		global.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shortctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, output, configPath string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+
+	store, closeStore, err := config.OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	auditLog, closeAudit, err := config.OpenAuditLog(cfg, store)
+	if err != nil {
+		return err
+	}
+	defer closeAudit()
+
+	svcOpts := []shortenerpkg.Option{
+		shortenerpkg.WithKeyring(signing.NewKeyring(cfg.Signing.Keys, cfg.Signing.DefaultTTL)),
+		shortenerpkg.WithHistory(store),
+	}
+	if auditLog != nil {
+		svcOpts = append(svcOpts, shortenerpkg.WithAuditLog(auditLog))
+	}
+	a := &app{
+		svc: shortenerpkg.NewShortener(
+			shortenerpkg.NewRandomCodeGenerator(cfg.ShortenerSettings.CodeLength),
+			store,
+			cfg.ShortenerSettings,
+			svcOpts...,
+		),
+		audit:  auditLog,
+		keys:   apikey.NewManager(store),
+		store:  store,
+		out:    printer{w: os.Stdout, json: output == "json"},
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+	}
+	return a.dispatch(audit.WithActor(context.Background(), cliActor()), args)
+}
+
+// cliActor names the local user in the audit trail, e.g. "cli:alice".
+func cliActor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return "cli:" + u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return "cli:" + name
+	}
+	return "cli"
+}
+
+func (a *app) dispatch(ctx context.Context, args []string) error {
+	cmd, rest := args[0], args[1:]
+	switch cmd {
+	case "create":
+		return a.create(ctx, rest)
+	case "lookup":
+		return a.lookup(ctx, rest)
+	case "stats":
+		return a.stats(ctx, rest)
+	case "list":
+		return a.list(ctx, rest)
+	case "sign":
+		return a.sign(ctx, rest)
+	case "retarget":
+		return a.retarget(ctx, rest)
+	case "edit":
+		return a.edit(ctx, rest)
+	case "history":
+		return a.history(ctx, rest)
+	case "rollback":
+		return a.rollback(ctx, rest)
+	case "delete":
+		return a.delete(ctx, rest)
+	case "export":
+		return a.export(ctx, rest)
+	case "import":
+		return a.importLinks(ctx, rest)
+	case "audit":
+		return a.auditCmd(ctx, rest)
+	case "keys":
+		return a.keysCmd(ctx, rest)
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, cmd)
+	}
+}
+
+// oneArg returns the single positional argument of a command.
+func oneArg(fs *flag.FlagSet, args []string, name string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("%w: %s expects exactly one %s", errUsage, fs.Name(), name)
+	}
+	return fs.Arg(0), nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"urlshortener/internal/services/signing"
+	"urlshortener/internal/services/storage"
+	"urlshortener/internal/services/transfer"
+)
+
+// printer renders results either as aligned tables for humans or as JSON for
+// scripts (-o json).
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+type entryJSON struct {
+	ShortCode   string    `json:"short_code"`
+	OriginalURL string    `json:"original_url"`
+	CreatedAt   time.Time `json:"created_at"`
+	CreatedBy   string    `json:"created_by"`
+	HitCount    int64     `json:"hit_count"`
+	ExpiresAt   time.Time `json:"expires_at,omitzero"`
+	ActivatesAt time.Time `json:"activates_at,omitzero"`
+	Signed      bool      `json:"signed,omitempty"`
+
+	Disabled       bool   `json:"disabled,omitempty"`
+	DisabledReason string `json:"disabled_reason,omitempty"`
+
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+
+	CheckedAt   time.Time `json:"checked_at,omitzero"`
+	CheckStatus int       `json:"check_status,omitempty"`
+	CheckError  string    `json:"check_error,omitempty"`
+}
+
+type keyJSON struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	RevokedAt time.Time `json:"revoked_at,omitzero"`
+	Token     string    `json:"token,omitempty"`
+}
+
+func (p printer) encode(v any) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (p printer) entries(entries []storage.Entry) error {
+	if p.json {
+		out := make([]entryJSON, len(entries))
+		for i, e := range entries {
+			out[i] = entryJSON(e)
+		}
+		return p.encode(out)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CODE\tURL\tHITS\tCREATED\tBY\tEXPIRES\tTAGS")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			e.ShortCode, e.OriginalURL, e.HitCount, formatTime(e.CreatedAt), e.CreatedBy, formatTime(e.ExpiresAt), formatTags(e.Tags))
+	}
+	return tw.Flush()
+}
+
+func (p printer) stats(e storage.Entry, now time.Time) error {
+	if p.json {
+		return p.encode(struct {
+			entryJSON
+			Expired bool `json:"expired"`
+		}{entryJSON(e), e.Expired(now)})
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Code:\t%s\n", e.ShortCode)
+	if e.Title != "" {
+		fmt.Fprintf(tw, "Title:\t%s\n", e.Title)
+	}
+	fmt.Fprintf(tw, "URL:\t%s\n", e.OriginalURL)
+	if e.Description != "" {
+		fmt.Fprintf(tw, "Description:\t%s\n", e.Description)
+	}
+	fmt.Fprintf(tw, "Tags:\t%s\n", formatTags(e.Tags))
+	fmt.Fprintf(tw, "Hits:\t%d\n", e.HitCount)
+	fmt.Fprintf(tw, "Created:\t%s (%s ago)\n", formatTime(e.CreatedAt), now.Sub(e.CreatedAt).Round(time.Second))
+	fmt.Fprintf(tw, "Created by:\t%s\n", e.CreatedBy)
+	fmt.Fprintf(tw, "Expires:\t%s\n", formatTime(e.ExpiresAt))
+	if !e.ActivatesAt.IsZero() {
+		fmt.Fprintf(tw, "Activates:\t%s\n", formatTime(e.ActivatesAt))
+	}
+	fmt.Fprintf(tw, "Expired:\t%t\n", e.Expired(now))
+	if e.Signed {
+		fmt.Fprintf(tw, "Signed:\tyes (redirects need a signature)\n")
+	}
+	if e.Disabled {
+		fmt.Fprintf(tw, "Disabled:\t%s\n", e.DisabledReason)
+	}
+	if !e.CheckedAt.IsZero() {
+		fmt.Fprintf(tw, "Checked:\t%s (%s)\n", formatTime(e.CheckedAt), formatCheck(e))
+	}
+	return tw.Flush()
+}
+
+func (p printer) keys(keys []storage.APIKey) error {
+	if p.json {
+		out := make([]keyJSON, len(keys))
+		for i, k := range keys {
+			out[i] = keyJSON{ID: k.ID, Name: k.Name, CreatedAt: k.CreatedAt, RevokedAt: k.RevokedAt}
+		}
+		return p.encode(out)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tCREATED\tREVOKED")
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", k.ID, k.Name, formatTime(k.CreatedAt), formatTime(k.RevokedAt))
+	}
+	return tw.Flush()
+}
+
+func (p printer) newKey(token string, k storage.APIKey) error {
+	if p.json {
+		return p.encode(keyJSON{ID: k.ID, Name: k.Name, CreatedAt: k.CreatedAt, Token: token})
+	}
+	fmt.Fprintf(p.w, "Created key %s (%s)\n", k.ID, k.Name)
+	fmt.Fprintf(p.w, "Token: %s\n", token)
+	fmt.Fprintln(p.w, "This token is shown only once.")
+	return nil
+}
+
+// signature prints the path a signed link is shared under; the server's
+// base URL goes in front of it.
+func (p printer) signature(code string, sig signing.Signature) error {
+	path := "/" + code + "?" + sig.Query()
+	if p.json {
+		return p.encode(map[string]string{"path": path, "expires_at": sig.Expires.Format(time.RFC3339)})
+	}
+	fmt.Fprintf(p.w, "Path: %s\n", path)
+	fmt.Fprintf(p.w, "Expires: %s\n", formatTime(sig.Expires))
+	return nil
+}
+
+func (p printer) importReport(r transfer.Report) error {
+	if p.json {
+		return p.encode(r)
+	}
+
+	prefix := ""
+	if r.DryRun {
+		prefix = "(dry run) "
+	}
+	fmt.Fprintf(p.w, "%s%d rows: %d created, %d overwritten, %d skipped, %d failed\n",
+		prefix, r.Total, r.Created, r.Overwritten, r.Skipped, r.Failed)
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROW\tCODE\tERROR")
+	for _, e := range r.Errors {
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", e.Row, e.ShortCode, e.Error)
+	}
+	return tw.Flush()
+}
+
+type auditEventJSON struct {
+	ID        string          `json:"id"`
+	At        time.Time       `json:"at"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	ShortCode string          `json:"short_code"`
+	RequestID string          `json:"request_id,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+}
+
+func (p printer) auditEvents(events []storage.AuditEvent) error {
+	if p.json {
+		out := make([]auditEventJSON, len(events))
+		for i, e := range events {
+			out[i] = auditEventJSON(e)
+		}
+		return p.encode(out)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tACTOR\tACTION\tCODE\tURL")
+	for _, e := range events {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", formatTime(e.At), e.Actor, e.Action, e.ShortCode, auditURL(e))
+	}
+	return tw.Flush()
+}
+
+// auditURL sums up what happened to the destination in one column.
+func auditURL(e storage.AuditEvent) string {
+	var before, after struct {
+		OriginalURL string `json:"original_url"`
+	}
+	_ = json.Unmarshal(e.Before, &before)
+	_ = json.Unmarshal(e.After, &after)
+	switch {
+	case before.OriginalURL == "":
+		return after.OriginalURL
+	case after.OriginalURL == "" || before.OriginalURL == after.OriginalURL:
+		return before.OriginalURL
+	default:
+		return before.OriginalURL + " -> " + after.OriginalURL
+	}
+}
+
+type versionJSON struct {
+	ShortCode      string    `json:"short_code"`
+	Version        int       `json:"version"`
+	At             time.Time `json:"at"`
+	Actor          string    `json:"actor,omitempty"`
+	Action         string    `json:"action"`
+	OriginalURL    string    `json:"original_url"`
+	ExpiresAt      time.Time `json:"expires_at,omitzero"`
+	ActivatesAt    time.Time `json:"activates_at,omitzero"`
+	Disabled       bool      `json:"disabled,omitempty"`
+	DisabledReason string    `json:"disabled_reason,omitempty"`
+	Title          string    `json:"title,omitempty"`
+	Description    string    `json:"description,omitempty"`
+	Tags           []string  `json:"tags,omitempty"`
+}
+
+func (p printer) versions(versions []storage.LinkVersion) error {
+	if p.json {
+		out := make([]versionJSON, len(versions))
+		for i, v := range versions {
+			out[i] = versionJSON(v)
+		}
+		return p.encode(out)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tTIME\tACTOR\tACTION\tURL\tTITLE\tTAGS")
+	for _, v := range versions {
+		actor := v.Actor
+		if actor == "" {
+			actor = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			v.Version, formatTime(v.At), actor, v.Action, v.OriginalURL, v.Title, formatTags(v.Tags))
+	}
+	return tw.Flush()
+}
+
+func (p printer) message(format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	if p.json {
+		return p.encode(map[string]string{"message": msg})
+	}
+	_, err := fmt.Fprintln(p.w, msg)
+	return err
+}
+
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return "-"
+	}
+	return strings.Join(tags, ",")
+}
+
+// formatCheck describes the last health check, e.g. "dead: status 404".
+func formatCheck(e storage.Entry) string {
+	state := "ok"
+	if e.Dead() {
+		state = "dead"
+	}
+	if e.CheckError != "" {
+		return state + ": " + e.CheckError
+	}
+	return fmt.Sprintf("%s: status %d", state, e.CheckStatus)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/audit"
+	"urlshortener/internal/services/storage"
+)
+
+// requestIDHeader carries the request ID in both directions, so a caller's
+// own ID ends up in the audit trail.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds caller-supplied request IDs.
+const maxRequestIDLen = 128
+
+// Paging of GET /api/audit.
+const (
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 1000
+)
+
+// auditEventJSON is the wire shape of a storage.AuditEvent.
+type auditEventJSON struct {
+	ID        string          `json:"id"`
+	At        time.Time       `json:"at"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	ShortCode string          `json:"short_code"`
+	RequestID string          `json:"request_id,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+}
+
+// auditContext tags every request with an ID (the caller's X-Request-ID if
+// it is usable) and a default actor, the client IP. apiKeyMiddleware
+// replaces the actor once it knows the key.
+func auditContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		ctx := audit.WithRequestID(r.Context(), id)
+		ctx = audit.WithActor(ctx, clientKey(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func auditHandler(log *audit.Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		filter := storage.AuditFilter{
+			ShortCode: q.Get("code"),
+			Actor:     q.Get("actor"),
+			Action:    q.Get("action"),
+			Limit:     defaultAuditPageSize,
+		}
+		if filter.Action != "" && !slices.Contains(audit.Actions, filter.Action) {
+			http.Error(w, "unknown action", http.StatusBadRequest)
+			return
+		}
+		for name, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+			if raw := q.Get(name); raw != "" {
+				t, err := time.Parse(time.RFC3339, raw)
+				if err != nil {
+					http.Error(w, name+" must be an RFC 3339 time", http.StatusBadRequest)
+					return
+				}
+				*dst = t
+			}
+		}
+		if raw := q.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > maxAuditPageSize {
+				http.Error(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+				return
+			}
+			filter.Limit = n
+		}
+		if raw := q.Get("offset"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			filter.Offset = n
+		}
+
+		events, err := log.Query(r.Context(), filter)
+		if err != nil {
+			logging.Errorf("❌ Failed to query audit log: %v", err)
+			http.Error(w, "failed to query audit log", http.StatusInternalServerError)
+			return
+		}
+		out := make([]auditEventJSON, len(events))
+		for i, e := range events {
+			out[i] = auditEventJSON(e)
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"urlshortener/internal/services/apikey"
+	"urlshortener/internal/services/audit"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/storage"
+)
+
+func TestAuditTrail(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	adminToken, admin, _ := keys.Create(ctx, "compliance")
+	userToken, user, _ := keys.Create(ctx, "marketing")
+	log := audit.New(store)
+	shortener := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings(), shortenerpkg.WithAuditLog(log))
+	router := NewRouter(shortener, WithAPIKeys(keys, false), WithAudit(log), WithAdmins([]string{admin.ID}))
+
+	do := func(method, target, body, token, requestID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/shorten", `{"url":"https://example.com/q3","alias":"q3-launch"}`, userToken, "job-7")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("X-Request-ID"); got != "job-7" {
+		t.Fatalf("expected the caller's request ID to be echoed, got %q", got)
+	}
+	if rec := do(http.MethodPatch, "/api/links/q3-launch", `{"url":"https://example.com/q3-final"}`, userToken, "bad id\x7f"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a retarget, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPatch, "/api/links/q3-launch", `{"url":"https://example.com/hijack"}`, adminToken, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for another key's link, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/shorten", `{"url":"https://example.com/anon"}`, "", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	if rec := do(http.MethodGet, "/api/audit", "", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a key, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/audit", "", userToken, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a non-admin key, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/audit?action=rename", "", adminToken, ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown action, got %d", rec.Code)
+	}
+
+	rec = do(http.MethodGet, "/api/audit?code=q3-launch", "", adminToken, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var events []auditEventJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &events); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected create and retarget, got %+v", events)
+	}
+	retarget, create := events[0], events[1]
+	if create.Action != audit.ActionCreate || create.Actor != "key:"+user.ID || create.RequestID != "job-7" || create.Before != nil {
+		t.Fatalf("unexpected create event: %+v", create)
+	}
+	if retarget.Action != audit.ActionRetarget || retarget.RequestID == "" || retarget.RequestID == "bad id\x7f" {
+		t.Fatalf("unexpected retarget event: %+v", retarget)
+	}
+	if !strings.Contains(string(retarget.Before), "/q3\"") || !strings.Contains(string(retarget.After), "/q3-final") {
+		t.Fatalf("expected before/after destinations, got %s -> %s", retarget.Before, retarget.After)
+	}
+
+	rec = do(http.MethodGet, "/api/audit?action=create&limit=1", "", adminToken, "")
+	events = nil
+	_ = json.Unmarshal(rec.Body.Bytes(), &events)
+	if len(events) != 1 || events[0].ShortCode != "stub123" || !strings.HasPrefix(events[0].Actor, "ip:") {
+		t.Fatalf("expected the anonymous create by IP, got %+v", events)
+	}
+}
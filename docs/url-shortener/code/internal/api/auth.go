@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/apikey"
+	"urlshortener/internal/services/audit"
+	"urlshortener/internal/services/storage"
+)
+
+type contextKey int
+
+const (
+	apiKeyContextKey contextKey = iota
+	clientIPContextKey
+)
+
+// apiKeyFromContext returns the key that authenticated the request, if any.
+func apiKeyFromContext(ctx context.Context) (storage.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey).(storage.APIKey)
+	return key, ok
+}
+
+// ownerFromContext is the CreatedBy value for links made by this request.
+func ownerFromContext(ctx context.Context) string {
+	if key, ok := apiKeyFromContext(ctx); ok {
+		return keyOwner(key)
+	}
+	return ""
+}
+
+// keyOwner is the CreatedBy value for links made with key.
+func keyOwner(key storage.APIKey) string {
+	return "key:" + key.ID
+}
+
+// tokenFromRequest accepts "Authorization: Bearer <token>" or "X-API-Key".
+func tokenFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+func apiKeyMiddleware(mgr *apikey.Manager, required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := tokenFromRequest(r)
+			if token == "" {
+				if required {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+					http.Error(w, "api key required", http.StatusUnauthorized)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := mgr.Authenticate(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, apikey.ErrInvalidKey) || errors.Is(err, apikey.ErrRevokedKey) {
+					logging.Warnf("⚠️  Rejected api key: %v", err)
+					w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				logging.Errorf("❌ Failed to check api key: %v", err)
+				http.Error(w, "failed to check api key", http.StatusInternalServerError)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+			ctx = audit.WithActor(ctx, keyOwner(key))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// requireAdmin lets through only requests authenticated with one of the
+// admin key IDs.
+func requireAdmin(admins map[string]struct{}) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := apiKeyFromContext(r.Context())
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				http.Error(w, "api key required", http.StatusUnauthorized)
+				return
+			}
+			if _, ok := admins[key.ID]; !ok {
+				logging.Warnf("⚠️  Key %s tried to use an admin endpoint", key.ID)
+				http.Error(w, "admin api key required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies parses proxy addresses and CIDR ranges, e.g.
+// "10.0.0.0/8" or "192.0.2.10", for WithTrustedProxies.
+func ParseTrustedProxies(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, s := range list {
+		if prefix, err := netip.ParsePrefix(s); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q is neither an IP address nor a CIDR range", s)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+// trustedProxies are the peers whose forwarding headers are believed.
+type trustedProxies []netip.Prefix
+
+func (p trustedProxies) contains(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// realClientIP finds the client behind a trusted proxy, for clientKey. A
+// request from any other peer keeps its own address, so clients cannot pick
+// their IP by sending the headers themselves.
+func realClientIP(proxies trustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := proxies.forwardedFor(r); ip != "" {
+				r = r.WithContext(context.WithValue(r.Context(), clientIPContextKey, ip))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedFor returns the client address the proxies report, or "" when
+// the peer is not a trusted proxy or reports none. X-Forwarded-For is read
+// from the right, skipping trusted hops, since everything left of the first
+// untrusted hop could have been sent by the client.
+func (p trustedProxies) forwardedFor(r *http.Request) string {
+	if !p.contains(remoteIP(r)) {
+		return ""
+	}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if _, err := netip.ParseAddr(hop); err != nil {
+				return ""
+			}
+			if !p.contains(hop) {
+				return hop
+			}
+		}
+		return ""
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		if _, err := netip.ParseAddr(ip); err == nil {
+			return ip
+		}
+	}
+	return ""
+}
+
+// clientIP is the address clientKey uses: the one realClientIP found, or
+// the peer's own.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"net/http"
+
+	"urlshortener/internal/logging"
+)
+
+// csrfCookie holds the token the dashboard's forms echo back in csrfField.
+// A form on another site can make the browser send the cookie along, but
+// cannot read it to fill in the field.
+const (
+	csrfCookie = "usk_csrf"
+	csrfField  = "csrf_token"
+)
+
+type csrfTokenKey struct{}
+
+// csrfProtect gives browsers without a token one, and refuses unsafe
+// requests whose form does not carry the token from their cookie.
+func csrfProtect(cookies cookiePolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var token string
+			if cookie, err := r.Cookie(csrfCookie); err == nil {
+				token = cookie.Value
+			}
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				if token == "" {
+					token = rand.Text()
+					cookies.set(w, r, &http.Cookie{Name: csrfCookie, Value: token, Path: "/"})
+				}
+			default:
+				sent := r.PostFormValue(csrfField)
+				if token == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+					logging.Warnf("⚠️  Rejected %s %s without a valid CSRF token", r.Method, r.URL.Path)
+					http.Error(w, "invalid or missing CSRF token; reload the page and try again", http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfTokenKey{}, token)))
+		})
+	}
+}
+
+// csrfToken is the token forms on the page for r must carry, if any.
+func csrfToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfTokenKey{}).(string)
+	return token
+}
@@ -0,0 +1,514 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/apikey"
+	"urlshortener/internal/services/audit"
+	"urlshortener/internal/services/moderation"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/signing"
+	"urlshortener/internal/services/storage"
+	"urlshortener/ui"
+
+	"github.com/go-chi/chi/v5"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// sessionCookie carries the session token a browser got by signing in with
+// an API key; the key itself stays out of the browser. Only the UI handlers
+// read it; /api takes keys from headers only.
+const sessionCookie = "usk_session"
+
+// DefaultSessionTTL is how long a dashboard sign-in lasts unless
+// WithSessions says otherwise.
+const DefaultSessionTTL = 12 * time.Hour
+
+// flashCookie holds a one-shot error message to show after a redirect.
+const flashCookie = "usk_flash"
+
+// dashboardPageSize is how many links one dashboard page lists.
+const dashboardPageSize = 50
+
+// qrSize is the edge length of generated QR codes, in pixels.
+const qrSize = 256
+
+// dashboard serves the server-rendered web UI.
+type dashboard struct {
+	svc  *shortenerpkg.Shortener
+	keys *apikey.Manager
+	ui   *ui.UI
+	// fallbackURL is where visitors of links that are not live yet go
+	// instead of the coming-soon page, if set.
+	fallbackURL string
+	cookies     cookiePolicy
+	sessionTTL  time.Duration
+}
+
+func newDashboard(svc *shortenerpkg.Shortener, keys *apikey.Manager, u *ui.UI, fallbackURL string, cookies cookiePolicy, sessionTTL time.Duration) *dashboard {
+	return &dashboard{svc: svc, keys: keys, ui: u, fallbackURL: fallbackURL, cookies: cookies, sessionTTL: sessionTTL}
+}
+
+// cookiePolicy sets the UI's cookies, all of which are for the server only.
+type cookiePolicy struct {
+	// secure marks cookies Secure even on plain HTTP requests, for servers
+	// behind a proxy that terminates TLS.
+	secure bool
+}
+
+func (p cookiePolicy) set(w http.ResponseWriter, r *http.Request, cookie *http.Cookie) {
+	cookie.HttpOnly = true
+	cookie.Secure = p.secure || r.TLS != nil
+	cookie.SameSite = http.SameSiteLaxMode
+	http.SetCookie(w, cookie)
+}
+
+func (d *dashboard) routes(r chi.Router) {
+	r.Get("/", d.list)
+	r.Post("/login", d.login)
+	r.Post("/logout", d.logout)
+	r.Post("/shorten", d.shorten)
+	r.Get("/links/{shortCode}", d.link)
+	r.Get("/links/{shortCode}/qr.png", d.qr)
+	r.Post("/links/{shortCode}/delete", d.delete)
+}
+
+// pageData is what every template receives.
+type pageData struct {
+	KeyName  string
+	Error    string
+	Created  *linkView
+	Links    []linkView
+	Link     linkView
+	PrevPage int
+	NextPage int
+	Query    string // the dashboard search, kept across pages
+	Tag      string
+	// MarkBroken shows a warning on the preview page when Link is dead.
+	MarkBroken bool
+	// CSRFToken goes into every form that posts; see csrfProtect.
+	CSRFToken string
+}
+
+// linkView is a link as the templates show it.
+type linkView struct {
+	Code        string
+	ShortURL    string
+	OriginalURL string
+	Hits        int64
+	HitsPerDay  float64
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	Expired     bool
+	ActivatesAt time.Time
+	Pending     bool // not live until ActivatesAt
+	Signed      bool // ShortURL then carries a fresh signature
+	Disabled    bool
+	Reason      string // why moderators disabled the link
+	Legal       bool   // disabled for legal reasons
+	Title       string
+	Description string
+	Tags        []string
+	CheckedAt   time.Time // last destination health check, zero if none yet
+	CheckStatus int
+	CheckError  string
+	Dead        bool
+}
+
+// newLinkView describes entry as of now.
+func newLinkView(r *http.Request, entry storage.Entry, now time.Time) linkView {
+	days := max(now.Sub(entry.CreatedAt).Hours()/24, 1)
+	return linkView{
+		Code:        entry.ShortCode,
+		ShortURL:    shortURL(r, entry.ShortCode),
+		OriginalURL: entry.OriginalURL,
+		Hits:        entry.HitCount,
+		HitsPerDay:  float64(entry.HitCount) / days,
+		CreatedAt:   entry.CreatedAt,
+		ExpiresAt:   entry.ExpiresAt,
+		Expired:     entry.Expired(now),
+		ActivatesAt: entry.ActivatesAt,
+		Pending:     entry.Pending(now),
+		Signed:      entry.Signed,
+		Disabled:    entry.Disabled,
+		Reason:      entry.DisabledReason,
+		Legal:       entry.DisabledReason == moderation.ReasonLegal,
+		Title:       entry.Title,
+		Description: entry.Description,
+		Tags:        entry.Tags,
+		CheckedAt:   entry.CheckedAt,
+		CheckStatus: entry.CheckStatus,
+		CheckError:  entry.CheckError,
+		Dead:        entry.Dead(),
+	}
+}
+
+// view is newLinkView with a freshly signed ShortURL for signed links, so
+// the copy button and QR code lead somewhere.
+func (d *dashboard) view(r *http.Request, entry storage.Entry) linkView {
+	v := newLinkView(r, entry, d.svc.Now())
+	if entry.Signed {
+		if sig, err := d.svc.SignEntry(entry, time.Time{}); err == nil {
+			v.ShortURL = signedURL(r, entry.ShortCode, sig)
+		}
+	}
+	return v
+}
+
+// shortURL is the public address of code, as seen by the client of r.
+func shortURL(r *http.Request, code string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/" + url.PathEscape(code)
+}
+
+// signedURL is shortURL with sig's query, the only address a signed link
+// resolves at.
+func signedURL(r *http.Request, code string, sig signing.Signature) string {
+	return shortURL(r, code) + "?" + sig.Query()
+}
+
+// session returns the API key the browser signed in with, if its session is
+// still valid.
+func (d *dashboard) session(r *http.Request) (storage.APIKey, bool) {
+	if d.keys == nil {
+		return storage.APIKey{}, false
+	}
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil || cookie.Value == "" {
+		return storage.APIKey{}, false
+	}
+	key, err := d.keys.Session(r.Context(), cookie.Value)
+	if err != nil {
+		if !errors.Is(err, apikey.ErrInvalidSession) && !errors.Is(err, apikey.ErrRevokedKey) {
+			logging.Errorf("❌ Failed to look up dashboard session: %v", err)
+		}
+		return storage.APIKey{}, false
+	}
+	return key, true
+}
+
+func (d *dashboard) render(w http.ResponseWriter, r *http.Request, status int, page string, data pageData) {
+	data.CSRFToken = csrfToken(r)
+	// Render to a buffer so a template error doesn't leave a half-written page.
+	var buf bytes.Buffer
+	tmpl, err := d.ui.Page(page)
+	if err == nil {
+		err = tmpl.ExecuteTemplate(&buf, "layout", data)
+	}
+	if err != nil {
+		logging.Errorf("❌ Failed to render %s: %v", page, err)
+		http.Error(w, "failed to render page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+}
+
+// disabled answers a visit to a link moderators took down.
+func (d *dashboard) disabled(w http.ResponseWriter, r *http.Request, entry storage.Entry) {
+	d.render(w, r, disabledStatus(entry.DisabledReason), "disabled.html", pageData{Link: linkView{
+		Code:     entry.ShortCode,
+		Disabled: true,
+		Reason:   entry.DisabledReason,
+		Legal:    entry.DisabledReason == moderation.ReasonLegal,
+	}})
+}
+
+// comingSoon answers a visit to a link before its activation time, with the
+// fallback URL if there is one. Neither may be cached past the launch.
+func (d *dashboard) comingSoon(w http.ResponseWriter, r *http.Request, entry storage.Entry) {
+	w.Header().Set("Cache-Control", "no-store")
+	if d.fallbackURL != "" {
+		http.Redirect(w, r, d.fallbackURL, http.StatusFound)
+		return
+	}
+	d.render(w, r, http.StatusOK, "soon.html", pageData{Link: linkView{
+		Code:        entry.ShortCode,
+		Title:       entry.Title,
+		ActivatesAt: entry.ActivatesAt,
+		Pending:     true,
+	}})
+}
+
+// preview shows where a link goes without following it or counting a hit.
+// With markBroken, it also warns when the destination looked dead at the last
+// health check. Signed links need their signature here too, and links that
+// are not live yet keep their destination to themselves.
+func (d *dashboard) preview(markBroken bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := chi.URLParam(r, "shortCode")
+		sig, signed := signing.FromQuery(r.URL.Query())
+		if signed {
+			if err := d.svc.VerifySignature(code, sig); errors.Is(err, signing.ErrExpired) {
+				http.Error(w, "signed link has expired", http.StatusGone)
+				return
+			} else if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		entry, err := d.svc.Stats(r.Context(), code)
+		if err == nil && entry.Signed && !signed {
+			err = storage.ErrNotFound
+		}
+		now := d.svc.Now()
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			http.NotFound(w, r)
+			return
+		case err != nil:
+			logging.Errorf("❌ Failed to preview %s: %v", code, err)
+			http.Error(w, "failed to load link", http.StatusInternalServerError)
+			return
+		case entry.Disabled:
+			d.disabled(w, r, entry)
+			return
+		case entry.Expired(now):
+			http.Error(w, "short link has expired", http.StatusGone)
+			return
+		case entry.Pending(now):
+			d.comingSoon(w, r, entry)
+			return
+		}
+		view := newLinkView(r, entry, now)
+		if signed {
+			view.ShortURL = signedURL(r, code, sig)
+		}
+		d.render(w, r, http.StatusOK, "preview.html", pageData{Link: view, MarkBroken: markBroken})
+	}
+}
+
+func (d *dashboard) home(w http.ResponseWriter, r *http.Request) {
+	var data pageData
+	if key, ok := d.session(r); ok {
+		data.KeyName = key.Name
+	}
+	d.render(w, r, http.StatusOK, "index.html", data)
+}
+
+func (d *dashboard) list(w http.ResponseWriter, r *http.Request) {
+	key, ok := d.session(r)
+	if !ok {
+		d.render(w, r, http.StatusOK, "dashboard.html", pageData{})
+		return
+	}
+
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	page = max(page, 1)
+	data := pageData{KeyName: key.Name, Error: d.takeFlash(w, r), Query: q.Get("q")}
+	if raw := q.Get("tag"); raw != "" {
+		tag, err := shortenerpkg.NormalizeTag(raw)
+		if err != nil {
+			d.redirectWithError(w, r, err.Error())
+			return
+		}
+		data.Tag = tag
+	}
+
+	// Ask for one extra link to learn whether there is a next page.
+	entries, err := d.svc.List(r.Context(), storage.ListOptions{
+		CreatedBy: keyOwner(key),
+		Query:     data.Query,
+		Tag:       data.Tag,
+		Limit:     dashboardPageSize + 1,
+		Offset:    (page - 1) * dashboardPageSize,
+	})
+	if err != nil {
+		logging.Errorf("❌ Failed to list links: %v", err)
+		http.Error(w, "failed to list links", http.StatusInternalServerError)
+		return
+	}
+
+	if len(entries) > dashboardPageSize {
+		entries = entries[:dashboardPageSize]
+		data.NextPage = page + 1
+	}
+	if page > 1 {
+		data.PrevPage = page - 1
+	}
+	for _, entry := range entries {
+		view := d.view(r, entry)
+		data.Links = append(data.Links, view)
+		if entry.ShortCode == r.URL.Query().Get("created") {
+			data.Created = &view
+		}
+	}
+	d.render(w, r, http.StatusOK, "dashboard.html", data)
+}
+
+func (d *dashboard) login(w http.ResponseWriter, r *http.Request) {
+	token := r.PostFormValue("token")
+	if d.keys == nil {
+		d.render(w, r, http.StatusBadRequest, "dashboard.html", pageData{Error: "API keys are not enabled on this server"})
+		return
+	}
+	session, _, err := d.keys.StartSession(r.Context(), token, d.sessionTTL)
+	if err != nil {
+		if errors.Is(err, apikey.ErrInvalidKey) || errors.Is(err, apikey.ErrRevokedKey) {
+			logging.Warnf("⚠️  Rejected dashboard sign-in: %v", err)
+			d.render(w, r, http.StatusUnauthorized, "dashboard.html", pageData{Error: err.Error()})
+			return
+		}
+		logging.Errorf("❌ Failed to start dashboard session: %v", err)
+		http.Error(w, "failed to sign in", http.StatusInternalServerError)
+		return
+	}
+
+	d.cookies.set(w, r, &http.Cookie{
+		Name:    sessionCookie,
+		Value:   session,
+		Path:    "/",
+		Expires: d.svc.Now().Add(d.sessionTTL),
+	})
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+func (d *dashboard) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil && cookie.Value != "" && d.keys != nil {
+		if err := d.keys.EndSession(r.Context(), cookie.Value); err != nil {
+			logging.Errorf("❌ Failed to end dashboard session: %v", err)
+			http.Error(w, "failed to sign out", http.StatusInternalServerError)
+			return
+		}
+	}
+	d.cookies.set(w, r, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+func (d *dashboard) shorten(w http.ResponseWriter, r *http.Request) {
+	key, ok := d.session(r)
+	if !ok {
+		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	req := shortenerpkg.ShortenRequest{
+		URL:       r.PostFormValue("url"),
+		Alias:     r.PostFormValue("alias"),
+		CreatedBy: keyOwner(key),
+		Title:     r.PostFormValue("title"),
+		Tags:      strings.Split(r.PostFormValue("tags"), ","),
+		Signed:    r.PostFormValue("signed") != "",
+	}
+	if strings.TrimSpace(r.PostFormValue("tags")) == "" {
+		req.Tags = nil
+	}
+	if raw := r.PostFormValue("expires_at"); raw != "" {
+		// datetime-local inputs have no zone; the form labels them as UTC.
+		expiresAt, err := time.Parse("2006-01-02T15:04", raw)
+		if err != nil {
+			d.redirectWithError(w, r, "expiry must be a date and time")
+			return
+		}
+		req.ExpiresAt = expiresAt
+	}
+	if raw := r.PostFormValue("activates_at"); raw != "" {
+		activatesAt, err := time.Parse("2006-01-02T15:04", raw)
+		if err != nil {
+			d.redirectWithError(w, r, "go-live time must be a date and time")
+			return
+		}
+		req.ActivatesAt = activatesAt
+	}
+
+	resp, err := d.svc.Shorten(audit.WithActor(r.Context(), keyOwner(key)), req)
+	if err != nil {
+		if _, ok := shortenErrorStatus(err); ok {
+			d.redirectWithError(w, r, err.Error())
+			return
+		}
+		logging.Errorf("❌ Failed to shorten URL: %v", err)
+		http.Error(w, "failed to shorten url", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/dashboard?created="+url.QueryEscape(resp.ShortCode), http.StatusSeeOther)
+}
+
+// redirectWithError sends the browser back to the dashboard, which shows msg once.
+func (d *dashboard) redirectWithError(w http.ResponseWriter, r *http.Request, msg string) {
+	d.cookies.set(w, r, &http.Cookie{Name: flashCookie, Value: url.QueryEscape(msg), Path: "/dashboard"})
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// takeFlash returns and clears the message set by redirectWithError.
+func (d *dashboard) takeFlash(w http.ResponseWriter, r *http.Request) string {
+	cookie, err := r.Cookie(flashCookie)
+	if err != nil {
+		return ""
+	}
+	d.cookies.set(w, r, &http.Cookie{Name: flashCookie, Path: "/dashboard", MaxAge: -1})
+	msg, _ := url.QueryUnescape(cookie.Value)
+	return msg
+}
+
+// ownedLink loads the link in the URL if the signed-in key created it. It
+// writes the response itself when it returns false.
+func (d *dashboard) ownedLink(w http.ResponseWriter, r *http.Request) (storage.APIKey, storage.Entry, bool) {
+	key, ok := d.session(r)
+	if !ok {
+		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+		return storage.APIKey{}, storage.Entry{}, false
+	}
+	entry, err := d.svc.Stats(r.Context(), chi.URLParam(r, "shortCode"))
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logging.Errorf("❌ Failed to load link: %v", err)
+		http.Error(w, "failed to load link", http.StatusInternalServerError)
+		return storage.APIKey{}, storage.Entry{}, false
+	}
+	// Someone else's link looks the same as a missing one.
+	if err != nil || entry.CreatedBy != keyOwner(key) {
+		http.NotFound(w, r)
+		return storage.APIKey{}, storage.Entry{}, false
+	}
+	return key, entry, true
+}
+
+func (d *dashboard) link(w http.ResponseWriter, r *http.Request) {
+	key, entry, ok := d.ownedLink(w, r)
+	if !ok {
+		return
+	}
+	d.render(w, r, http.StatusOK, "link.html", pageData{KeyName: key.Name, Link: d.view(r, entry)})
+}
+
+func (d *dashboard) qr(w http.ResponseWriter, r *http.Request) {
+	_, entry, ok := d.ownedLink(w, r)
+	if !ok {
+		return
+	}
+	png, err := qrcode.Encode(d.view(r, entry).ShortURL, qrcode.Medium, qrSize)
+	if err != nil {
+		logging.Errorf("❌ Failed to render QR code: %v", err)
+		http.Error(w, "failed to render qr code", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(png)
+}
+
+func (d *dashboard) delete(w http.ResponseWriter, r *http.Request) {
+	key, entry, ok := d.ownedLink(w, r)
+	if !ok {
+		return
+	}
+	ctx := audit.WithActor(r.Context(), keyOwner(key))
+	if err := d.svc.Delete(ctx, entry.ShortCode); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logging.Errorf("❌ Failed to delete %s: %v", entry.ShortCode, err)
+		http.Error(w, "failed to delete link", http.StatusInternalServerError)
+		return
+	}
+	logging.Infof("🗑️  Deleted %s from the dashboard", entry.ShortCode)
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
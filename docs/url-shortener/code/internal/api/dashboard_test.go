@@ -0,0 +1,330 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"urlshortener/internal/clock/clocktest"
+	"urlshortener/internal/services/apikey"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/storage"
+)
+
+// browser keeps the session cookie between requests like a real one would.
+// Its forms carry the CSRF token, as the rendered ones do.
+type browser struct {
+	t       *testing.T
+	router  http.Handler
+	cookies map[string]*http.Cookie
+}
+
+func (b *browser) do(method, target string, form url.Values) *httptest.ResponseRecorder {
+	b.t.Helper()
+	var req *http.Request
+	if form != nil {
+		if b.cookies[csrfCookie] == nil {
+			// A person opens the page before submitting its form.
+			b.do(http.MethodGet, "/dashboard", nil)
+		}
+		form.Set(csrfField, b.cookies[csrfCookie].Value)
+		req = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, target, nil)
+	}
+	for _, c := range b.cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	b.router.ServeHTTP(rec, req)
+	for _, c := range rec.Result().Cookies() {
+		if c.MaxAge < 0 {
+			delete(b.cookies, c.Name)
+			continue
+		}
+		b.cookies[c.Name] = c
+	}
+	return rec
+}
+
+func TestDashboardFlow(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	token, key, err := keys.Create(ctx, "alice")
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	shortener := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
+	b := &browser{t: t, router: NewRouter(shortener, WithAPIKeys(keys, false)), cookies: map[string]*http.Cookie{}}
+
+	// Someone else's link must stay invisible.
+	_ = store.Save(ctx, storage.Entry{ShortCode: "other1", OriginalURL: "https://other.example", CreatedBy: "key:someone"})
+
+	rec := b.do(http.MethodGet, "/dashboard", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `action="/dashboard/login"`) {
+		t.Fatalf("expected sign-in form, got %d", rec.Code)
+	}
+
+	rec = b.do(http.MethodPost, "/dashboard/login", url.Values{"token": {token}})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect after login, got %d", rec.Code)
+	}
+	if c := b.cookies[sessionCookie]; c == nil || !c.HttpOnly {
+		t.Fatalf("expected an HttpOnly session cookie, got %+v", c)
+	}
+	if strings.Contains(b.cookies[sessionCookie].Value, token) {
+		t.Fatal("session cookie carries the API key")
+	}
+	session := b.cookies[sessionCookie]
+
+	rec = b.do(http.MethodPost, "/dashboard/shorten", url.Values{
+		"url": {"https://example.com/page"}, "title": {"Q3 launch"}, "tags": {"Launch/Q3, marketing"},
+	})
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/dashboard?created=stub123" {
+		t.Fatalf("expected redirect to created link, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+	entry, err := store.Find(ctx, "stub123")
+	if err != nil {
+		t.Fatalf("expected link to be stored: %v", err)
+	}
+	if entry.CreatedBy != keyOwner(key) {
+		t.Fatalf("expected link owned by %s, got %s", keyOwner(key), entry.CreatedBy)
+	}
+
+	rec = b.do(http.MethodGet, "/dashboard?created=stub123", nil)
+	body := rec.Body.String()
+	if !strings.Contains(body, `data-copy="http://example.com/stub123"`) {
+		t.Fatalf("expected copy button for the new link, got:\n%s", body)
+	}
+	if strings.Contains(body, "other1") {
+		t.Fatal("dashboard listed another key's link")
+	}
+	if !strings.Contains(body, "Q3 launch") || !strings.Contains(body, `href="/dashboard?tag=launch%2fq3"`) {
+		t.Fatalf("expected the title and tag links, got:\n%s", body)
+	}
+	if body := b.do(http.MethodGet, "/dashboard?q=launch&tag=marketing", nil).Body.String(); !strings.Contains(body, "stub123") {
+		t.Fatalf("expected the search to find the link, got:\n%s", body)
+	}
+	if body := b.do(http.MethodGet, "/dashboard?q=hiring", nil).Body.String(); !strings.Contains(body, "No links match.") {
+		t.Fatalf("expected an empty search result, got:\n%s", body)
+	}
+
+	rec = b.do(http.MethodGet, "/dashboard/links/stub123", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/dashboard/links/stub123/qr.png") {
+		t.Fatalf("expected stats page, got %d", rec.Code)
+	}
+	rec = b.do(http.MethodGet, "/dashboard/links/stub123/qr.png", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected png, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	for _, target := range []string{"/dashboard/links/other1", "/dashboard/links/other1/qr.png"} {
+		if rec := b.do(http.MethodGet, target, nil); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404 for another key's link, got %d", target, rec.Code)
+		}
+	}
+	if rec := b.do(http.MethodPost, "/dashboard/links/other1/delete", url.Values{}); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting another key's link, got %d", rec.Code)
+	}
+
+	rec = b.do(http.MethodPost, "/dashboard/links/stub123/delete", url.Values{})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect after delete, got %d", rec.Code)
+	}
+	if _, err := store.Find(ctx, "stub123"); err == nil {
+		t.Fatal("expected link to be deleted")
+	}
+	if _, err := store.Find(ctx, "other1"); err != nil {
+		t.Fatalf("another key's link was touched: %v", err)
+	}
+
+	b.do(http.MethodPost, "/dashboard/logout", url.Values{})
+	rec = b.do(http.MethodGet, "/dashboard", nil)
+	if !strings.Contains(rec.Body.String(), `action="/dashboard/login"`) {
+		t.Fatal("expected sign-in form after logout")
+	}
+	// A copy of the cookie taken before signing out is no good either.
+	b.cookies[sessionCookie] = session
+	rec = b.do(http.MethodGet, "/dashboard", nil)
+	if !strings.Contains(rec.Body.String(), `action="/dashboard/login"`) {
+		t.Fatal("expected a signed-out session to stay signed out")
+	}
+}
+
+func TestDashboardSecureCookies(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	token, _, err := keys.Create(ctx, "alice")
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	shortener := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
+	router := NewRouter(shortener, WithAPIKeys(keys, false), WithSessions(time.Hour, true))
+	b := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+
+	var set []*http.Cookie
+	collect := func(rec *httptest.ResponseRecorder) {
+		set = append(set, rec.Result().Cookies()...)
+	}
+	collect(b.do(http.MethodGet, "/dashboard", nil))
+	collect(b.do(http.MethodPost, "/dashboard/login", url.Values{"token": {token}}))
+	collect(b.do(http.MethodPost, "/dashboard/shorten", url.Values{"url": {"not a url"}}))
+	collect(b.do(http.MethodGet, "/dashboard", nil))
+	collect(b.do(http.MethodPost, "/dashboard/logout", url.Values{}))
+
+	names := map[string]bool{}
+	for _, c := range set {
+		names[c.Name] = true
+		if !c.Secure || !c.HttpOnly {
+			t.Errorf("cookie %s set without Secure and HttpOnly: %+v", c.Name, c)
+		}
+		if c.Name == sessionCookie && c.MaxAge >= 0 {
+			if until := time.Until(c.Expires); until <= 0 || until > time.Hour {
+				t.Errorf("expected the session cookie to expire within the hour, got %v", c.Expires)
+			}
+		}
+	}
+	for _, name := range []string{csrfCookie, sessionCookie, flashCookie} {
+		if !names[name] {
+			t.Errorf("expected %s to be set", name)
+		}
+	}
+}
+
+func TestDashboardShortenErrorIsFlashedOnce(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	token, _, err := keys.Create(ctx, "alice")
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	shortener := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
+	b := &browser{t: t, router: NewRouter(shortener, WithAPIKeys(keys, false)), cookies: map[string]*http.Cookie{}}
+	b.do(http.MethodPost, "/dashboard/login", url.Values{"token": {token}})
+
+	b.do(http.MethodPost, "/dashboard/shorten", url.Values{"url": {"https://example.com"}, "alias": {"api"}})
+	rec := b.do(http.MethodGet, "/dashboard", nil)
+	if !strings.Contains(rec.Body.String(), "alias is reserved") {
+		t.Fatalf("expected flashed error, got:\n%s", rec.Body.String())
+	}
+	rec = b.do(http.MethodGet, "/dashboard", nil)
+	if strings.Contains(rec.Body.String(), "alias is reserved") {
+		t.Fatal("expected flash to be shown only once")
+	}
+}
+
+func TestDashboardCSRF(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	token, _, err := keys.Create(ctx, "alice")
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	shortener := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
+	b := &browser{t: t, router: NewRouter(shortener, WithAPIKeys(keys, false)), cookies: map[string]*http.Cookie{}}
+
+	rec := b.do(http.MethodGet, "/dashboard", nil)
+	csrf := b.cookies[csrfCookie]
+	if csrf == nil || !csrf.HttpOnly || csrf.Value == "" {
+		t.Fatalf("expected an HttpOnly CSRF cookie, got %+v", csrf)
+	}
+	if !strings.Contains(rec.Body.String(), `name="csrf_token" value="`+csrf.Value+`"`) {
+		t.Fatalf("expected the sign-in form to carry the token, got:\n%s", rec.Body.String())
+	}
+	b.do(http.MethodPost, "/dashboard/login", url.Values{"token": {token}})
+	if b.do(http.MethodGet, "/dashboard", nil); b.cookies[csrfCookie].Value != csrf.Value {
+		t.Fatal("expected the token to stay the same for the session")
+	}
+
+	// A form on another site can send the cookies, but not the token.
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/dashboard/shorten", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for _, c := range b.cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		b.router.ServeHTTP(rec, req)
+		return rec
+	}
+	for _, form := range []url.Values{
+		{"url": {"https://evil.example"}},
+		{"url": {"https://evil.example"}, csrfField: {"guessed"}},
+	} {
+		if rec := post(form); rec.Code != http.StatusForbidden {
+			t.Fatalf("expected 403 for %v, got %d", form, rec.Code)
+		}
+	}
+	if _, err := store.Find(ctx, "stub123"); err == nil {
+		t.Fatal("a forged post created a link")
+	}
+	if rec := post(url.Values{"url": {"https://example.com"}, csrfField: {csrf.Value}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected the real form to work, got %d", rec.Code)
+	}
+}
+
+func TestPreviewMarksBrokenLinks(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	_ = store.Save(ctx, storage.Entry{ShortCode: "gone01", OriginalURL: "https://example.com/gone", Title: "Old launch page"})
+	_ = store.RecordCheck(ctx, "gone01", storage.LinkCheck{At: time.Now(), Status: http.StatusNotFound})
+	_ = store.Save(ctx, storage.Entry{ShortCode: "fine01", OriginalURL: "https://example.com/fine"})
+	_ = store.RecordCheck(ctx, "fine01", storage.LinkCheck{At: time.Now(), Status: http.StatusOK})
+	shortener := shortenerpkg.NewShortener(nil, store, defaultTestSettings())
+
+	get := func(router http.Handler, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	marking := NewRouter(shortener, WithBrokenLinkWarnings())
+	rec := get(marking, "/gone01/preview")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "looks broken") || !strings.Contains(rec.Body.String(), "https://example.com/gone") {
+		t.Fatalf("expected a broken-link warning, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if body := get(marking, "/fine01/preview").Body.String(); strings.Contains(body, "looks broken") {
+		t.Fatalf("expected no warning for a healthy link, got %s", body)
+	}
+	if body := get(NewRouter(shortener), "/gone01/preview").Body.String(); strings.Contains(body, "looks broken") {
+		t.Fatalf("expected no warning when marking is off, got %s", body)
+	}
+	if entry, _ := store.Find(ctx, "gone01"); entry.HitCount != 0 {
+		t.Fatalf("expected previews not to count hits, got %d", entry.HitCount)
+	}
+}
+
+func TestPreviewFollowsServiceClock(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	clk := clocktest.New(time.Date(2030, 6, 1, 9, 0, 0, 0, time.UTC))
+	_ = store.Save(ctx, storage.Entry{ShortCode: "launch", OriginalURL: "https://example.com/launch",
+		ActivatesAt: clk.Now().Add(time.Hour), ExpiresAt: clk.Now().Add(2 * time.Hour)})
+	router := NewRouter(shortenerpkg.NewShortener(nil, store, defaultTestSettings(), shortenerpkg.WithClock(clk)))
+
+	for _, tc := range []struct {
+		advance time.Duration
+		want    int
+		text    string
+	}{
+		{0, http.StatusOK, "Coming soon"},
+		{90 * time.Minute, http.StatusOK, "https://example.com/launch"},
+		{time.Hour, http.StatusGone, "expired"},
+	} {
+		clk.Advance(tc.advance)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/launch/preview", nil))
+		if rec.Code != tc.want || !strings.Contains(rec.Body.String(), tc.text) {
+			t.Fatalf("at %s: expected %d with %q, got %d: %s", clk.Now(), tc.want, tc.text, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/idempotency"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayHeader marks a response sent again for a repeat.
+	idempotentReplayHeader = "Idempotent-Replayed"
+	// maxIdempotentBodyBytes bounds the request body buffered to compare
+	// repeats; shorten requests are far smaller.
+	maxIdempotentBodyBytes = 1 << 20
+)
+
+// idempotent serves requests carrying an Idempotency-Key once per key and
+// caller: repeats get the first response back, a key reused for a different
+// request is 422, and a repeat of a request still in flight is 409. Server
+// errors are not kept, so the caller can retry them. Requests without the
+// header pass straight through.
+func idempotent(svc *idempotency.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+			if err != nil {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			rec, err := svc.Begin(r.Context(), clientKey(r), key, idempotency.Fingerprint(r.Method, r.URL.Path, body))
+			switch {
+			case errors.Is(err, idempotency.ErrInvalidKey):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			case errors.Is(err, idempotency.ErrKeyReused):
+				logging.Warnf("⚠️  Rejected reused idempotency key from %s", clientKey(r))
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			case errors.Is(err, idempotency.ErrInProgress):
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			case err != nil:
+				logging.Errorf("❌ Failed to check idempotency key: %v", err)
+				http.Error(w, "failed to check idempotency key", http.StatusInternalServerError)
+				return
+			}
+
+			if rec.Completed() {
+				if rec.ContentType != "" {
+					w.Header().Set("Content-Type", rec.ContentType)
+				}
+				w.Header().Set(idempotentReplayHeader, "true")
+				w.WriteHeader(rec.Status)
+				_, _ = w.Write(rec.Body)
+				return
+			}
+
+			rw := &recordingWriter{ResponseWriter: w}
+			// The claim must not outlive a failed or cancelled request.
+			ctx := context.WithoutCancel(r.Context())
+			kept := false
+			defer func() {
+				if kept {
+					return
+				}
+				if err := svc.Release(ctx, rec); err != nil {
+					logging.Errorf("❌ Failed to release idempotency key: %v", err)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+
+			if rw.status() >= http.StatusInternalServerError {
+				return
+			}
+			if err := svc.Complete(ctx, rec, rw.status(), w.Header().Get("Content-Type"), rw.body.Bytes()); err != nil {
+				logging.Errorf("❌ Failed to keep idempotent response: %v", err)
+				return
+			}
+			kept = true
+		})
+	}
+}
+
+// recordingWriter keeps a copy of the status and body it writes.
+type recordingWriter struct {
+	http.ResponseWriter
+	code int
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.code == 0 {
+		w.code = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
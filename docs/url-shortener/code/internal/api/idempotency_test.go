@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"urlshortener/internal/services/apikey"
+	"urlshortener/internal/services/idempotency"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/storage"
+)
+
+// flakyStore fails the first Save, like a database timing out.
+type flakyStore struct {
+	*storage.InMemoryStore
+	failed bool
+}
+
+func (s *flakyStore) Save(ctx context.Context, entry storage.Entry) error {
+	if !s.failed {
+		s.failed = true
+		return errors.New("connection reset")
+	}
+	return s.InMemoryStore.Save(ctx, entry)
+}
+
+func TestIdempotentShorten(t *testing.T) {
+	ctx := context.Background()
+	store := &flakyStore{InMemoryStore: storage.NewInMemoryStore()}
+	keys := apikey.NewManager(store)
+	token, _, _ := keys.Create(ctx, "jobs")
+	otherToken, _, _ := keys.Create(ctx, "other")
+	shortener := shortenerpkg.NewShortener(shortenerpkg.NewRandomCodeGenerator(6), store, defaultTestSettings())
+	router := NewRouter(shortener,
+		WithAPIKeys(keys, false),
+		WithIdempotency(idempotency.New(store, idempotency.Options{})),
+	)
+
+	shorten := func(token, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	links := func() int {
+		entries, _ := store.List(ctx, storage.ListOptions{})
+		return len(entries)
+	}
+
+	// The first attempt fails on the server, so the retry runs for real.
+	if rec := shorten(token, "job-42", `{"url":"https://example.com/report"}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	first := shorten(token, "job-42", `{"url":"https://example.com/report"}`)
+	if first.Code != http.StatusOK || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("expected a fresh 200, got %d: %s", first.Code, first.Body.String())
+	}
+
+	retry := shorten(token, "job-42", `{"url":"https://example.com/report"}`)
+	if retry.Code != http.StatusOK || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected a replayed 200, got %d", retry.Code)
+	}
+	if retry.Body.String() != first.Body.String() || retry.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected the identical response, got %q after %q", retry.Body.String(), first.Body.String())
+	}
+	if n := links(); n != 1 {
+		t.Fatalf("expected one link after the retry, got %d", n)
+	}
+
+	if rec := shorten(token, "job-42", `{"url":"https://example.com/other"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for a reused key, got %d", rec.Code)
+	}
+
+	// Keys are per caller, and requests without one are not deduplicated.
+	other := shorten(otherToken, "job-42", `{"url":"https://example.com/report"}`)
+	var a, b struct {
+		ShortCode string `json:"short_code"`
+	}
+	_ = json.Unmarshal(first.Body.Bytes(), &a)
+	_ = json.Unmarshal(other.Body.Bytes(), &b)
+	if other.Code != http.StatusOK || a.ShortCode == b.ShortCode {
+		t.Fatalf("expected another key's request to create its own link, got %d %q", other.Code, b.ShortCode)
+	}
+	shorten(token, "", `{"url":"https://example.com/report"}`)
+	if n := links(); n != 3 {
+		t.Fatalf("expected 3 links, got %d", n)
+	}
+
+	if rec := shorten(token, strings.Repeat("k", 300), `{"url":"https://example.com/report"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an oversized key, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,335 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"urlshortener/internal/logging"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/signing"
+	"urlshortener/internal/services/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxLinkPatchBytes bounds the body of PATCH /api/links/{shortCode}.
+const maxLinkPatchBytes = 8 << 10
+
+// Page sizes for GET /api/links.
+const (
+	defaultLinkPageSize = 50
+	maxLinkPageSize     = 500
+)
+
+// linkJSON is the wire shape of a storage.Entry, the Link schema in the spec.
+type linkJSON struct {
+	ShortCode      string    `json:"short_code"`
+	OriginalURL    string    `json:"original_url"`
+	CreatedAt      time.Time `json:"created_at"`
+	CreatedBy      string    `json:"created_by"`
+	HitCount       int64     `json:"hit_count"`
+	ExpiresAt      time.Time `json:"expires_at,omitzero"`
+	ActivatesAt    time.Time `json:"activates_at,omitzero"`
+	Signed         bool      `json:"signed,omitempty"`
+	Disabled       bool      `json:"disabled,omitempty"`
+	DisabledReason string    `json:"disabled_reason,omitempty"`
+	Title          string    `json:"title,omitempty"`
+	Description    string    `json:"description,omitempty"`
+	Tags           []string  `json:"tags,omitempty"`
+	CheckedAt      time.Time `json:"checked_at,omitzero"`
+	CheckStatus    int       `json:"check_status,omitempty"`
+	CheckError     string    `json:"check_error,omitempty"`
+	Dead           bool      `json:"dead,omitempty"`
+}
+
+func newLinkJSON(e storage.Entry) linkJSON {
+	return linkJSON{
+		ShortCode:      e.ShortCode,
+		OriginalURL:    e.OriginalURL,
+		CreatedAt:      e.CreatedAt,
+		CreatedBy:      e.CreatedBy,
+		HitCount:       e.HitCount,
+		ExpiresAt:      e.ExpiresAt,
+		ActivatesAt:    e.ActivatesAt,
+		Signed:         e.Signed,
+		Disabled:       e.Disabled,
+		DisabledReason: e.DisabledReason,
+		Title:          e.Title,
+		Description:    e.Description,
+		Tags:           e.Tags,
+		CheckedAt:      e.CheckedAt,
+		CheckStatus:    e.CheckStatus,
+		CheckError:     e.CheckError,
+		Dead:           e.Dead(),
+	}
+}
+
+// listLinksHandler finds links by words in their title, description, URL,
+// code or tags, by tag or folder, and with dead=true by a failed last health
+// check. Keys see only their own links; admin
+// keys see everyone's and may narrow them with created_by.
+func listLinksHandler(shortsvc *shortenerpkg.Shortener, admins map[string]struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		opts := storage.ListOptions{
+			CreatedBy: ownerFromContext(r.Context()),
+			Query:     q.Get("q"),
+			Limit:     defaultLinkPageSize,
+		}
+		if key, ok := apiKeyFromContext(r.Context()); ok {
+			if _, admin := admins[key.ID]; admin {
+				opts.CreatedBy = q.Get("created_by")
+			}
+		}
+		if raw := q.Get("tag"); raw != "" {
+			tag, err := shortenerpkg.NormalizeTag(raw)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			opts.Tag = tag
+		}
+		if raw := q.Get("dead"); raw != "" {
+			dead, err := strconv.ParseBool(raw)
+			if err != nil {
+				http.Error(w, "dead must be true or false", http.StatusBadRequest)
+				return
+			}
+			opts.Dead = dead
+		}
+		if raw := q.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > maxLinkPageSize {
+				http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+			opts.Limit = n
+		}
+		if raw := q.Get("offset"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			opts.Offset = n
+		}
+
+		entries, err := shortsvc.List(r.Context(), opts)
+		if err != nil {
+			logging.Errorf("❌ Failed to list links: %v", err)
+			http.Error(w, "failed to list links", http.StatusInternalServerError)
+			return
+		}
+		out := make([]linkJSON, len(entries))
+		for i, e := range entries {
+			out[i] = newLinkJSON(e)
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// updateLinkHandler retargets a link or edits its details; fields left out of
+// the body stay as they are. Only the key that created the link may; other
+// keys get the same 404 as for a missing link.
+func updateLinkHandler(shortsvc *shortenerpkg.Shortener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			URL         *string   `json:"url"`
+			Title       *string   `json:"title"`
+			Description *string   `json:"description"`
+			Tags        *[]string `json:"tags"`
+		}
+		body := http.MaxBytesReader(w, r.Body, maxLinkPatchBytes)
+		defer body.Close()
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			http.Error(w, "invalid json payload", http.StatusBadRequest)
+			return
+		}
+
+		code := chi.URLParam(r, "shortCode")
+		current, err := shortsvc.Stats(r.Context(), code)
+		if err == nil && current.CreatedBy != ownerFromContext(r.Context()) {
+			err = storage.ErrNotFound
+		}
+		if err == nil {
+			current, err = shortsvc.Edit(r.Context(), code, shortenerpkg.LinkChanges{
+				URL:         req.URL,
+				Title:       req.Title,
+				Description: req.Description,
+				Tags:        req.Tags,
+			})
+		}
+		if err != nil {
+			status, ok := shortenErrorStatus(err)
+			switch {
+			case errors.Is(err, storage.ErrNotFound):
+				http.NotFound(w, r)
+			case errors.Is(err, shortenerpkg.ErrDisabled):
+				http.Error(w, err.Error(), http.StatusConflict)
+			case ok:
+				http.Error(w, err.Error(), status)
+			default:
+				logging.Errorf("❌ Failed to update %s: %v", code, err)
+				http.Error(w, "failed to update link", http.StatusInternalServerError)
+			}
+			return
+		}
+		if req.URL != nil {
+			logging.Infof("✏️  Retargeted %s -> %s", code, current.OriginalURL)
+		} else {
+			logging.Infof("✏️  Edited %s", code)
+		}
+		writeJSON(w, http.StatusOK, newLinkJSON(current))
+	}
+}
+
+// signLinkHandler issues a new signed URL for a signed link, e.g. after the
+// one returned by /api/shorten expired. The body may name expires_at; the
+// signature never outlives the link. Only the key that created the link may.
+func signLinkHandler(shortsvc *shortenerpkg.Shortener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ExpiresAt time.Time `json:"expires_at"`
+		}
+		body := http.MaxBytesReader(w, r.Body, maxLinkPatchBytes)
+		defer body.Close()
+		if err := json.NewDecoder(body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "invalid json payload", http.StatusBadRequest)
+			return
+		}
+
+		code := chi.URLParam(r, "shortCode")
+		current, err := shortsvc.Stats(r.Context(), code)
+		if err == nil && current.CreatedBy != ownerFromContext(r.Context()) {
+			err = storage.ErrNotFound
+		}
+		var sig signing.Signature
+		if err == nil {
+			sig, err = shortsvc.Sign(r.Context(), code, req.ExpiresAt)
+		}
+		if err != nil {
+			status, ok := shortenErrorStatus(err)
+			switch {
+			case errors.Is(err, storage.ErrNotFound):
+				http.NotFound(w, r)
+			case errors.Is(err, shortenerpkg.ErrNotSigned), errors.Is(err, shortenerpkg.ErrDisabled):
+				http.Error(w, err.Error(), http.StatusConflict)
+			case ok:
+				http.Error(w, err.Error(), status)
+			default:
+				logging.Errorf("❌ Failed to sign %s: %v", code, err)
+				http.Error(w, "failed to sign link", http.StatusInternalServerError)
+			}
+			return
+		}
+		logging.Infof("🔏 Signed %s until %s", code, sig.Expires.Format(time.RFC3339))
+		writeJSON(w, http.StatusOK, map[string]string{
+			"signed_url": signedURL(r, code, sig),
+			"expires_at": sig.Expires.Format(time.RFC3339),
+		})
+	}
+}
+
+// linkVersionJSON is the wire shape of a storage.LinkVersion, the LinkVersion
+// schema in the spec.
+type linkVersionJSON struct {
+	Version        int       `json:"version"`
+	At             time.Time `json:"at"`
+	Actor          string    `json:"actor,omitempty"`
+	Action         string    `json:"action"`
+	OriginalURL    string    `json:"original_url"`
+	ExpiresAt      time.Time `json:"expires_at,omitzero"`
+	ActivatesAt    time.Time `json:"activates_at,omitzero"`
+	Disabled       bool      `json:"disabled,omitempty"`
+	DisabledReason string    `json:"disabled_reason,omitempty"`
+	Title          string    `json:"title,omitempty"`
+	Description    string    `json:"description,omitempty"`
+	Tags           []string  `json:"tags,omitempty"`
+}
+
+// linkHistoryHandler lists a link's versions, newest first. Only the key that
+// created the link may see them.
+func linkHistoryHandler(shortsvc *shortenerpkg.Shortener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := chi.URLParam(r, "shortCode")
+		current, err := shortsvc.Stats(r.Context(), code)
+		if err == nil && current.CreatedBy != ownerFromContext(r.Context()) {
+			err = storage.ErrNotFound
+		}
+		var versions []storage.LinkVersion
+		if err == nil {
+			versions, err = shortsvc.History(r.Context(), code)
+		}
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			logging.Errorf("❌ Failed to list history of %s: %v", code, err)
+			http.Error(w, "failed to list history", http.StatusInternalServerError)
+			return
+		}
+
+		out := make([]linkVersionJSON, 0, len(versions))
+		for _, v := range versions {
+			out = append(out, linkVersionJSON{
+				Version:        v.Version,
+				At:             v.At,
+				Actor:          v.Actor,
+				Action:         v.Action,
+				OriginalURL:    v.OriginalURL,
+				ExpiresAt:      v.ExpiresAt,
+				ActivatesAt:    v.ActivatesAt,
+				Disabled:       v.Disabled,
+				DisabledReason: v.DisabledReason,
+				Title:          v.Title,
+				Description:    v.Description,
+				Tags:           v.Tags,
+			})
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// rollbackLinkHandler restores a link to one of its versions. Only the key
+// that created the link may; other keys get the same 404 as for a missing
+// link or version.
+func rollbackLinkHandler(shortsvc *shortenerpkg.Shortener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := chi.URLParam(r, "shortCode")
+		version, err := strconv.Atoi(chi.URLParam(r, "version"))
+		if err != nil || version < 1 {
+			http.Error(w, "version must be a positive integer", http.StatusBadRequest)
+			return
+		}
+
+		current, err := shortsvc.Stats(r.Context(), code)
+		if err == nil && current.CreatedBy != ownerFromContext(r.Context()) {
+			err = storage.ErrNotFound
+		}
+		if err == nil {
+			current, err = shortsvc.Rollback(r.Context(), code, version)
+		}
+		if err != nil {
+			status, ok := shortenErrorStatus(err)
+			switch {
+			case errors.Is(err, storage.ErrNotFound):
+				http.NotFound(w, r)
+			case errors.Is(err, shortenerpkg.ErrDisabled):
+				http.Error(w, err.Error(), http.StatusConflict)
+			case ok:
+				http.Error(w, err.Error(), status)
+			default:
+				logging.Errorf("❌ Failed to roll back %s: %v", code, err)
+				http.Error(w, "failed to roll back link", http.StatusInternalServerError)
+			}
+			return
+		}
+		logging.Infof("⏪ Rolled back %s to version %d", code, version)
+		writeJSON(w, http.StatusOK, newLinkJSON(current))
+	}
+}
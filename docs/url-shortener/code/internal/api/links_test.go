@@ -0,0 +1,289 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"urlshortener/internal/services/apikey"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/signing"
+	"urlshortener/internal/services/storage"
+)
+
+func TestSearchAndEditLinks(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	adminToken, admin, _ := keys.Create(ctx, "ops")
+	userToken, _, _ := keys.Create(ctx, "marketing")
+	otherToken, _, _ := keys.Create(ctx, "sales")
+	shortener := shortenerpkg.NewShortener(shortenerpkg.NewRandomCodeGenerator(6), store, defaultTestSettings())
+	router := NewRouter(shortener, WithAPIKeys(keys, false), WithAdmins([]string{admin.ID}))
+
+	do := func(method, target, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	codes := func(rec *httptest.ResponseRecorder) []string {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var links []linkJSON
+		if err := json.Unmarshal(rec.Body.Bytes(), &links); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		out := make([]string, len(links))
+		for i, l := range links {
+			out[i] = l.ShortCode
+		}
+		slices.Sort(out)
+		return out
+	}
+
+	for _, tc := range []struct{ token, body string }{
+		{userToken, `{"url":"https://example.com/q3","alias":"q3-page","title":"Q3 Launch","tags":["Launch/Q3"," marketing "]}`},
+		{userToken, `{"url":"https://example.com/blog","alias":"q3-blog","description":"Blog post for the q3 launch","tags":["launch/q3/blog"]}`},
+		{userToken, `{"url":"https://example.com/hiring","alias":"hiring"}`},
+		{otherToken, `{"url":"https://example.com/deck","alias":"q3-deck","title":"Q3 launch deck","tags":["launch/q3"]}`},
+	} {
+		if rec := do(http.MethodPost, "/api/shorten", tc.body, tc.token); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	if rec := do(http.MethodPost, "/api/shorten", `{"url":"https://example.com","tags":["no spaces"]}`, userToken); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad tag, got %d", rec.Code)
+	}
+
+	if got := codes(do(http.MethodGet, "/api/links?q=Q3+LAUNCH", "", userToken)); !slices.Equal(got, []string{"q3-blog", "q3-page"}) {
+		t.Fatalf("expected the caller's q3 launch links, got %v", got)
+	}
+	if got := codes(do(http.MethodGet, "/api/links?tag=launch", "", userToken)); !slices.Equal(got, []string{"q3-blog", "q3-page"}) {
+		t.Fatalf("expected everything in the launch folder, got %v", got)
+	}
+	if got := codes(do(http.MethodGet, "/api/links?tag=launch/q3/blog", "", userToken)); !slices.Equal(got, []string{"q3-blog"}) {
+		t.Fatalf("expected only the blog subfolder, got %v", got)
+	}
+	if got := codes(do(http.MethodGet, "/api/links?tag=launch/q3", "", adminToken)); !slices.Equal(got, []string{"q3-blog", "q3-deck", "q3-page"}) {
+		t.Fatalf("expected admins to see every key's links, got %v", got)
+	}
+	if rec := do(http.MethodGet, "/api/links", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a key, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/links?limit=0", "", userToken); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad limit, got %d", rec.Code)
+	}
+
+	rec := do(http.MethodPatch, "/api/links/hiring", `{"title":"Careers","tags":["HR","hr"]}`, userToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var link linkJSON
+	_ = json.Unmarshal(rec.Body.Bytes(), &link)
+	if link.Title != "Careers" || link.OriginalURL != "https://example.com/hiring" || !slices.Equal(link.Tags, []string{"hr"}) {
+		t.Fatalf("unexpected edited link: %+v", link)
+	}
+	if got := codes(do(http.MethodGet, "/api/links?q=careers", "", userToken)); !slices.Equal(got, []string{"hiring"}) {
+		t.Fatalf("expected the edited title to be searchable, got %v", got)
+	}
+	if rec := do(http.MethodPatch, "/api/links/hiring", `{}`, userToken); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an empty patch, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPatch, "/api/links/hiring", `{"tags":[]}`, userToken); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for clearing tags, got %d", rec.Code)
+	}
+	if entry, _ := store.Find(ctx, "hiring"); len(entry.Tags) != 0 || entry.Title != "Careers" {
+		t.Fatalf("expected tags cleared and title kept, got %+v", entry)
+	}
+
+	_ = store.RecordCheck(ctx, "q3-blog", storage.LinkCheck{At: time.Now(), Status: http.StatusNotFound})
+	_ = store.RecordCheck(ctx, "q3-page", storage.LinkCheck{At: time.Now(), Status: http.StatusOK})
+	_ = store.RecordCheck(ctx, "q3-deck", storage.LinkCheck{At: time.Now(), Error: "timeout"})
+	if got := codes(do(http.MethodGet, "/api/links?dead=true", "", userToken)); !slices.Equal(got, []string{"q3-blog"}) {
+		t.Fatalf("expected the caller's dead links, got %v", got)
+	}
+	if got := codes(do(http.MethodGet, "/api/links?dead=1", "", adminToken)); !slices.Equal(got, []string{"q3-blog", "q3-deck"}) {
+		t.Fatalf("expected every dead link for admins, got %v", got)
+	}
+	rec = do(http.MethodGet, "/api/links?q=blog", "", userToken)
+	var dead []linkJSON
+	_ = json.Unmarshal(rec.Body.Bytes(), &dead)
+	if len(dead) != 1 || !dead[0].Dead || dead[0].CheckStatus != http.StatusNotFound || dead[0].CheckedAt.IsZero() {
+		t.Fatalf("expected the last check on the link, got %+v", dead)
+	}
+	if rec := do(http.MethodGet, "/api/links?dead=maybe", "", userToken); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad dead flag, got %d", rec.Code)
+	}
+}
+
+func TestSignedLinks(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	ownerToken, _, _ := keys.Create(ctx, "legal")
+	otherToken, _, _ := keys.Create(ctx, "sales")
+	// Keys arrive later, as on a config reload.
+	keyring := signing.NewKeyring(nil, time.Hour)
+	shortener := shortenerpkg.NewShortener(shortenerpkg.NewRandomCodeGenerator(6), store, defaultTestSettings(), shortenerpkg.WithKeyring(keyring))
+	router := NewRouter(shortener, WithAPIKeys(keys, false))
+
+	do := func(method, target, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	signedPath := func(rec *httptest.ResponseRecorder, field string) string {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var payload map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		raw, _ := payload[field].(string)
+		u, err := url.Parse(raw)
+		if err != nil || u.RawQuery == "" {
+			t.Fatalf("expected a signed url in %q, got %q", field, raw)
+		}
+		return u.RequestURI()
+	}
+
+	body := `{"url":"https://example.com/contract.pdf","alias":"contract","signed":true}`
+	if rec := do(http.MethodPost, "/api/shorten", body, ownerToken); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without signing keys, got %d", rec.Code)
+	}
+
+	keyring.Set([]signing.Key{{ID: "k1", Secret: "test-secret-0123456789"}}, time.Hour)
+	path := signedPath(do(http.MethodPost, "/api/shorten", body, ownerToken), "signed_url")
+
+	if rec := do(http.MethodGet, path, "", ""); rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://example.com/contract.pdf" {
+		t.Fatalf("expected the signed url to redirect, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := do(http.MethodGet, "/contract", "", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for the bare code, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/contract/preview", "", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unsigned preview, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/contract/preview"+strings.TrimPrefix(path, "/contract"), "", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected the signed preview to render, got %d", rec.Code)
+	}
+	tampered := strings.Replace(path, "/contract?", "/contrac?", 1)
+	if rec := do(http.MethodGet, tampered, "", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a signature of another code, got %d", rec.Code)
+	}
+
+	// Owners can mint a fresh signature; it never outlives what they asked for.
+	expires := time.Now().Add(10 * time.Minute).UTC().Truncate(time.Second)
+	fresh := signedPath(do(http.MethodPost, "/api/links/contract/sign", `{"expires_at":"`+expires.Format(time.RFC3339)+`"}`, ownerToken), "signed_url")
+	if !strings.Contains(fresh, "exp="+strconv.FormatInt(expires.Unix(), 10)) {
+		t.Fatalf("expected the requested expiry in %q", fresh)
+	}
+	if rec := do(http.MethodPost, "/api/links/contract/sign", "", otherToken); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for another key's link, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/shorten", `{"url":"https://example.com/public","alias":"public"}`, ownerToken); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/links/public/sign", "", ownerToken); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for an unsigned link, got %d", rec.Code)
+	}
+
+	// A signature past its expiry is gone, not missing.
+	stale, _ := signing.NewKeyring([]signing.Key{{ID: "k1", Secret: "test-secret-0123456789"}}, time.Hour).Sign("contract", time.Now().Add(-time.Minute))
+	if rec := do(http.MethodGet, "/contract?"+stale.Query(), "", ""); rec.Code != http.StatusGone {
+		t.Fatalf("expected 410 for an expired signature, got %d", rec.Code)
+	}
+}
+
+func TestLinkHistoryAndRollback(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	userToken, user, _ := keys.Create(ctx, "marketing")
+	otherToken, _, _ := keys.Create(ctx, "sales")
+	shortener := shortenerpkg.NewShortener(shortenerpkg.NewRandomCodeGenerator(6), store, defaultTestSettings(), shortenerpkg.WithHistory(store))
+	router := NewRouter(shortener, WithAPIKeys(keys, false))
+
+	do := func(method, target, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	history := func(token string) []linkVersionJSON {
+		t.Helper()
+		rec := do(http.MethodGet, "/api/links/launch/history", "", token)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var versions []linkVersionJSON
+		if err := json.Unmarshal(rec.Body.Bytes(), &versions); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		return versions
+	}
+
+	if rec := do(http.MethodPost, "/api/shorten", `{"url":"https://example.com/v1","alias":"launch","title":"Launch"}`, userToken); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPatch, "/api/links/launch", `{"url":"https://example.com/v2","tags":["q3"]}`, userToken); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	versions := history(userToken)
+	if len(versions) != 2 || versions[0].Version != 2 || versions[0].OriginalURL != "https://example.com/v2" ||
+		versions[1].Action != "create" || versions[1].Actor != keyOwner(user) {
+		t.Fatalf("unexpected history: %+v", versions)
+	}
+
+	rec := do(http.MethodPost, "/api/links/launch/rollback/1", "", userToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var link linkJSON
+	_ = json.Unmarshal(rec.Body.Bytes(), &link)
+	if link.OriginalURL != "https://example.com/v1" || link.Title != "Launch" || len(link.Tags) != 0 {
+		t.Fatalf("expected the first version restored, got %+v", link)
+	}
+	if versions := history(userToken); len(versions) != 3 || versions[0].Action != "rollback" {
+		t.Fatalf("expected the rollback as a new version, got %+v", versions)
+	}
+
+	for _, tc := range []struct {
+		method, target, token string
+		want                  int
+	}{
+		{http.MethodGet, "/api/links/launch/history", otherToken, http.StatusNotFound},
+		{http.MethodGet, "/api/links/launch/history", "", http.StatusUnauthorized},
+		{http.MethodPost, "/api/links/launch/rollback/1", otherToken, http.StatusNotFound},
+		{http.MethodPost, "/api/links/launch/rollback/0", userToken, http.StatusBadRequest},
+		{http.MethodPost, "/api/links/launch/rollback/v1", userToken, http.StatusBadRequest},
+		{http.MethodPost, "/api/links/launch/rollback/9", userToken, http.StatusNotFound},
+		{http.MethodPost, "/api/links/missing/rollback/1", userToken, http.StatusNotFound},
+	} {
+		if rec := do(tc.method, tc.target, "", tc.token); rec.Code != tc.want {
+			t.Fatalf("%s %s: expected %d, got %d", tc.method, tc.target, tc.want, rec.Code)
+		}
+	}
+}
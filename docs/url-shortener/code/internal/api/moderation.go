@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/moderation"
+	"urlshortener/internal/services/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxReportBytes bounds the body of POST /{shortCode}/report.
+const maxReportBytes = 8 << 10
+
+// Paging of GET /api/moderation/reports.
+const (
+	defaultReportPageSize = 50
+	maxReportPageSize     = 500
+)
+
+// reportJSON is the wire shape of a storage.Report.
+type reportJSON struct {
+	ID         string    `json:"id"`
+	ShortCode  string    `json:"short_code"`
+	Reason     string    `json:"reason"`
+	Details    string    `json:"details,omitempty"`
+	Reporter   string    `json:"reporter,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	ResolvedAt time.Time `json:"resolved_at,omitzero"`
+	ResolvedBy string    `json:"resolved_by,omitempty"`
+	Resolution string    `json:"resolution,omitempty"`
+}
+
+// moderatedLinkJSON is what disable and enable answer with.
+type moderatedLinkJSON struct {
+	ShortCode      string `json:"short_code"`
+	OriginalURL    string `json:"original_url"`
+	CreatedBy      string `json:"created_by"`
+	Disabled       bool   `json:"disabled"`
+	DisabledReason string `json:"disabled_reason,omitempty"`
+}
+
+// reportHandler lets anyone flag a link for moderators.
+func reportHandler(mod *moderation.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Reason  string `json:"reason"`
+			Details string `json:"details"`
+		}
+		body := http.MaxBytesReader(w, r.Body, maxReportBytes)
+		defer body.Close()
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			http.Error(w, "invalid json payload", http.StatusBadRequest)
+			return
+		}
+
+		report, err := mod.Report(r.Context(), moderation.ReportRequest{
+			ShortCode: chi.URLParam(r, "shortCode"),
+			Reason:    req.Reason,
+			Details:   req.Details,
+			Reporter:  clientKey(r),
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrNotFound):
+				http.NotFound(w, r)
+			case errors.Is(err, moderation.ErrInvalidReason), errors.Is(err, moderation.ErrDetailsTooLong):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				logging.Errorf("❌ Failed to file report: %v", err)
+				http.Error(w, "failed to file report", http.StatusInternalServerError)
+			}
+			return
+		}
+		// Reporters only learn that the report was taken, not who else reported.
+		writeJSON(w, http.StatusAccepted, map[string]string{"id": report.ID, "status": "received"})
+	}
+}
+
+func moderationRoutes(mod *moderation.Service, admins map[string]struct{}) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Use(requireAdmin(admins))
+		r.Get("/reports", listReportsHandler(mod))
+		r.Post("/reports/{id}/dismiss", dismissReportHandler(mod))
+		r.Post("/links/{shortCode}/disable", disableLinkHandler(mod))
+		r.Post("/links/{shortCode}/enable", enableLinkHandler(mod))
+	}
+}
+
+func listReportsHandler(mod *moderation.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		opts := storage.ReportListOptions{ShortCode: q.Get("code"), Limit: defaultReportPageSize}
+		switch status := queryOrDefault(r, "status", "open"); status {
+		case "open":
+			opts.OpenOnly = true
+		case "all":
+		default:
+			http.Error(w, "status must be open or all", http.StatusBadRequest)
+			return
+		}
+		if raw := q.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > maxReportPageSize {
+				http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+			opts.Limit = n
+		}
+		if raw := q.Get("offset"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			opts.Offset = n
+		}
+
+		reports, err := mod.Queue(r.Context(), opts)
+		if err != nil {
+			logging.Errorf("❌ Failed to list reports: %v", err)
+			http.Error(w, "failed to list reports", http.StatusInternalServerError)
+			return
+		}
+		out := make([]reportJSON, len(reports))
+		for i, rep := range reports {
+			out[i] = reportJSON(rep)
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+func dismissReportHandler(mod *moderation.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := mod.Dismiss(r.Context(), chi.URLParam(r, "id"), ownerFromContext(r.Context()))
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			logging.Errorf("❌ Failed to dismiss report: %v", err)
+			http.Error(w, "failed to dismiss report", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, reportJSON(report))
+	}
+}
+
+func disableLinkHandler(mod *moderation.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid json payload", http.StatusBadRequest)
+			return
+		}
+
+		entry, err := mod.Disable(r.Context(), chi.URLParam(r, "shortCode"), req.Reason, ownerFromContext(r.Context()))
+		writeModeratedLink(w, r, entry, err)
+	}
+}
+
+func enableLinkHandler(mod *moderation.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entry, err := mod.Enable(r.Context(), chi.URLParam(r, "shortCode"), ownerFromContext(r.Context()))
+		writeModeratedLink(w, r, entry, err)
+	}
+}
+
+func writeModeratedLink(w http.ResponseWriter, r *http.Request, entry storage.Entry, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			http.NotFound(w, r)
+		case errors.Is(err, moderation.ErrInvalidReason):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			logging.Errorf("❌ Failed to moderate link: %v", err)
+			http.Error(w, "failed to moderate link", http.StatusInternalServerError)
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, moderatedLinkJSON{
+		ShortCode:      entry.ShortCode,
+		OriginalURL:    entry.OriginalURL,
+		CreatedBy:      entry.CreatedBy,
+		Disabled:       entry.Disabled,
+		DisabledReason: entry.DisabledReason,
+	})
+}
+
+// disabledStatus is the redirect status for a taken-down link: 451 for legal
+// takedowns, 410 for abuse.
+func disabledStatus(reason string) int {
+	if reason == moderation.ReasonLegal {
+		return http.StatusUnavailableForLegalReasons
+	}
+	return http.StatusGone
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"urlshortener/internal/services/apikey"
+	"urlshortener/internal/services/moderation"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/storage"
+)
+
+func TestModerationFlow(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	adminToken, admin, _ := keys.Create(ctx, "trust-and-safety")
+	userToken, _, _ := keys.Create(ctx, "marketing")
+	shortener := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
+	router := NewRouter(shortener,
+		WithAPIKeys(keys, false),
+		WithModeration(moderation.NewService(shortener, store)),
+		WithAdmins([]string{admin.ID}),
+	)
+	_ = store.Save(ctx, storage.Entry{ShortCode: "phish1", OriginalURL: "https://phish.example/login"})
+	_ = store.Save(ctx, storage.Entry{ShortCode: "court1", OriginalURL: "https://leak.example/"})
+
+	do := func(method, target, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/phish1/report", `{"reason":"phishing","details":"fake bank"}`, ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 for a report, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/phish1/report", `{"reason":"ugly"}`, ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown reason, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/nope99/report", `{"reason":"spam"}`, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing link, got %d", rec.Code)
+	}
+
+	// The queue is for admins only.
+	if rec := do(http.MethodGet, "/api/moderation/reports", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a key, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/moderation/reports", "", userToken); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a non-admin key, got %d", rec.Code)
+	}
+	rec := do(http.MethodGet, "/api/moderation/reports", "", adminToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for the admin, got %d", rec.Code)
+	}
+	var queue []reportJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &queue); err != nil {
+		t.Fatalf("failed to decode queue: %v", err)
+	}
+	if len(queue) != 1 || queue[0].ShortCode != "phish1" || queue[0].Reporter == "" {
+		t.Fatalf("unexpected queue %+v", queue)
+	}
+
+	rec = do(http.MethodPost, "/api/moderation/links/phish1/disable", `{"reason":"phishing"}`, adminToken)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"disabled":true`) {
+		t.Fatalf("expected link to be disabled, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = do(http.MethodGet, "/phish1", "", "")
+	if rec.Code != http.StatusGone || !strings.Contains(rec.Body.String(), "Link disabled") {
+		t.Fatalf("expected the disabled page with 410, got %d", rec.Code)
+	}
+	if rec.Header().Get("Location") != "" {
+		t.Fatalf("expected no redirect for a disabled link")
+	}
+
+	// Disabling resolved the report, so the open queue is empty.
+	rec = do(http.MethodGet, "/api/moderation/reports", "", adminToken)
+	if strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Fatalf("expected empty open queue, got %s", rec.Body.String())
+	}
+	rec = do(http.MethodGet, "/api/moderation/reports?status=all", "", adminToken)
+	if !strings.Contains(rec.Body.String(), `"resolution":"disabled"`) {
+		t.Fatalf("expected resolved report in the full queue, got %s", rec.Body.String())
+	}
+
+	_ = do(http.MethodPost, "/api/moderation/links/court1/disable", `{"reason":"legal"}`, adminToken)
+	if rec := do(http.MethodGet, "/court1", "", ""); rec.Code != http.StatusUnavailableForLegalReasons {
+		t.Fatalf("expected 451 for a legal takedown, got %d", rec.Code)
+	}
+
+	if rec := do(http.MethodPost, "/api/moderation/links/phish1/enable", "", adminToken); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 when enabling, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/phish1", "", ""); rec.Code != http.StatusFound {
+		t.Fatalf("expected re-enabled link to redirect, got %d", rec.Code)
+	}
+}
+
+func TestDismissReport(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	adminToken, admin, _ := keys.Create(ctx, "trust-and-safety")
+	shortener := shortenerpkg.NewShortener(nil, store, defaultTestSettings())
+	mod := moderation.NewService(shortener, store)
+	router := NewRouter(shortener, WithAPIKeys(keys, false), WithModeration(mod), WithAdmins([]string{admin.ID}))
+	_ = store.Save(ctx, storage.Entry{ShortCode: "fine01", OriginalURL: "https://example.com"})
+	report, _ := mod.Report(ctx, moderation.ReportRequest{ShortCode: "fine01", Reason: "spam"})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/moderation/reports/"+report.ID+"/dismiss", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"resolved_by":"key:`+admin.ID+`"`) {
+		t.Fatalf("expected dismissed report, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/fine01", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected dismissed link to keep redirecting, got %d", rec.Code)
+	}
+}
+
+func TestReportRoutesNeedModeration(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	_ = store.Save(context.Background(), storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com"})
+	router := NewRouter(shortenerpkg.NewShortener(nil, store, defaultTestSettings()))
+
+	req := httptest.NewRequest(http.MethodPost, "/abc123/report", strings.NewReader(`{"reason":"spam"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound && rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected reporting to be off without moderation, got %d", rec.Code)
+	}
+}
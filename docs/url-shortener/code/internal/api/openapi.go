@@ -0,0 +1,18 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec documents every route registered in NewRouter. The contract
+// test in openapi_test.go checks real responses against it, so update both
+// together.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPISpec)
+}
@@ -0,0 +1,348 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"urlshortener/internal/services/apikey"
+	"urlshortener/internal/services/audit"
+	"urlshortener/internal/services/idempotency"
+	"urlshortener/internal/services/moderation"
+	"urlshortener/internal/services/scanguard"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/signing"
+	"urlshortener/internal/services/storage"
+	"urlshortener/internal/services/webhook"
+	"urlshortener/ui"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/go-chi/chi/v5"
+)
+
+func loadSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	if err != nil {
+		t.Fatalf("failed to load openapi.json: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("openapi.json is not a valid OpenAPI document: %v", err)
+	}
+	return doc
+}
+
+func init() {
+	// Export and import bodies and UI pages are validated as strings; NDJSON
+	// lines are checked against the Link schema separately.
+	plain := openapi3filter.RegisteredBodyDecoder("text/plain")
+	openapi3filter.RegisterBodyDecoder("text/html", plain)
+	openapi3filter.RegisterBodyDecoder("text/csv", plain)
+	openapi3filter.RegisterBodyDecoder("application/x-ndjson", plain)
+}
+
+// TestOpenAPICoversRoutes keeps the spec and NewRouter in step: every route
+// is documented and every documented operation is routed.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	doc := loadSpec(t)
+	// Every optional feature is on, so the spec has to cover all of them.
+	store := storage.NewInMemoryStore()
+	shortener := shortenerpkg.NewShortener(nil, store, defaultTestSettings())
+	router := NewRouter(shortener,
+		WithAPIKeys(apikey.NewManager(store), false),
+		WithRateLimiter(NewRateLimiter(60, 10)),
+		WithModeration(moderation.NewService(shortener, store)),
+		WithAudit(audit.New(store)),
+		WithWebhooks(webhook.New(store, webhook.Options{})),
+		WithScanGuard(scanguard.New(scanguard.Options{})),
+		WithIdempotency(idempotency.New(store, idempotency.Options{})),
+	).(chi.Routes)
+
+	routed := map[string]bool{}
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.ReplaceAll(route, "/*/", "/")
+		route = strings.Replace(route, "/static/*", "/static/{file}", 1)
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		routed[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk routes: %v", err)
+	}
+
+	documented := map[string]bool{}
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			documented[method+" "+path] = true
+		}
+	}
+
+	for route := range routed {
+		if !documented[route] {
+			t.Errorf("route %s is missing from openapi.json", route)
+		}
+	}
+	for op := range documented {
+		if !routed[op] {
+			t.Errorf("openapi.json documents %s, which is not routed", op)
+		}
+	}
+}
+
+// TestOpenAPIContract sends real requests through the router and checks both
+// the requests and the responses against the spec.
+func TestOpenAPIContract(t *testing.T) {
+	ctx := context.Background()
+	doc := loadSpec(t)
+	specRouter, err := legacy.NewRouter(doc)
+	if err != nil {
+		t.Fatalf("failed to build spec router: %v", err)
+	}
+
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	token, key, err := keys.Create(ctx, "contract")
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	auditLog := audit.New(store)
+	// Nothing dispatches in this test, so deliveries stay queued.
+	webhooks := webhook.New(store, webhook.Options{Endpoints: []webhook.Endpoint{{Name: "crm", URL: "http://crm.invalid"}}})
+	keyring := signing.NewKeyring([]signing.Key{{ID: "k1", Secret: "contract-secret-0123456789"}}, time.Hour)
+	shortener := shortenerpkg.NewShortener(shortenerpkg.NewRandomCodeGenerator(6), store, defaultTestSettings(),
+		shortenerpkg.WithAuditLog(auditLog),
+		shortenerpkg.WithWebhooks(webhooks),
+		shortenerpkg.WithKeyring(keyring),
+		shortenerpkg.WithHistory(store),
+	)
+	router := NewRouter(shortener,
+		WithAPIKeys(keys, false),
+		WithRateLimiter(NewRateLimiter(60, 100)),
+		WithModeration(moderation.NewService(shortener, store)),
+		WithAdmins([]string{key.ID}),
+		WithAudit(auditLog),
+		WithWebhooks(webhooks),
+		WithScanGuard(scanguard.New(scanguard.Options{})),
+		WithIdempotency(idempotency.New(store, idempotency.Options{})),
+	)
+
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com", CreatedBy: "anonymous", HitCount: 2})
+	_ = store.Save(ctx, storage.Entry{ShortCode: "old123", OriginalURL: "https://example.com", ExpiresAt: time.Now().Add(-time.Hour)})
+	_ = store.Save(ctx, storage.Entry{ShortCode: "bad123", OriginalURL: "https://phish.example"})
+	_ = store.Save(ctx, storage.Entry{ShortCode: "soon123", OriginalURL: "https://example.com/launch", ActivatesAt: time.Now().Add(time.Hour)})
+	_ = store.Save(ctx, storage.Entry{ShortCode: "sig123", OriginalURL: "https://example.com/private", CreatedBy: keyOwner(key), Signed: true})
+	sig, err := keyring.Sign("sig123", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to sign link: %v", err)
+	}
+	staleSig, _ := keyring.Sign("sig123", time.Now().Add(-time.Minute))
+	_ = store.EnqueueDelivery(ctx, storage.WebhookDelivery{
+		ID: "evt1.crm", Endpoint: "crm", Event: webhook.EventCreated, Status: storage.DeliveryFailed, Attempts: 8,
+		Payload:   []byte(`{"id":"evt1","event":"link.created","occurred_at":"2024-01-01T00:00:00Z","link":{"short_code":"abc123","original_url":"https://example.com","created_by":"anonymous","created_at":"2024-01-01T00:00:00Z","hit_count":0}}`),
+		LastError: "unexpected status 500", LastStatus: 500, CreatedAt: time.Now().Add(-time.Hour),
+	})
+
+	embeddedUI, err := ui.Embedded()
+	if err != nil {
+		t.Fatalf("failed to load ui: %v", err)
+	}
+
+	auth := map[string]string{"Authorization": "Bearer " + token}
+	csrf := map[string]string{"Cookie": csrfCookie + "=contract"}
+	retry := map[string]string{"Authorization": "Bearer " + token, "Idempotency-Key": "contract-retry-1"}
+	cases := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		headers     map[string]string
+		wantStatus  int
+	}{
+		{name: "health", method: http.MethodGet, target: "/healthz", wantStatus: http.StatusOK},
+		{name: "spec", method: http.MethodGet, target: "/api/openapi.json", wantStatus: http.StatusOK},
+		{name: "home", method: http.MethodGet, target: "/", wantStatus: http.StatusOK},
+		{name: "static", method: http.MethodGet, target: "/static/app.js", wantStatus: http.StatusOK},
+		{name: "static hashed", method: http.MethodGet, target: embeddedUI.AssetPath("app.js"),
+			headers: map[string]string{"Accept-Encoding": "br"}, wantStatus: http.StatusOK},
+		{name: "static missing", method: http.MethodGet, target: "/static/nope.js", wantStatus: http.StatusNotFound},
+		{name: "dashboard signed out", method: http.MethodGet, target: "/dashboard", wantStatus: http.StatusOK},
+		{name: "dashboard login", method: http.MethodPost, target: "/dashboard/login", contentType: "application/x-www-form-urlencoded",
+			body: "token=" + token + "&csrf_token=contract", headers: csrf, wantStatus: http.StatusSeeOther},
+		{name: "dashboard bad login", method: http.MethodPost, target: "/dashboard/login", contentType: "application/x-www-form-urlencoded",
+			body: "token=usk_wrong&csrf_token=contract", headers: csrf, wantStatus: http.StatusUnauthorized},
+		{name: "dashboard login forged", method: http.MethodPost, target: "/dashboard/login", contentType: "application/x-www-form-urlencoded",
+			body: "token=" + token + "&csrf_token=forged", headers: csrf, wantStatus: http.StatusForbidden},
+		{name: "redirect", method: http.MethodGet, target: "/abc123", wantStatus: http.StatusFound},
+		{name: "redirect missing", method: http.MethodGet, target: "/nope404", wantStatus: http.StatusNotFound},
+		{name: "redirect expired", method: http.MethodGet, target: "/old123", wantStatus: http.StatusGone},
+		{name: "redirect signed", method: http.MethodGet, target: "/sig123?" + sig.Query(), wantStatus: http.StatusFound},
+		{name: "redirect signed without signature", method: http.MethodGet, target: "/sig123", wantStatus: http.StatusNotFound},
+		{name: "redirect forged signature", method: http.MethodGet, target: "/sig123?exp=" + strconv.FormatInt(sig.Expires.Unix(), 10) + "&sig=k1.forged",
+			wantStatus: http.StatusNotFound},
+		{name: "redirect signature expired", method: http.MethodGet, target: "/sig123?" + staleSig.Query(), wantStatus: http.StatusGone},
+		{name: "preview signed", method: http.MethodGet, target: "/sig123/preview?" + sig.Query(), wantStatus: http.StatusOK},
+		{name: "redirect not live yet", method: http.MethodGet, target: "/soon123", wantStatus: http.StatusOK},
+		{name: "preview not live yet", method: http.MethodGet, target: "/soon123/preview", wantStatus: http.StatusOK},
+		{name: "preview", method: http.MethodGet, target: "/abc123/preview", wantStatus: http.StatusOK},
+		{name: "preview missing", method: http.MethodGet, target: "/nope404/preview", wantStatus: http.StatusNotFound},
+		{name: "preview expired", method: http.MethodGet, target: "/old123/preview", wantStatus: http.StatusGone},
+		{name: "shorten", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":"https://example.com/long"}`, wantStatus: http.StatusOK},
+		{name: "shorten with alias and expiry", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body:    `{"url":"https://example.com/long","alias":"launch","expires_at":"` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`,
+			headers: auth, wantStatus: http.StatusOK},
+		{name: "shorten with details", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body:    `{"url":"https://example.com/q3","title":"Q3 launch","description":"Landing page","tags":["Launch/Q3","marketing"]}`,
+			headers: auth, wantStatus: http.StatusOK},
+		{name: "shorten bad tag", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":"https://example.com","tags":["no spaces"]}`, wantStatus: http.StatusBadRequest},
+		{name: "shorten signed", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":"https://example.com/private","signed":true}`, headers: auth, wantStatus: http.StatusOK},
+		{name: "shorten scheduled", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":"https://example.com/q4","activates_at":"` + time.Now().Add(24*time.Hour).UTC().Format(time.RFC3339) + `"}`, wantStatus: http.StatusOK},
+		{name: "shorten activation after expiry", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":"https://example.com/q4","activates_at":"2099-01-02T00:00:00Z","expires_at":"2099-01-01T00:00:00Z"}`, wantStatus: http.StatusBadRequest},
+		{name: "shorten alias taken", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":"https://example.com/long","alias":"launch"}`, wantStatus: http.StatusConflict},
+		{name: "shorten idempotent", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":"https://example.com/retry"}`, headers: retry, wantStatus: http.StatusOK},
+		{name: "shorten idempotent replay", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":"https://example.com/retry"}`, headers: retry, wantStatus: http.StatusOK},
+		{name: "shorten idempotency key reused", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":"https://example.com/other"}`, headers: retry, wantStatus: http.StatusUnprocessableEntity},
+		{name: "shorten bad url", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":"not a url"}`, wantStatus: http.StatusBadRequest},
+		{name: "shorten bad key", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":"https://example.com"}`, headers: map[string]string{"X-API-Key": "usk_wrong"}, wantStatus: http.StatusUnauthorized},
+		{name: "export ndjson", method: http.MethodGet, target: "/api/export", headers: auth, wantStatus: http.StatusOK},
+		{name: "export json", method: http.MethodGet, target: "/api/export?format=json", headers: auth, wantStatus: http.StatusOK},
+		{name: "export csv", method: http.MethodGet, target: "/api/export?format=csv", headers: auth, wantStatus: http.StatusOK},
+		{name: "export without key", method: http.MethodGet, target: "/api/export", wantStatus: http.StatusUnauthorized},
+		{name: "import", method: http.MethodPost, target: "/api/import?policy=skip", contentType: "application/x-ndjson",
+			body:    "{\"short_code\":\"abc123\",\"original_url\":\"https://example.com\"}\n{\"short_code\":\"imp456\",\"original_url\":\"https://example.org\"}\n",
+			headers: auth, wantStatus: http.StatusOK},
+		{name: "import conflict", method: http.MethodPost, target: "/api/import?format=json&policy=fail&dry_run=true", contentType: "application/json",
+			body: `[{"short_code":"abc123","original_url":"https://example.com"}]`, headers: auth, wantStatus: http.StatusConflict},
+		{name: "report", method: http.MethodPost, target: "/bad123/report", contentType: "application/json",
+			body: `{"reason":"phishing","details":"fake login page"}`, wantStatus: http.StatusAccepted},
+		{name: "report missing", method: http.MethodPost, target: "/nope404/report", contentType: "application/json",
+			body: `{"reason":"spam"}`, wantStatus: http.StatusNotFound},
+		{name: "report queue", method: http.MethodGet, target: "/api/moderation/reports?status=open", headers: auth, wantStatus: http.StatusOK},
+		{name: "report queue without key", method: http.MethodGet, target: "/api/moderation/reports", wantStatus: http.StatusUnauthorized},
+		{name: "disable", method: http.MethodPost, target: "/api/moderation/links/bad123/disable", contentType: "application/json",
+			body: `{"reason":"phishing"}`, headers: auth, wantStatus: http.StatusOK},
+		{name: "redirect disabled", method: http.MethodGet, target: "/bad123", wantStatus: http.StatusGone},
+		{name: "enable", method: http.MethodPost, target: "/api/moderation/links/bad123/enable", headers: auth, wantStatus: http.StatusOK},
+		{name: "retarget", method: http.MethodPatch, target: "/api/links/launch", contentType: "application/json",
+			body: `{"url":"https://example.com/relaunch"}`, headers: auth, wantStatus: http.StatusOK},
+		{name: "retarget bad url", method: http.MethodPatch, target: "/api/links/launch", contentType: "application/json",
+			body: `{"url":"not a url"}`, headers: auth, wantStatus: http.StatusBadRequest},
+		{name: "edit details", method: http.MethodPatch, target: "/api/links/launch", contentType: "application/json",
+			body: `{"title":"Relaunch","tags":["launch/q3"]}`, headers: auth, wantStatus: http.StatusOK},
+		{name: "search links", method: http.MethodGet, target: "/api/links?q=launch&tag=launch", headers: auth, wantStatus: http.StatusOK},
+		{name: "search links bad tag", method: http.MethodGet, target: "/api/links?tag=a%20b", headers: auth, wantStatus: http.StatusBadRequest},
+		{name: "dead links", method: http.MethodGet, target: "/api/links?dead=true", headers: auth, wantStatus: http.StatusOK},
+		{name: "search links without key", method: http.MethodGet, target: "/api/links", wantStatus: http.StatusUnauthorized},
+		{name: "retarget not owner", method: http.MethodPatch, target: "/api/links/abc123", contentType: "application/json",
+			body: `{"url":"https://example.com/mine"}`, headers: auth, wantStatus: http.StatusNotFound},
+		{name: "sign link", method: http.MethodPost, target: "/api/links/sig123/sign", contentType: "application/json",
+			body: `{"expires_at":"` + time.Now().Add(30*time.Minute).UTC().Format(time.RFC3339) + `"}`, headers: auth, wantStatus: http.StatusOK},
+		{name: "sign link default expiry", method: http.MethodPost, target: "/api/links/sig123/sign", headers: auth, wantStatus: http.StatusOK},
+		{name: "sign unsigned link", method: http.MethodPost, target: "/api/links/launch/sign", headers: auth, wantStatus: http.StatusConflict},
+		{name: "sign not owner", method: http.MethodPost, target: "/api/links/abc123/sign", headers: auth, wantStatus: http.StatusNotFound},
+		{name: "sign without key", method: http.MethodPost, target: "/api/links/sig123/sign", wantStatus: http.StatusUnauthorized},
+		{name: "link history", method: http.MethodGet, target: "/api/links/launch/history", headers: auth, wantStatus: http.StatusOK},
+		{name: "link history not owner", method: http.MethodGet, target: "/api/links/abc123/history", headers: auth, wantStatus: http.StatusNotFound},
+		{name: "rollback", method: http.MethodPost, target: "/api/links/launch/rollback/1", headers: auth, wantStatus: http.StatusOK},
+		{name: "rollback missing version", method: http.MethodPost, target: "/api/links/launch/rollback/99", headers: auth, wantStatus: http.StatusNotFound},
+		{name: "rollback without key", method: http.MethodPost, target: "/api/links/launch/rollback/1", wantStatus: http.StatusUnauthorized},
+		{name: "audit", method: http.MethodGet, target: "/api/audit?code=launch&action=retarget", headers: auth, wantStatus: http.StatusOK},
+		{name: "audit without key", method: http.MethodGet, target: "/api/audit", wantStatus: http.StatusUnauthorized},
+		{name: "webhook deliveries", method: http.MethodGet, target: "/api/webhooks/deliveries?limit=10", headers: auth, wantStatus: http.StatusOK},
+		{name: "webhook deliveries failed", method: http.MethodGet, target: "/api/webhooks/deliveries?status=failed", headers: auth, wantStatus: http.StatusOK},
+		{name: "webhook deliveries without key", method: http.MethodGet, target: "/api/webhooks/deliveries", wantStatus: http.StatusUnauthorized},
+		{name: "webhook replay", method: http.MethodPost, target: "/api/webhooks/deliveries/evt1.crm/replay", headers: auth, wantStatus: http.StatusAccepted},
+		{name: "webhook replay not failed", method: http.MethodPost, target: "/api/webhooks/deliveries/evt1.crm/replay", headers: auth, wantStatus: http.StatusConflict},
+		{name: "webhook replay missing", method: http.MethodPost, target: "/api/webhooks/deliveries/nope/replay", headers: auth, wantStatus: http.StatusNotFound},
+		{name: "webhook replay failed", method: http.MethodPost, target: "/api/webhooks/deliveries/replay", headers: auth, wantStatus: http.StatusAccepted},
+		{name: "scan guard stats", method: http.MethodGet, target: "/api/scanguard", headers: auth, wantStatus: http.StatusOK},
+		{name: "scan guard stats without key", method: http.MethodGet, target: "/api/scanguard", wantStatus: http.StatusUnauthorized},
+		{name: "dismiss missing", method: http.MethodPost, target: "/api/moderation/reports/nope/dismiss", headers: auth, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			route, pathParams, err := specRouter.FindRoute(req)
+			if err != nil {
+				t.Fatalf("request does not match the spec: %v", err)
+			}
+			reqInput := &openapi3filter.RequestValidationInput{
+				Request:    req.Clone(ctx),
+				PathParams: pathParams,
+				Route:      route,
+				Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+			}
+			reqInput.Request.Body = io.NopCloser(strings.NewReader(tc.body))
+			if err := openapi3filter.ValidateRequest(ctx, reqInput); err != nil {
+				t.Fatalf("request does not conform to the spec: %v", err)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			respInput := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: reqInput,
+				Status:                 rec.Code,
+				Header:                 rec.Header(),
+				Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+			}
+			respInput.SetBodyBytes(rec.Body.Bytes())
+			if err := openapi3filter.ValidateResponse(ctx, respInput); err != nil {
+				t.Fatalf("response does not conform to the spec: %v", err)
+			}
+			if rec.Header().Get("Content-Type") == "application/x-ndjson" {
+				validateNDJSON(t, doc, rec.Body.Bytes())
+			}
+		})
+	}
+}
+
+// validateNDJSON checks each line of an NDJSON body against the Link schema.
+func validateNDJSON(t *testing.T, doc *openapi3.T, body []byte) {
+	t.Helper()
+	schema := doc.Components.Schemas["Link"].Value
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for line := 1; scanner.Scan(); line++ {
+		var value any
+		if err := json.Unmarshal(scanner.Bytes(), &value); err != nil {
+			t.Fatalf("line %d is not json: %v", line, err)
+		}
+		if err := schema.VisitJSON(value); err != nil {
+			t.Fatalf("line %d does not match Link: %v", line, err)
+		}
+	}
+}
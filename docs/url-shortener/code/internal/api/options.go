@@ -0,0 +1,165 @@
+package api
+
+import (
+	"net/netip"
+	"time"
+
+	"urlshortener/internal/services/apikey"
+	"urlshortener/internal/services/audit"
+	"urlshortener/internal/services/idempotency"
+	"urlshortener/internal/services/moderation"
+	"urlshortener/internal/services/scanguard"
+	"urlshortener/internal/services/webhook"
+	"urlshortener/ui"
+)
+
+// Option configures optional router features.
+type Option func(*routerConfig)
+
+type routerConfig struct {
+	keys          *apikey.Manager
+	requireAPIKey bool
+	limiter       *RateLimiter
+	ui            *ui.UI
+	moderation    *moderation.Service
+	audit         *audit.Log
+	webhooks      *webhook.Service
+	admins        map[string]struct{}
+	markBroken    bool
+	guard         *scanguard.Guard
+	idempotency   *idempotency.Service
+	fallbackURL   string
+	cors          CORS
+	security      *SecurityHeaders
+	sessionTTL    time.Duration
+	secureCookies bool
+	proxies       trustedProxies
+}
+
+// WithAPIKeys authenticates /api requests with keys from mgr. A valid key
+// makes the key's ID the owner of links it creates. When required is false,
+// requests without a key are still served anonymously.
+func WithAPIKeys(mgr *apikey.Manager, required bool) Option {
+	return func(c *routerConfig) {
+		c.keys = mgr
+		c.requireAPIKey = required
+	}
+}
+
+// WithRateLimiter throttles /api requests per API key, or per client IP for
+// anonymous callers.
+func WithRateLimiter(rl *RateLimiter) Option {
+	return func(c *routerConfig) {
+		c.limiter = rl
+	}
+}
+
+// WithUI serves the web UI from u instead of the embedded copy, e.g. ui.Dev
+// for live editing.
+func WithUI(u *ui.UI) Option {
+	return func(c *routerConfig) {
+		c.ui = u
+	}
+}
+
+// WithAdmins lets the API keys whose IDs are in keyIDs use the admin
+// endpoints under /api/moderation, /api/audit and /api/webhooks.
+func WithAdmins(keyIDs []string) Option {
+	return func(c *routerConfig) {
+		c.admins = make(map[string]struct{}, len(keyIDs))
+		for _, id := range keyIDs {
+			c.admins[id] = struct{}{}
+		}
+	}
+}
+
+// WithModeration enables POST /{shortCode}/report and the /api/moderation
+// endpoints, which need an admin key (see WithAdmins).
+func WithModeration(svc *moderation.Service) Option {
+	return func(c *routerConfig) {
+		c.moderation = svc
+	}
+}
+
+// WithAudit enables GET /api/audit, which needs an admin key (see
+// WithAdmins).
+func WithAudit(log *audit.Log) Option {
+	return func(c *routerConfig) {
+		c.audit = log
+	}
+}
+
+// WithWebhooks enables the /api/webhooks endpoints for inspecting and
+// replaying deliveries, which need an admin key (see WithAdmins).
+func WithWebhooks(svc *webhook.Service) Option {
+	return func(c *routerConfig) {
+		c.webhooks = svc
+	}
+}
+
+// WithBrokenLinkWarnings makes the /{shortCode}/preview page warn when the
+// link's last health check found its destination dead.
+func WithBrokenLinkWarnings() Option {
+	return func(c *routerConfig) {
+		c.markBroken = true
+	}
+}
+
+// WithScanGuard slows down and bans clients that request many short codes
+// that don't exist, and enables GET /api/scanguard, which needs an admin key
+// (see WithAdmins).
+func WithScanGuard(g *scanguard.Guard) Option {
+	return func(c *routerConfig) {
+		c.guard = g
+	}
+}
+
+// WithTrustedProxies identifies clients by the X-Forwarded-For or X-Real-IP
+// address that a proxy in proxies reports, for rate limits, the scan guard
+// and the audit trail. Other peers are identified by their own address.
+func WithTrustedProxies(proxies []netip.Prefix) Option {
+	return func(c *routerConfig) {
+		c.proxies = proxies
+	}
+}
+
+// WithIdempotency makes POST /api/shorten honour the Idempotency-Key header,
+// so a retried request gets the first response instead of a second link.
+func WithIdempotency(svc *idempotency.Service) Option {
+	return func(c *routerConfig) {
+		c.idempotency = svc
+	}
+}
+
+// WithActivationFallback sends visitors of links that are not live yet to
+// fallbackURL instead of showing them the coming-soon page.
+func WithActivationFallback(fallbackURL string) Option {
+	return func(c *routerConfig) {
+		c.fallbackURL = fallbackURL
+	}
+}
+
+// WithCORS lets browser pages on c.AllowedOrigins call /api.
+func WithCORS(c CORS) Option {
+	return func(cfg *routerConfig) {
+		cfg.cors = c
+	}
+}
+
+// WithSecurityHeaders replaces DefaultSecurityHeaders.
+func WithSecurityHeaders(h SecurityHeaders) Option {
+	return func(c *routerConfig) {
+		c.security = &h
+	}
+}
+
+// WithSessions signs dashboard sessions out after ttl, DefaultSessionTTL if
+// ttl is not positive. With secureCookies, the UI's cookies are marked
+// Secure even on plain HTTP requests, as they should be behind a proxy that
+// terminates TLS; requests that arrive over TLS always get Secure cookies.
+func WithSessions(ttl time.Duration, secureCookies bool) Option {
+	return func(c *routerConfig) {
+		c.sessionTTL = ttl
+		c.secureCookies = secureCookies
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a per-client token bucket. Clients are identified by API key
+// when one authenticated the request, else by remote IP. The limit can be
+// changed while serving; a non-positive rate turns limiting off.
+type RateLimiter struct {
+	mu        sync.Mutex
+	perSecond float64
+	burst     float64
+	buckets   map[string]*bucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	seen   time.Time
+}
+
+// bucketIdleTTL is how long an untouched bucket is kept before pruning.
+const bucketIdleTTL = 10 * time.Minute
+
+func NewRateLimiter(requestsPerMinute, burst int) *RateLimiter {
+	rl := &RateLimiter{buckets: map[string]*bucket{}, now: time.Now}
+	rl.SetLimit(requestsPerMinute, burst)
+	return rl
+}
+
+// SetLimit replaces the rate and burst. Existing buckets keep their tokens,
+// capped to the new burst.
+func (rl *RateLimiter) SetLimit(requestsPerMinute, burst int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if burst <= 0 {
+		burst = max(requestsPerMinute, 1)
+	}
+	rl.perSecond = float64(requestsPerMinute) / 60
+	rl.burst = float64(burst)
+}
+
+// Allow takes a token for key. When none is left it returns how long until
+// the next one is available.
+func (rl *RateLimiter) Allow(key string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.perSecond <= 0 {
+		return true, 0
+	}
+
+	now := rl.now()
+	rl.prune(now)
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: rl.burst, seen: now}
+		rl.buckets[key] = b
+	}
+	b.tokens = min(rl.burst, b.tokens+now.Sub(b.seen).Seconds()*rl.perSecond)
+	b.seen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / rl.perSecond * float64(time.Second))
+	return false, wait
+}
+
+func (rl *RateLimiter) prune(now time.Time) {
+	if now.Sub(rl.lastPrune) < bucketIdleTTL {
+		return
+	}
+	rl.lastPrune = now
+	for key, b := range rl.buckets {
+		if now.Sub(b.seen) > bucketIdleTTL {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+func (rl *RateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := rl.Allow(clientKey(r))
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientKey identifies who a request counts against.
+func clientKey(r *http.Request) string {
+	if key, ok := apiKeyFromContext(r.Context()); ok {
+		return "key:" + key.ID
+	}
+	return "ip:" + clientIP(r)
+}
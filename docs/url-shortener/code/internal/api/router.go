@@ -3,56 +3,190 @@ package api
 import (
 	"encoding/json"
 	"errors"
-	"log"
 	"net/http"
+	"time"
 
+	"urlshortener/internal/logging"
 	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/signing"
 	"urlshortener/internal/services/storage"
+	"urlshortener/ui"
 
 	"github.com/go-chi/chi/v5"
 )
 
-func NewRouter(shortsvc *shortenerpkg.Shortener) http.Handler {
-	chi := chi.NewRouter()
+func NewRouter(shortsvc *shortenerpkg.Shortener, opts ...Option) http.Handler {
+	var cfg routerConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.ui == nil {
+		embedded, err := ui.Embedded()
+		if err != nil {
+			panic(err) // the UI is embedded, so this is a build-time mistake
+		}
+		cfg.ui = embedded
+	}
+
+	if cfg.security == nil {
+		defaults := DefaultSecurityHeaders()
+		cfg.security = &defaults
+	}
 
-	chi.Get("/healthz", healthHandler)
-	chi.Get("/", rootHandler)
-	chi.Get("/{shortCode}", shortCodeHandler(shortsvc))
-	chi.Handle("/static/*", staticFilesHandler())
-	chi.Post("/api/shorten", shortenHandler(shortsvc))
+	router := chi.NewRouter()
+	if len(cfg.proxies) > 0 {
+		router.Use(realClientIP(cfg.proxies))
+	}
+	router.Use(auditContext)
+	router.Use(securityHeaders(*cfg.security))
+	if cfg.sessionTTL <= 0 {
+		cfg.sessionTTL = DefaultSessionTTL
+	}
+	cookies := cookiePolicy{secure: cfg.secureCookies}
+	web := newDashboard(shortsvc, cfg.keys, cfg.ui, cfg.fallbackURL, cookies, cfg.sessionTTL)
+
+	router.Get("/healthz", healthHandler)
+	// The home page carries the sign-out form when a session is open.
+	router.With(csrfProtect(cookies)).Get("/", web.home)
+	// Both routes answer 404 for unknown codes, so both are guarded.
+	lookups := router.With()
+	if cfg.guard != nil {
+		lookups = lookups.With(scanGuard(cfg.guard))
+	}
+	lookups.Get("/{shortCode}", shortCodeHandler(shortsvc, web))
+	lookups.Get("/{shortCode}/preview", web.preview(cfg.markBroken))
+	if cfg.moderation != nil {
+		// Reports are anonymous, so they are limited per IP like /api calls.
+		report := router.With()
+		if cfg.limiter != nil {
+			report = report.With(cfg.limiter.middleware)
+		}
+		report.Post("/{shortCode}/report", reportHandler(cfg.moderation))
+	}
+	static := http.StripPrefix("/static/", cfg.ui.Static())
+	router.Get("/static/*", static.ServeHTTP)
+	router.Head("/static/*", static.ServeHTTP)
+	router.With(csrfProtect(cookies)).Route("/dashboard", web.routes)
+	router.Route("/api", func(r chi.Router) {
+		// Preflights carry no credentials, so CORS goes before the key check.
+		if len(cfg.cors.AllowedOrigins) > 0 {
+			r.Use(cors(cfg.cors))
+		}
 
-	return chi
+		// The spec is public so clients can fetch it before they have a key.
+		r.Get("/openapi.json", openAPIHandler)
+
+		r.Group(func(r chi.Router) {
+			if cfg.keys != nil {
+				r.Use(apiKeyMiddleware(cfg.keys, cfg.requireAPIKey))
+			}
+			if cfg.limiter != nil {
+				r.Use(cfg.limiter.middleware)
+			}
+			shorten := r.With()
+			if cfg.idempotency != nil {
+				shorten = shorten.With(idempotent(cfg.idempotency))
+			}
+			shorten.Post("/shorten", shortenHandler(shortsvc))
+
+			// Bulk endpoints see and write every owner's links, moderation
+			// state included, so they are for admins only.
+			r.With(requireAdmin(cfg.admins)).Get("/export", exportHandler(shortsvc))
+			r.With(requireAdmin(cfg.admins)).Post("/import", importHandler(shortsvc))
+			r.With(requireAPIKey).Get("/links", listLinksHandler(shortsvc, cfg.admins))
+			r.With(requireAPIKey).Patch("/links/{shortCode}", updateLinkHandler(shortsvc))
+			r.With(requireAPIKey).Post("/links/{shortCode}/sign", signLinkHandler(shortsvc))
+			r.With(requireAPIKey).Get("/links/{shortCode}/history", linkHistoryHandler(shortsvc))
+			r.With(requireAPIKey).Post("/links/{shortCode}/rollback/{version}", rollbackLinkHandler(shortsvc))
+
+			if cfg.moderation != nil {
+				r.Route("/moderation", moderationRoutes(cfg.moderation, cfg.admins))
+			}
+			if cfg.audit != nil {
+				r.With(requireAdmin(cfg.admins)).Get("/audit", auditHandler(cfg.audit))
+			}
+			if cfg.webhooks != nil {
+				r.Route("/webhooks", webhookRoutes(cfg.webhooks, cfg.admins))
+			}
+			if cfg.guard != nil {
+				r.With(requireAdmin(cfg.admins)).Get("/scanguard", scanGuardStatsHandler(cfg.guard))
+			}
+		})
+	})
+
+	return router
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.Errorf("❌ Failed to encode response: %v", err)
+	}
 }
 
 func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("ok"))
 }
 
-func rootHandler(w http.ResponseWriter, r *http.Request) {
-	http.ServeFile(w, r, "ui/index.html")
-}
-
-func shortCodeHandler(shortsvc *shortenerpkg.Shortener) http.HandlerFunc {
+func shortCodeHandler(shortsvc *shortenerpkg.Shortener, web *dashboard) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		shortCode := chi.URLParam(r, "shortCode")
 		if shortCode == "" {
-			log.Println("⚠️  Empty short code in request")
+			logging.Warnf("⚠️  Empty short code in request")
 			http.Error(w, "short-code is required", http.StatusBadRequest)
 			return
 		}
-		entry, err := shortsvc.Lookup(r.Context(), shortCode)
+		var (
+			entry storage.Entry
+			err   error
+		)
+		// A signature is checked before the code is looked up.
+		if sig, ok := signing.FromQuery(r.URL.Query()); ok {
+			entry, err = shortsvc.LookupSigned(r.Context(), shortCode, sig)
+		} else {
+			entry, err = shortsvc.Lookup(r.Context(), shortCode)
+		}
 		if err != nil {
 			if errors.Is(err, storage.ErrNotFound) {
-				log.Printf("⚠️  Short code not found: %s", shortCode)
+				logging.Warnf("⚠️  Short code not found: %s", shortCode)
+				http.NotFound(w, r)
+				return
+			}
+			// Without a valid signature, a signed link looks like no link.
+			if errors.Is(err, shortenerpkg.ErrSignatureRequired) || errors.Is(err, signing.ErrInvalidSignature) {
+				logging.Warnf("⚠️  Rejected unsigned or forged link: %s", shortCode)
 				http.NotFound(w, r)
 				return
 			}
-			log.Printf("❌ Failed to lookup short code %s: %v", shortCode, err)
+			if errors.Is(err, signing.ErrExpired) {
+				logging.Warnf("⚠️  Signed link expired: %s", shortCode)
+				http.Error(w, "signed link has expired", http.StatusGone)
+				return
+			}
+			if errors.Is(err, shortenerpkg.ErrDisabled) {
+				logging.Warnf("⚠️  Short code disabled: %s", shortCode)
+				web.disabled(w, r, entry)
+				return
+			}
+			if errors.Is(err, shortenerpkg.ErrExpired) {
+				logging.Warnf("⚠️  Short code expired: %s", shortCode)
+				http.Error(w, "short link has expired", http.StatusGone)
+				return
+			}
+			if errors.Is(err, shortenerpkg.ErrNotActive) {
+				logging.Debugf("⏳ Short code not live yet: %s", shortCode)
+				web.comingSoon(w, r, entry)
+				return
+			}
+			logging.Errorf("❌ Failed to lookup short code %s: %v", shortCode, err)
 			http.Error(w, "failed to resolve short code", http.StatusInternalServerError)
 			return
 		}
-		log.Printf("✅ Redirecting %s -> %s", shortCode, entry.OriginalURL)
+		logging.Debugf("✅ Redirecting %s -> %s", shortCode, entry.OriginalURL)
 		http.Redirect(w, r, entry.OriginalURL, http.StatusFound)
 	}
 }
@@ -68,41 +202,103 @@ func shortenHandler(shortsvc *shortenerpkg.Shortener) http.HandlerFunc {
 		defer r.Body.Close()
 
 		var req struct {
-			URL string `json:"url"`
+			URL         string    `json:"url"`
+			Alias       string    `json:"alias"`
+			ExpiresAt   time.Time `json:"expires_at"`
+			ActivatesAt time.Time `json:"activates_at"`
+			Title       string    `json:"title"`
+			Description string    `json:"description"`
+			Tags        []string  `json:"tags"`
+			Signed      bool      `json:"signed"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			log.Printf("❌ Failed to decode JSON: %v", err)
+			logging.Errorf("❌ Failed to decode JSON: %v", err)
 			http.Error(w, "invalid json payload", http.StatusBadRequest)
 			return
 		}
 		if req.URL == "" {
-			log.Println("⚠️  Empty URL in request")
+			logging.Warnf("⚠️  Empty URL in request")
 			http.Error(w, "url is required", http.StatusBadRequest)
 			return
 		}
 
-		resp, err := shortsvc.Shorten(r.Context(), shortenerpkg.ShortenRequest{URL: req.URL})
+		resp, err := shortsvc.Shorten(r.Context(), shortenerpkg.ShortenRequest{
+			URL:       req.URL,
+			Alias:     req.Alias,
+			ExpiresAt: req.ExpiresAt,
+			CreatedBy: ownerFromContext(r.Context()),
+
+			ActivatesAt: req.ActivatesAt,
+
+			Title:       req.Title,
+			Description: req.Description,
+			Tags:        req.Tags,
+			Signed:      req.Signed,
+		})
 		if err != nil {
-			log.Printf("❌ Failed to shorten URL: %v", err)
+			if status, ok := shortenErrorStatus(err); ok {
+				logging.Warnf("⚠️  Rejected shorten request: %v", err)
+				http.Error(w, err.Error(), status)
+				return
+			}
+			logging.Errorf("❌ Failed to shorten URL: %v", err)
 			http.Error(w, "failed to shorten url", http.StatusInternalServerError)
 			return
 		}
 
-		payload := map[string]string{
+		payload := map[string]any{
 			"short_code":   resp.ShortCode,
 			"original_url": resp.OriginalURL,
 		}
+		if !resp.ExpiresAt.IsZero() {
+			payload["expires_at"] = resp.ExpiresAt.Format(time.RFC3339)
+		}
+		if !resp.ActivatesAt.IsZero() {
+			payload["activates_at"] = resp.ActivatesAt.Format(time.RFC3339)
+		}
+		if resp.Title != "" {
+			payload["title"] = resp.Title
+		}
+		if resp.Description != "" {
+			payload["description"] = resp.Description
+		}
+		if len(resp.Tags) > 0 {
+			payload["tags"] = resp.Tags
+		}
+		if resp.Signed {
+			payload["signed"] = true
+			payload["signed_url"] = signedURL(r, resp.ShortCode, resp.Signature)
+			payload["signature_expires_at"] = resp.Signature.Expires.Format(time.RFC3339)
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(payload); err != nil {
-			log.Printf("❌ Failed to encode response: %v", err)
+			logging.Errorf("❌ Failed to encode response: %v", err)
 			http.Error(w, "failed to encode response", http.StatusInternalServerError)
 		}
 	}
 }
 
-func staticFilesHandler() http.Handler {
-	const prefix = "/static/"
-	fs := http.FileServer(http.Dir("ui"))
-	return http.StripPrefix(prefix, fs)
+// shortenErrorStatus maps the caller's mistakes to 4xx statuses.
+func shortenErrorStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, shortenerpkg.ErrEmptyURL),
+		errors.Is(err, shortenerpkg.ErrInvalidURL),
+		errors.Is(err, shortenerpkg.ErrInvalidAlias),
+		errors.Is(err, shortenerpkg.ErrReservedAlias),
+		errors.Is(err, shortenerpkg.ErrInvalidExpiry),
+		errors.Is(err, shortenerpkg.ErrInvalidActivation),
+		errors.Is(err, shortenerpkg.ErrDeniedURL),
+		errors.Is(err, shortenerpkg.ErrBlockedURL),
+		errors.Is(err, shortenerpkg.ErrTitleTooLong),
+		errors.Is(err, shortenerpkg.ErrDescriptionTooLong),
+		errors.Is(err, shortenerpkg.ErrInvalidTag),
+		errors.Is(err, shortenerpkg.ErrTooManyTags),
+		errors.Is(err, shortenerpkg.ErrNoChanges),
+		errors.Is(err, signing.ErrNoKeys):
+		return http.StatusBadRequest, true
+	case errors.Is(err, shortenerpkg.ErrAliasTaken):
+		return http.StatusConflict, true
+	}
+	return 0, false
 }
@@ -4,10 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"math/rand/v2"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"urlshortener/internal/services/apikey"
 	shortenerpkg "urlshortener/internal/services/shortener"
 	"urlshortener/internal/services/storage"
 )
@@ -101,3 +106,329 @@ func TestRedirectHandlerSuccess(t *testing.T) {
 		t.Fatalf("expected HitCount 1, got %d", entry.HitCount)
 	}
 }
+
+func postShorten(t *testing.T, router http.Handler, body map[string]string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	buf, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("failed to marshal request body: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(buf))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShortenHandlerAlias(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
+	router := NewRouter(shortener)
+
+	rec := postShorten(t, router, map[string]string{"url": "https://example.com", "alias": "launch"}, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	rec = postShorten(t, router, map[string]string{"url": "https://example.com", "alias": "launch"}, nil)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for taken alias, got %d", rec.Code)
+	}
+
+	rec = postShorten(t, router, map[string]string{"url": "https://example.com", "alias": "x"}, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for invalid alias, got %d", rec.Code)
+	}
+}
+
+func TestRedirectHandlerExpired(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
+	router := NewRouter(shortener)
+
+	_ = store.Save(context.Background(), storage.Entry{
+		ShortCode:   "old123",
+		OriginalURL: "https://example.com",
+		ExpiresAt:   time.Now().Add(-time.Hour),
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/old123", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusGone {
+		t.Fatalf("expected status 410, got %d", rec.Code)
+	}
+}
+
+func TestRedirectHandlerNotActive(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
+	_ = store.Save(context.Background(), storage.Entry{
+		ShortCode:   "launch",
+		OriginalURL: "https://example.com/secret-product",
+		ActivatesAt: time.Now().Add(time.Hour),
+	})
+
+	get := func(router http.Handler, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	router := NewRouter(shortener)
+	for _, target := range []string{"/launch", "/launch/preview"} {
+		rec := get(router, target)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goes live") {
+			t.Fatalf("%s: expected the coming-soon page, got %d %q", target, rec.Code, rec.Body.String())
+		}
+		if strings.Contains(rec.Body.String(), "secret-product") || rec.Header().Get("Cache-Control") != "no-store" {
+			t.Fatalf("%s: expected an uncached page without the destination, got %q", target, rec.Body.String())
+		}
+	}
+	if entry, _ := store.Find(context.Background(), "launch"); entry.HitCount != 0 {
+		t.Fatalf("expected no hits before launch, got %d", entry.HitCount)
+	}
+
+	router = NewRouter(shortener, WithActivationFallback("https://example.com/coming-soon"))
+	rec := get(router, "/launch")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://example.com/coming-soon" {
+		t.Fatalf("expected a redirect to the fallback, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestShortenHandlerAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	token, key, err := keys.Create(ctx, "ci")
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	shortener := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
+	router := NewRouter(shortener, WithAPIKeys(keys, true))
+
+	rec := postShorten(t, router, map[string]string{"url": "https://example.com"}, nil)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 without key, got %d", rec.Code)
+	}
+
+	rec = postShorten(t, router, map[string]string{"url": "https://example.com"}, map[string]string{"Authorization": "Bearer usk_wrong"})
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 with bad key, got %d", rec.Code)
+	}
+
+	rec = postShorten(t, router, map[string]string{"url": "https://example.com"}, map[string]string{"Authorization": "Bearer " + token})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 with valid key, got %d", rec.Code)
+	}
+	entry, err := store.Find(ctx, "stub123")
+	if err != nil {
+		t.Fatalf("expected entry to exist, got error: %v", err)
+	}
+	if entry.CreatedBy != "key:"+key.ID {
+		t.Fatalf("expected link owned by key %s, got %s", key.ID, entry.CreatedBy)
+	}
+}
+
+func TestExportImportHandlers(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	token, admin, err := keys.Create(ctx, "ops")
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	userToken, _, err := keys.Create(ctx, "alice")
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	shortener := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
+	router := NewRouter(shortener, WithAPIKeys(keys, false), WithAdmins([]string{admin.ID}))
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com", HitCount: 5})
+
+	// No key: rejected even though keys are optional for /api/shorten.
+	req := httptest.NewRequest(http.MethodGet, "/api/export?format=csv", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 without key, got %d", rec.Code)
+	}
+	// Other keys would see every owner's links and could overwrite them.
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/export", nil),
+		httptest.NewRequest(http.MethodPost, "/api/import?policy=overwrite", strings.NewReader(`{"short_code":"abc123","original_url":"https://evil.example"}`)),
+	} {
+		req.Header.Set("Authorization", "Bearer "+userToken)
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("%s %s: expected status 403 for a non-admin key, got %d", req.Method, req.URL, rec.Code)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/export?format=csv", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+	if !bytes.Contains(rec.Body.Bytes(), []byte("abc123,https://example.com,")) {
+		t.Fatalf("expected link in export, got %q", rec.Body.String())
+	}
+
+	body := `{"short_code":"abc123","original_url":"https://other.com"}
+{"short_code":"new456","original_url":"https://new.example.com"}
+`
+	req = httptest.NewRequest(http.MethodPost, "/api/import?format=ndjson&policy=fail&dry_run=true", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for conflict with policy=fail, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/import?format=ndjson&policy=skip", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var report struct {
+		Created int `json:"created"`
+		Skipped int `json:"skipped"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	if report.Created != 1 || report.Skipped != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if _, err := store.Find(ctx, "new456"); err != nil {
+		t.Fatalf("expected imported link, got error: %v", err)
+	}
+}
+
+func TestShortenHandlerRateLimit(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := shortenerpkg.NewShortener(shortenerpkg.NewRandomCodeGenerator(6), store, defaultTestSettings())
+	limiter := NewRateLimiter(60, 2)
+	router := NewRouter(shortener, WithRateLimiter(limiter))
+
+	for i := 0; i < 2; i++ {
+		rec := postShorten(t, router, map[string]string{"url": "https://example.com"}, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d", i, rec.Code)
+		}
+	}
+
+	rec := postShorten(t, router, map[string]string{"url": "https://example.com"}, nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 after burst, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("expected Retry-After 1, got %q", rec.Header().Get("Retry-After"))
+	}
+
+	limiter.SetLimit(0, 0)
+	rec = postShorten(t, router, map[string]string{"url": "https://example.com"}, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 with limiting disabled, got %d", rec.Code)
+	}
+}
+
+func TestShortenHandlerDeniedHost(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	settings := defaultTestSettings()
+	settings.Denylist = []string{"evil.example"}
+	shortener := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, settings)
+	router := NewRouter(shortener)
+
+	rec := postShorten(t, router, map[string]string{"url": "https://login.evil.example/x"}, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for denied host, got %d", rec.Code)
+	}
+}
+
+// benchLinks is how many links the redirect benchmarks spread their hits over.
+const benchLinks = 10000
+
+// newBenchRouter is the router with its default middleware over an
+// in-memory store holding benchLinks links.
+func newBenchRouter(b *testing.B) (http.Handler, []string) {
+	b.Helper()
+	store := storage.NewInMemoryStore()
+	codes := make([]string, benchLinks)
+	for i := range codes {
+		codes[i] = fmt.Sprintf("b%05d", i)
+		if err := store.Save(context.Background(), storage.Entry{ShortCode: codes[i], OriginalURL: "https://example.com"}); err != nil {
+			b.Fatalf("Save returned error: %v", err)
+		}
+	}
+	shortener := shortenerpkg.NewShortener(shortenerpkg.NewRandomCodeGenerator(8), store, defaultTestSettings())
+	return NewRouter(shortener), codes
+}
+
+func benchRedirect(b *testing.B, router http.Handler, code string) {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+	if rec.Code != http.StatusFound {
+		b.Errorf("expected status 302, got %d", rec.Code)
+	}
+}
+
+func benchShorten(b *testing.B, router http.Handler) {
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://example.com/some/long/path"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		b.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func BenchmarkRouterRedirect(b *testing.B) {
+	router, codes := newBenchRouter(b)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		benchRedirect(b, router, codes[i%len(codes)])
+		i++
+	}
+}
+
+func BenchmarkRouterRedirectParallel(b *testing.B) {
+	router, codes := newBenchRouter(b)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := rand.IntN(len(codes))
+		for pb.Next() {
+			benchRedirect(b, router, codes[i%len(codes)])
+			i++
+		}
+	})
+}
+
+func BenchmarkRouterShorten(b *testing.B) {
+	router, _ := newBenchRouter(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		benchShorten(b, router)
+	}
+}
+
+func BenchmarkRouterShortenParallel(b *testing.B) {
+	router, _ := newBenchRouter(b)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			benchShorten(b, router)
+		}
+	})
+}
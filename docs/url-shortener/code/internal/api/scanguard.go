@@ -0,0 +1,90 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"urlshortener/internal/services/scanguard"
+)
+
+// unguardedPaths are fetched by browsers and crawlers on their own, so a
+// 404 there says nothing about scanning.
+var unguardedPaths = map[string]struct{}{
+	"/favicon.ico": {},
+	"/robots.txt":  {},
+}
+
+// scanGuard refuses banned clients and counts every 404 a client gets
+// against it, holding the response for as long as the guard says.
+func scanGuard(g *scanguard.Guard) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := clientKey(r)
+			if wait := g.Banned(r.Context(), client); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "too many requests for unknown links", http.StatusTooManyRequests)
+				return
+			}
+			if _, ok := unguardedPaths[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(&missWriter{ResponseWriter: w, r: r, guard: g, client: client}, r)
+		})
+	}
+}
+
+// missWriter delays a 404 before its header goes out, so a scanner can't
+// read the miss early.
+type missWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	guard       *scanguard.Guard
+	client      string
+	wroteHeader bool
+}
+
+func (w *missWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status == http.StatusNotFound {
+			w.hold(w.guard.Miss(w.r.Context(), w.client))
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *missWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// hold waits d, or until the client goes away.
+func (w *missWriter) hold(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-w.r.Context().Done():
+	}
+}
+
+func scanGuardStatsHandler(g *scanguard.Guard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s := g.Stats()
+		writeJSON(w, http.StatusOK, map[string]any{
+			"misses":  s.Misses,
+			"delayed": s.Delayed,
+			"bans":    s.Bans,
+			"blocked": s.Blocked,
+			"tracked": s.Tracked,
+		})
+	}
+}
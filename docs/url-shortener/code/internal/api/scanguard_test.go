@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"urlshortener/internal/services/apikey"
+	"urlshortener/internal/services/scanguard"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/storage"
+)
+
+func TestScanGuardStopsScanner(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	adminToken, admin, _ := keys.Create(ctx, "ops")
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com"})
+	shortener := shortenerpkg.NewShortener(shortenerpkg.NewRandomCodeGenerator(6), store, defaultTestSettings())
+	guard := scanguard.New(scanguard.Options{
+		Threshold:   3,
+		Delay:       20 * time.Millisecond,
+		MaxDelay:    40 * time.Millisecond,
+		BanAfter:    6,
+		BanDuration: time.Minute,
+	})
+	router := NewRouter(shortener, WithAPIKeys(keys, false), WithAdmins([]string{admin.ID}), WithScanGuard(guard))
+
+	get := func(target, remoteAddr string) (*httptest.ResponseRecorder, time.Duration) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		router.ServeHTTP(rec, req)
+		return rec, time.Since(start)
+	}
+
+	// A legitimate client resolving real links is never counted.
+	for range 10 {
+		if rec, _ := get("/abc123", "198.51.100.20:4000"); rec.Code != http.StatusFound {
+			t.Fatalf("expected 302, got %d", rec.Code)
+		}
+	}
+
+	// The scanner walks the code space: fast 404s, then slower ones, then a ban.
+	const scanner = "203.0.113.7:5000"
+	for i := range 6 {
+		rec, took := get(fmt.Sprintf("/zz%04d", i), scanner)
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("probe %d: expected 404, got %d", i+1, rec.Code)
+		}
+		if i >= 3 && took < 20*time.Millisecond {
+			t.Fatalf("probe %d: expected a delayed 404, took %s", i+1, took)
+		}
+	}
+	rec, _ := get("/abc123", scanner)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected the banned scanner to get 429 with Retry-After 60, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec, _ := get("/zz9999/preview", scanner); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the ban to cover previews, got %d", rec.Code)
+	}
+	if rec, _ := get("/abc123", "198.51.100.20:4000"); rec.Code != http.StatusFound {
+		t.Fatalf("expected other clients to be unaffected, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/scanguard", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	statsRec := httptest.NewRecorder()
+	router.ServeHTTP(statsRec, req)
+	var stats map[string]int
+	if err := json.Unmarshal(statsRec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("invalid stats response %q: %v", statsRec.Body.String(), err)
+	}
+	want := map[string]int{"misses": 6, "delayed": 3, "bans": 1, "blocked": 2, "tracked": 0}
+	for k, v := range want {
+		if stats[k] != v {
+			t.Fatalf("expected %s=%d, got %v", k, v, stats)
+		}
+	}
+}
+
+func TestScanGuardKeysOnTrustedProxyHeaders(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := shortenerpkg.NewShortener(shortenerpkg.NewRandomCodeGenerator(6), store, defaultTestSettings())
+	guard := scanguard.New(scanguard.Options{Threshold: 10, BanAfter: 2, BanDuration: time.Minute})
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies returned error: %v", err)
+	}
+	router := NewRouter(shortener, WithScanGuard(guard), WithTrustedProxies(proxies))
+
+	get := func(target, remoteAddr string, header ...string) int {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = remoteAddr
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Add(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Favicon and robots.txt misses are not probes.
+	for range 5 {
+		for _, path := range []string{"/favicon.ico", "/robots.txt"} {
+			if code := get(path, "198.51.100.20:4000"); code != http.StatusNotFound {
+				t.Fatalf("%s: expected 404, got %d", path, code)
+			}
+		}
+	}
+	if code := get("/zz0000", "198.51.100.20:4000"); code != http.StatusNotFound {
+		t.Fatalf("expected favicon and robots.txt misses not to count, got %d", code)
+	}
+
+	// Behind the proxy, the scanner is the hop the proxy appended; whatever
+	// the client sent itself further left is ignored.
+	const proxy = "10.0.0.1:443"
+	for i := range 2 {
+		get(fmt.Sprintf("/zz%04d", i), proxy, "X-Forwarded-For", "192.0.2.1, 203.0.113.7")
+	}
+	if code := get("/zz0002", proxy, "X-Forwarded-For", "192.0.2.99, 203.0.113.7"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the scanner behind the proxy to be banned, got %d", code)
+	}
+	if code := get("/zz0003", proxy, "X-Forwarded-For", "198.51.100.30"); code != http.StatusNotFound {
+		t.Fatalf("expected other clients behind the proxy to be unaffected, got %d", code)
+	}
+	if code := get("/zz0004", proxy, "X-Real-IP", "198.51.100.40"); code != http.StatusNotFound {
+		t.Fatalf("expected X-Real-IP to name the client, got %d", code)
+	}
+
+	// An untrusted peer cannot pose as someone else, nor dodge its own ban.
+	const direct = "192.0.2.50:5000"
+	for i := range 2 {
+		get(fmt.Sprintf("/zz%04d", i), direct, "X-Forwarded-For", fmt.Sprintf("198.51.100.%d", 100+i))
+	}
+	if code := get("/zz0002", direct, "X-Real-IP", "198.51.100.200"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected forwarding headers from an untrusted peer to be ignored, got %d", code)
+	}
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"urlshortener/internal/logging"
+)
+
+// CORS lets pages on other origins call /api from the browser.
+type CORS struct {
+	// AllowedOrigins are exact origins such as https://app.example.com; "*"
+	// allows any origin, but then never with credentials. None turns CORS off.
+	AllowedOrigins   []string
+	AllowedMethods   []string      // defaults to DefaultCORSMethods
+	AllowCredentials bool          // let browsers send cookies and auth headers
+	MaxAge           time.Duration // how long browsers may cache a preflight
+}
+
+// DefaultCORSMethods are the methods the /api endpoints use.
+var DefaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete}
+
+// corsRequestHeaders are the request headers the API reads.
+var corsRequestHeaders = []string{"Authorization", "Content-Type", "X-API-Key", idempotencyKeyHeader, requestIDHeader}
+
+// corsExposedHeaders are the response headers scripts may read.
+var corsExposedHeaders = []string{"Retry-After", idempotentReplayHeader, requestIDHeader}
+
+// SecurityHeaders are sent with every response.
+type SecurityHeaders struct {
+	CSP            string        // Content-Security-Policy; empty sends none
+	HSTSMaxAge     time.Duration // Strict-Transport-Security, only over TLS; zero sends none
+	ReferrerPolicy string        // empty sends none
+	// Overrides replace headers for paths under a prefix, the longest
+	// matching prefix winning; an empty value drops the header.
+	Overrides map[string]map[string]string
+}
+
+// DefaultSecurityHeaders fit the server-rendered pages, which load scripts,
+// styles and images from /static only. The JSON API gets a policy that
+// loads nothing.
+func DefaultSecurityHeaders() SecurityHeaders {
+	return SecurityHeaders{
+		CSP:            "default-src 'self'; img-src 'self' data:; object-src 'none'; base-uri 'none'; form-action 'self'; frame-ancestors 'none'",
+		HSTSMaxAge:     180 * 24 * time.Hour,
+		ReferrerPolicy: "strict-origin-when-cross-origin",
+		Overrides: map[string]map[string]string{
+			"/api/": {"Content-Security-Policy": "default-src 'none'; frame-ancestors 'none'"},
+		},
+	}
+}
+
+// securityHeaders sets h on every response before the handler runs, so a
+// handler may still change them.
+func securityHeaders(h SecurityHeaders) func(http.Handler) http.Handler {
+	base := map[string]string{
+		"Content-Security-Policy": h.CSP,
+		"Referrer-Policy":         h.ReferrerPolicy,
+		"X-Content-Type-Options":  "nosniff",
+	}
+	var hsts string
+	if h.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(h.HSTSMaxAge.Seconds()))
+	}
+	// Longest prefix first, so the first match is the one that applies.
+	prefixes := make([]string, 0, len(h.Overrides))
+	for prefix := range h.Overrides {
+		prefixes = append(prefixes, prefix)
+	}
+	slices.SortFunc(prefixes, func(a, b string) int { return len(b) - len(a) })
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			set := func(name, value string) {
+				if value == "" {
+					header.Del(name)
+					return
+				}
+				header.Set(name, value)
+			}
+			for name, value := range base {
+				set(name, value)
+			}
+			if r.TLS != nil {
+				set("Strict-Transport-Security", hsts)
+			}
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					for name, value := range h.Overrides[prefix] {
+						set(name, value)
+					}
+					break
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// cors answers preflight requests from the allowed origins and marks their
+// actual requests readable. It runs before authentication, since browsers
+// send preflights without credentials.
+func cors(c CORS) func(http.Handler) http.Handler {
+	methods := c.AllowedMethods
+	if len(methods) == 0 {
+		methods = DefaultCORSMethods
+	}
+	anyOrigin := slices.Contains(c.AllowedOrigins, "*")
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(corsRequestHeaders, ", ")
+	exposeHeaders := strings.Join(corsExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(c.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if !anyOrigin && !slices.Contains(c.AllowedOrigins, origin) {
+				if preflight {
+					logging.Warnf("⚠️  Rejected CORS preflight from %s", origin)
+					http.Error(w, "origin not allowed", http.StatusForbidden)
+					return
+				}
+				// Without the headers the browser keeps the response from the page.
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if anyOrigin {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				if c.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+			if !preflight {
+				w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !slices.Contains(methods, r.Header.Get("Access-Control-Request-Method")) {
+				http.Error(w, "method not allowed", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+			if c.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/storage"
+)
+
+func TestSecurityHeadersDefaults(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	router := NewRouter(shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings()))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if csp := rec.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "default-src 'self'") {
+		t.Fatalf("expected the page policy on HTML, got %q", csp)
+	}
+	if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Fatalf("expected nosniff, got %q", got)
+	}
+	if got := rec.Header().Get("Referrer-Policy"); got != "strict-origin-when-cross-origin" {
+		t.Fatalf("unexpected Referrer-Policy %q", got)
+	}
+	if got := rec.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Fatalf("expected no HSTS over plain HTTP, got %q", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.TLS = &tls.ConnectionState{}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Strict-Transport-Security"); got != "max-age=15552000" {
+		t.Fatalf("expected HSTS over TLS, got %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if csp := rec.Header().Get("Content-Security-Policy"); !strings.HasPrefix(csp, "default-src 'none'") {
+		t.Fatalf("expected the API to load nothing, got %q", csp)
+	}
+}
+
+func TestSecurityHeadersOverrides(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	router := NewRouter(shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings()),
+		WithSecurityHeaders(SecurityHeaders{
+			CSP:            "default-src 'self'",
+			ReferrerPolicy: "no-referrer",
+			Overrides: map[string]map[string]string{
+				"/static/":      {"Content-Security-Policy": ""},
+				"/static/docs.": {"Content-Security-Policy": "default-src 'self' https://cdn.example", "Referrer-Policy": "same-origin"},
+			},
+		}))
+
+	for _, tc := range []struct {
+		target, csp, referrer string
+	}{
+		{"/", "default-src 'self'", "no-referrer"},
+		{"/static/app.js", "", "no-referrer"},
+		{"/static/docs.html", "default-src 'self' https://cdn.example", "same-origin"},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+		if got := rec.Header().Get("Content-Security-Policy"); got != tc.csp {
+			t.Fatalf("%s: expected CSP %q, got %q", tc.target, tc.csp, got)
+		}
+		if got := rec.Header().Get("Referrer-Policy"); got != tc.referrer {
+			t.Fatalf("%s: expected Referrer-Policy %q, got %q", tc.target, tc.referrer, got)
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	svc := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
+	router := NewRouter(svc, WithCORS(CORS{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}))
+
+	preflight := func(origin, method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/api/shorten", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		req.Header.Set("Access-Control-Request-Headers", "content-type")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := preflight("https://app.example.com", http.MethodPost)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 for an allowed preflight, got %d", rec.Code)
+	}
+	h := rec.Header()
+	if h.Get("Access-Control-Allow-Origin") != "https://app.example.com" || h.Get("Access-Control-Allow-Credentials") != "true" ||
+		h.Get("Access-Control-Allow-Methods") != "GET, POST" || !strings.Contains(h.Get("Access-Control-Allow-Headers"), "Content-Type") ||
+		h.Get("Access-Control-Max-Age") != "600" {
+		t.Fatalf("unexpected preflight headers: %v", h)
+	}
+	if rec := preflight("https://evil.example", http.MethodPost); rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("expected 403 without CORS headers for another origin, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := preflight("https://app.example.com", http.MethodDelete); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a method that is not allowed, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://example.com"}`))
+	req.Header.Set("Origin", "https://app.example.com")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		!strings.Contains(rec.Header().Get("Access-Control-Expose-Headers"), requestIDHeader) || rec.Header().Get("Vary") != "Origin" {
+		t.Fatalf("expected a readable response, got %d %v", rec.Code, rec.Header())
+	}
+
+	// Pages outside /api are never shared.
+	req = httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("expected no CORS headers outside /api, got %v", rec.Header())
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	svc := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
+	router := NewRouter(svc, WithCORS(CORS{AllowedOrigins: []string{"*"}, AllowCredentials: true}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/shorten", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("expected any origin to be allowed, got %d %v", rec.Code, rec.Header())
+	}
+	if rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatal("credentials must never be allowed for any origin")
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"urlshortener/internal/logging"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/transfer"
+)
+
+// maxImportBytes bounds the request body accepted by /api/import.
+const maxImportBytes = 64 << 20
+
+// requireAPIKey rejects requests that apiKeyMiddleware did not authenticate.
+func requireAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := apiKeyFromContext(r.Context()); !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			http.Error(w, "api key required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func exportHandler(shortsvc *shortenerpkg.Shortener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, err := transfer.ParseFormat(queryOrDefault(r, "format", string(transfer.FormatNDJSON)))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", `attachment; filename="links.`+string(format)+`"`)
+		n, err := shortsvc.Export(r.Context(), w, format)
+		if err != nil {
+			// Headers (and maybe rows) are already out; all we can do is log.
+			logging.Errorf("❌ Export failed after %d links: %v", n, err)
+			return
+		}
+		logging.Infof("📦 Exported %d links as %s", n, format)
+	}
+}
+
+func importHandler(shortsvc *shortenerpkg.Shortener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, err := transfer.ParseFormat(queryOrDefault(r, "format", string(transfer.FormatNDJSON)))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		policy, err := transfer.ParsePolicy(r.URL.Query().Get("policy"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+		body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+		defer body.Close()
+
+		report, err := shortsvc.Import(r.Context(), body, transfer.ImportOptions{
+			Format:       format,
+			Policy:       policy,
+			DryRun:       dryRun,
+			DefaultOwner: ownerFromContext(r.Context()),
+		})
+
+		status := http.StatusOK
+		switch {
+		case err == nil:
+		case errors.Is(err, transfer.ErrConflict):
+			status = http.StatusConflict
+		case report.Total == 0:
+			// Nothing was read: the body itself is unusable.
+			logging.Warnf("⚠️  Rejected import: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		default:
+			logging.Errorf("❌ Import failed: %v", err)
+			status = http.StatusInternalServerError
+		}
+
+		logging.Infof("📥 Import (%s, policy=%s, dry_run=%t): %+v", format, policy, dryRun, report)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			logging.Errorf("❌ Failed to encode response: %v", err)
+		}
+	}
+}
+
+func queryOrDefault(r *http.Request, key, defaultValue string) string {
+	if v := r.URL.Query().Get(key); v != "" {
+		return v
+	}
+	return defaultValue
+}
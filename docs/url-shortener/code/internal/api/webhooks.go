@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/storage"
+	"urlshortener/internal/services/webhook"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Paging of GET /api/webhooks/deliveries.
+const (
+	defaultDeliveryPageSize = 50
+	maxDeliveryPageSize     = 500
+)
+
+// deliveryJSON is the wire shape of a storage.WebhookDelivery.
+type deliveryJSON struct {
+	ID            string          `json:"id"`
+	Endpoint      string          `json:"endpoint"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	LastStatus    int             `json:"last_status,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   time.Time       `json:"delivered_at,omitzero"`
+}
+
+func newDeliveryJSON(d storage.WebhookDelivery) deliveryJSON {
+	return deliveryJSON{
+		ID:            d.ID,
+		Endpoint:      d.Endpoint,
+		Event:         d.Event,
+		Payload:       d.Payload,
+		Status:        d.Status,
+		Attempts:      d.Attempts,
+		NextAttemptAt: d.NextAttemptAt,
+		LastError:     d.LastError,
+		LastStatus:    d.LastStatus,
+		CreatedAt:     d.CreatedAt,
+		DeliveredAt:   d.DeliveredAt,
+	}
+}
+
+func webhookRoutes(svc *webhook.Service, admins map[string]struct{}) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Use(requireAdmin(admins))
+		r.Get("/deliveries", listDeliveriesHandler(svc))
+		r.Post("/deliveries/replay", replayFailedHandler(svc))
+		r.Post("/deliveries/{id}/replay", replayDeliveryHandler(svc))
+	}
+}
+
+func listDeliveriesHandler(svc *webhook.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		opts := storage.DeliveryListOptions{Status: q.Get("status"), Limit: defaultDeliveryPageSize}
+		switch opts.Status {
+		case "", storage.DeliveryPending, storage.DeliveryDelivered, storage.DeliveryFailed:
+		default:
+			http.Error(w, "status must be pending, delivered or failed", http.StatusBadRequest)
+			return
+		}
+		if raw := q.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > maxDeliveryPageSize {
+				http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+			opts.Limit = n
+		}
+		if raw := q.Get("offset"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			opts.Offset = n
+		}
+
+		deliveries, err := svc.Deliveries(r.Context(), opts)
+		if err != nil {
+			logging.Errorf("❌ Failed to list webhook deliveries: %v", err)
+			http.Error(w, "failed to list deliveries", http.StatusInternalServerError)
+			return
+		}
+		out := make([]deliveryJSON, len(deliveries))
+		for i, d := range deliveries {
+			out[i] = newDeliveryJSON(d)
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+func replayDeliveryHandler(svc *webhook.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d, err := svc.Replay(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrNotFound):
+				http.NotFound(w, r)
+			case errors.Is(err, webhook.ErrNotFailed):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				logging.Errorf("❌ Failed to replay webhook delivery: %v", err)
+				http.Error(w, "failed to replay delivery", http.StatusInternalServerError)
+			}
+			return
+		}
+		logging.Infof("🔁 Replaying webhook %s to %s", d.ID, d.Endpoint)
+		writeJSON(w, http.StatusAccepted, newDeliveryJSON(d))
+	}
+}
+
+func replayFailedHandler(svc *webhook.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n, err := svc.ReplayFailed(r.Context())
+		if err != nil {
+			logging.Errorf("❌ Failed to replay webhook deliveries: %v", err)
+			http.Error(w, "failed to replay deliveries", http.StatusInternalServerError)
+			return
+		}
+		if n > 0 {
+			logging.Infof("🔁 Replaying %d failed webhooks", n)
+		}
+		writeJSON(w, http.StatusAccepted, map[string]int{"replayed": n})
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"urlshortener/internal/services/apikey"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/storage"
+	"urlshortener/internal/services/webhook"
+)
+
+func TestWebhookDeliveryAndReplay(t *testing.T) {
+	ctx := context.Background()
+	failing := true
+	var received []webhook.Payload
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhook.Verify("hook-secret", r.Header.Get(webhook.SignatureHeader), body, time.Now(), time.Minute); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if failing {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		var p webhook.Payload
+		_ = json.Unmarshal(body, &p)
+		received = append(received, p)
+	}))
+	defer receiver.Close()
+
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	adminToken, admin, _ := keys.Create(ctx, "ops")
+	userToken, _, _ := keys.Create(ctx, "marketing")
+	hooks := webhook.New(store, webhook.Options{
+		Endpoints:   []webhook.Endpoint{{Name: "crm", URL: receiver.URL, Secret: "hook-secret"}},
+		MaxAttempts: 2,
+		Backoff:     func(int) time.Duration { return 0 },
+	})
+	shortener := shortenerpkg.NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings(), shortenerpkg.WithWebhooks(hooks))
+	router := NewRouter(shortener, WithAPIKeys(keys, false), WithWebhooks(hooks), WithAdmins([]string{admin.ID}))
+
+	do := func(method, target, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/api/shorten", `{"url":"https://example.com/launch"}`, userToken); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	// Both attempts hit the failing receiver.
+	_, _ = hooks.RunOnce(ctx)
+	_, _ = hooks.RunOnce(ctx)
+
+	if rec := do(http.MethodGet, "/api/webhooks/deliveries", "", userToken); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a non-admin key, got %d", rec.Code)
+	}
+	rec := do(http.MethodGet, "/api/webhooks/deliveries?status=failed", "", adminToken)
+	var failed []deliveryJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &failed); err != nil {
+		t.Fatalf("failed to decode deliveries: %v", err)
+	}
+	if len(failed) != 1 || failed[0].Event != webhook.EventCreated || failed[0].Attempts != 2 || failed[0].LastStatus != http.StatusInternalServerError {
+		t.Fatalf("unexpected failed deliveries %+v", failed)
+	}
+
+	failing = false
+	if rec := do(http.MethodPost, "/api/webhooks/deliveries/"+failed[0].ID+"/replay", "", adminToken); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 for a replay, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := hooks.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce returned error: %v", err)
+	}
+	if len(received) != 1 || received[0].Link.ShortCode != "stub123" || received[0].Link.OriginalURL != "https://example.com/launch" {
+		t.Fatalf("unexpected payloads %+v", received)
+	}
+	if rec := do(http.MethodPost, "/api/webhooks/deliveries/"+failed[0].ID+"/replay", "", adminToken); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 when replaying a delivered webhook, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/webhooks/deliveries?status=lost", "", adminToken); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown status, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,17 @@
+// Package clock abstracts the current time so services can be tested
+// without sleeping or racing the wall clock.
+package clock
+
+import "time"
+
+// Clock tells the time.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the wall clock.
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
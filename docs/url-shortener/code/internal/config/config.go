@@ -0,0 +1,757 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"urlshortener/internal/api"
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/signing"
+	"urlshortener/internal/services/storage/bolt"
+	"urlshortener/internal/services/storage/postgres"
+	"urlshortener/internal/services/webhook"
+
+	"github.com/joho/godotenv"
+)
+
+// Config is shared by every binary in cmd/, so they all read the same env vars
+// and config file.
+type Config struct {
+	Server struct {
+		Address string
+		// UIDevDir, when set, serves the web UI from this directory (the ui/
+		// folder of a checkout) instead of the embedded copy.
+		UIDevDir string
+		// GRPCAddress, when set, serves the gRPC API on its own listener.
+		GRPCAddress string
+		// TLS, when set up, serves HTTPS on Address instead of HTTP.
+		TLS TLS
+		// TrustedProxies lists the reverse proxies (IPs or CIDR ranges) whose
+		// X-Forwarded-For and X-Real-IP headers name the client.
+		TrustedProxies []string
+	}
+	ShortenerSettings shortener.ShortenerSettings
+	StorageDriver     string
+	PostgresConfig    postgres.PostgresConfig
+	BoltConfig        bolt.BoltConfig
+	AutoMigrate       bool
+	RequireAPIKey     bool
+	RateLimit         RateLimit
+	Moderation        Moderation
+	Audit             Audit
+	Webhooks          Webhooks
+	Health            Health
+	Signing           Signing
+	ScanGuard         ScanGuard
+	Idempotency       Idempotency
+	Activation        Activation
+	Dashboard         Dashboard
+	CORS              api.CORS
+	SecurityHeaders   api.SecurityHeaders
+	LogLevel          string
+}
+
+// TLS serves HTTPS from a PEM certificate and key, re-read when they change,
+// checked every ReloadInterval (zero reads them only at startup). With
+// RedirectAddress set, plain HTTP there redirects to HTTPS.
+type TLS struct {
+	CertFile        string
+	KeyFile         string
+	RedirectAddress string
+	ReloadInterval  time.Duration
+}
+
+// Enabled reports whether HTTPS is configured.
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// RateLimit throttles /api per API key (or client IP). Zero RequestsPerMinute
+// disables it; zero Burst means one minute's worth.
+type RateLimit struct {
+	RequestsPerMinute int
+	Burst             int
+}
+
+// Moderation controls abuse reports and takedowns. BlocklistFile, when set,
+// lists hosts that may not be shortened; it is re-read whenever it changes,
+// checked every BlocklistRefresh (zero loads it only at startup).
+type Moderation struct {
+	AdminKeys        []string // API key IDs allowed to use /api/moderation
+	BlocklistFile    string
+	BlocklistRefresh time.Duration
+}
+
+// Audit selects where the audit trail of link changes goes: the storage
+// backend's audit table, an NDJSON file, or nowhere.
+type Audit struct {
+	Sink string // AuditStore, AuditFile or AuditOff
+	File string // NDJSON path when Sink is AuditFile
+}
+
+// Webhooks sends signed notifications about link events to Endpoints.
+// Deliveries are retried up to MaxAttempts times; the dispatcher looks for
+// due retries every PollInterval. Links are checked for expiry every
+// ExpiryCheck (zero turns link.expired off).
+type Webhooks struct {
+	Endpoints    []webhook.Endpoint
+	MaxAttempts  int
+	PollInterval time.Duration
+	ExpiryCheck  time.Duration
+}
+
+// Health controls the background checker that requests link destinations
+// to find dead links. Each link is re-checked every Interval, with at most
+// Concurrency requests in flight and requests to one host HostDelay apart.
+// MarkBroken flags dead links on their public preview page.
+type Health struct {
+	Enabled     bool
+	Interval    time.Duration
+	Concurrency int
+	HostDelay   time.Duration
+	Timeout     time.Duration
+	MarkBroken  bool
+}
+
+// Signing holds the keys for signed links. The first key signs and every
+// key verifies, so a key is rotated by putting its successor first and
+// dropping it once its links have expired. Signatures last DefaultTTL unless
+// the caller asks otherwise. No keys turns signed links off.
+type Signing struct {
+	Keys       []signing.Key
+	DefaultTTL time.Duration
+}
+
+// ScanGuard slows down and bans clients that request many short codes that
+// don't exist. Past Threshold misses in a Window, each 404 is held Delay
+// longer than the last, up to MaxDelay; BanAfter misses ban the client for
+// BanDuration. Clients are told apart by IP, so behind a reverse proxy it
+// needs the proxy in Server.TrustedProxies.
+type ScanGuard struct {
+	Enabled     bool
+	Window      time.Duration
+	Threshold   int
+	Delay       time.Duration
+	MaxDelay    time.Duration
+	BanAfter    int
+	BanDuration time.Duration
+}
+
+// Idempotency keeps the responses to POST /api/shorten requests sent with an
+// Idempotency-Key for TTL, so retries get them back instead of making a
+// second link. Zero TTL ignores the header.
+type Idempotency struct {
+	TTL time.Duration
+}
+
+// Activation controls visits to links before their activation time. They
+// see a coming-soon page, or are redirected to FallbackURL when it is set.
+type Activation struct {
+	FallbackURL string
+}
+
+// Dashboard controls web UI sign-ins, which last SessionTTL. SecureCookies
+// marks the UI's cookies Secure on plain HTTP too, for servers behind a proxy
+// that terminates TLS; with TLS set up they always are.
+type Dashboard struct {
+	SessionTTL    time.Duration
+	SecureCookies bool
+}
+
+// minSigningSecret is the shortest secret a signing key may have.
+const minSigningSecret = 16
+
+// defaultWebhook names the endpoint configured by WEBHOOK_URL.
+const defaultWebhook = "default"
+
+var (
+	webhookNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,50}$`)
+	signingKeyPattern  = regexp.MustCompile(`^[A-Za-z0-9_-]{1,20}$`)
+	headerNamePattern  = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+)
+
+// corsMethods are the methods cors allowed_methods may list.
+var corsMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+// Supported values for AUDIT_SINK.
+const (
+	AuditStore = "store"
+	AuditFile  = "file"
+	AuditOff   = "off"
+)
+
+// Supported values for STORAGE_DRIVER.
+const (
+	DriverPostgres = "postgres"
+	DriverBolt     = "bolt"
+	DriverMemory   = "memory"
+)
+
+// Defaults returns the configuration used when nothing overrides it.
+func Defaults() Config {
+	var cfg Config
+	cfg.Server.Address = ":8080"
+	cfg.Server.TLS.ReloadInterval = time.Minute
+	cfg.ShortenerSettings = shortener.ShortenerSettings{CodeLength: 6, MaxRetries: 3}
+	cfg.StorageDriver = DriverPostgres
+	cfg.BoltConfig = bolt.BoltConfig{Path: "urlshortener.db"}
+	cfg.PostgresConfig = postgres.PostgresConfig{
+		Host:     "localhost",
+		Port:     "5432",
+		User:     "urlshortener",
+		Database: "urlshortener",
+		SSLMode:  "disable",
+	}
+	cfg.Moderation.BlocklistRefresh = time.Minute
+	cfg.Audit.Sink = AuditStore
+	cfg.Audit.File = "audit.ndjson"
+	cfg.Webhooks.MaxAttempts = 8
+	cfg.Webhooks.PollInterval = 5 * time.Second
+	cfg.Webhooks.ExpiryCheck = time.Minute
+	cfg.Health.Interval = 24 * time.Hour
+	cfg.Health.Concurrency = 8
+	cfg.Health.HostDelay = time.Second
+	cfg.Health.Timeout = 10 * time.Second
+	cfg.Signing.DefaultTTL = 7 * 24 * time.Hour
+	cfg.ScanGuard = ScanGuard{
+		Window:      10 * time.Minute,
+		Threshold:   20,
+		Delay:       250 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		BanAfter:    100,
+		BanDuration: 15 * time.Minute,
+	}
+	cfg.Idempotency.TTL = 24 * time.Hour
+	cfg.Dashboard.SessionTTL = 12 * time.Hour
+	cfg.CORS = api.CORS{AllowedMethods: slices.Clone(api.DefaultCORSMethods), MaxAge: 10 * time.Minute}
+	cfg.SecurityHeaders = api.DefaultSecurityHeaders()
+	cfg.LogLevel = "info"
+	return cfg
+}
+
+// Load resolves the configuration with the precedence
+//
+//	defaults < config file < environment (including .env)
+//
+// path names a .yaml/.yml, .toml or .json file; when empty, CONFIG_FILE is
+// used, and with neither only the environment is read. Every problem found is
+// reported together rather than one per run.
+func Load(path string) (Config, error) {
+	// Load .env file (optional - can use system env vars)
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  No .env file found, using system environment variables")
+	}
+
+	cfg := Defaults()
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := applyFile(&cfg, path); err != nil {
+			return cfg, err
+		}
+	}
+
+	env := envReader{}
+	env.apply(&cfg)
+
+	errs := append(env.errs, cfg.Validate())
+	return cfg, errors.Join(errs...)
+}
+
+// Validate checks the resolved values and returns every problem joined.
+func (cfg Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(strings.TrimPrefix(cfg.Server.Address, ":"))
+	check(err == nil && port > 0 && port <= 65535, "server port must be between 1 and 65535, got %q", strings.TrimPrefix(cfg.Server.Address, ":"))
+	if cfg.Server.GRPCAddress != "" {
+		grpcPort, err := strconv.Atoi(strings.TrimPrefix(cfg.Server.GRPCAddress, ":"))
+		check(err == nil && grpcPort > 0 && grpcPort <= 65535, "server grpc_port must be between 1 and 65535, got %q", strings.TrimPrefix(cfg.Server.GRPCAddress, ":"))
+		check(cfg.Server.GRPCAddress != cfg.Server.Address, "server grpc_port must differ from port")
+	}
+	tlsCfg := cfg.Server.TLS
+	check((tlsCfg.CertFile == "") == (tlsCfg.KeyFile == ""), "server tls cert_file and key_file must be set together")
+	check(tlsCfg.ReloadInterval >= 0, "server tls reload_interval must not be negative")
+	if tlsCfg.RedirectAddress != "" {
+		redirectPort, err := strconv.Atoi(strings.TrimPrefix(tlsCfg.RedirectAddress, ":"))
+		check(err == nil && redirectPort > 0 && redirectPort <= 65535, "server tls redirect_port must be between 1 and 65535, got %q", strings.TrimPrefix(tlsCfg.RedirectAddress, ":"))
+		check(tlsCfg.Enabled(), "server tls redirect_port needs cert_file and key_file")
+		check(tlsCfg.RedirectAddress != cfg.Server.Address && tlsCfg.RedirectAddress != cfg.Server.GRPCAddress,
+			"server tls redirect_port must differ from port and grpc_port")
+	}
+
+	check(cfg.ShortenerSettings.CodeLength > 0, "shortener code_length must be positive, got %d", cfg.ShortenerSettings.CodeLength)
+	check(cfg.ShortenerSettings.MaxRetries > 0, "shortener max_retries must be positive, got %d", cfg.ShortenerSettings.MaxRetries)
+	for _, host := range cfg.ShortenerSettings.Denylist {
+		check(host != "" && !strings.ContainsAny(host, "/: "), "shortener denylist entry %q must be a bare host name", host)
+	}
+
+	switch cfg.StorageDriver {
+	case DriverPostgres:
+		pg := cfg.PostgresConfig
+		check(pg.Password != "", "postgres password is required (PSQL_PASSWORD)")
+		check(pg.Host != "", "postgres host is required")
+		check(pg.MaxOpenConns >= 0, "postgres max_open_conns must not be negative")
+		check(pg.MaxIdleConns >= 0, "postgres max_idle_conns must not be negative")
+		check(pg.ConnMaxLifetime >= 0, "postgres conn_max_lifetime must not be negative")
+		check(pg.ConnMaxIdleTime >= 0, "postgres conn_max_idle_time must not be negative")
+		check(pg.StatementTimeout >= 0, "postgres statement_timeout must not be negative")
+	case DriverBolt:
+		check(cfg.BoltConfig.Path != "", "bolt path is required when storage driver is %s", DriverBolt)
+	case DriverMemory:
+	default:
+		check(false, "storage driver must be one of %s, %s, %s; got %q",
+			DriverPostgres, DriverBolt, DriverMemory, cfg.StorageDriver)
+	}
+
+	check(cfg.RateLimit.RequestsPerMinute >= 0, "rate_limit requests_per_minute must not be negative")
+	check(cfg.RateLimit.Burst >= 0, "rate_limit burst must not be negative")
+	check(cfg.Moderation.BlocklistRefresh >= 0, "moderation blocklist_refresh must not be negative")
+
+	switch cfg.Audit.Sink {
+	case AuditStore, AuditOff:
+	case AuditFile:
+		check(cfg.Audit.File != "", "audit file is required when audit sink is %s", AuditFile)
+	default:
+		check(false, "audit sink must be one of %s, %s, %s; got %q", AuditStore, AuditFile, AuditOff, cfg.Audit.Sink)
+	}
+
+	wh := cfg.Webhooks
+	check(wh.MaxAttempts > 0, "webhooks max_attempts must be positive, got %d", wh.MaxAttempts)
+	check(wh.PollInterval > 0, "webhooks poll_interval must be positive")
+	check(wh.ExpiryCheck >= 0, "webhooks expiry_check must not be negative")
+	names := map[string]bool{}
+	for _, e := range wh.Endpoints {
+		check(webhookNamePattern.MatchString(e.Name), "webhook name %q must be 1-50 letters, digits, '-' or '_'", e.Name)
+		check(!names[e.Name], "webhook name %q is used twice", e.Name)
+		names[e.Name] = true
+		u, err := url.Parse(e.URL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "webhook %s url must be an http(s) URL, got %q", e.Name, e.URL)
+		check(e.Secret != "", "webhook %s secret is required", e.Name)
+		for _, event := range e.Events {
+			check(slices.Contains(webhook.Events, event), "webhook %s event must be one of %s; got %q", e.Name, strings.Join(webhook.Events, ", "), event)
+		}
+		check(e.HitEvery >= 0, "webhook %s hit_every must not be negative", e.Name)
+		check(e.HitEvery > 0 || !slices.Contains(e.Events, webhook.EventHits), "webhook %s hit_every is required for %s", e.Name, webhook.EventHits)
+	}
+
+	hc := cfg.Health
+	check(hc.Interval > 0, "health interval must be positive")
+	check(hc.Concurrency > 0, "health concurrency must be positive, got %d", hc.Concurrency)
+	check(hc.HostDelay >= 0, "health host_delay must not be negative")
+	check(hc.Timeout > 0, "health timeout must be positive")
+
+	check(cfg.Signing.DefaultTTL > 0, "signing default_ttl must be positive")
+	keyIDs := map[string]bool{}
+	for _, k := range cfg.Signing.Keys {
+		check(signingKeyPattern.MatchString(k.ID), "signing key id %q must be 1-20 letters, digits, '-' or '_'", k.ID)
+		check(!keyIDs[k.ID], "signing key id %q is used twice", k.ID)
+		keyIDs[k.ID] = true
+		check(len(k.Secret) >= minSigningSecret, "signing key %s secret must be at least %d characters", k.ID, minSigningSecret)
+	}
+
+	_, err = api.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	check(err == nil, "server trusted_proxies: %v", err)
+
+	sg := cfg.ScanGuard
+	check(sg.Window > 0, "scan_guard window must be positive")
+	check(sg.Threshold > 0, "scan_guard threshold must be positive, got %d", sg.Threshold)
+	check(sg.Delay > 0, "scan_guard delay must be positive")
+	check(sg.MaxDelay >= sg.Delay, "scan_guard max_delay must be at least delay")
+	check(sg.BanAfter > 0, "scan_guard ban_after must be positive, got %d", sg.BanAfter)
+	check(sg.BanDuration > 0, "scan_guard ban_duration must be positive")
+	check(cfg.Idempotency.TTL >= 0, "idempotency ttl must not be negative")
+	if fallback := cfg.Activation.FallbackURL; fallback != "" {
+		u, err := url.Parse(fallback)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "activation fallback_url must be an http(s) URL, got %q", fallback)
+	}
+	check(cfg.Dashboard.SessionTTL > 0, "dashboard session_ttl must be positive")
+
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		if origin == "*" {
+			check(!cfg.CORS.AllowCredentials, "cors allow_credentials cannot be used with origin *")
+			continue
+		}
+		u, err := url.Parse(origin)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.Scheme+"://"+u.Host == origin,
+			"cors origin must be * or scheme://host[:port], got %q", origin)
+	}
+	for _, method := range cfg.CORS.AllowedMethods {
+		check(slices.Contains(corsMethods, method), "cors method must be one of %s; got %q", strings.Join(corsMethods, ", "), method)
+	}
+	check(cfg.CORS.MaxAge >= 0, "cors max_age must not be negative")
+	check(cfg.SecurityHeaders.HSTSMaxAge >= 0, "security_headers hsts_max_age must not be negative")
+	for prefix, headers := range cfg.SecurityHeaders.Overrides {
+		check(strings.HasPrefix(prefix, "/"), "security_headers override path %q must start with /", prefix)
+		for name := range headers {
+			check(headerNamePattern.MatchString(name), "security_headers override for %s: invalid header name %q", prefix, name)
+		}
+	}
+
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Level is the parsed LogLevel; Validate has already rejected bad values.
+func (cfg Config) Level() logging.Level {
+	level, _ := logging.ParseLevel(cfg.LogLevel)
+	return level
+}
+
+// SecureCookies reports whether the web UI's cookies should be Secure on
+// every request: when asked to, or when the server itself speaks HTTPS.
+func (cfg Config) SecureCookies() bool {
+	return cfg.Dashboard.SecureCookies || cfg.Server.TLS.Enabled()
+}
+
+// LogSummary logs the resolved configuration, without secrets.
+func (cfg Config) LogSummary() {
+	log.Printf("📋 Configuration loaded:")
+	log.Printf("   Server: %s", cfg.Server.Address)
+	if t := cfg.Server.TLS; t.Enabled() {
+		log.Printf("   TLS: %s (checked every %s)", t.CertFile, t.ReloadInterval)
+		if t.RedirectAddress != "" {
+			log.Printf("   HTTP to HTTPS redirect: %s", t.RedirectAddress)
+		}
+	}
+	if cfg.Server.GRPCAddress != "" {
+		log.Printf("   gRPC: %s", cfg.Server.GRPCAddress)
+	}
+	log.Printf("   Code Length: %d", cfg.ShortenerSettings.CodeLength)
+	log.Printf("   Max Retries: %d", cfg.ShortenerSettings.MaxRetries)
+	log.Printf("   Storage: %s", cfg.StorageDriver)
+	switch cfg.StorageDriver {
+	case DriverPostgres:
+		log.Printf("   Database: %s@%s:%s/%s",
+			cfg.PostgresConfig.User,
+			cfg.PostgresConfig.Host,
+			cfg.PostgresConfig.Port,
+			cfg.PostgresConfig.Database)
+	case DriverBolt:
+		log.Printf("   Database file: %s", cfg.BoltConfig.Path)
+	}
+	log.Printf("   API key required: %t", cfg.RequireAPIKey)
+	if cfg.Server.UIDevDir != "" {
+		log.Printf("   UI dev dir: %s", cfg.Server.UIDevDir)
+	}
+	if cfg.RateLimit.RequestsPerMinute > 0 {
+		log.Printf("   Rate limit: %d/min (burst %d)", cfg.RateLimit.RequestsPerMinute, cfg.RateLimit.Burst)
+	}
+	if n := len(cfg.ShortenerSettings.Denylist); n > 0 {
+		log.Printf("   Denied hosts: %d", n)
+	}
+	if cfg.Moderation.BlocklistFile != "" {
+		log.Printf("   Blocklist: %s (checked every %s)", cfg.Moderation.BlocklistFile, cfg.Moderation.BlocklistRefresh)
+	}
+	log.Printf("   Moderation admins: %d", len(cfg.Moderation.AdminKeys))
+	if cfg.Audit.Sink == AuditFile {
+		log.Printf("   Audit log: %s", cfg.Audit.File)
+	} else {
+		log.Printf("   Audit log: %s", cfg.Audit.Sink)
+	}
+	if n := len(cfg.Webhooks.Endpoints); n > 0 {
+		names := make([]string, n)
+		for i, e := range cfg.Webhooks.Endpoints {
+			names[i] = e.Name
+		}
+		log.Printf("   Webhooks: %s", strings.Join(names, ", "))
+	}
+	if cfg.Health.Enabled {
+		log.Printf("   Health checks: every %s (%d at once, %s per host)",
+			cfg.Health.Interval, cfg.Health.Concurrency, cfg.Health.HostDelay)
+	}
+	if proxies := cfg.Server.TrustedProxies; len(proxies) > 0 {
+		log.Printf("   Trusted proxies: %s", strings.Join(proxies, ", "))
+	}
+	if sg := cfg.ScanGuard; sg.Enabled {
+		log.Printf("   Scan guard: delay after %d misses, ban after %d (per %s) for %s",
+			sg.Threshold, sg.BanAfter, sg.Window, sg.BanDuration)
+	}
+	if ttl := cfg.Idempotency.TTL; ttl > 0 {
+		log.Printf("   Idempotency keys: kept %s", ttl)
+	}
+	if fallback := cfg.Activation.FallbackURL; fallback != "" {
+		log.Printf("   Links not live yet: redirect to %s", fallback)
+	}
+	log.Printf("   Dashboard sessions: %s (secure cookies %t)", cfg.Dashboard.SessionTTL, cfg.SecureCookies())
+	if origins := cfg.CORS.AllowedOrigins; len(origins) > 0 {
+		log.Printf("   CORS: %s (credentials %t)", strings.Join(origins, ", "), cfg.CORS.AllowCredentials)
+	}
+	if n := len(cfg.Signing.Keys); n > 0 {
+		ids := make([]string, n)
+		for i, k := range cfg.Signing.Keys {
+			ids[i] = k.ID
+		}
+		log.Printf("   Signing keys: %s (signatures last %s)", strings.Join(ids, ", "), cfg.Signing.DefaultTTL)
+	}
+	log.Printf("   Log level: %s", cfg.LogLevel)
+}
+
+// NeedsRestart reports whether next differs from cfg in anything a running
+// server cannot pick up on SIGHUP; only RateLimit, the denylist, Signing and
+// LogLevel are reloadable.
+func (cfg Config) NeedsRestart(next Config) bool {
+	a, b := cfg.withoutReloadable(), next.withoutReloadable()
+	return a != b
+}
+
+// withoutReloadable returns a comparable copy with the reloadable fields cleared.
+func (cfg Config) withoutReloadable() fixedConfig {
+	return fixedConfig{
+		Address:       cfg.Server.Address,
+		UIDevDir:      cfg.Server.UIDevDir,
+		GRPCAddress:   cfg.Server.GRPCAddress,
+		TLS:           cfg.Server.TLS,
+		Proxies:       strings.Join(cfg.Server.TrustedProxies, ","),
+		CodeLength:    cfg.ShortenerSettings.CodeLength,
+		MaxRetries:    cfg.ShortenerSettings.MaxRetries,
+		StorageDriver: cfg.StorageDriver,
+		Postgres:      cfg.PostgresConfig,
+		Bolt:          cfg.BoltConfig,
+		AutoMigrate:   cfg.AutoMigrate,
+		RequireAPIKey: cfg.RequireAPIKey,
+		AdminKeys:     strings.Join(cfg.Moderation.AdminKeys, ","),
+		Blocklist:     cfg.Moderation.BlocklistFile,
+		BlocklistPoll: cfg.Moderation.BlocklistRefresh,
+		Audit:         cfg.Audit,
+		Webhooks:      fmt.Sprint(cfg.Webhooks),
+		Health:        cfg.Health,
+		ScanGuard:     cfg.ScanGuard,
+		Idempotency:   cfg.Idempotency,
+		Activation:    cfg.Activation,
+		Dashboard:     cfg.Dashboard,
+		CORS:          fmt.Sprint(cfg.CORS),
+		Security:      fmt.Sprint(cfg.SecurityHeaders),
+	}
+}
+
+type fixedConfig struct {
+	Address       string
+	UIDevDir      string
+	GRPCAddress   string
+	TLS           TLS
+	Proxies       string
+	CodeLength    int
+	MaxRetries    int
+	StorageDriver string
+	Postgres      postgres.PostgresConfig
+	Bolt          bolt.BoltConfig
+	AutoMigrate   bool
+	RequireAPIKey bool
+	AdminKeys     string
+	Blocklist     string
+	BlocklistPoll time.Duration
+	Audit         Audit
+	Webhooks      string
+	Health        Health
+	ScanGuard     ScanGuard
+	Idempotency   Idempotency
+	Activation    Activation
+	Dashboard     Dashboard
+	CORS          string
+	Security      string
+}
+
+// envReader overrides config fields from environment variables, collecting
+// malformed values instead of silently falling back to defaults.
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) apply(cfg *Config) {
+	if port := os.Getenv("PORT"); port != "" {
+		cfg.Server.Address = ":" + port
+	}
+	e.string("UI_DEV_DIR", &cfg.Server.UIDevDir)
+	if port := os.Getenv("GRPC_PORT"); port != "" {
+		cfg.Server.GRPCAddress = ":" + port
+	}
+	e.string("TLS_CERT_FILE", &cfg.Server.TLS.CertFile)
+	e.string("TLS_KEY_FILE", &cfg.Server.TLS.KeyFile)
+	if port := os.Getenv("TLS_REDIRECT_PORT"); port != "" {
+		cfg.Server.TLS.RedirectAddress = ":" + port
+	}
+	e.duration("TLS_RELOAD_INTERVAL", &cfg.Server.TLS.ReloadInterval)
+	e.list("TRUSTED_PROXIES", &cfg.Server.TrustedProxies)
+
+	e.int("CODE_LENGTH", &cfg.ShortenerSettings.CodeLength)
+	e.int("SHORTENER_MAX_RETRIES", &cfg.ShortenerSettings.MaxRetries)
+	e.list("URL_DENYLIST", &cfg.ShortenerSettings.Denylist)
+
+	e.string("STORAGE_DRIVER", &cfg.StorageDriver)
+	e.string("BOLT_PATH", &cfg.BoltConfig.Path)
+	e.bool("AUTO_MIGRATE", &cfg.AutoMigrate)
+
+	pg := &cfg.PostgresConfig
+	e.string("PSQL_HOST", &pg.Host)
+	e.string("PSQL_PORT", &pg.Port)
+	e.string("PSQL_USER", &pg.User)
+	e.string("PSQL_PASSWORD", &pg.Password)
+	e.string("PSQL_DATABASE", &pg.Database)
+	e.string("PSQL_SSLMODE", &pg.SSLMode)
+	e.int("PSQL_MAX_OPEN_CONNS", &pg.MaxOpenConns)
+	e.int("PSQL_MAX_IDLE_CONNS", &pg.MaxIdleConns)
+	e.duration("PSQL_CONN_MAX_LIFETIME", &pg.ConnMaxLifetime)
+	e.duration("PSQL_CONN_MAX_IDLE_TIME", &pg.ConnMaxIdleTime)
+	e.duration("PSQL_STATEMENT_TIMEOUT", &pg.StatementTimeout)
+	e.bool("PSQL_USE_PGXPOOL", &pg.UsePgxPool)
+
+	e.bool("API_KEY_REQUIRED", &cfg.RequireAPIKey)
+	e.int("RATE_LIMIT_RPM", &cfg.RateLimit.RequestsPerMinute)
+	e.int("RATE_LIMIT_BURST", &cfg.RateLimit.Burst)
+	e.list("ADMIN_KEYS", &cfg.Moderation.AdminKeys)
+	e.string("BLOCKLIST_FILE", &cfg.Moderation.BlocklistFile)
+	e.duration("BLOCKLIST_REFRESH", &cfg.Moderation.BlocklistRefresh)
+	e.string("AUDIT_SINK", &cfg.Audit.Sink)
+	e.string("AUDIT_FILE", &cfg.Audit.File)
+	e.webhook(&cfg.Webhooks)
+	e.bool("HEALTH_CHECK_ENABLED", &cfg.Health.Enabled)
+	e.duration("HEALTH_CHECK_INTERVAL", &cfg.Health.Interval)
+	e.int("HEALTH_CHECK_CONCURRENCY", &cfg.Health.Concurrency)
+	e.duration("HEALTH_CHECK_HOST_DELAY", &cfg.Health.HostDelay)
+	e.duration("HEALTH_CHECK_TIMEOUT", &cfg.Health.Timeout)
+	e.bool("HEALTH_MARK_BROKEN", &cfg.Health.MarkBroken)
+	e.bool("SCAN_GUARD_ENABLED", &cfg.ScanGuard.Enabled)
+	e.duration("SCAN_GUARD_WINDOW", &cfg.ScanGuard.Window)
+	e.int("SCAN_GUARD_THRESHOLD", &cfg.ScanGuard.Threshold)
+	e.duration("SCAN_GUARD_DELAY", &cfg.ScanGuard.Delay)
+	e.duration("SCAN_GUARD_MAX_DELAY", &cfg.ScanGuard.MaxDelay)
+	e.int("SCAN_GUARD_BAN_AFTER", &cfg.ScanGuard.BanAfter)
+	e.duration("SCAN_GUARD_BAN_DURATION", &cfg.ScanGuard.BanDuration)
+	e.duration("IDEMPOTENCY_TTL", &cfg.Idempotency.TTL)
+	e.string("ACTIVATION_FALLBACK_URL", &cfg.Activation.FallbackURL)
+	e.duration("DASHBOARD_SESSION_TTL", &cfg.Dashboard.SessionTTL)
+	e.bool("DASHBOARD_SECURE_COOKIES", &cfg.Dashboard.SecureCookies)
+	e.list("CORS_ALLOWED_ORIGINS", &cfg.CORS.AllowedOrigins)
+	e.list("CORS_ALLOWED_METHODS", &cfg.CORS.AllowedMethods)
+	e.bool("CORS_ALLOW_CREDENTIALS", &cfg.CORS.AllowCredentials)
+	e.duration("CORS_MAX_AGE", &cfg.CORS.MaxAge)
+	e.string("SECURITY_CSP", &cfg.SecurityHeaders.CSP)
+	e.duration("SECURITY_HSTS_MAX_AGE", &cfg.SecurityHeaders.HSTSMaxAge)
+	e.string("SECURITY_REFERRER_POLICY", &cfg.SecurityHeaders.ReferrerPolicy)
+	e.signingKeys("SIGNING_KEYS", &cfg.Signing.Keys)
+	e.duration("SIGNING_DEFAULT_TTL", &cfg.Signing.DefaultTTL)
+	e.string("LOG_LEVEL", &cfg.LogLevel)
+}
+
+func (e *envReader) string(key string, dst *string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+}
+
+func (e *envReader) int(key string, dst *int) {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return
+	}
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid integer %q", key, valueStr))
+		return
+	}
+	*dst = value
+}
+
+func (e *envReader) duration(key string, dst *time.Duration) {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return
+	}
+	value, err := time.ParseDuration(valueStr)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid duration %q", key, valueStr))
+		return
+	}
+	*dst = value
+}
+
+func (e *envReader) bool(key string, dst *bool) {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid boolean %q", key, valueStr))
+		return
+	}
+	*dst = value
+}
+
+// list reads a comma-separated value; blank items are dropped.
+func (e *envReader) list(key string, dst *[]string) {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(valueStr, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+// signingKeys reads comma-separated "id:secret" pairs, first key first.
+func (e *envReader) signingKeys(key string, dst *[]signing.Key) {
+	var pairs []string
+	e.list(key, &pairs)
+	if pairs == nil {
+		return
+	}
+	keys := make([]signing.Key, 0, len(pairs))
+	for _, pair := range pairs {
+		id, secret, ok := strings.Cut(pair, ":")
+		if !ok {
+			// The value may be a bare secret, so it is not echoed back.
+			e.errs = append(e.errs, fmt.Errorf("%s: expected comma-separated id:secret pairs", key))
+			return
+		}
+		keys = append(keys, signing.Key{ID: id, Secret: secret})
+	}
+	*dst = keys
+}
+
+// webhook reads the WEBHOOK_* variables. WEBHOOK_URL sets up an endpoint
+// named "default", replacing one of that name from the config file.
+func (e *envReader) webhook(wh *Webhooks) {
+	e.int("WEBHOOK_MAX_ATTEMPTS", &wh.MaxAttempts)
+	e.duration("WEBHOOK_POLL_INTERVAL", &wh.PollInterval)
+	e.duration("WEBHOOK_EXPIRY_CHECK", &wh.ExpiryCheck)
+
+	hookURL := os.Getenv("WEBHOOK_URL")
+	if hookURL == "" {
+		return
+	}
+	hook := webhook.Endpoint{Name: defaultWebhook, URL: hookURL}
+	e.string("WEBHOOK_SECRET", &hook.Secret)
+	e.list("WEBHOOK_EVENTS", &hook.Events)
+	hitEvery := 0
+	e.int("WEBHOOK_HIT_EVERY", &hitEvery)
+	hook.HitEvery = int64(hitEvery)
+
+	wh.Endpoints = slices.DeleteFunc(slices.Clone(wh.Endpoints), func(ep webhook.Endpoint) bool {
+		return ep.Name == defaultWebhook
+	})
+	wh.Endpoints = append(wh.Endpoints, hook)
+}
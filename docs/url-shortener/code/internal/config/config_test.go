@@ -0,0 +1,732 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"urlshortener/internal/api"
+	"urlshortener/internal/services/signing"
+)
+
+// clearEnv blanks every variable Load reads, so the host environment can't
+// leak into a test. Empty values count as unset.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{
+		"CONFIG_FILE", "PORT", "GRPC_PORT", "CODE_LENGTH", "SHORTENER_MAX_RETRIES", "URL_DENYLIST",
+		"STORAGE_DRIVER", "BOLT_PATH", "AUTO_MIGRATE",
+		"PSQL_HOST", "PSQL_PORT", "PSQL_USER", "PSQL_PASSWORD", "PSQL_DATABASE", "PSQL_SSLMODE",
+		"PSQL_MAX_OPEN_CONNS", "PSQL_MAX_IDLE_CONNS", "PSQL_CONN_MAX_LIFETIME",
+		"PSQL_CONN_MAX_IDLE_TIME", "PSQL_STATEMENT_TIMEOUT", "PSQL_USE_PGXPOOL",
+		"API_KEY_REQUIRED", "RATE_LIMIT_RPM", "RATE_LIMIT_BURST", "LOG_LEVEL",
+		"ADMIN_KEYS", "BLOCKLIST_FILE", "BLOCKLIST_REFRESH", "AUDIT_SINK", "AUDIT_FILE",
+		"WEBHOOK_URL", "WEBHOOK_SECRET", "WEBHOOK_EVENTS", "WEBHOOK_HIT_EVERY",
+		"WEBHOOK_MAX_ATTEMPTS", "WEBHOOK_POLL_INTERVAL", "WEBHOOK_EXPIRY_CHECK",
+		"HEALTH_CHECK_ENABLED", "HEALTH_CHECK_INTERVAL", "HEALTH_CHECK_CONCURRENCY",
+		"HEALTH_CHECK_HOST_DELAY", "HEALTH_CHECK_TIMEOUT", "HEALTH_MARK_BROKEN",
+		"SIGNING_KEYS", "SIGNING_DEFAULT_TTL",
+		"SCAN_GUARD_ENABLED", "SCAN_GUARD_WINDOW", "SCAN_GUARD_THRESHOLD", "SCAN_GUARD_DELAY",
+		"SCAN_GUARD_MAX_DELAY", "SCAN_GUARD_BAN_AFTER", "SCAN_GUARD_BAN_DURATION",
+		"IDEMPOTENCY_TTL", "ACTIVATION_FALLBACK_URL", "DASHBOARD_SESSION_TTL", "DASHBOARD_SECURE_COOKIES",
+		"CORS_ALLOWED_ORIGINS", "CORS_ALLOWED_METHODS", "CORS_ALLOW_CREDENTIALS", "CORS_MAX_AGE",
+		"SECURITY_CSP", "SECURITY_HSTS_MAX_AGE", "SECURITY_REFERRER_POLICY",
+		"TLS_CERT_FILE", "TLS_KEY_FILE", "TLS_REDIRECT_PORT", "TLS_RELOAD_INTERVAL", "TRUSTED_PROXIES",
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+server:
+  port: 9090
+shortener:
+  denylist: [evil.example]
+storage:
+  driver: bolt
+  bolt:
+    path: /tmp/links.db
+rate_limit:
+  requests_per_minute: 120
+`,
+		"config.toml": `
+[server]
+port = 9090
+[shortener]
+denylist = ["evil.example"]
+[storage]
+driver = "bolt"
+[storage.bolt]
+path = "/tmp/links.db"
+[rate_limit]
+requests_per_minute = 120
+`,
+		"config.json": `{
+  "server": {"port": 9090},
+  "shortener": {"denylist": ["evil.example"]},
+  "storage": {"driver": "bolt", "bolt": {"path": "/tmp/links.db"}},
+  "rate_limit": {"requests_per_minute": 120}
+}`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			clearEnv(t)
+			cfg, err := Load(writeFile(t, name, content))
+			if err != nil {
+				t.Fatalf("Load returned error: %v", err)
+			}
+			if cfg.Server.Address != ":9090" {
+				t.Fatalf("expected address :9090, got %s", cfg.Server.Address)
+			}
+			if cfg.StorageDriver != DriverBolt || cfg.BoltConfig.Path != "/tmp/links.db" {
+				t.Fatalf("unexpected storage config: %s %s", cfg.StorageDriver, cfg.BoltConfig.Path)
+			}
+			if cfg.RateLimit.RequestsPerMinute != 120 {
+				t.Fatalf("expected rate limit 120, got %d", cfg.RateLimit.RequestsPerMinute)
+			}
+			if !slices.Equal(cfg.ShortenerSettings.Denylist, []string{"evil.example"}) {
+				t.Fatalf("unexpected denylist: %v", cfg.ShortenerSettings.Denylist)
+			}
+			// Untouched fields keep their defaults.
+			if cfg.ShortenerSettings.CodeLength != 6 {
+				t.Fatalf("expected default code length 6, got %d", cfg.ShortenerSettings.CodeLength)
+			}
+		})
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+shortener:
+  code_length: 8
+storage:
+  driver: memory
+log_level: warn
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("CODE_LENGTH", "10")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.ShortenerSettings.CodeLength != 10 {
+		t.Fatalf("expected env to win with 10, got %d", cfg.ShortenerSettings.CodeLength)
+	}
+	if cfg.LogLevel != "warn" {
+		t.Fatalf("expected log level from file, got %s", cfg.LogLevel)
+	}
+}
+
+func TestLoadGRPCPort(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+server:
+  grpc_port: 9090
+storage:
+  driver: memory
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Server.GRPCAddress != ":9090" {
+		t.Fatalf("expected gRPC on :9090, got %q", cfg.Server.GRPCAddress)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.WriteRedacted(&buf); err != nil {
+		t.Fatalf("WriteRedacted returned error: %v", err)
+	}
+	reloaded, err := Load(writeFile(t, "printed.yaml", buf.String()))
+	if err != nil {
+		t.Fatalf("printed config did not load: %v", err)
+	}
+	if reloaded.Server.GRPCAddress != ":9090" {
+		t.Fatalf("grpc_port did not round-trip: %q", reloaded.Server.GRPCAddress)
+	}
+
+	t.Setenv("GRPC_PORT", "8080")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "grpc_port must differ") {
+		t.Fatalf("expected a clash with the HTTP port to be reported, got %v", err)
+	}
+}
+
+func TestLoadTLS(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+server:
+  port: 8443
+  tls:
+    cert_file: /etc/shortener/cert.pem
+    key_file: /etc/shortener/key.pem
+    redirect_port: 8080
+storage:
+  driver: memory
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	want := TLS{CertFile: "/etc/shortener/cert.pem", KeyFile: "/etc/shortener/key.pem", RedirectAddress: ":8080", ReloadInterval: time.Minute}
+	if cfg.Server.TLS != want || !cfg.Server.TLS.Enabled() {
+		t.Fatalf("expected %+v, got %+v", want, cfg.Server.TLS)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.WriteRedacted(&buf); err != nil {
+		t.Fatalf("WriteRedacted returned error: %v", err)
+	}
+	reloaded, err := Load(writeFile(t, "printed.yaml", buf.String()))
+	if err != nil {
+		t.Fatalf("printed config did not load: %v", err)
+	}
+	if reloaded.Server.TLS != want {
+		t.Fatalf("tls did not round-trip: %+v", reloaded.Server.TLS)
+	}
+
+	t.Setenv("TLS_REDIRECT_PORT", "8443")
+	t.Setenv("TLS_RELOAD_INTERVAL", "-1s")
+	_, err = Load(path)
+	for _, msg := range []string{"redirect_port must differ from port", "reload_interval must not be negative"} {
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Fatalf("expected error containing %q, got %v", msg, err)
+		}
+	}
+
+	clearEnv(t)
+	t.Setenv("STORAGE_DRIVER", "memory")
+	t.Setenv("TLS_CERT_FILE", "cert.pem")
+	t.Setenv("TLS_REDIRECT_PORT", "80")
+	_, err = Load("")
+	for _, msg := range []string{"cert_file and key_file must be set together", "redirect_port needs cert_file and key_file"} {
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Fatalf("expected error containing %q, got %v", msg, err)
+		}
+	}
+}
+
+func TestLoadModeration(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+storage:
+  driver: memory
+moderation:
+  admin_keys: [key-1]
+  blocklist_file: /etc/shortener/blocklist.txt
+  blocklist_refresh: 30s
+`)
+	t.Setenv("ADMIN_KEYS", "key-2, key-3")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if !slices.Equal(cfg.Moderation.AdminKeys, []string{"key-2", "key-3"}) {
+		t.Fatalf("expected admin keys from env, got %v", cfg.Moderation.AdminKeys)
+	}
+	if cfg.Moderation.BlocklistFile != "/etc/shortener/blocklist.txt" || cfg.Moderation.BlocklistRefresh != 30*time.Second {
+		t.Fatalf("unexpected blocklist config: %+v", cfg.Moderation)
+	}
+
+	next := cfg
+	next.Moderation.AdminKeys = []string{"key-2"}
+	if !cfg.NeedsRestart(next) {
+		t.Fatal("expected admin key change to need a restart")
+	}
+}
+
+func TestLoadWebhooks(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+storage:
+  driver: memory
+webhooks:
+  max_attempts: 5
+  endpoints:
+    - name: crm
+      url: https://crm.example/hooks
+      secret: crm-secret
+      events: [link.created, link.hits]
+      hit_every: 100
+    - name: default
+      url: https://old.example/hooks
+      secret: old-secret
+`)
+	t.Setenv("WEBHOOK_URL", "https://ops.example/hooks")
+	t.Setenv("WEBHOOK_SECRET", "ops-secret")
+	t.Setenv("WEBHOOK_EVENTS", "link.deleted, link.expired")
+	t.Setenv("WEBHOOK_EXPIRY_CHECK", "0s")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	wh := cfg.Webhooks
+	if wh.MaxAttempts != 5 || wh.PollInterval != 5*time.Second || wh.ExpiryCheck != 0 {
+		t.Fatalf("unexpected webhook settings: %+v", wh)
+	}
+	if len(wh.Endpoints) != 2 {
+		t.Fatalf("expected env to replace the default endpoint, got %+v", wh.Endpoints)
+	}
+	crm, hook := wh.Endpoints[0], wh.Endpoints[1]
+	if crm.Name != "crm" || crm.HitEvery != 100 || !slices.Equal(crm.Events, []string{"link.created", "link.hits"}) {
+		t.Fatalf("unexpected crm endpoint: %+v", crm)
+	}
+	if hook.Name != "default" || hook.URL != "https://ops.example/hooks" || hook.Secret != "ops-secret" ||
+		!slices.Equal(hook.Events, []string{"link.deleted", "link.expired"}) {
+		t.Fatalf("unexpected default endpoint: %+v", hook)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.WriteRedacted(&buf); err != nil {
+		t.Fatalf("WriteRedacted returned error: %v", err)
+	}
+	if strings.Contains(buf.String(), "crm-secret") || strings.Contains(buf.String(), "ops-secret") {
+		t.Fatalf("webhook secret leaked into output:\n%s", buf.String())
+	}
+	clearEnv(t)
+	reloaded, err := Load(writeFile(t, "printed.yaml", buf.String()))
+	if err != nil {
+		t.Fatalf("printed config did not load: %v", err)
+	}
+	if reloaded.Webhooks.ExpiryCheck != 0 || len(reloaded.Webhooks.Endpoints) != 2 {
+		t.Fatalf("webhooks did not round-trip: %+v", reloaded.Webhooks)
+	}
+
+	next := cfg
+	next.Webhooks.Endpoints = wh.Endpoints[:1]
+	if !cfg.NeedsRestart(next) {
+		t.Fatal("expected webhook change to need a restart")
+	}
+}
+
+func TestLoadRejectsBadWebhooks(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+storage:
+  driver: memory
+webhooks:
+  endpoints:
+    - name: crm
+      url: ftp://crm.example
+      events: [link.visited, link.hits]
+    - name: crm
+      url: https://crm.example
+      secret: s
+`)
+
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"http(s) URL", "secret is required", "link.visited", "hit_every is required", "used twice"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error to mention %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadHealth(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+storage:
+  driver: memory
+health:
+  enabled: true
+  interval: 6h
+  concurrency: 4
+  mark_broken: true
+`)
+	t.Setenv("HEALTH_CHECK_HOST_DELAY", "0s")
+	t.Setenv("HEALTH_CHECK_TIMEOUT", "5s")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	want := Health{Enabled: true, Interval: 6 * time.Hour, Concurrency: 4, HostDelay: 0, Timeout: 5 * time.Second, MarkBroken: true}
+	if cfg.Health != want {
+		t.Fatalf("expected %+v, got %+v", want, cfg.Health)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.WriteRedacted(&buf); err != nil {
+		t.Fatalf("WriteRedacted returned error: %v", err)
+	}
+	clearEnv(t)
+	reloaded, err := Load(writeFile(t, "printed.yaml", buf.String()))
+	if err != nil {
+		t.Fatalf("printed config did not load: %v", err)
+	}
+	if reloaded.Health != want {
+		t.Fatalf("health settings did not round-trip: %+v", reloaded.Health)
+	}
+
+	t.Setenv("HEALTH_CHECK_CONCURRENCY", "0")
+	t.Setenv("HEALTH_CHECK_TIMEOUT", "soon")
+	_, err = Load(path)
+	if err == nil || !strings.Contains(err.Error(), "HEALTH_CHECK_TIMEOUT") || !strings.Contains(err.Error(), "health concurrency") {
+		t.Fatalf("expected bad health settings to be reported, got %v", err)
+	}
+}
+
+func TestLoadSigning(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+storage:
+  driver: memory
+signing:
+  keys:
+    - id: "2026"
+      secret: new-secret-0123456789
+    - id: "2025"
+      secret: old-secret-0123456789
+  default_ttl: 24h
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	want := []signing.Key{{ID: "2026", Secret: "new-secret-0123456789"}, {ID: "2025", Secret: "old-secret-0123456789"}}
+	if !slices.Equal(cfg.Signing.Keys, want) || cfg.Signing.DefaultTTL != 24*time.Hour {
+		t.Fatalf("unexpected signing settings: %+v", cfg.Signing)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.WriteRedacted(&buf); err != nil {
+		t.Fatalf("WriteRedacted returned error: %v", err)
+	}
+	if strings.Contains(buf.String(), "secret-0123456789") || !strings.Contains(buf.String(), "id: \"2025\"") {
+		t.Fatalf("expected key ids without secrets, got:\n%s", buf.String())
+	}
+
+	t.Setenv("SIGNING_KEYS", "k1:env-secret-0123456789")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if want := []signing.Key{{ID: "k1", Secret: "env-secret-0123456789"}}; !slices.Equal(cfg.Signing.Keys, want) {
+		t.Fatalf("expected SIGNING_KEYS to replace the file's keys, got %+v", cfg.Signing.Keys)
+	}
+
+	t.Setenv("SIGNING_KEYS", "k1:short,k1:env-secret-0123456789,bad id:env-secret-0123456789")
+	t.Setenv("SIGNING_DEFAULT_TTL", "0s")
+	_, err = Load(path)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"at least 16 characters", "used twice", "letters, digits", "default_ttl must be positive"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error to mention %q, got:\n%v", want, err)
+		}
+	}
+
+	t.Setenv("SIGNING_KEYS", "bare-secret-0123456789")
+	_, err = Load(path)
+	if err == nil || !strings.Contains(err.Error(), "SIGNING_KEYS") || strings.Contains(err.Error(), "bare-secret") {
+		t.Fatalf("expected a malformed SIGNING_KEYS without its value, got %v", err)
+	}
+}
+
+func TestLoadScanGuard(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+storage:
+  driver: memory
+scan_guard:
+  enabled: true
+  threshold: 10
+  ban_after: 50
+`)
+	t.Setenv("SCAN_GUARD_BAN_DURATION", "1h")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	want := ScanGuard{Enabled: true, Window: 10 * time.Minute, Threshold: 10, Delay: 250 * time.Millisecond,
+		MaxDelay: 5 * time.Second, BanAfter: 50, BanDuration: time.Hour}
+	if cfg.ScanGuard != want {
+		t.Fatalf("expected %+v, got %+v", want, cfg.ScanGuard)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.WriteRedacted(&buf); err != nil {
+		t.Fatalf("WriteRedacted returned error: %v", err)
+	}
+	clearEnv(t)
+	reloaded, err := Load(writeFile(t, "printed.yaml", buf.String()))
+	if err != nil {
+		t.Fatalf("printed config did not load: %v", err)
+	}
+	if reloaded.ScanGuard != want {
+		t.Fatalf("scan guard settings did not round-trip: %+v", reloaded.ScanGuard)
+	}
+
+	t.Setenv("SCAN_GUARD_DELAY", "10s")
+	t.Setenv("SCAN_GUARD_THRESHOLD", "0")
+	_, err = Load(path)
+	if err == nil || !strings.Contains(err.Error(), "max_delay must be at least delay") || !strings.Contains(err.Error(), "threshold must be positive") {
+		t.Fatalf("expected bad scan guard settings to be reported, got %v", err)
+	}
+}
+
+func TestLoadIdempotency(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("STORAGE_DRIVER", "memory")
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Idempotency.TTL != 24*time.Hour {
+		t.Fatalf("expected a 24h default, got %s", cfg.Idempotency.TTL)
+	}
+
+	path := writeFile(t, "config.yaml", "storage:\n  driver: memory\nidempotency:\n  ttl: 2h\n")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Idempotency.TTL != 2*time.Hour {
+		t.Fatalf("expected the file's ttl, got %s", cfg.Idempotency.TTL)
+	}
+	next := cfg
+	next.Idempotency.TTL = time.Hour
+	if !cfg.NeedsRestart(next) {
+		t.Fatalf("expected an idempotency change to need a restart")
+	}
+
+	t.Setenv("IDEMPOTENCY_TTL", "-1m")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "idempotency ttl must not be negative") {
+		t.Fatalf("expected a negative ttl to be reported, got %v", err)
+	}
+}
+
+func TestLoadActivation(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", "storage:\n  driver: memory\nactivation:\n  fallback_url: https://example.com/soon\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Activation.FallbackURL != "https://example.com/soon" {
+		t.Fatalf("expected the file's fallback url, got %q", cfg.Activation.FallbackURL)
+	}
+	next := cfg
+	next.Activation.FallbackURL = ""
+	if !cfg.NeedsRestart(next) {
+		t.Fatalf("expected a fallback change to need a restart")
+	}
+
+	t.Setenv("ACTIVATION_FALLBACK_URL", "/soon")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "activation fallback_url must be an http(s) URL") {
+		t.Fatalf("expected a relative fallback url to be reported, got %v", err)
+	}
+}
+
+func TestLoadDashboard(t *testing.T) {
+	clearEnv(t)
+	cfg, err := Load(writeFile(t, "config.yaml", "storage:\n  driver: memory\n"))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Dashboard.SessionTTL != 12*time.Hour || cfg.SecureCookies() {
+		t.Fatalf("expected 12h sessions without secure cookies by default, got %+v", cfg.Dashboard)
+	}
+
+	path := writeFile(t, "config.yaml", "storage:\n  driver: memory\ndashboard:\n  session_ttl: 1h\n  secure_cookies: true\n")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Dashboard.SessionTTL != time.Hour || !cfg.SecureCookies() {
+		t.Fatalf("expected the file's dashboard settings, got %+v", cfg.Dashboard)
+	}
+	next := cfg
+	next.Dashboard.SecureCookies = false
+	if !cfg.NeedsRestart(next) {
+		t.Fatalf("expected a dashboard change to need a restart")
+	}
+	next.Server.TLS = TLS{CertFile: "cert.pem", KeyFile: "key.pem"}
+	if !next.SecureCookies() {
+		t.Fatalf("expected TLS to make cookies secure")
+	}
+
+	t.Setenv("DASHBOARD_SESSION_TTL", "0s")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "dashboard session_ttl must be positive") {
+		t.Fatalf("expected a zero session ttl to be reported, got %v", err)
+	}
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", "storage:\n  driver: memory\nserver:\n  trusted_proxies: [10.0.0.0/8, 192.0.2.10]\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if !slices.Equal(cfg.Server.TrustedProxies, []string{"10.0.0.0/8", "192.0.2.10"}) {
+		t.Fatalf("unexpected trusted proxies: %v", cfg.Server.TrustedProxies)
+	}
+	next := cfg
+	next.Server.TrustedProxies = nil
+	if !cfg.NeedsRestart(next) {
+		t.Fatalf("expected a trusted proxy change to need a restart")
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,proxy.internal")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), `trusted proxy "proxy.internal"`) {
+		t.Fatalf("expected a bad proxy address to be reported, got %v", err)
+	}
+}
+
+func TestLoadSecurity(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `storage:
+  driver: memory
+cors:
+  allowed_origins: [https://app.example.com]
+  allow_credentials: true
+security_headers:
+  hsts_max_age: 24h
+  overrides:
+    /static/: {Content-Security-Policy: ""}
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if !slices.Equal(cfg.CORS.AllowedOrigins, []string{"https://app.example.com"}) || !cfg.CORS.AllowCredentials ||
+		!slices.Equal(cfg.CORS.AllowedMethods, api.DefaultCORSMethods) || cfg.CORS.MaxAge != 10*time.Minute {
+		t.Fatalf("unexpected cors config: %+v", cfg.CORS)
+	}
+	sh := cfg.SecurityHeaders
+	if sh.HSTSMaxAge != 24*time.Hour || sh.CSP != api.DefaultSecurityHeaders().CSP {
+		t.Fatalf("unexpected security headers: %+v", sh)
+	}
+	if csp, ok := sh.Overrides["/static/"]["Content-Security-Policy"]; !ok || csp != "" || sh.Overrides["/api/"] == nil {
+		t.Fatalf("expected the file's override next to the default one, got %+v", sh.Overrides)
+	}
+	next := cfg
+	next.CORS.AllowedOrigins = nil
+	if !cfg.NeedsRestart(next) {
+		t.Fatalf("expected a cors change to need a restart")
+	}
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*,https://app.example.com/path")
+	t.Setenv("CORS_ALLOWED_METHODS", "GET,FETCH")
+	_, err = Load(path)
+	for _, want := range []string{
+		"cors allow_credentials cannot be used with origin *",
+		`cors origin must be * or scheme://host[:port], got "https://app.example.com/path"`,
+		`cors method must be one of GET, HEAD, POST, PUT, PATCH, DELETE; got "FETCH"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	for name, content := range map[string]string{
+		"config.yaml": "storage:\n  drvier: memory\n",
+		"config.toml": "[storage]\ndrvier = \"memory\"\n",
+		"config.json": `{"storage": {"drvier": "memory"}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			clearEnv(t)
+			_, err := Load(writeFile(t, name, content))
+			if err == nil || !strings.Contains(err.Error(), "drvier") {
+				t.Fatalf("expected unknown key error naming drvier, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("STORAGE_DRIVER", "postgres")
+	t.Setenv("SHORTENER_MAX_RETRIES", "three")
+	t.Setenv("CODE_LENGTH", "0")
+	t.Setenv("LOG_LEVEL", "loud")
+	t.Setenv("AUDIT_SINK", "syslog")
+
+	_, err := Load("")
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"SHORTENER_MAX_RETRIES", "code_length", "password", "loud", "syslog"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error to mention %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestWriteRedacted(t *testing.T) {
+	cfg := Defaults()
+	cfg.PostgresConfig.Password = "hunter2"
+	cfg.PostgresConfig.StatementTimeout = 5 * time.Second
+
+	var buf bytes.Buffer
+	if err := cfg.WriteRedacted(&buf); err != nil {
+		t.Fatalf("WriteRedacted returned error: %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "hunter2") {
+		t.Fatalf("password leaked into output:\n%s", out)
+	}
+	if !strings.Contains(out, "password: '****'") || !strings.Contains(out, "statement_timeout: 5s") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	// The printed config is itself a valid config file.
+	clearEnv(t)
+	reloaded, err := Load(writeFile(t, "printed.yaml", out))
+	if err != nil {
+		t.Fatalf("printed config did not load: %v", err)
+	}
+	if reloaded.PostgresConfig.StatementTimeout != 5*time.Second {
+		t.Fatalf("expected statement timeout to round-trip, got %s", reloaded.PostgresConfig.StatementTimeout)
+	}
+}
+
+func TestNeedsRestart(t *testing.T) {
+	cfg := Defaults()
+
+	next := cfg
+	next.RateLimit.RequestsPerMinute = 60
+	next.ShortenerSettings.Denylist = []string{"evil.example"}
+	next.LogLevel = "debug"
+	next.Signing.Keys = []signing.Key{{ID: "k2", Secret: "rotated-secret-0123456789"}}
+	if cfg.NeedsRestart(next) {
+		t.Fatal("reloadable changes should not need a restart")
+	}
+
+	next.ScanGuard.Enabled = true
+	if !cfg.NeedsRestart(next) {
+		t.Fatal("scan guard change should need a restart")
+	}
+
+	next.StorageDriver = DriverBolt
+	if !cfg.NeedsRestart(next) {
+		t.Fatal("storage driver change should need a restart")
+	}
+}
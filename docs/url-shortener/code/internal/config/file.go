@@ -0,0 +1,575 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"urlshortener/internal/services/signing"
+	"urlshortener/internal/services/webhook"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig is the schema of the optional config file. Pointer fields tell
+// "not set" apart from zero values, so the file only overrides what it names.
+// Durations are strings in time.ParseDuration form, e.g. "30s".
+type fileConfig struct {
+	Server      *fileServer      `yaml:"server,omitempty" toml:"server,omitempty" json:"server,omitempty"`
+	Shortener   *fileShortener   `yaml:"shortener,omitempty" toml:"shortener,omitempty" json:"shortener,omitempty"`
+	Storage     *fileStorage     `yaml:"storage,omitempty" toml:"storage,omitempty" json:"storage,omitempty"`
+	RateLimit   *fileRateLimit   `yaml:"rate_limit,omitempty" toml:"rate_limit,omitempty" json:"rate_limit,omitempty"`
+	Moderation  *fileModeration  `yaml:"moderation,omitempty" toml:"moderation,omitempty" json:"moderation,omitempty"`
+	Audit       *fileAudit       `yaml:"audit,omitempty" toml:"audit,omitempty" json:"audit,omitempty"`
+	Webhooks    *fileWebhooks    `yaml:"webhooks,omitempty" toml:"webhooks,omitempty" json:"webhooks,omitempty"`
+	Health      *fileHealth      `yaml:"health,omitempty" toml:"health,omitempty" json:"health,omitempty"`
+	Signing     *fileSigning     `yaml:"signing,omitempty" toml:"signing,omitempty" json:"signing,omitempty"`
+	ScanGuard   *fileScanGuard   `yaml:"scan_guard,omitempty" toml:"scan_guard,omitempty" json:"scan_guard,omitempty"`
+	Idempotency *fileIdempotency `yaml:"idempotency,omitempty" toml:"idempotency,omitempty" json:"idempotency,omitempty"`
+	Activation  *fileActivation  `yaml:"activation,omitempty" toml:"activation,omitempty" json:"activation,omitempty"`
+	Dashboard   *fileDashboard   `yaml:"dashboard,omitempty" toml:"dashboard,omitempty" json:"dashboard,omitempty"`
+	CORS        *fileCORS        `yaml:"cors,omitempty" toml:"cors,omitempty" json:"cors,omitempty"`
+	Security    *fileSecurity    `yaml:"security_headers,omitempty" toml:"security_headers,omitempty" json:"security_headers,omitempty"`
+	LogLevel    *string          `yaml:"log_level,omitempty" toml:"log_level,omitempty" json:"log_level,omitempty"`
+}
+
+type fileServer struct {
+	Port           *int     `yaml:"port,omitempty" toml:"port,omitempty" json:"port,omitempty"`
+	RequireAPIKey  *bool    `yaml:"require_api_key,omitempty" toml:"require_api_key,omitempty" json:"require_api_key,omitempty"`
+	UIDevDir       *string  `yaml:"ui_dev_dir,omitempty" toml:"ui_dev_dir,omitempty" json:"ui_dev_dir,omitempty"`
+	GRPCPort       *int     `yaml:"grpc_port,omitempty" toml:"grpc_port,omitempty" json:"grpc_port,omitempty"`
+	TLS            *fileTLS `yaml:"tls,omitempty" toml:"tls,omitempty" json:"tls,omitempty"`
+	TrustedProxies []string `yaml:"trusted_proxies,omitempty" toml:"trusted_proxies,omitempty" json:"trusted_proxies,omitempty"`
+}
+
+type fileTLS struct {
+	CertFile       *string `yaml:"cert_file,omitempty" toml:"cert_file,omitempty" json:"cert_file,omitempty"`
+	KeyFile        *string `yaml:"key_file,omitempty" toml:"key_file,omitempty" json:"key_file,omitempty"`
+	RedirectPort   *int    `yaml:"redirect_port,omitempty" toml:"redirect_port,omitempty" json:"redirect_port,omitempty"`
+	ReloadInterval *string `yaml:"reload_interval,omitempty" toml:"reload_interval,omitempty" json:"reload_interval,omitempty"`
+}
+
+type fileShortener struct {
+	CodeLength *int     `yaml:"code_length,omitempty" toml:"code_length,omitempty" json:"code_length,omitempty"`
+	MaxRetries *int     `yaml:"max_retries,omitempty" toml:"max_retries,omitempty" json:"max_retries,omitempty"`
+	Denylist   []string `yaml:"denylist,omitempty" toml:"denylist,omitempty" json:"denylist,omitempty"`
+}
+
+type fileStorage struct {
+	Driver      *string       `yaml:"driver,omitempty" toml:"driver,omitempty" json:"driver,omitempty"`
+	AutoMigrate *bool         `yaml:"auto_migrate,omitempty" toml:"auto_migrate,omitempty" json:"auto_migrate,omitempty"`
+	Bolt        *fileBolt     `yaml:"bolt,omitempty" toml:"bolt,omitempty" json:"bolt,omitempty"`
+	Postgres    *filePostgres `yaml:"postgres,omitempty" toml:"postgres,omitempty" json:"postgres,omitempty"`
+}
+
+type fileBolt struct {
+	Path *string `yaml:"path,omitempty" toml:"path,omitempty" json:"path,omitempty"`
+}
+
+type filePostgres struct {
+	Host             *string `yaml:"host,omitempty" toml:"host,omitempty" json:"host,omitempty"`
+	Port             *string `yaml:"port,omitempty" toml:"port,omitempty" json:"port,omitempty"`
+	User             *string `yaml:"user,omitempty" toml:"user,omitempty" json:"user,omitempty"`
+	Password         *string `yaml:"password,omitempty" toml:"password,omitempty" json:"password,omitempty"`
+	Database         *string `yaml:"database,omitempty" toml:"database,omitempty" json:"database,omitempty"`
+	SSLMode          *string `yaml:"sslmode,omitempty" toml:"sslmode,omitempty" json:"sslmode,omitempty"`
+	MaxOpenConns     *int    `yaml:"max_open_conns,omitempty" toml:"max_open_conns,omitempty" json:"max_open_conns,omitempty"`
+	MaxIdleConns     *int    `yaml:"max_idle_conns,omitempty" toml:"max_idle_conns,omitempty" json:"max_idle_conns,omitempty"`
+	ConnMaxLifetime  *string `yaml:"conn_max_lifetime,omitempty" toml:"conn_max_lifetime,omitempty" json:"conn_max_lifetime,omitempty"`
+	ConnMaxIdleTime  *string `yaml:"conn_max_idle_time,omitempty" toml:"conn_max_idle_time,omitempty" json:"conn_max_idle_time,omitempty"`
+	StatementTimeout *string `yaml:"statement_timeout,omitempty" toml:"statement_timeout,omitempty" json:"statement_timeout,omitempty"`
+	UsePgxPool       *bool   `yaml:"use_pgxpool,omitempty" toml:"use_pgxpool,omitempty" json:"use_pgxpool,omitempty"`
+}
+
+type fileRateLimit struct {
+	RequestsPerMinute *int `yaml:"requests_per_minute,omitempty" toml:"requests_per_minute,omitempty" json:"requests_per_minute,omitempty"`
+	Burst             *int `yaml:"burst,omitempty" toml:"burst,omitempty" json:"burst,omitempty"`
+}
+
+type fileModeration struct {
+	AdminKeys        []string `yaml:"admin_keys,omitempty" toml:"admin_keys,omitempty" json:"admin_keys,omitempty"`
+	BlocklistFile    *string  `yaml:"blocklist_file,omitempty" toml:"blocklist_file,omitempty" json:"blocklist_file,omitempty"`
+	BlocklistRefresh *string  `yaml:"blocklist_refresh,omitempty" toml:"blocklist_refresh,omitempty" json:"blocklist_refresh,omitempty"`
+}
+
+type fileAudit struct {
+	Sink *string `yaml:"sink,omitempty" toml:"sink,omitempty" json:"sink,omitempty"`
+	File *string `yaml:"file,omitempty" toml:"file,omitempty" json:"file,omitempty"`
+}
+
+type fileWebhooks struct {
+	MaxAttempts  *int                  `yaml:"max_attempts,omitempty" toml:"max_attempts,omitempty" json:"max_attempts,omitempty"`
+	PollInterval *string               `yaml:"poll_interval,omitempty" toml:"poll_interval,omitempty" json:"poll_interval,omitempty"`
+	ExpiryCheck  *string               `yaml:"expiry_check,omitempty" toml:"expiry_check,omitempty" json:"expiry_check,omitempty"`
+	Endpoints    []fileWebhookEndpoint `yaml:"endpoints,omitempty" toml:"endpoints,omitempty" json:"endpoints,omitempty"`
+}
+
+type fileWebhookEndpoint struct {
+	Name     string   `yaml:"name" toml:"name" json:"name"`
+	URL      string   `yaml:"url" toml:"url" json:"url"`
+	Secret   string   `yaml:"secret" toml:"secret" json:"secret"`
+	Events   []string `yaml:"events,omitempty" toml:"events,omitempty" json:"events,omitempty"`
+	HitEvery int64    `yaml:"hit_every,omitempty" toml:"hit_every,omitempty" json:"hit_every,omitempty"`
+}
+
+type fileHealth struct {
+	Enabled     *bool   `yaml:"enabled,omitempty" toml:"enabled,omitempty" json:"enabled,omitempty"`
+	Interval    *string `yaml:"interval,omitempty" toml:"interval,omitempty" json:"interval,omitempty"`
+	Concurrency *int    `yaml:"concurrency,omitempty" toml:"concurrency,omitempty" json:"concurrency,omitempty"`
+	HostDelay   *string `yaml:"host_delay,omitempty" toml:"host_delay,omitempty" json:"host_delay,omitempty"`
+	Timeout     *string `yaml:"timeout,omitempty" toml:"timeout,omitempty" json:"timeout,omitempty"`
+	MarkBroken  *bool   `yaml:"mark_broken,omitempty" toml:"mark_broken,omitempty" json:"mark_broken,omitempty"`
+}
+
+type fileSigning struct {
+	Keys       []fileSigningKey `yaml:"keys,omitempty" toml:"keys,omitempty" json:"keys,omitempty"`
+	DefaultTTL *string          `yaml:"default_ttl,omitempty" toml:"default_ttl,omitempty" json:"default_ttl,omitempty"`
+}
+
+type fileSigningKey struct {
+	ID     string `yaml:"id" toml:"id" json:"id"`
+	Secret string `yaml:"secret" toml:"secret" json:"secret"`
+}
+
+type fileScanGuard struct {
+	Enabled     *bool   `yaml:"enabled,omitempty" toml:"enabled,omitempty" json:"enabled,omitempty"`
+	Window      *string `yaml:"window,omitempty" toml:"window,omitempty" json:"window,omitempty"`
+	Threshold   *int    `yaml:"threshold,omitempty" toml:"threshold,omitempty" json:"threshold,omitempty"`
+	Delay       *string `yaml:"delay,omitempty" toml:"delay,omitempty" json:"delay,omitempty"`
+	MaxDelay    *string `yaml:"max_delay,omitempty" toml:"max_delay,omitempty" json:"max_delay,omitempty"`
+	BanAfter    *int    `yaml:"ban_after,omitempty" toml:"ban_after,omitempty" json:"ban_after,omitempty"`
+	BanDuration *string `yaml:"ban_duration,omitempty" toml:"ban_duration,omitempty" json:"ban_duration,omitempty"`
+}
+
+type fileIdempotency struct {
+	TTL *string `yaml:"ttl,omitempty" toml:"ttl,omitempty" json:"ttl,omitempty"`
+}
+
+type fileActivation struct {
+	FallbackURL *string `yaml:"fallback_url,omitempty" toml:"fallback_url,omitempty" json:"fallback_url,omitempty"`
+}
+
+type fileDashboard struct {
+	SessionTTL    *string `yaml:"session_ttl,omitempty" toml:"session_ttl,omitempty" json:"session_ttl,omitempty"`
+	SecureCookies *bool   `yaml:"secure_cookies,omitempty" toml:"secure_cookies,omitempty" json:"secure_cookies,omitempty"`
+}
+
+type fileCORS struct {
+	AllowedOrigins   []string `yaml:"allowed_origins,omitempty" toml:"allowed_origins,omitempty" json:"allowed_origins,omitempty"`
+	AllowedMethods   []string `yaml:"allowed_methods,omitempty" toml:"allowed_methods,omitempty" json:"allowed_methods,omitempty"`
+	AllowCredentials *bool    `yaml:"allow_credentials,omitempty" toml:"allow_credentials,omitempty" json:"allow_credentials,omitempty"`
+	MaxAge           *string  `yaml:"max_age,omitempty" toml:"max_age,omitempty" json:"max_age,omitempty"`
+}
+
+// fileSecurity.Overrides is keyed by path prefix, then header name; a prefix
+// named in the file replaces that prefix's default headers.
+type fileSecurity struct {
+	CSP            *string                      `yaml:"csp,omitempty" toml:"csp,omitempty" json:"csp,omitempty"`
+	HSTSMaxAge     *string                      `yaml:"hsts_max_age,omitempty" toml:"hsts_max_age,omitempty" json:"hsts_max_age,omitempty"`
+	ReferrerPolicy *string                      `yaml:"referrer_policy,omitempty" toml:"referrer_policy,omitempty" json:"referrer_policy,omitempty"`
+	Overrides      map[string]map[string]string `yaml:"overrides,omitempty" toml:"overrides,omitempty" json:"overrides,omitempty"`
+}
+
+// applyFile decodes path (format chosen by extension) onto cfg. Unknown keys
+// are errors so typos don't silently fall back to defaults.
+func applyFile(cfg *Config, path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	var fc fileConfig
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(raw))
+		dec.KnownFields(true)
+		if err := dec.Decode(&fc); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(raw), &fc)
+		if err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, key := range undecoded {
+				keys[i] = key.String()
+			}
+			return fmt.Errorf("config file %s: unknown keys %s", path, strings.Join(keys, ", "))
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&fc); err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config file %s: unsupported extension %q (want .yaml, .yml, .toml or .json)", path, ext)
+	}
+
+	if err := fc.apply(cfg); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (fc fileConfig) apply(cfg *Config) error {
+	var errs []error
+	duration := func(key string, src *string, dst *time.Duration) {
+		if src == nil {
+			return
+		}
+		d, err := time.ParseDuration(*src)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid duration %q", key, *src))
+			return
+		}
+		*dst = d
+	}
+
+	if s := fc.Server; s != nil {
+		if s.Port != nil {
+			cfg.Server.Address = ":" + strconv.Itoa(*s.Port)
+		}
+		set(&cfg.RequireAPIKey, s.RequireAPIKey)
+		set(&cfg.Server.UIDevDir, s.UIDevDir)
+		if s.GRPCPort != nil {
+			cfg.Server.GRPCAddress = ":" + strconv.Itoa(*s.GRPCPort)
+		}
+		if t := s.TLS; t != nil {
+			set(&cfg.Server.TLS.CertFile, t.CertFile)
+			set(&cfg.Server.TLS.KeyFile, t.KeyFile)
+			if t.RedirectPort != nil {
+				cfg.Server.TLS.RedirectAddress = ":" + strconv.Itoa(*t.RedirectPort)
+			}
+			duration("server.tls.reload_interval", t.ReloadInterval, &cfg.Server.TLS.ReloadInterval)
+		}
+		if s.TrustedProxies != nil {
+			cfg.Server.TrustedProxies = slices.Clone(s.TrustedProxies)
+		}
+	}
+	if s := fc.Shortener; s != nil {
+		set(&cfg.ShortenerSettings.CodeLength, s.CodeLength)
+		set(&cfg.ShortenerSettings.MaxRetries, s.MaxRetries)
+		if s.Denylist != nil {
+			cfg.ShortenerSettings.Denylist = slices.Clone(s.Denylist)
+		}
+	}
+	if s := fc.Storage; s != nil {
+		set(&cfg.StorageDriver, s.Driver)
+		set(&cfg.AutoMigrate, s.AutoMigrate)
+		if s.Bolt != nil {
+			set(&cfg.BoltConfig.Path, s.Bolt.Path)
+		}
+		if p := s.Postgres; p != nil {
+			pg := &cfg.PostgresConfig
+			set(&pg.Host, p.Host)
+			set(&pg.Port, p.Port)
+			set(&pg.User, p.User)
+			set(&pg.Password, p.Password)
+			set(&pg.Database, p.Database)
+			set(&pg.SSLMode, p.SSLMode)
+			set(&pg.MaxOpenConns, p.MaxOpenConns)
+			set(&pg.MaxIdleConns, p.MaxIdleConns)
+			duration("storage.postgres.conn_max_lifetime", p.ConnMaxLifetime, &pg.ConnMaxLifetime)
+			duration("storage.postgres.conn_max_idle_time", p.ConnMaxIdleTime, &pg.ConnMaxIdleTime)
+			duration("storage.postgres.statement_timeout", p.StatementTimeout, &pg.StatementTimeout)
+			set(&pg.UsePgxPool, p.UsePgxPool)
+		}
+	}
+	if s := fc.RateLimit; s != nil {
+		set(&cfg.RateLimit.RequestsPerMinute, s.RequestsPerMinute)
+		set(&cfg.RateLimit.Burst, s.Burst)
+	}
+	if s := fc.Moderation; s != nil {
+		if s.AdminKeys != nil {
+			cfg.Moderation.AdminKeys = slices.Clone(s.AdminKeys)
+		}
+		set(&cfg.Moderation.BlocklistFile, s.BlocklistFile)
+		duration("moderation.blocklist_refresh", s.BlocklistRefresh, &cfg.Moderation.BlocklistRefresh)
+	}
+	if s := fc.Audit; s != nil {
+		set(&cfg.Audit.Sink, s.Sink)
+		set(&cfg.Audit.File, s.File)
+	}
+	if s := fc.Webhooks; s != nil {
+		set(&cfg.Webhooks.MaxAttempts, s.MaxAttempts)
+		duration("webhooks.poll_interval", s.PollInterval, &cfg.Webhooks.PollInterval)
+		duration("webhooks.expiry_check", s.ExpiryCheck, &cfg.Webhooks.ExpiryCheck)
+		if s.Endpoints != nil {
+			cfg.Webhooks.Endpoints = make([]webhook.Endpoint, len(s.Endpoints))
+			for i, e := range s.Endpoints {
+				cfg.Webhooks.Endpoints[i] = webhook.Endpoint{
+					Name:     e.Name,
+					URL:      e.URL,
+					Secret:   e.Secret,
+					Events:   slices.Clone(e.Events),
+					HitEvery: e.HitEvery,
+				}
+			}
+		}
+	}
+	if s := fc.Health; s != nil {
+		set(&cfg.Health.Enabled, s.Enabled)
+		duration("health.interval", s.Interval, &cfg.Health.Interval)
+		set(&cfg.Health.Concurrency, s.Concurrency)
+		duration("health.host_delay", s.HostDelay, &cfg.Health.HostDelay)
+		duration("health.timeout", s.Timeout, &cfg.Health.Timeout)
+		set(&cfg.Health.MarkBroken, s.MarkBroken)
+	}
+	if s := fc.Signing; s != nil {
+		if s.Keys != nil {
+			cfg.Signing.Keys = make([]signing.Key, len(s.Keys))
+			for i, k := range s.Keys {
+				cfg.Signing.Keys[i] = signing.Key{ID: k.ID, Secret: k.Secret}
+			}
+		}
+		duration("signing.default_ttl", s.DefaultTTL, &cfg.Signing.DefaultTTL)
+	}
+	if s := fc.ScanGuard; s != nil {
+		sg := &cfg.ScanGuard
+		set(&sg.Enabled, s.Enabled)
+		duration("scan_guard.window", s.Window, &sg.Window)
+		set(&sg.Threshold, s.Threshold)
+		duration("scan_guard.delay", s.Delay, &sg.Delay)
+		duration("scan_guard.max_delay", s.MaxDelay, &sg.MaxDelay)
+		set(&sg.BanAfter, s.BanAfter)
+		duration("scan_guard.ban_duration", s.BanDuration, &sg.BanDuration)
+	}
+	if s := fc.Idempotency; s != nil {
+		duration("idempotency.ttl", s.TTL, &cfg.Idempotency.TTL)
+	}
+	if s := fc.Activation; s != nil {
+		set(&cfg.Activation.FallbackURL, s.FallbackURL)
+	}
+	if s := fc.Dashboard; s != nil {
+		duration("dashboard.session_ttl", s.SessionTTL, &cfg.Dashboard.SessionTTL)
+		set(&cfg.Dashboard.SecureCookies, s.SecureCookies)
+	}
+	if s := fc.CORS; s != nil {
+		if s.AllowedOrigins != nil {
+			cfg.CORS.AllowedOrigins = slices.Clone(s.AllowedOrigins)
+		}
+		if s.AllowedMethods != nil {
+			cfg.CORS.AllowedMethods = slices.Clone(s.AllowedMethods)
+		}
+		set(&cfg.CORS.AllowCredentials, s.AllowCredentials)
+		duration("cors.max_age", s.MaxAge, &cfg.CORS.MaxAge)
+	}
+	if s := fc.Security; s != nil {
+		sh := &cfg.SecurityHeaders
+		set(&sh.CSP, s.CSP)
+		duration("security_headers.hsts_max_age", s.HSTSMaxAge, &sh.HSTSMaxAge)
+		set(&sh.ReferrerPolicy, s.ReferrerPolicy)
+		if s.Overrides != nil {
+			overrides := maps.Clone(sh.Overrides)
+			if overrides == nil {
+				overrides = map[string]map[string]string{}
+			}
+			for prefix, headers := range s.Overrides {
+				overrides[prefix] = maps.Clone(headers)
+			}
+			sh.Overrides = overrides
+		}
+	}
+	set(&cfg.LogLevel, fc.LogLevel)
+
+	return errors.Join(errs...)
+}
+
+func set[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+// redactedPassword replaces secrets in printed configs.
+const redactedPassword = "****"
+
+// WriteRedacted prints cfg as YAML in the config file schema, with secrets
+// masked, so the output can be used as a starting config file.
+func (cfg Config) WriteRedacted(w io.Writer) error {
+	pg := cfg.PostgresConfig
+	password := ""
+	if pg.Password != "" {
+		password = redactedPassword
+	}
+	port, _ := strconv.Atoi(strings.TrimPrefix(cfg.Server.Address, ":"))
+	var uiDevDir *string
+	if cfg.Server.UIDevDir != "" {
+		uiDevDir = &cfg.Server.UIDevDir
+	}
+	var grpcPort *int
+	if cfg.Server.GRPCAddress != "" {
+		p, _ := strconv.Atoi(strings.TrimPrefix(cfg.Server.GRPCAddress, ":"))
+		grpcPort = &p
+	}
+	tlsReload := cfg.Server.TLS.ReloadInterval.String()
+	serverTLS := &fileTLS{ReloadInterval: &tlsReload}
+	if cfg.Server.TLS.CertFile != "" {
+		serverTLS.CertFile = &cfg.Server.TLS.CertFile
+	}
+	if cfg.Server.TLS.KeyFile != "" {
+		serverTLS.KeyFile = &cfg.Server.TLS.KeyFile
+	}
+	if cfg.Server.TLS.RedirectAddress != "" {
+		p, _ := strconv.Atoi(strings.TrimPrefix(cfg.Server.TLS.RedirectAddress, ":"))
+		serverTLS.RedirectPort = &p
+	}
+	var blocklistFile *string
+	if cfg.Moderation.BlocklistFile != "" {
+		blocklistFile = &cfg.Moderation.BlocklistFile
+	}
+	blocklistRefresh := cfg.Moderation.BlocklistRefresh.String()
+	pollInterval := cfg.Webhooks.PollInterval.String()
+	expiryCheck := cfg.Webhooks.ExpiryCheck.String()
+	webhooks := &fileWebhooks{
+		MaxAttempts:  &cfg.Webhooks.MaxAttempts,
+		PollInterval: &pollInterval,
+		ExpiryCheck:  &expiryCheck,
+	}
+	for _, e := range cfg.Webhooks.Endpoints {
+		secret := ""
+		if e.Secret != "" {
+			secret = redactedPassword
+		}
+		webhooks.Endpoints = append(webhooks.Endpoints, fileWebhookEndpoint{
+			Name:     e.Name,
+			URL:      e.URL,
+			Secret:   secret,
+			Events:   e.Events,
+			HitEvery: e.HitEvery,
+		})
+	}
+
+	healthInterval := cfg.Health.Interval.String()
+	hostDelay := cfg.Health.HostDelay.String()
+	healthTimeout := cfg.Health.Timeout.String()
+
+	defaultTTL := cfg.Signing.DefaultTTL.String()
+	signingCfg := &fileSigning{DefaultTTL: &defaultTTL}
+	for _, k := range cfg.Signing.Keys {
+		secret := ""
+		if k.Secret != "" {
+			secret = redactedPassword
+		}
+		signingCfg.Keys = append(signingCfg.Keys, fileSigningKey{ID: k.ID, Secret: secret})
+	}
+
+	sg := cfg.ScanGuard
+	window, delay, maxDelay, banDuration := sg.Window.String(), sg.Delay.String(), sg.MaxDelay.String(), sg.BanDuration.String()
+	idempotencyTTL := cfg.Idempotency.TTL.String()
+	sessionTTL := cfg.Dashboard.SessionTTL.String()
+	corsMaxAge := cfg.CORS.MaxAge.String()
+	hstsMaxAge := cfg.SecurityHeaders.HSTSMaxAge.String()
+
+	fc := fileConfig{
+		Server: &fileServer{
+			Port:           &port,
+			RequireAPIKey:  &cfg.RequireAPIKey,
+			UIDevDir:       uiDevDir,
+			GRPCPort:       grpcPort,
+			TLS:            serverTLS,
+			TrustedProxies: cfg.Server.TrustedProxies,
+		},
+		Shortener: &fileShortener{
+			CodeLength: &cfg.ShortenerSettings.CodeLength,
+			MaxRetries: &cfg.ShortenerSettings.MaxRetries,
+			Denylist:   cfg.ShortenerSettings.Denylist,
+		},
+		Storage: &fileStorage{
+			Driver:      &cfg.StorageDriver,
+			AutoMigrate: &cfg.AutoMigrate,
+			Bolt:        &fileBolt{Path: &cfg.BoltConfig.Path},
+			Postgres: &filePostgres{
+				Host:             &pg.Host,
+				Port:             &pg.Port,
+				User:             &pg.User,
+				Password:         &password,
+				Database:         &pg.Database,
+				SSLMode:          &pg.SSLMode,
+				MaxOpenConns:     &pg.MaxOpenConns,
+				MaxIdleConns:     &pg.MaxIdleConns,
+				ConnMaxLifetime:  durationString(pg.ConnMaxLifetime),
+				ConnMaxIdleTime:  durationString(pg.ConnMaxIdleTime),
+				StatementTimeout: durationString(pg.StatementTimeout),
+				UsePgxPool:       &pg.UsePgxPool,
+			},
+		},
+		RateLimit: &fileRateLimit{
+			RequestsPerMinute: &cfg.RateLimit.RequestsPerMinute,
+			Burst:             &cfg.RateLimit.Burst,
+		},
+		Moderation: &fileModeration{
+			AdminKeys:        cfg.Moderation.AdminKeys,
+			BlocklistFile:    blocklistFile,
+			BlocklistRefresh: &blocklistRefresh,
+		},
+		Audit: &fileAudit{
+			Sink: &cfg.Audit.Sink,
+			File: &cfg.Audit.File,
+		},
+		Webhooks: webhooks,
+		Health: &fileHealth{
+			Enabled:     &cfg.Health.Enabled,
+			Interval:    &healthInterval,
+			Concurrency: &cfg.Health.Concurrency,
+			HostDelay:   &hostDelay,
+			Timeout:     &healthTimeout,
+			MarkBroken:  &cfg.Health.MarkBroken,
+		},
+		Signing: signingCfg,
+		ScanGuard: &fileScanGuard{
+			Enabled:     &sg.Enabled,
+			Window:      &window,
+			Threshold:   &sg.Threshold,
+			Delay:       &delay,
+			MaxDelay:    &maxDelay,
+			BanAfter:    &sg.BanAfter,
+			BanDuration: &banDuration,
+		},
+		Idempotency: &fileIdempotency{TTL: &idempotencyTTL},
+		Activation:  &fileActivation{FallbackURL: &cfg.Activation.FallbackURL},
+		Dashboard:   &fileDashboard{SessionTTL: &sessionTTL, SecureCookies: &cfg.Dashboard.SecureCookies},
+		CORS: &fileCORS{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowCredentials: &cfg.CORS.AllowCredentials,
+			MaxAge:           &corsMaxAge,
+		},
+		Security: &fileSecurity{
+			CSP:            &cfg.SecurityHeaders.CSP,
+			HSTSMaxAge:     &hstsMaxAge,
+			ReferrerPolicy: &cfg.SecurityHeaders.ReferrerPolicy,
+			Overrides:      cfg.SecurityHeaders.Overrides,
+		},
+		LogLevel: &cfg.LogLevel,
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(fc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// durationString leaves zero durations out of printed configs.
+func durationString(d time.Duration) *string {
+	if d == 0 {
+		return nil
+	}
+	s := d.String()
+	return &s
+}
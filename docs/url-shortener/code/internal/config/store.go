@@ -0,0 +1,60 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"urlshortener/internal/services/audit"
+	"urlshortener/internal/services/storage"
+	"urlshortener/internal/services/storage/bolt"
+	"urlshortener/internal/services/storage/postgres"
+)
+
+// OpenStore builds the storage backend selected by STORAGE_DRIVER. The returned
+// func releases whatever the backend holds (connections, file locks).
+func OpenStore(cfg Config) (storage.Backend, func() error, error) {
+	switch cfg.StorageDriver {
+	case DriverPostgres:
+		if cfg.PostgresConfig.UsePgxPool {
+			pool, err := postgres.OpenPool(context.Background(), cfg.PostgresConfig)
+			if err != nil {
+				return nil, nil, err
+			}
+			return postgres.NewStoreFromPool(pool), func() error { pool.Close(); return nil }, nil
+		}
+		conn, err := postgres.Open(cfg.PostgresConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		return postgres.NewStore(conn), conn.Close, nil
+	case DriverBolt:
+		db, err := bolt.Open(cfg.BoltConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		return bolt.NewStore(db), db.Close, nil
+	case DriverMemory:
+		return storage.NewInMemoryStore(), func() error { return nil }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
+	}
+}
+
+// OpenAuditLog builds the audit log selected by AUDIT_SINK on top of store.
+// It returns nil when auditing is off; the func closes the file sink, if any.
+func OpenAuditLog(cfg Config, store storage.AuditStore) (*audit.Log, func() error, error) {
+	switch cfg.Audit.Sink {
+	case AuditStore:
+		return audit.New(store), func() error { return nil }, nil
+	case AuditFile:
+		sink, err := audit.OpenFile(cfg.Audit.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		return audit.New(sink), sink.Close, nil
+	case AuditOff:
+		return nil, func() error { return nil }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown audit sink %q", cfg.Audit.Sink)
+	}
+}
@@ -0,0 +1,54 @@
+package grpcapi
+
+import (
+	"errors"
+
+	"urlshortener/internal/logging"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/signing"
+	"urlshortener/internal/services/storage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusError maps the shortener's sentinel errors to gRPC status codes the
+// way the REST API maps them to HTTP statuses. Anything unexpected is
+// logged and returned as Internal without its details; doing names the
+// failed operation for the log.
+func statusError(doing string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound),
+		// Without a valid signature, a signed link looks like no link.
+		errors.Is(err, shortenerpkg.ErrSignatureRequired),
+		errors.Is(err, signing.ErrInvalidSignature):
+		return status.Error(codes.NotFound, storage.ErrNotFound.Error())
+	case errors.Is(err, shortenerpkg.ErrAliasTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, shortenerpkg.ErrExpired),
+		errors.Is(err, shortenerpkg.ErrNotActive),
+		errors.Is(err, shortenerpkg.ErrDisabled),
+		errors.Is(err, shortenerpkg.ErrNotSigned),
+		errors.Is(err, signing.ErrExpired):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, shortenerpkg.ErrEmptyURL),
+		errors.Is(err, shortenerpkg.ErrInvalidURL),
+		errors.Is(err, shortenerpkg.ErrEmptyCode),
+		errors.Is(err, shortenerpkg.ErrInvalidAlias),
+		errors.Is(err, shortenerpkg.ErrReservedAlias),
+		errors.Is(err, shortenerpkg.ErrInvalidExpiry),
+		errors.Is(err, shortenerpkg.ErrInvalidActivation),
+		errors.Is(err, shortenerpkg.ErrDeniedURL),
+		errors.Is(err, shortenerpkg.ErrBlockedURL),
+		errors.Is(err, shortenerpkg.ErrTitleTooLong),
+		errors.Is(err, shortenerpkg.ErrDescriptionTooLong),
+		errors.Is(err, shortenerpkg.ErrInvalidTag),
+		errors.Is(err, shortenerpkg.ErrTooManyTags),
+		errors.Is(err, signing.ErrNoKeys):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, shortenerpkg.ErrTooManyCollisions):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	logging.Errorf("❌ Failed to %s over gRPC: %v", doing, err)
+	return status.Error(codes.Internal, "failed to "+doing)
+}
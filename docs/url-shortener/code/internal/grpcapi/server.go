@@ -0,0 +1,238 @@
+// Package grpcapi serves the shortener over gRPC, next to the REST router in
+// internal/api. The service is defined in proto/shortener/v1.
+package grpcapi
+
+//go:generate protoc -I ../../proto --go_out=. --go_opt=module=urlshortener/internal/grpcapi --go-grpc_out=. --go-grpc_opt=module=urlshortener/internal/grpcapi shortener/v1/shortener.proto
+
+import (
+	"context"
+	"time"
+
+	pb "urlshortener/internal/grpcapi/shortenerv1"
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/apikey"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/signing"
+	"urlshortener/internal/services/storage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Page sizes for List, as for GET /api/links.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// Option configures optional server features.
+type Option func(*server)
+
+// WithAPIKeys authenticates calls with keys from mgr. When required is
+// false, calls without a key are still served anonymously.
+func WithAPIKeys(mgr *apikey.Manager, required bool) Option {
+	return func(s *server) {
+		s.keys = mgr
+		s.requireAPIKey = required
+	}
+}
+
+// WithAdmins lets the API keys whose IDs are in keyIDs see and delete every
+// key's links.
+func WithAdmins(keyIDs []string) Option {
+	return func(s *server) {
+		s.admins = make(map[string]struct{}, len(keyIDs))
+		for _, id := range keyIDs {
+			s.admins[id] = struct{}{}
+		}
+	}
+}
+
+type server struct {
+	pb.UnimplementedShortenerServiceServer
+
+	svc           *shortenerpkg.Shortener
+	keys          *apikey.Manager
+	requireAPIKey bool
+	admins        map[string]struct{}
+}
+
+// NewServer returns a gRPC server with ShortenerService registered; the
+// caller serves it on a listener of its choosing.
+func NewServer(shortsvc *shortenerpkg.Shortener, opts ...Option) *grpc.Server {
+	s := &server{svc: shortsvc}
+	for _, opt := range opts {
+		opt(s)
+	}
+	gs := grpc.NewServer(grpc.ChainUnaryInterceptor(s.authenticate))
+	pb.RegisterShortenerServiceServer(gs, s)
+	return gs
+}
+
+func (s *server) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	if req.GetUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, shortenerpkg.ErrEmptyURL.Error())
+	}
+	var expiresAt, activatesAt time.Time
+	if req.GetExpiresAt() != nil {
+		expiresAt = req.GetExpiresAt().AsTime()
+	}
+	if req.GetActivatesAt() != nil {
+		activatesAt = req.GetActivatesAt().AsTime()
+	}
+	resp, err := s.svc.Shorten(ctx, shortenerpkg.ShortenRequest{
+		URL:       req.GetUrl(),
+		Alias:     req.GetAlias(),
+		ExpiresAt: expiresAt,
+		CreatedBy: ownerFromContext(ctx),
+
+		ActivatesAt: activatesAt,
+		Title:       req.GetTitle(),
+		Description: req.GetDescription(),
+		Tags:        req.GetTags(),
+		Signed:      req.GetSigned(),
+	})
+	if err != nil {
+		return nil, statusError("shorten url", err)
+	}
+	entry, err := s.svc.Stats(ctx, resp.ShortCode)
+	if err != nil {
+		return nil, statusError("shorten url", err)
+	}
+
+	out := &pb.ShortenResponse{Link: linkProto(entry)}
+	if resp.Signed {
+		out.SignedPath = "/" + resp.ShortCode + "?" + resp.Signature.Query()
+		out.SignatureExpiresAt = timestamppb.New(resp.Signature.Expires)
+	}
+	return out, nil
+}
+
+func (s *server) Lookup(ctx context.Context, req *pb.LookupRequest) (*pb.LookupResponse, error) {
+	var (
+		entry storage.Entry
+		err   error
+	)
+	if req.GetSig() != "" {
+		sig := signing.Signature{Sig: req.GetSig()}
+		if req.GetExp() > 0 {
+			sig.Expires = time.Unix(req.GetExp(), 0).UTC()
+		}
+		entry, err = s.svc.LookupSigned(ctx, req.GetShortCode(), sig)
+	} else {
+		entry, err = s.svc.Lookup(ctx, req.GetShortCode())
+	}
+	if err != nil {
+		return nil, statusError("lookup short code", err)
+	}
+	return &pb.LookupResponse{OriginalUrl: entry.OriginalURL}, nil
+}
+
+func (s *server) Stats(ctx context.Context, req *pb.StatsRequest) (*pb.StatsResponse, error) {
+	entry, err := s.ownLink(ctx, req.GetShortCode())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.StatsResponse{Link: linkProto(entry), Expired: entry.Expired(s.svc.Now())}, nil
+}
+
+func (s *server) List(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	key, ok := apiKeyFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "api key required")
+	}
+	opts := storage.ListOptions{
+		CreatedBy: keyOwner(key),
+		Limit:     defaultPageSize,
+		Offset:    int(req.GetOffset()),
+		Query:     req.GetQuery(),
+		Dead:      req.GetDead(),
+	}
+	if s.isAdmin(key) {
+		opts.CreatedBy = req.GetCreatedBy()
+	}
+	if n := req.GetLimit(); n != 0 {
+		if n < 1 || n > maxPageSize {
+			return nil, status.Error(codes.InvalidArgument, "limit must be between 1 and 500")
+		}
+		opts.Limit = int(n)
+	}
+	if opts.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+	if raw := req.GetTag(); raw != "" {
+		tag, err := shortenerpkg.NormalizeTag(raw)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		opts.Tag = tag
+	}
+
+	entries, err := s.svc.List(ctx, opts)
+	if err != nil {
+		return nil, statusError("list links", err)
+	}
+	out := &pb.ListResponse{Links: make([]*pb.Link, len(entries))}
+	for i, e := range entries {
+		out.Links[i] = linkProto(e)
+	}
+	return out, nil
+}
+
+func (s *server) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	if _, err := s.ownLink(ctx, req.GetShortCode()); err != nil {
+		return nil, err
+	}
+	if err := s.svc.Delete(ctx, req.GetShortCode()); err != nil {
+		return nil, statusError("delete link", err)
+	}
+	logging.Infof("🗑️  Deleted %s over gRPC", req.GetShortCode())
+	return &pb.DeleteResponse{}, nil
+}
+
+// ownLink finds a link the caller's key may see; other keys' links are
+// NotFound, so they can't be probed.
+func (s *server) ownLink(ctx context.Context, code string) (storage.Entry, error) {
+	key, ok := apiKeyFromContext(ctx)
+	if !ok {
+		return storage.Entry{}, status.Error(codes.Unauthenticated, "api key required")
+	}
+	entry, err := s.svc.Stats(ctx, code)
+	if err == nil && entry.CreatedBy != keyOwner(key) && !s.isAdmin(key) {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		return storage.Entry{}, statusError("find link", err)
+	}
+	return entry, nil
+}
+
+func (s *server) isAdmin(key storage.APIKey) bool {
+	_, ok := s.admins[key.ID]
+	return ok
+}
+
+func linkProto(e storage.Entry) *pb.Link {
+	l := &pb.Link{
+		ShortCode:   e.ShortCode,
+		OriginalUrl: e.OriginalURL,
+		CreatedBy:   e.CreatedBy,
+		CreatedAt:   timestamppb.New(e.CreatedAt),
+		HitCount:    e.HitCount,
+		Title:       e.Title,
+		Description: e.Description,
+		Tags:        e.Tags,
+		Disabled:    e.Disabled,
+		Signed:      e.Signed,
+		Dead:        e.Dead(),
+	}
+	if !e.ExpiresAt.IsZero() {
+		l.ExpiresAt = timestamppb.New(e.ExpiresAt)
+	}
+	if !e.ActivatesAt.IsZero() {
+		l.ActivatesAt = timestamppb.New(e.ActivatesAt)
+	}
+	return l
+}
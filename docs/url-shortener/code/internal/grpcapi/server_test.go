@@ -0,0 +1,262 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	pb "urlshortener/internal/grpcapi/shortenerv1"
+	"urlshortener/internal/services/apikey"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/signing"
+	"urlshortener/internal/services/storage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type testEnv struct {
+	client     pb.ShortenerServiceClient
+	store      *storage.InMemoryStore
+	userToken  string
+	otherToken string
+	adminToken string
+}
+
+// newTestEnv serves a ShortenerService over an in-memory connection.
+func newTestEnv(t *testing.T, required bool) testEnv {
+	t.Helper()
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	keys := apikey.NewManager(store)
+	userToken, _, _ := keys.Create(ctx, "marketing")
+	otherToken, _, _ := keys.Create(ctx, "sales")
+	adminToken, admin, _ := keys.Create(ctx, "ops")
+
+	keyring := signing.NewKeyring([]signing.Key{{ID: "k1", Secret: "grpc-secret-0123456789"}}, time.Hour)
+	shortener := shortenerpkg.NewShortener(shortenerpkg.NewRandomCodeGenerator(6), store,
+		shortenerpkg.ShortenerSettings{CodeLength: 6, MaxRetries: 3}, shortenerpkg.WithKeyring(keyring))
+	gs := NewServer(shortener, WithAPIKeys(keys, required), WithAdmins([]string{admin.ID}))
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return testEnv{
+		client:     pb.NewShortenerServiceClient(conn),
+		store:      store,
+		userToken:  userToken,
+		otherToken: otherToken,
+		adminToken: adminToken,
+	}
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func wantCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Fatalf("expected %s, got %s (%v)", want, got, err)
+	}
+}
+
+func TestShortenLookupStats(t *testing.T) {
+	env := newTestEnv(t, false)
+	ctx := withToken(env.userToken)
+
+	resp, err := env.client.Shorten(ctx, &pb.ShortenRequest{
+		Url:   "https://example.com/q3",
+		Alias: "q3-page",
+		Title: "Q3 launch",
+		Tags:  []string{"Launch/Q3"},
+	})
+	if err != nil {
+		t.Fatalf("Shorten returned error: %v", err)
+	}
+	if l := resp.GetLink(); l.GetShortCode() != "q3-page" || l.GetCreatedBy() == "anonymous" || l.GetTags()[0] != "launch/q3" || l.GetExpiresAt() != nil {
+		t.Fatalf("unexpected link: %v", l)
+	}
+
+	lookup, err := env.client.Lookup(context.Background(), &pb.LookupRequest{ShortCode: "q3-page"})
+	if err != nil {
+		t.Fatalf("Lookup returned error: %v", err)
+	}
+	if lookup.GetOriginalUrl() != "https://example.com/q3" {
+		t.Fatalf("unexpected lookup: %v", lookup)
+	}
+
+	stats, err := env.client.Stats(ctx, &pb.StatsRequest{ShortCode: "q3-page"})
+	if err != nil {
+		t.Fatalf("Stats returned error: %v", err)
+	}
+	if stats.GetLink().GetHitCount() != 1 || stats.GetExpired() {
+		t.Fatalf("expected one hit from Lookup, got %v", stats)
+	}
+
+	anon, err := env.client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://example.com/anon"})
+	if err != nil {
+		t.Fatalf("anonymous Shorten returned error: %v", err)
+	}
+	if anon.GetLink().GetCreatedBy() != "anonymous" {
+		t.Fatalf("expected an anonymous owner, got %q", anon.GetLink().GetCreatedBy())
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	env := newTestEnv(t, false)
+	ctx := withToken(env.userToken)
+	_ = env.store.Save(context.Background(), storage.Entry{ShortCode: "old123", OriginalURL: "https://example.com", ExpiresAt: time.Now().Add(-time.Hour)})
+	_ = env.store.Save(context.Background(), storage.Entry{ShortCode: "bad123", OriginalURL: "https://example.com", Disabled: true})
+	if _, err := env.client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com", Alias: "taken"}); err != nil {
+		t.Fatalf("Shorten returned error: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		call func() error
+		want codes.Code
+	}{
+		"empty url": {func() error {
+			_, err := env.client.Shorten(ctx, &pb.ShortenRequest{})
+			return err
+		}, codes.InvalidArgument},
+		"bad url": {func() error {
+			_, err := env.client.Shorten(ctx, &pb.ShortenRequest{Url: "not a url"})
+			return err
+		}, codes.InvalidArgument},
+		"past expiry": {func() error {
+			_, err := env.client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com", ExpiresAt: timestamppb.New(time.Now().Add(-time.Hour))})
+			return err
+		}, codes.InvalidArgument},
+		"alias taken": {func() error {
+			_, err := env.client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com", Alias: "taken"})
+			return err
+		}, codes.AlreadyExists},
+		"missing": {func() error {
+			_, err := env.client.Lookup(ctx, &pb.LookupRequest{ShortCode: "nope404"})
+			return err
+		}, codes.NotFound},
+		"expired": {func() error {
+			_, err := env.client.Lookup(ctx, &pb.LookupRequest{ShortCode: "old123"})
+			return err
+		}, codes.FailedPrecondition},
+		"disabled": {func() error {
+			_, err := env.client.Lookup(ctx, &pb.LookupRequest{ShortCode: "bad123"})
+			return err
+		}, codes.FailedPrecondition},
+		"bad limit": {func() error {
+			_, err := env.client.List(ctx, &pb.ListRequest{Limit: 501})
+			return err
+		}, codes.InvalidArgument},
+		"bad tag": {func() error {
+			_, err := env.client.List(ctx, &pb.ListRequest{Tag: "no spaces"})
+			return err
+		}, codes.InvalidArgument},
+		"bad token": {func() error {
+			_, err := env.client.Lookup(withToken("usk_wrong"), &pb.LookupRequest{ShortCode: "taken"})
+			return err
+		}, codes.Unauthenticated},
+	} {
+		t.Run(name, func(t *testing.T) {
+			wantCode(t, tc.call(), tc.want)
+		})
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	env := newTestEnv(t, false)
+	user, other, admin := withToken(env.userToken), withToken(env.otherToken), withToken(env.adminToken)
+	for _, alias := range []string{"mine-1", "mine-2"} {
+		if _, err := env.client.Shorten(user, &pb.ShortenRequest{Url: "https://example.com/" + alias, Alias: alias}); err != nil {
+			t.Fatalf("Shorten returned error: %v", err)
+		}
+	}
+	if _, err := env.client.Shorten(other, &pb.ShortenRequest{Url: "https://example.com/theirs", Alias: "theirs"}); err != nil {
+		t.Fatalf("Shorten returned error: %v", err)
+	}
+
+	_, err := env.client.List(context.Background(), &pb.ListRequest{})
+	wantCode(t, err, codes.Unauthenticated)
+	_, err = env.client.Stats(context.Background(), &pb.StatsRequest{ShortCode: "mine-1"})
+	wantCode(t, err, codes.Unauthenticated)
+
+	list, err := env.client.List(user, &pb.ListRequest{CreatedBy: "anyone"})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if n := len(list.GetLinks()); n != 2 {
+		t.Fatalf("expected only the caller's 2 links, got %d", n)
+	}
+	list, err = env.client.List(admin, &pb.ListRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if n := len(list.GetLinks()); n != 3 {
+		t.Fatalf("expected admins to see all 3 links, got %d", n)
+	}
+
+	_, err = env.client.Stats(other, &pb.StatsRequest{ShortCode: "mine-1"})
+	wantCode(t, err, codes.NotFound)
+	_, err = env.client.Delete(other, &pb.DeleteRequest{ShortCode: "mine-1"})
+	wantCode(t, err, codes.NotFound)
+
+	if _, err := env.client.Delete(user, &pb.DeleteRequest{ShortCode: "mine-1"}); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, err := env.client.Delete(admin, &pb.DeleteRequest{ShortCode: "theirs"}); err != nil {
+		t.Fatalf("admin Delete returned error: %v", err)
+	}
+	_, err = env.client.Lookup(user, &pb.LookupRequest{ShortCode: "mine-1"})
+	wantCode(t, err, codes.NotFound)
+}
+
+func TestSignedLookup(t *testing.T) {
+	env := newTestEnv(t, true)
+	ctx := withToken(env.userToken)
+
+	resp, err := env.client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/contract.pdf", Alias: "contract", Signed: true})
+	if err != nil {
+		t.Fatalf("Shorten returned error: %v", err)
+	}
+	u, err := url.Parse(resp.GetSignedPath())
+	if err != nil || u.Path != "/contract" {
+		t.Fatalf("unexpected signed path %q", resp.GetSignedPath())
+	}
+	sig, ok := signing.FromQuery(u.Query())
+	if !ok || !sig.Expires.Equal(resp.GetSignatureExpiresAt().AsTime()) {
+		t.Fatalf("expected the signed path to carry the signature, got %q", resp.GetSignedPath())
+	}
+
+	_, err = env.client.Lookup(ctx, &pb.LookupRequest{ShortCode: "contract"})
+	wantCode(t, err, codes.NotFound)
+	_, err = env.client.Lookup(ctx, &pb.LookupRequest{ShortCode: "contract", Exp: sig.Expires.Unix(), Sig: "k1.forged"})
+	wantCode(t, err, codes.NotFound)
+	lookup, err := env.client.Lookup(ctx, &pb.LookupRequest{ShortCode: "contract", Exp: sig.Expires.Unix(), Sig: sig.Sig})
+	if err != nil {
+		t.Fatalf("signed Lookup returned error: %v", err)
+	}
+	if lookup.GetOriginalUrl() != "https://example.com/contract.pdf" {
+		t.Fatalf("unexpected lookup: %v", lookup)
+	}
+
+	// Keys are required here, so even Lookup needs one.
+	_, err = env.client.Lookup(context.Background(), &pb.LookupRequest{ShortCode: "contract"})
+	wantCode(t, err, codes.Unauthenticated)
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestLevelFiltering(t *testing.T) {
+	var buf bytes.Buffer
+	prevOutput, prevLevel := log.Writer(), CurrentLevel()
+	log.SetOutput(&buf)
+	defer log.SetOutput(prevOutput)
+	defer SetLevel(prevLevel)
+
+	SetLevel(LevelWarn)
+	Debugf("debug line")
+	Infof("info line")
+	Warnf("warn line")
+	Errorf("error line")
+
+	out := buf.String()
+	if strings.Contains(out, "debug line") || strings.Contains(out, "info line") {
+		t.Fatalf("expected debug and info to be filtered, got %q", out)
+	}
+	if !strings.Contains(out, "warn line") || !strings.Contains(out, "error line") {
+		t.Fatalf("expected warn and error lines, got %q", out)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]Level{"debug": LevelDebug, "": LevelInfo, "WARNING": LevelWarn, "error": LevelError}
+	for in, want := range tests {
+		got, err := ParseLevel(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseLevel(%q): expected %v, got %v (%v)", in, want, got, err)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Fatalf("expected error for unknown level")
+	}
+}
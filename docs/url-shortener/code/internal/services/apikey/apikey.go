@@ -0,0 +1,194 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"urlshortener/internal/clock"
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/storage"
+)
+
+var (
+	ErrEmptyName      = errors.New("api key name is required")
+	ErrInvalidKey     = errors.New("api key is invalid")
+	ErrRevokedKey     = errors.New("api key has been revoked")
+	ErrInvalidSession = errors.New("session is invalid or has expired")
+)
+
+// tokenPrefix makes leaked keys easy to spot in logs and secret scanners.
+const tokenPrefix = "usk_"
+
+// sessionPrefix marks session tokens, which are not API keys.
+const sessionPrefix = "uss_"
+
+// Manager issues, checks and revokes API keys. The plaintext token is only
+// ever returned by Create; the store keeps a SHA-256 hash of it.
+type Manager struct {
+	store storage.KeyStore
+	clock clock.Clock
+}
+
+// Option configures a Manager at construction.
+type Option func(*Manager)
+
+// WithClock makes the manager tell the time from c instead of the wall
+// clock, for creation and revocation times and session expiry.
+func WithClock(c clock.Clock) Option {
+	return func(m *Manager) { m.clock = c }
+}
+
+func NewManager(store storage.KeyStore, opts ...Option) *Manager {
+	m := &Manager{store: store, clock: clock.System}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Create issues a new key and returns its plaintext token alongside the
+// stored record.
+func (m *Manager) Create(ctx context.Context, name string) (string, storage.APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", storage.APIKey{}, ErrEmptyName
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return "", storage.APIKey{}, err
+	}
+	token, err := randomToken(tokenPrefix)
+	if err != nil {
+		return "", storage.APIKey{}, err
+	}
+
+	key := storage.APIKey{
+		ID:        id,
+		Name:      name,
+		Hash:      HashToken(token),
+		CreatedAt: m.clock.Now().UTC(),
+	}
+	if err := m.store.SaveAPIKey(ctx, key); err != nil {
+		return "", storage.APIKey{}, err
+	}
+	return token, key, nil
+}
+
+// Authenticate resolves a plaintext token to its key.
+func (m *Manager) Authenticate(ctx context.Context, token string) (storage.APIKey, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return storage.APIKey{}, ErrInvalidKey
+	}
+	key, err := m.store.FindAPIKeyByHash(ctx, HashToken(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return storage.APIKey{}, ErrInvalidKey
+		}
+		return storage.APIKey{}, err
+	}
+	if key.Revoked() {
+		return storage.APIKey{}, ErrRevokedKey
+	}
+	return key, nil
+}
+
+func (m *Manager) List(ctx context.Context) ([]storage.APIKey, error) {
+	return m.store.ListAPIKeys(ctx)
+}
+
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	return m.store.RevokeAPIKey(ctx, id, m.clock.Now().UTC())
+}
+
+// StartSession signs a browser in with an API key token. The returned
+// session token stands in for the key until ttl passes, the session is ended
+// or the key is revoked, so the key itself never has to be stored in a
+// cookie.
+func (m *Manager) StartSession(ctx context.Context, token string, ttl time.Duration) (string, storage.APIKey, error) {
+	key, err := m.Authenticate(ctx, token)
+	if err != nil {
+		return "", storage.APIKey{}, err
+	}
+	sessionToken, err := randomToken(sessionPrefix)
+	if err != nil {
+		return "", storage.APIKey{}, err
+	}
+	now := m.clock.Now().UTC()
+	// Sign-ins are rare, so they are a fine time to clear out old sessions.
+	if _, err := m.store.PurgeSessions(ctx, now); err != nil {
+		logging.Warnf("⚠️  Failed to purge expired sessions: %v", err)
+	}
+	err = m.store.SaveSession(ctx, storage.Session{
+		Hash:      HashToken(sessionToken),
+		KeyID:     key.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", storage.APIKey{}, err
+	}
+	return sessionToken, key, nil
+}
+
+// Session resolves a session token to the key it was started with.
+func (m *Manager) Session(ctx context.Context, sessionToken string) (storage.APIKey, error) {
+	if !strings.HasPrefix(sessionToken, sessionPrefix) {
+		return storage.APIKey{}, ErrInvalidSession
+	}
+	session, err := m.store.FindSession(ctx, HashToken(sessionToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return storage.APIKey{}, ErrInvalidSession
+		}
+		return storage.APIKey{}, err
+	}
+	if !session.ExpiresAt.After(m.clock.Now()) {
+		return storage.APIKey{}, ErrInvalidSession
+	}
+	key, err := m.store.FindAPIKey(ctx, session.KeyID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return storage.APIKey{}, ErrInvalidSession
+		}
+		return storage.APIKey{}, err
+	}
+	if key.Revoked() {
+		return storage.APIKey{}, ErrRevokedKey
+	}
+	return key, nil
+}
+
+// EndSession signs the session out; ending an unknown session is not an
+// error.
+func (m *Manager) EndSession(ctx context.Context, sessionToken string) error {
+	return m.store.DeleteSession(ctx, HashToken(sessionToken))
+}
+
+// HashToken returns the hex SHA-256 of a plaintext token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken returns prefix followed by 32 random bytes, base64url-encoded.
+func randomToken(prefix string) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,151 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"urlshortener/internal/clock/clocktest"
+	"urlshortener/internal/services/storage"
+)
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewManager(storage.NewInMemoryStore())
+
+	token, key, err := mgr.Create(ctx, "ci")
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if !strings.HasPrefix(token, tokenPrefix) {
+		t.Fatalf("expected token prefix %s, got %s", tokenPrefix, token)
+	}
+	if key.Hash == token || strings.Contains(key.Hash, token) {
+		t.Fatalf("expected only a hash of the token to be stored")
+	}
+
+	got, err := mgr.Authenticate(ctx, token)
+	if err != nil {
+		t.Fatalf("Authenticate returned error: %v", err)
+	}
+	if got.ID != key.ID || got.Name != "ci" {
+		t.Fatalf("unexpected key: %+v", got)
+	}
+}
+
+func TestCreateRequiresName(t *testing.T) {
+	mgr := NewManager(storage.NewInMemoryStore())
+
+	_, _, err := mgr.Create(context.Background(), "  ")
+	if !errors.Is(err, ErrEmptyName) {
+		t.Fatalf("expected %v, got %v", ErrEmptyName, err)
+	}
+}
+
+func TestAuthenticateInvalid(t *testing.T) {
+	mgr := NewManager(storage.NewInMemoryStore())
+
+	for _, token := range []string{"", "nope", tokenPrefix + "unknown"} {
+		_, err := mgr.Authenticate(context.Background(), token)
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("token %q: expected %v, got %v", token, ErrInvalidKey, err)
+		}
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewManager(storage.NewInMemoryStore())
+	token, key, _ := mgr.Create(ctx, "ci")
+
+	if err := mgr.Revoke(ctx, key.ID); err != nil {
+		t.Fatalf("Revoke returned error: %v", err)
+	}
+	_, err := mgr.Authenticate(ctx, token)
+	if !errors.Is(err, ErrRevokedKey) {
+		t.Fatalf("expected %v, got %v", ErrRevokedKey, err)
+	}
+
+	keys, err := mgr.List(ctx)
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(keys) != 1 || !keys[0].Revoked() {
+		t.Fatalf("expected one revoked key, got %+v", keys)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	mgr := NewManager(store)
+	token, key, _ := mgr.Create(ctx, "alice")
+
+	if _, _, err := mgr.StartSession(ctx, tokenPrefix+"unknown", time.Hour); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected %v for a bad key, got %v", ErrInvalidKey, err)
+	}
+	session, _, err := mgr.StartSession(ctx, token, time.Hour)
+	if err != nil {
+		t.Fatalf("StartSession returned error: %v", err)
+	}
+	if !strings.HasPrefix(session, sessionPrefix) || strings.Contains(session, token) {
+		t.Fatalf("expected an opaque session token, got %s", session)
+	}
+	got, err := mgr.Session(ctx, session)
+	if err != nil || got.ID != key.ID {
+		t.Fatalf("expected the session to resolve to %s, got %+v, %v", key.ID, got, err)
+	}
+	// The API key is not a session, and a session is not an API key.
+	if _, err := mgr.Session(ctx, token); !errors.Is(err, ErrInvalidSession) {
+		t.Fatalf("expected the API key to be refused as a session, got %v", err)
+	}
+	if _, err := mgr.Authenticate(ctx, session); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected the session to be refused as an API key, got %v", err)
+	}
+
+	if err := mgr.EndSession(ctx, session); err != nil {
+		t.Fatalf("EndSession returned error: %v", err)
+	}
+	if _, err := mgr.Session(ctx, session); !errors.Is(err, ErrInvalidSession) {
+		t.Fatalf("expected an ended session to be invalid, got %v", err)
+	}
+
+	expired, _, _ := mgr.StartSession(ctx, token, -time.Second)
+	if _, err := mgr.Session(ctx, expired); !errors.Is(err, ErrInvalidSession) {
+		t.Fatalf("expected an expired session to be invalid, got %v", err)
+	}
+	// The next sign-in clears it out.
+	revoked, _, _ := mgr.StartSession(ctx, token, time.Hour)
+	if _, err := store.FindSession(ctx, HashToken(expired)); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected the expired session to be purged, got %v", err)
+	}
+
+	_ = mgr.Revoke(ctx, key.ID)
+	if _, err := mgr.Session(ctx, revoked); !errors.Is(err, ErrRevokedKey) {
+		t.Fatalf("expected revoking the key to end its sessions, got %v", err)
+	}
+}
+
+func TestSessionExpiresByClock(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktest.New(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC))
+	mgr := NewManager(storage.NewInMemoryStore(), WithClock(clk))
+	token, key, _ := mgr.Create(ctx, "alice")
+	if !key.CreatedAt.Equal(clk.Now()) {
+		t.Fatalf("expected the key to be created at the clock's time, got %s", key.CreatedAt)
+	}
+
+	session, _, err := mgr.StartSession(ctx, token, time.Hour)
+	if err != nil {
+		t.Fatalf("StartSession returned error: %v", err)
+	}
+	clk.Advance(59 * time.Minute)
+	if _, err := mgr.Session(ctx, session); err != nil {
+		t.Fatalf("expected the session to last its ttl, got %v", err)
+	}
+	clk.Advance(time.Minute)
+	if _, err := mgr.Session(ctx, session); !errors.Is(err, ErrInvalidSession) {
+		t.Fatalf("expected the session to expire after its ttl, got %v", err)
+	}
+}
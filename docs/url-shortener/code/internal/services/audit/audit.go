@@ -0,0 +1,171 @@
+// Package audit keeps an append-only trail of link changes: who changed which
+// link, when, in which request, and what it looked like before and after.
+//
+// Events go to any storage.AuditStore: the storage backend's own audit table,
+// or a FileSink that appends NDJSON to a file.
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"urlshortener/internal/clock"
+	"urlshortener/internal/services/storage"
+)
+
+// Actions recorded by the shortener.
+const (
+	ActionCreate   = "create"
+	ActionRetarget = "retarget"
+	ActionDelete   = "delete"
+	ActionDisable  = "disable"
+	ActionEnable   = "enable"
+	ActionImport   = "import"
+	ActionEdit     = "edit" // title, description or tags changed
+	ActionRollback = "rollback"
+)
+
+// Actions lists every action, for validating filters.
+var Actions = []string{ActionCreate, ActionRetarget, ActionDelete, ActionDisable, ActionEnable, ActionImport, ActionEdit, ActionRollback}
+
+// unknownActor is recorded when nothing put an actor on the context.
+const unknownActor = "anonymous"
+
+// Link is the part of a storage.Entry the trail records. Hit counts are left
+// out: they change on every redirect and are not a setting anyone edits.
+type Link struct {
+	OriginalURL    string    `json:"original_url"`
+	CreatedBy      string    `json:"created_by,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at,omitzero"`
+	ActivatesAt    time.Time `json:"activates_at,omitzero"`
+	Signed         bool      `json:"signed,omitempty"`
+	Disabled       bool      `json:"disabled,omitempty"`
+	DisabledReason string    `json:"disabled_reason,omitempty"`
+	Title          string    `json:"title,omitempty"`
+	Description    string    `json:"description,omitempty"`
+	Tags           []string  `json:"tags,omitempty"`
+}
+
+func snapshot(entry *storage.Entry) (json.RawMessage, error) {
+	if entry == nil {
+		return nil, nil
+	}
+	return json.Marshal(Link{
+		OriginalURL:    entry.OriginalURL,
+		CreatedBy:      entry.CreatedBy,
+		ExpiresAt:      entry.ExpiresAt,
+		ActivatesAt:    entry.ActivatesAt,
+		Signed:         entry.Signed,
+		Disabled:       entry.Disabled,
+		DisabledReason: entry.DisabledReason,
+		Title:          entry.Title,
+		Description:    entry.Description,
+		Tags:           entry.Tags,
+	})
+}
+
+type contextKey int
+
+const (
+	actorKey contextKey = iota
+	requestIDKey
+)
+
+// WithActor names who is making the changes done with ctx, e.g. "key:<id>".
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// Actor returns the actor set by WithActor, or "anonymous".
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey).(string); ok && actor != "" {
+		return actor
+	}
+	return unknownActor
+}
+
+// WithRequestID ties the changes done with ctx to one request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the ID set by WithRequestID, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Option configures a Log or FileSink at construction.
+type Option func(*options)
+
+type options struct {
+	clock clock.Clock
+}
+
+func newOptions(opts []Option) options {
+	o := options{clock: clock.System}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithClock stamps events with the time from c instead of the wall clock.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) { o.clock = c }
+}
+
+// Log records link changes to a sink.
+type Log struct {
+	sink  storage.AuditStore
+	clock clock.Clock
+}
+
+func New(sink storage.AuditStore, opts ...Option) *Log {
+	return &Log{sink: sink, clock: newOptions(opts).clock}
+}
+
+// Record appends one event. The actor and request ID come from ctx; before
+// is nil for creations and after is nil for deletions.
+func (l *Log) Record(ctx context.Context, action, shortCode string, before, after *storage.Entry) error {
+	event := storage.AuditEvent{
+		At:        l.clock.Now().UTC(),
+		Actor:     Actor(ctx),
+		Action:    action,
+		ShortCode: shortCode,
+		RequestID: RequestID(ctx),
+	}
+	var err error
+	if event.Before, err = snapshot(before); err != nil {
+		return err
+	}
+	if event.After, err = snapshot(after); err != nil {
+		return err
+	}
+	if event.ID, err = newEventID(event.At); err != nil {
+		return err
+	}
+	if err := l.sink.AppendAudit(ctx, event); err != nil {
+		return fmt.Errorf("audit %s %s: %w", action, shortCode, err)
+	}
+	return nil
+}
+
+// Query lists recorded events, newest first.
+func (l *Log) Query(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditEvent, error) {
+	return l.sink.ListAudit(ctx, filter)
+}
+
+// newEventID is the time in hex followed by random bits, so IDs sort in
+// recording order and stay unique across processes.
+func newEventID(at time.Time) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%016x%s", at.UnixNano(), hex.EncodeToString(b)), nil
+}
@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"urlshortener/internal/clock/clocktest"
+	"urlshortener/internal/services/storage"
+	"urlshortener/internal/services/storage/storagetest"
+)
+
+func TestFileSink(t *testing.T) {
+	storagetest.RunAuditStore(t, func(t *testing.T) storage.AuditStore {
+		sink, err := OpenFile(filepath.Join(t.TempDir(), "audit.ndjson"))
+		if err != nil {
+			t.Fatalf("OpenFile returned error: %v", err)
+		}
+		t.Cleanup(func() { _ = sink.Close() })
+		return sink
+	})
+}
+
+func TestFileSinkAppendsAcrossReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+
+	for _, id := range []string{"a", "b"} {
+		sink, err := OpenFile(path)
+		if err != nil {
+			t.Fatalf("OpenFile returned error: %v", err)
+		}
+		if err := sink.AppendAudit(ctx, storage.AuditEvent{ID: id, Actor: "cli:ops", Action: ActionDelete, ShortCode: "abc123"}); err != nil {
+			t.Fatalf("AppendAudit returned error: %v", err)
+		}
+		_ = sink.Close()
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read audit file: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(raw)), "\n"); len(lines) != 2 {
+		t.Fatalf("expected two ndjson lines, got %q", raw)
+	}
+
+	sink, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile returned error: %v", err)
+	}
+	defer sink.Close()
+	if err := sink.AppendAudit(ctx, storage.AuditEvent{ID: "a"}); err == nil {
+		t.Fatalf("expected a reused ID from an earlier run to be rejected")
+	}
+}
+
+func TestRecord(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	log := New(store)
+
+	ctx := WithRequestID(WithActor(context.Background(), "key:k1"), "req-42")
+	before := &storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com/old", CreatedBy: "key:k1", HitCount: 7}
+	after := &storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com/new", CreatedBy: "key:k1", HitCount: 7}
+	if err := log.Record(ctx, ActionRetarget, "abc123", before, after); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if err := log.Record(context.Background(), ActionDelete, "abc123", after, nil); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+
+	events, err := log.Query(context.Background(), storage.AuditFilter{ShortCode: "abc123"})
+	if err != nil {
+		t.Fatalf("Query returned error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+
+	deleted, retargeted := events[0], events[1]
+	if deleted.Action != ActionDelete || deleted.Actor != "anonymous" || deleted.After != nil {
+		t.Fatalf("unexpected delete event: %+v", deleted)
+	}
+	if retargeted.Actor != "key:k1" || retargeted.RequestID != "req-42" {
+		t.Fatalf("expected actor and request ID from the context, got %+v", retargeted)
+	}
+	if retargeted.ID >= deleted.ID {
+		t.Fatalf("expected IDs in recording order, got %s then %s", retargeted.ID, deleted.ID)
+	}
+	if time.Since(retargeted.At) > time.Minute {
+		t.Fatalf("unexpected event time %v", retargeted.At)
+	}
+
+	var was, now Link
+	if err := json.Unmarshal(retargeted.Before, &was); err != nil {
+		t.Fatalf("invalid before state: %v", err)
+	}
+	if err := json.Unmarshal(retargeted.After, &now); err != nil {
+		t.Fatalf("invalid after state: %v", err)
+	}
+	if was.OriginalURL != "https://example.com/old" || now.OriginalURL != "https://example.com/new" {
+		t.Fatalf("unexpected before/after: %+v %+v", was, now)
+	}
+	if strings.Contains(string(retargeted.After), "hit") {
+		t.Fatalf("expected hit counts to stay out of the trail, got %s", retargeted.After)
+	}
+}
+
+func TestClock(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktest.New(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC))
+	sink, err := OpenFile(filepath.Join(t.TempDir(), "audit.ndjson"), WithClock(clk))
+	if err != nil {
+		t.Fatalf("OpenFile returned error: %v", err)
+	}
+	defer sink.Close()
+
+	if err := New(sink, WithClock(clk)).Record(ctx, ActionCreate, "abc123", nil, &storage.Entry{ShortCode: "abc123"}); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	clk.Advance(time.Hour)
+	if err := sink.AppendAudit(ctx, storage.AuditEvent{ID: "bare", Action: ActionDelete, ShortCode: "abc123"}); err != nil {
+		t.Fatalf("AppendAudit returned error: %v", err)
+	}
+
+	events, err := sink.ListAudit(ctx, storage.AuditFilter{})
+	if err != nil {
+		t.Fatalf("ListAudit returned error: %v", err)
+	}
+	if len(events) != 2 || !events[0].At.Equal(clk.Now()) || !events[1].At.Equal(clk.Now().Add(-time.Hour)) {
+		t.Fatalf("expected events stamped by the clock, got %+v", events)
+	}
+}
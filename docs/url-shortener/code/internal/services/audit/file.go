@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"urlshortener/internal/clock"
+	"urlshortener/internal/services/storage"
+)
+
+// maxLine bounds one NDJSON line when reading the file back.
+const maxLine = 1 << 20
+
+// fileEvent is the NDJSON shape of a storage.AuditEvent.
+type fileEvent struct {
+	ID        string          `json:"id"`
+	At        time.Time       `json:"at"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	ShortCode string          `json:"short_code"`
+	RequestID string          `json:"request_id,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+}
+
+// FileSink is a storage.AuditStore that appends one JSON event per line to a
+// file. The file is only ever appended to, so it can be shipped or rotated by
+// external tools; queries read the whole file back.
+type FileSink struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	ids   map[string]struct{}
+	clock clock.Clock
+}
+
+// OpenFile opens (or creates) the NDJSON file at path.
+func OpenFile(path string, opts ...Option) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit file: %w", err)
+	}
+	sink := &FileSink{path: path, file: file, ids: map[string]struct{}{}, clock: newOptions(opts).clock}
+	events, err := sink.read()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	for _, e := range events {
+		sink.ids[e.ID] = struct{}{}
+	}
+	return sink, nil
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+func (s *FileSink) AppendAudit(ctx context.Context, event storage.AuditEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if event.At.IsZero() {
+		event.At = s.clock.Now().UTC()
+	}
+	line, err := json.Marshal(fileEvent(event))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.ids[event.ID]; ok {
+		return storage.ErrConflict
+	}
+	// One write per line keeps lines whole even with other appenders.
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	s.ids[event.ID] = struct{}{}
+	return nil
+}
+
+func (s *FileSink) ListAudit(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	events, err := s.read()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return storage.ApplyAuditFilter(events, filter), nil
+}
+
+func (s *FileSink) read() ([]storage.AuditEvent, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("read audit file: %w", err)
+	}
+	defer file.Close()
+
+	var events []storage.AuditEvent
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), maxLine)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e fileEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("audit file line %d: %w", line, err)
+		}
+		events = append(events, storage.AuditEvent(e))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read audit file: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,325 @@
+// Package health checks that link destinations still respond and records the
+// outcome on each link, so dead links can be found and flagged.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"urlshortener/internal/clock"
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/storage"
+)
+
+// Defaults for the zero values in Options.
+const (
+	defaultInterval     = 24 * time.Hour
+	defaultPollInterval = time.Minute
+	defaultConcurrency  = 8
+	defaultHostDelay    = time.Second
+	defaultTimeout      = 10 * time.Second
+)
+
+// batchSize bounds how many links one RunOnce checks.
+const batchSize = 100
+
+// ErrPrivateAddress is the check error for destinations that resolve to a
+// loopback, private, link-local or otherwise non-public address.
+var ErrPrivateAddress = errors.New("destination address is not public")
+
+// userAgent identifies the checker to destination servers.
+const userAgent = "urlshortener-health (+link checker)"
+
+type Options struct {
+	Interval     time.Duration // how long a result stays fresh; default 24h
+	PollInterval time.Duration // how often Run looks for links due a check; default 1m
+	Concurrency  int           // checks in flight at once; default 8
+	HostDelay    time.Duration // minimum gap between requests to one host; default 1s
+	Timeout      time.Duration // per request; default 10s
+	Client       *http.Client  // default is built from Timeout and AllowPrivate
+	// AllowPrivate lets the default client reach loopback, private and
+	// link-local addresses. Without it, a link cannot make the checker probe
+	// the internal network or a cloud metadata endpoint.
+	AllowPrivate bool
+	Clock        clock.Clock // default clock.System
+}
+
+// Checker periodically requests link destinations and records how they
+// responded.
+type Checker struct {
+	store    storage.HealthStore
+	client   *http.Client
+	interval time.Duration
+	poll     time.Duration
+	sem      chan struct{}
+	gate     *hostGate
+	clock    clock.Clock
+}
+
+func New(store storage.HealthStore, opts Options) *Checker {
+	c := &Checker{
+		store:    store,
+		client:   opts.Client,
+		interval: opts.Interval,
+		poll:     opts.PollInterval,
+		clock:    opts.Clock,
+	}
+	if c.interval <= 0 {
+		c.interval = defaultInterval
+	}
+	if c.poll <= 0 {
+		c.poll = defaultPollInterval
+	}
+	if c.clock == nil {
+		c.clock = clock.System
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	c.sem = make(chan struct{}, concurrency)
+	delay := opts.HostDelay
+	if delay <= 0 {
+		delay = defaultHostDelay
+	}
+	c.gate = newHostGate(delay, c.clock)
+	if c.client == nil {
+		timeout := opts.Timeout
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		c.client = newClient(timeout, opts.AllowPrivate)
+	}
+	return c
+}
+
+// newClient builds the default client. Unless allowPrivate is set, every
+// connection it makes, redirects included, is refused when the resolved
+// address is not public.
+func newClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = refusePrivate
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialed in place of the destination, hiding it from
+	// the check.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// refusePrivate is a net.Dialer Control hook. It runs after DNS resolution,
+// so a public hostname pointing at a private address is caught too.
+func refusePrivate(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	// IsGlobalUnicast rules out loopback, link-local (169.254.169.254
+	// among them), multicast and unspecified addresses.
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, ip)
+	}
+	return nil
+}
+
+// Run checks links as they fall due until ctx is done.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.poll)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := c.RunOnce(ctx)
+			if err != nil {
+				logging.Errorf("❌ Failed to check link destinations: %v", err)
+			}
+			// A full batch means more may be waiting.
+			if err != nil || n < batchSize || ctx.Err() != nil {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce checks the links whose last result is older than Interval and
+// reports how many it checked. Each host's links are checked one after
+// another, HostDelay apart; different hosts are checked in parallel.
+func (c *Checker) RunOnce(ctx context.Context) (int, error) {
+	due, err := c.store.DueForCheck(ctx, c.clock.Now().UTC().Add(-c.interval), batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	byHost := make(map[string][]storage.Entry)
+	for _, e := range due {
+		host := hostOf(e.OriginalURL)
+		byHost[host] = append(byHost[host], e)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for host, entries := range byHost {
+		wg.Go(func() {
+			for _, e := range entries {
+				if err := c.gate.wait(ctx, host); err != nil {
+					return
+				}
+				c.sem <- struct{}{}
+				check := c.Check(ctx, e.OriginalURL)
+				<-c.sem
+				if ctx.Err() != nil {
+					return // a cancelled check says nothing about the link
+				}
+				if err := c.record(ctx, e, check); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		})
+	}
+	wg.Wait()
+	return len(due), firstErr
+}
+
+func (c *Checker) record(ctx context.Context, e storage.Entry, check storage.LinkCheck) error {
+	err := c.store.RecordCheck(ctx, e.ShortCode, check)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil // deleted while it was being checked
+	}
+	if err != nil {
+		return err
+	}
+	was := e.Dead()
+	e.CheckedAt, e.CheckStatus, e.CheckError = check.At, check.Status, check.Error
+	switch {
+	case e.Dead() && !was:
+		logging.Warnf("🩺 %s looks dead: %s", e.ShortCode, describe(check))
+	case !e.Dead() && was:
+		logging.Infof("🩺 %s is reachable again", e.ShortCode)
+	default:
+		logging.Debugf("🩺 Checked %s: %s", e.ShortCode, describe(check))
+	}
+	return nil
+}
+
+func describe(check storage.LinkCheck) string {
+	if check.Error != "" {
+		return check.Error
+	}
+	return fmt.Sprintf("status %d", check.Status)
+}
+
+// Check requests rawURL and reports how it responded, following redirects.
+// It tries HEAD first and falls back to GET when HEAD gets an error status,
+// since some servers do not support HEAD.
+func (c *Checker) Check(ctx context.Context, rawURL string) storage.LinkCheck {
+	status, err := c.request(ctx, http.MethodHead, rawURL)
+	if err == nil && status >= 400 {
+		if err := c.gate.wait(ctx, hostOf(rawURL)); err != nil {
+			return storage.LinkCheck{At: c.clock.Now().UTC(), Error: err.Error()}
+		}
+		status, err = c.request(ctx, http.MethodGet, rawURL)
+	}
+	check := storage.LinkCheck{At: c.clock.Now().UTC(), Status: status}
+	if err != nil {
+		check.Status = 0
+		check.Error = err.Error()
+	}
+	return check
+}
+
+func (c *Checker) request(ctx context.Context, method, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little so the connection can be reused; the rest is dropped.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	return resp.StatusCode, nil
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// hostGate spaces requests to each host at least delay apart, across batches.
+type hostGate struct {
+	delay time.Duration
+	clock clock.Clock
+	mu    sync.Mutex
+	next  map[string]time.Time // earliest time of the next request per host
+}
+
+func newHostGate(delay time.Duration, c clock.Clock) *hostGate {
+	return &hostGate{delay: delay, clock: c, next: make(map[string]time.Time)}
+}
+
+// wait blocks until a request to host is allowed and books the slot.
+func (g *hostGate) wait(ctx context.Context, host string) error {
+	g.mu.Lock()
+	now := g.clock.Now()
+	at := g.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	g.next[host] = at.Add(g.delay)
+	// Forget hosts whose slots have passed so the map stays small.
+	if len(g.next) > 1024 {
+		for h, t := range g.next {
+			if t.Before(now) {
+				delete(g.next, h)
+			}
+		}
+	}
+	g.mu.Unlock()
+
+	wait := at.Sub(now)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,276 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"urlshortener/internal/clock/clocktest"
+	"urlshortener/internal/services/storage"
+)
+
+// routeTo sends every request, whatever its host, to srv, so tests can use
+// many hostnames offline.
+func routeTo(srv *httptest.Server, timeout time.Duration) *http.Client {
+	addr := srv.Listener.Addr().String()
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		},
+	}
+}
+
+func save(t *testing.T, store storage.Store, code, url string) {
+	t.Helper()
+	if err := store.Save(context.Background(), storage.Entry{ShortCode: code, OriginalURL: url}); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+}
+
+func TestRunOnceRecordsResults(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) { http.NotFound(w, r) })
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/gone", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	for _, code := range []string{"ok", "gone", "no-head", "moved", "slow"} {
+		save(t, store, code, srv.URL+"/"+code)
+	}
+	save(t, store, "down", closed.URL)
+	save(t, store, "taken", srv.URL+"/ok")
+	_ = store.Update(ctx, storage.Entry{ShortCode: "taken", OriginalURL: srv.URL + "/ok", Disabled: true})
+
+	checker := New(store, Options{HostDelay: time.Millisecond, Timeout: 100 * time.Millisecond, AllowPrivate: true})
+	n, err := checker.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("RunOnce returned error: %v", err)
+	}
+	if n != 6 {
+		t.Fatalf("expected 6 links checked, got %d", n)
+	}
+
+	want := map[string]struct {
+		status int
+		dead   bool
+	}{
+		"ok":      {200, false},
+		"gone":    {404, true},
+		"no-head": {200, false},
+		"moved":   {404, true},
+		"slow":    {0, true},
+		"down":    {0, true},
+	}
+	for code, w := range want {
+		e, _ := store.Find(ctx, code)
+		if e.CheckedAt.IsZero() || e.CheckStatus != w.status || e.Dead() != w.dead {
+			t.Fatalf("%s: expected status %d dead=%t, got %+v", code, w.status, w.dead, e)
+		}
+		if w.status == 0 && e.CheckError == "" {
+			t.Fatalf("%s: expected the failure to be explained, got %+v", code, e)
+		}
+	}
+	if e, _ := store.Find(ctx, "taken"); !e.CheckedAt.IsZero() {
+		t.Fatalf("expected disabled links to be skipped, got %+v", e)
+	}
+
+	// Results stay fresh for Interval.
+	if n, _ := checker.RunOnce(ctx); n != 0 {
+		t.Fatalf("expected nothing due right after a run, got %d", n)
+	}
+	dead, _ := store.List(ctx, storage.ListOptions{Dead: true})
+	if len(dead) != 4 {
+		t.Fatalf("expected 4 dead links, got %+v", dead)
+	}
+}
+
+func TestRunOnceRechecksAfterInterval(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	save(t, store, "abc123", srv.URL)
+	clk := clocktest.New(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC))
+	checker := New(store, Options{Interval: time.Hour, HostDelay: time.Millisecond, AllowPrivate: true, Clock: clk})
+
+	if n, err := checker.RunOnce(ctx); n != 1 || err != nil {
+		t.Fatalf("RunOnce returned %d, %v", n, err)
+	}
+	if e, _ := store.Find(ctx, "abc123"); !e.CheckedAt.Equal(clk.Now()) {
+		t.Fatalf("expected the check to be stamped with the clock's time, got %+v", e)
+	}
+	clk.Advance(59 * time.Minute)
+	if n, _ := checker.RunOnce(ctx); n != 0 {
+		t.Fatalf("expected the result to stay fresh for Interval, got %d checked", n)
+	}
+	clk.Advance(2 * time.Minute)
+	if n, _ := checker.RunOnce(ctx); n != 1 {
+		t.Fatalf("expected a recheck once Interval passed, got %d checked", n)
+	}
+}
+
+func TestHostDelay(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times = map[string][]time.Time{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times[r.Host] = append(times[r.Host], time.Now())
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	store := storage.NewInMemoryStore()
+	for _, code := range []string{"a1", "a2", "a3"} {
+		save(t, store, code, "http://a.test/"+code)
+	}
+	save(t, store, "b1", "http://b.test/b1")
+
+	const delay = 50 * time.Millisecond
+	checker := New(store, Options{HostDelay: delay, Client: routeTo(srv, time.Second)})
+	start := time.Now()
+	if _, err := checker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce returned error: %v", err)
+	}
+
+	a := times["a.test"]
+	if len(a) != 3 {
+		t.Fatalf("expected 3 requests to a.test, got %d", len(a))
+	}
+	// Slots are booked delay apart from the first, and a request never leaves
+	// before its slot. Jitter can delay any one of them, so gaps between two
+	// arrivals prove nothing; the time since the start does.
+	for i, at := range a {
+		if since := at.Sub(start); since < time.Duration(i)*delay {
+			t.Fatalf("request %d to a.test arrived %s after the start, want at least %s", i+1, since, time.Duration(i)*delay)
+		}
+	}
+	if b := times["b.test"]; len(b) != 1 || !b[0].Before(a[len(a)-1]) {
+		t.Fatalf("expected b.test to be checked without waiting for a.test, got %v", b)
+	}
+}
+
+func TestConcurrencyBound(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	store := storage.NewInMemoryStore()
+	for _, host := range []string{"a", "b", "c", "d", "e", "f"} {
+		save(t, store, host, "http://"+host+".test/")
+	}
+	checker := New(store, Options{Concurrency: 2, Client: routeTo(srv, time.Second)})
+	if n, err := checker.RunOnce(context.Background()); err != nil || n != 6 {
+		t.Fatalf("RunOnce returned %d, %v", n, err)
+	}
+	if p := peak.Load(); p != 2 {
+		t.Fatalf("expected at most 2 checks at once and some overlap, peak was %d", p)
+	}
+}
+
+func TestRunOnceCancelledRecordsNothing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	store := storage.NewInMemoryStore()
+	save(t, store, "abc123", srv.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := New(store, Options{AllowPrivate: true}).RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce returned error: %v", err)
+	}
+	if e, _ := store.Find(context.Background(), "abc123"); !e.CheckedAt.IsZero() {
+		t.Fatalf("expected a cancelled check not to be recorded, got %+v", e)
+	}
+}
+
+func TestCheckUserAgent(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.UserAgent()
+	}))
+	defer srv.Close()
+
+	check := New(storage.NewInMemoryStore(), Options{AllowPrivate: true}).Check(context.Background(), srv.URL)
+	if check.Status != http.StatusOK || !strings.HasPrefix(got, "urlshortener-health") {
+		t.Fatalf("unexpected check %+v with user agent %q", check, got)
+	}
+}
+
+func TestCheckRefusesPrivateAddresses(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	check := New(storage.NewInMemoryStore(), Options{}).Check(context.Background(), srv.URL)
+	if check.Status != 0 || !strings.Contains(check.Error, ErrPrivateAddress.Error()) {
+		t.Fatalf("expected %s to be refused, got %+v", srv.URL, check)
+	}
+	if n := hits.Load(); n != 0 {
+		t.Fatalf("expected no request to reach the server, got %d", n)
+	}
+}
+
+func TestRefusePrivate(t *testing.T) {
+	for addr, refused := range map[string]bool{
+		"127.0.0.1:80":          true,
+		"10.1.2.3:443":          true,
+		"192.168.0.10:80":       true,
+		"169.254.169.254:80":    true,
+		"0.0.0.0:80":            true,
+		"[::1]:80":              true,
+		"[fd00::1]:80":          true,
+		"[::ffff:127.0.0.1]:80": true,
+		"93.184.216.34:443":     false,
+		"[2606:4700::1111]:443": false,
+	} {
+		err := refusePrivate("tcp", addr, nil)
+		if got := errors.Is(err, ErrPrivateAddress); got != refused {
+			t.Fatalf("%s: expected refused=%t, got %v", addr, refused, err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Package idempotency makes retries safe: the first response to a request
+// sent with an idempotency key is kept, and a repeat of the request gets that
+// response back instead of running again.
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"urlshortener/internal/clock"
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/storage"
+)
+
+// Defaults for the zero values in Options.
+const (
+	defaultTTL           = 24 * time.Hour
+	defaultLockTimeout   = time.Minute
+	defaultPurgeInterval = time.Hour
+)
+
+// maxKeyLen bounds idempotency keys; a UUID is 36 characters.
+const maxKeyLen = 255
+
+var (
+	ErrInvalidKey = errors.New("idempotency key must be 1-255 printable ASCII characters")
+	// ErrKeyReused means the key was first sent with a different request.
+	ErrKeyReused = errors.New("idempotency key was already used for a different request")
+	// ErrInProgress means the first request with the key has not finished.
+	ErrInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+type Options struct {
+	TTL time.Duration // how long a response is replayed; default 24h
+	// LockTimeout is how long a request may hold its key before a retry
+	// takes over, in case the server died serving it; default 1m.
+	LockTimeout   time.Duration
+	PurgeInterval time.Duration // how often Run deletes expired records; default 1h
+	Clock         clock.Clock   // default clock.System
+}
+
+// Service claims idempotency keys and keeps the responses to them.
+type Service struct {
+	store storage.IdempotencyStore
+	ttl   time.Duration
+	lock  time.Duration
+	purge time.Duration
+	clock clock.Clock
+}
+
+func New(store storage.IdempotencyStore, opts Options) *Service {
+	s := &Service{
+		store: store,
+		ttl:   opts.TTL,
+		lock:  opts.LockTimeout,
+		purge: opts.PurgeInterval,
+		clock: opts.Clock,
+	}
+	if s.ttl <= 0 {
+		s.ttl = defaultTTL
+	}
+	if s.lock <= 0 {
+		s.lock = defaultLockTimeout
+	}
+	if s.purge <= 0 {
+		s.purge = defaultPurgeInterval
+	}
+	if s.clock == nil {
+		s.clock = clock.System
+	}
+	return s
+}
+
+// Fingerprint hashes what has to match for a request to count as a repeat.
+func Fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin claims key for owner's request with the given fingerprint. For a
+// repeat of a finished request it returns the completed record, whose
+// response the caller sends again. Otherwise the request is new: the record
+// has no response, and the caller must Complete or Release it once the
+// request is served.
+func (s *Service) Begin(ctx context.Context, owner, key, fingerprint string) (storage.IdempotencyRecord, error) {
+	if !validKey(key) {
+		return storage.IdempotencyRecord{}, ErrInvalidKey
+	}
+	now := s.clock.Now().UTC()
+	rec := storage.IdempotencyRecord{
+		Owner:       owner,
+		Key:         key,
+		RequestHash: fingerprint,
+		Claim:       rand.Text(),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.lock),
+	}
+	// The record can be released between a failed reserve and the lookup,
+	// so try a second time before giving up.
+	for range 2 {
+		err := s.store.ReserveIdempotencyKey(ctx, rec)
+		if err == nil {
+			return rec, nil
+		}
+		if !errors.Is(err, storage.ErrConflict) {
+			return storage.IdempotencyRecord{}, err
+		}
+
+		old, err := s.store.FindIdempotencyKey(ctx, owner, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return storage.IdempotencyRecord{}, err
+		}
+		if old.RequestHash != fingerprint {
+			return storage.IdempotencyRecord{}, ErrKeyReused
+		}
+		if !old.Completed() {
+			return storage.IdempotencyRecord{}, ErrInProgress
+		}
+		return old, nil
+	}
+	return storage.IdempotencyRecord{}, ErrInProgress
+}
+
+// Complete keeps the response to the request rec was claimed for, to be
+// replayed for TTL. It is storage.ErrNotFound if a retry has taken the key
+// over since.
+func (s *Service) Complete(ctx context.Context, rec storage.IdempotencyRecord, status int, contentType string, body []byte) error {
+	rec.Status = status
+	rec.ContentType = contentType
+	rec.Body = body
+	rec.ExpiresAt = s.clock.Now().UTC().Add(s.ttl)
+	return s.store.CompleteIdempotencyKey(ctx, rec)
+}
+
+// Release gives up the claim on rec's key without keeping a response, so a
+// retry runs the request again. A claim a retry has taken over is left alone.
+func (s *Service) Release(ctx context.Context, rec storage.IdempotencyRecord) error {
+	return s.store.ReleaseIdempotencyKey(ctx, rec.Owner, rec.Key, rec.Claim)
+}
+
+// Run deletes expired records every PurgeInterval until ctx is done.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.purge)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n, err := s.store.PurgeIdempotencyKeys(ctx, s.clock.Now().UTC())
+		if err != nil {
+			logging.Errorf("❌ Failed to purge idempotency keys: %v", err)
+			continue
+		}
+		if n > 0 {
+			logging.Debugf("🧹 Purged %d expired idempotency keys", n)
+		}
+	}
+}
+
+func validKey(key string) bool {
+	if key == "" || len(key) > maxKeyLen {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"urlshortener/internal/clock/clocktest"
+	"urlshortener/internal/services/storage"
+)
+
+func newTestService() (*Service, *clocktest.Clock) {
+	clk := clocktest.New(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC))
+	return New(storage.NewInMemoryStore(), Options{TTL: time.Hour, LockTimeout: time.Minute, Clock: clk}), clk
+}
+
+func TestBeginAndReplay(t *testing.T) {
+	ctx := context.Background()
+	s, clk := newTestService()
+	fp := Fingerprint("POST", "/api/shorten", []byte(`{"url":"https://example.com"}`))
+
+	rec, err := s.Begin(ctx, "key:k1", "retry-1", fp)
+	if err != nil {
+		t.Fatalf("Begin returned error: %v", err)
+	}
+	if rec.Completed() {
+		t.Fatalf("expected a new request, got %+v", rec)
+	}
+	if _, err := s.Begin(ctx, "key:k1", "retry-1", fp); !errors.Is(err, ErrInProgress) {
+		t.Fatalf("expected ErrInProgress while the first request runs, got %v", err)
+	}
+	if err := s.Complete(ctx, rec, 200, "application/json", []byte(`{"short_code":"abc123"}`)); err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+
+	clk.Advance(30 * time.Minute)
+	replay, err := s.Begin(ctx, "key:k1", "retry-1", fp)
+	if err != nil {
+		t.Fatalf("Begin returned error: %v", err)
+	}
+	if replay.Status != 200 || replay.ContentType != "application/json" || string(replay.Body) != `{"short_code":"abc123"}` {
+		t.Fatalf("expected the stored response, got %+v", replay)
+	}
+
+	other := Fingerprint("POST", "/api/shorten", []byte(`{"url":"https://example.org"}`))
+	if _, err := s.Begin(ctx, "key:k1", "retry-1", other); !errors.Is(err, ErrKeyReused) {
+		t.Fatalf("expected ErrKeyReused for a different payload, got %v", err)
+	}
+	if rec, err := s.Begin(ctx, "key:k2", "retry-1", other); err != nil || rec.Completed() {
+		t.Fatalf("expected another owner's key to be new, got %+v, %v", rec, err)
+	}
+
+	// Once the response expires, the key starts over.
+	clk.Advance(time.Hour)
+	if rec, err := s.Begin(ctx, "key:k1", "retry-1", other); err != nil || rec.Completed() {
+		t.Fatalf("expected an expired key to be new, got %+v, %v", rec, err)
+	}
+}
+
+func TestReleaseAndLockTimeout(t *testing.T) {
+	ctx := context.Background()
+	s, clk := newTestService()
+
+	rec, err := s.Begin(ctx, "ip:203.0.113.7", "retry-1", "fp")
+	if err != nil {
+		t.Fatalf("Begin returned error: %v", err)
+	}
+	if err := s.Release(ctx, rec); err != nil {
+		t.Fatalf("Release returned error: %v", err)
+	}
+	if _, err := s.Begin(ctx, "ip:203.0.113.7", "retry-1", "fp"); err != nil {
+		t.Fatalf("expected a released key to be claimable, got %v", err)
+	}
+
+	// A request that never finishes holds its key only for LockTimeout.
+	clk.Advance(time.Minute)
+	if rec, err := s.Begin(ctx, "ip:203.0.113.7", "retry-1", "fp"); err != nil || rec.Completed() {
+		t.Fatalf("expected a retry to take over a stale claim, got %+v, %v", rec, err)
+	}
+}
+
+func TestStaleClaimCannotTouchTakeover(t *testing.T) {
+	ctx := context.Background()
+	s, clk := newTestService()
+
+	stale, err := s.Begin(ctx, "key:k1", "retry-1", "fp")
+	if err != nil {
+		t.Fatalf("Begin returned error: %v", err)
+	}
+	clk.Advance(time.Minute)
+	retry, err := s.Begin(ctx, "key:k1", "retry-1", "fp")
+	if err != nil {
+		t.Fatalf("expected a retry to take over a stale claim, got %v", err)
+	}
+	if retry.Claim == stale.Claim {
+		t.Fatalf("expected each claim to get its own token, got %q twice", retry.Claim)
+	}
+
+	// The first request finishes late; the retry's claim must survive it.
+	if err := s.Complete(ctx, stale, 200, "text/plain", []byte("stale")); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected a stale claim not to complete, got %v", err)
+	}
+	if err := s.Release(ctx, stale); err != nil {
+		t.Fatalf("Release returned error: %v", err)
+	}
+	if _, err := s.Begin(ctx, "key:k1", "retry-1", "fp"); !errors.Is(err, ErrInProgress) {
+		t.Fatalf("expected the retry to still hold the key, got %v", err)
+	}
+	if err := s.Complete(ctx, retry, 201, "text/plain", []byte("retry")); err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	if rec, err := s.Begin(ctx, "key:k1", "retry-1", "fp"); err != nil || string(rec.Body) != "retry" {
+		t.Fatalf("expected the retry's response, got %+v, %v", rec, err)
+	}
+}
+
+func TestInvalidKey(t *testing.T) {
+	s := New(storage.NewInMemoryStore(), Options{})
+	for _, key := range []string{"", strings.Repeat("a", 256), "tab\tkey", "ключ"} {
+		if _, err := s.Begin(context.Background(), "key:k1", key, "fp"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Begin(%q): expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"urlshortener/internal/logging"
+)
+
+// LoadBlocklist reads a blocklist file: one host per line, optionally written
+// as a URL. Blank lines and "#" comments are ignored. A listed host also
+// blocks its subdomains.
+func LoadBlocklist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("blocklist: %w", err)
+	}
+	defer f.Close()
+
+	var hosts []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.Contains(line, "://") {
+			u, err := url.Parse(line)
+			if err != nil || u.Hostname() == "" {
+				continue
+			}
+			line = u.Hostname()
+		}
+		hosts = append(hosts, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("blocklist %s: %w", path, err)
+	}
+	return hosts, nil
+}
+
+// WatchBlocklist loads path into apply, then reloads it in the background
+// whenever its modification time changes, checking every interval until ctx
+// is done. Only the first load can fail; later errors are logged and the
+// previous list stays in effect.
+func WatchBlocklist(ctx context.Context, path string, interval time.Duration, apply func(hosts []string)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("blocklist: %w", err)
+	}
+	hosts, err := LoadBlocklist(path)
+	if err != nil {
+		return err
+	}
+	apply(hosts)
+	logging.Infof("🚫 Loaded %d blocklisted hosts from %s", len(hosts), path)
+
+	if interval <= 0 {
+		return nil
+	}
+	go func() {
+		modTime := info.ModTime()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				logging.Warnf("⚠️  Blocklist not reloaded: %v", err)
+				continue
+			}
+			if info.ModTime().Equal(modTime) {
+				continue
+			}
+			hosts, err := LoadBlocklist(path)
+			if err != nil {
+				logging.Warnf("⚠️  Blocklist not reloaded: %v", err)
+				continue
+			}
+			modTime = info.ModTime()
+			apply(hosts)
+			logging.Infof("🚫 Reloaded %d blocklisted hosts from %s", len(hosts), path)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package moderation handles abuse reports against links and the admin
+// actions taken on them. Every action is logged with the acting key.
+package moderation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/audit"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/storage"
+)
+
+var (
+	ErrInvalidReason  = errors.New("reason must be one of phishing, malware, spam, legal, other")
+	ErrDetailsTooLong = errors.New("details must be at most 1000 characters")
+)
+
+// Reasons a link can be reported and disabled for. "legal" takedowns are
+// served as 451 rather than 410.
+var Reasons = []string{"phishing", "malware", "spam", "legal", "other"}
+
+// ReasonLegal marks links taken down for legal reasons.
+const ReasonLegal = "legal"
+
+// maxDetails bounds the free-text part of a report.
+const maxDetails = 1000
+
+// Resolutions recorded on reports.
+const (
+	ResolutionDisabled  = "disabled"
+	ResolutionDismissed = "dismissed"
+)
+
+// Service files reports and applies moderation decisions.
+type Service struct {
+	links   *shortenerpkg.Shortener
+	reports storage.ReportStore
+}
+
+func NewService(links *shortenerpkg.Shortener, reports storage.ReportStore) *Service {
+	return &Service{links: links, reports: reports}
+}
+
+// ValidReason reports whether reason is one of Reasons.
+func ValidReason(reason string) bool {
+	return slices.Contains(Reasons, reason)
+}
+
+type ReportRequest struct {
+	ShortCode string
+	Reason    string
+	Details   string
+	Reporter  string // who is reporting, e.g. "ip:203.0.113.7"
+}
+
+// Report files an abuse report. A reporter who already has an open report
+// on the same link gets that report back instead of a duplicate.
+func (s *Service) Report(ctx context.Context, req ReportRequest) (storage.Report, error) {
+	req.Reason = strings.ToLower(strings.TrimSpace(req.Reason))
+	if !ValidReason(req.Reason) {
+		return storage.Report{}, ErrInvalidReason
+	}
+	req.Details = strings.TrimSpace(req.Details)
+	if len(req.Details) > maxDetails {
+		return storage.Report{}, ErrDetailsTooLong
+	}
+	if _, err := s.links.Stats(ctx, req.ShortCode); err != nil {
+		return storage.Report{}, err
+	}
+
+	open, err := s.reports.ListReports(ctx, storage.ReportListOptions{ShortCode: req.ShortCode, OpenOnly: true})
+	if err != nil {
+		return storage.Report{}, err
+	}
+	for _, r := range open {
+		if req.Reporter != "" && r.Reporter == req.Reporter {
+			return r, nil
+		}
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return storage.Report{}, err
+	}
+	report := storage.Report{
+		ID:        id,
+		ShortCode: req.ShortCode,
+		Reason:    req.Reason,
+		Details:   req.Details,
+		Reporter:  req.Reporter,
+		CreatedAt: s.links.Now().UTC(),
+	}
+	if err := s.reports.SaveReport(ctx, report); err != nil {
+		return storage.Report{}, err
+	}
+	logging.Infof("🚩 Report %s filed against %s (%s) by %s", report.ID, report.ShortCode, report.Reason, report.Reporter)
+	return report, nil
+}
+
+// Queue lists reports, oldest first.
+func (s *Service) Queue(ctx context.Context, opts storage.ReportListOptions) ([]storage.Report, error) {
+	return s.reports.ListReports(ctx, opts)
+}
+
+// Disable takes a link down and resolves its open reports.
+func (s *Service) Disable(ctx context.Context, shortCode, reason, actor string) (storage.Entry, error) {
+	if !ValidReason(reason) {
+		return storage.Entry{}, ErrInvalidReason
+	}
+	entry, err := s.links.Disable(audit.WithActor(ctx, actor), shortCode, reason)
+	if err != nil {
+		return storage.Entry{}, err
+	}
+	logging.Infof("🛡️  %s disabled %s (%s)", actor, shortCode, reason)
+
+	open, err := s.reports.ListReports(ctx, storage.ReportListOptions{ShortCode: shortCode, OpenOnly: true})
+	if err != nil {
+		return entry, err
+	}
+	now := s.links.Now().UTC()
+	for _, r := range open {
+		if err := s.reports.ResolveReport(ctx, r.ID, ResolutionDisabled, actor, now); err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
+
+// Enable restores a disabled link. Reports already resolved stay resolved.
+func (s *Service) Enable(ctx context.Context, shortCode, actor string) (storage.Entry, error) {
+	entry, err := s.links.Enable(audit.WithActor(ctx, actor), shortCode)
+	if err != nil {
+		return storage.Entry{}, err
+	}
+	logging.Infof("🛡️  %s re-enabled %s", actor, shortCode)
+	return entry, nil
+}
+
+// Dismiss closes a report without acting on its link.
+func (s *Service) Dismiss(ctx context.Context, id, actor string) (storage.Report, error) {
+	if err := s.reports.ResolveReport(ctx, id, ResolutionDismissed, actor, s.links.Now().UTC()); err != nil {
+		return storage.Report{}, err
+	}
+	report, err := s.reports.FindReport(ctx, id)
+	if err != nil {
+		return storage.Report{}, err
+	}
+	logging.Infof("🛡️  %s dismissed report %s against %s", actor, id, report.ShortCode)
+	return report, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,191 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+	shortenerpkg "urlshortener/internal/services/shortener"
+	"urlshortener/internal/services/storage"
+)
+
+func newTestService(t *testing.T) (*Service, *storage.InMemoryStore) {
+	t.Helper()
+	store := storage.NewInMemoryStore()
+	links := shortenerpkg.NewShortener(nil, store, shortenerpkg.ShortenerSettings{CodeLength: 6})
+	if err := store.Save(context.Background(), storage.Entry{ShortCode: "bad123", OriginalURL: "https://phish.example"}); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	return NewService(links, store), store
+}
+
+func TestReportValidation(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	tests := map[string]struct {
+		req  ReportRequest
+		want error
+	}{
+		"unknown reason": {ReportRequest{ShortCode: "bad123", Reason: "boring"}, ErrInvalidReason},
+		"long details":   {ReportRequest{ShortCode: "bad123", Reason: "spam", Details: string(make([]byte, maxDetails+1))}, ErrDetailsTooLong},
+		"missing link":   {ReportRequest{ShortCode: "nope", Reason: "spam"}, storage.ErrNotFound},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := svc.Report(ctx, tt.req); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestReportDeduplicatesPerReporter(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	first, err := svc.Report(ctx, ReportRequest{ShortCode: "bad123", Reason: " Phishing ", Reporter: "ip:192.0.2.1"})
+	if err != nil {
+		t.Fatalf("Report returned error: %v", err)
+	}
+	if first.Reason != "phishing" || !first.Open() {
+		t.Fatalf("unexpected report %+v", first)
+	}
+	again, _ := svc.Report(ctx, ReportRequest{ShortCode: "bad123", Reason: "spam", Reporter: "ip:192.0.2.1"})
+	if again.ID != first.ID {
+		t.Fatalf("expected the open report to be returned, got a new one")
+	}
+	_, _ = svc.Report(ctx, ReportRequest{ShortCode: "bad123", Reason: "spam", Reporter: "ip:192.0.2.2"})
+
+	queue, err := svc.Queue(ctx, storage.ReportListOptions{OpenOnly: true})
+	if err != nil {
+		t.Fatalf("Queue returned error: %v", err)
+	}
+	if len(queue) != 2 {
+		t.Fatalf("expected 2 open reports, got %d", len(queue))
+	}
+}
+
+func TestDisableResolvesReports(t *testing.T) {
+	svc, store := newTestService(t)
+	ctx := context.Background()
+	report, _ := svc.Report(ctx, ReportRequest{ShortCode: "bad123", Reason: "phishing", Reporter: "ip:192.0.2.1"})
+
+	if _, err := svc.Disable(ctx, "bad123", "boring", "key:admin"); !errors.Is(err, ErrInvalidReason) {
+		t.Fatalf("expected %v, got %v", ErrInvalidReason, err)
+	}
+	entry, err := svc.Disable(ctx, "bad123", "phishing", "key:admin")
+	if err != nil {
+		t.Fatalf("Disable returned error: %v", err)
+	}
+	if !entry.Disabled {
+		t.Fatalf("expected link to be disabled")
+	}
+
+	got, _ := store.FindReport(ctx, report.ID)
+	if got.Open() || got.Resolution != ResolutionDisabled || got.ResolvedBy != "key:admin" {
+		t.Fatalf("expected report to be resolved by the takedown, got %+v", got)
+	}
+
+	if _, err := svc.Enable(ctx, "bad123", "key:admin"); err != nil {
+		t.Fatalf("Enable returned error: %v", err)
+	}
+	stored, _ := store.Find(ctx, "bad123")
+	if stored.Disabled {
+		t.Fatalf("expected link to be enabled again")
+	}
+}
+
+func TestDismiss(t *testing.T) {
+	svc, store := newTestService(t)
+	ctx := context.Background()
+	report, _ := svc.Report(ctx, ReportRequest{ShortCode: "bad123", Reason: "spam"})
+
+	got, err := svc.Dismiss(ctx, report.ID, "key:admin")
+	if err != nil {
+		t.Fatalf("Dismiss returned error: %v", err)
+	}
+	if got.Resolution != ResolutionDismissed {
+		t.Fatalf("expected dismissed report, got %+v", got)
+	}
+	if entry, _ := store.Find(ctx, "bad123"); entry.Disabled {
+		t.Fatalf("expected dismissing not to touch the link")
+	}
+	if _, err := svc.Dismiss(ctx, "missing", "key:admin"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+}
+
+func TestLoadBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	content := "# phishing kits\nphish.example\n\n  https://Malware.test/payload  # full URLs work too\nnot a url://\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	hosts, err := LoadBlocklist(path)
+	if err != nil {
+		t.Fatalf("LoadBlocklist returned error: %v", err)
+	}
+	// Unparseable URLs are skipped; hosts are normalised when matched.
+	want := []string{"phish.example", "Malware.test"}
+	if !slices.Equal(hosts, want) {
+		t.Fatalf("expected %v, got %v", want, hosts)
+	}
+
+	if _, err := LoadBlocklist(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Fatal("expected error for a missing file")
+	}
+}
+
+func TestWatchBlocklistReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte("one.example\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var current []string
+	apply := func(hosts []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		current = hosts
+	}
+	get := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return current
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := WatchBlocklist(ctx, path, 10*time.Millisecond, apply); err != nil {
+		t.Fatalf("WatchBlocklist returned error: %v", err)
+	}
+	if got := get(); !slices.Equal(got, []string{"one.example"}) {
+		t.Fatalf("expected initial load, got %v", got)
+	}
+
+	if err := os.WriteFile(path, []byte("one.example\ntwo.example\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the change is visible even on filesystems with coarse mtimes.
+	later := time.Now().Add(time.Second)
+	_ = os.Chtimes(path, later, later)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(get()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("blocklist was not reloaded, still %v", get())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := WatchBlocklist(ctx, filepath.Join(t.TempDir(), "missing.txt"), time.Second, apply); err == nil {
+		t.Fatal("expected error for a missing file")
+	}
+}
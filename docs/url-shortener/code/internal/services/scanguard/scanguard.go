@@ -0,0 +1,212 @@
+// Package scanguard slows down and bans clients that look like they are
+// scanning for short codes: a client that keeps asking for codes that don't
+// exist has its responses delayed more and more, and is banned for a while
+// once it misses too often.
+package scanguard
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"urlshortener/internal/clock"
+	"urlshortener/internal/logging"
+)
+
+// Defaults for the zero values in Options.
+const (
+	defaultWindow      = 10 * time.Minute
+	defaultThreshold   = 20
+	defaultDelay       = 250 * time.Millisecond
+	defaultMaxDelay    = 5 * time.Second
+	defaultBanAfter    = 100
+	defaultBanDuration = 15 * time.Minute
+)
+
+type Options struct {
+	Window      time.Duration // how long a client's misses add up; default 10m
+	Threshold   int           // misses in a window before responses are delayed; default 20
+	Delay       time.Duration // added for every miss past Threshold; default 250ms
+	MaxDelay    time.Duration // cap on the delay; default 5s
+	BanAfter    int           // misses in a window that ban the client; default 100
+	BanDuration time.Duration // default 15m
+	Bans        BanStore      // default is in memory, for a single instance
+	Clock       clock.Clock   // default clock.System
+}
+
+// BanStore keeps bans. Misses are counted per instance, but a BanStore
+// shared between instances bans a client everywhere at once.
+type BanStore interface {
+	// Ban bars client until the given time.
+	Ban(ctx context.Context, client string, until time.Time) error
+	// BannedUntil returns when client's ban ends; a time in the past or the
+	// zero time means it is not banned.
+	BannedUntil(ctx context.Context, client string) (time.Time, error)
+}
+
+// Stats counts what a Guard has seen since it started.
+type Stats struct {
+	Misses  uint64 // unknown codes requested
+	Delayed uint64 // responses slowed down
+	Bans    uint64 // clients banned
+	Blocked uint64 // requests refused because the client was banned
+	Tracked int    // clients with misses in their current window
+}
+
+// Guard tracks misses per client. Clients are whatever the caller keys them
+// by, e.g. "ip:192.0.2.1".
+type Guard struct {
+	opts Options
+
+	mu        sync.Mutex
+	clients   map[string]*tally
+	lastPrune time.Time
+
+	misses, delayed, bans, blocked atomic.Uint64
+}
+
+// tally is a client's misses in the window that started at start.
+type tally struct {
+	start  time.Time
+	misses int
+}
+
+func New(opts Options) *Guard {
+	if opts.Window <= 0 {
+		opts.Window = defaultWindow
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = defaultThreshold
+	}
+	if opts.Delay <= 0 {
+		opts.Delay = defaultDelay
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = defaultMaxDelay
+	}
+	if opts.BanAfter <= 0 {
+		opts.BanAfter = defaultBanAfter
+	}
+	if opts.BanDuration <= 0 {
+		opts.BanDuration = defaultBanDuration
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.System
+	}
+	if opts.Bans == nil {
+		opts.Bans = &MemoryBans{clock: opts.Clock, until: map[string]time.Time{}}
+	}
+	return &Guard{opts: opts, clients: map[string]*tally{}}
+}
+
+// Banned returns how much longer client is banned, or zero. A BanStore
+// error is logged and lets the request through.
+func (g *Guard) Banned(ctx context.Context, client string) time.Duration {
+	until, err := g.opts.Bans.BannedUntil(ctx, client)
+	if err != nil {
+		logging.Errorf("❌ Failed to look up ban for %s: %v", client, err)
+		return 0
+	}
+	wait := until.Sub(g.opts.Clock.Now())
+	if wait <= 0 {
+		return 0
+	}
+	g.blocked.Add(1)
+	return wait
+}
+
+// Miss records that client asked for a code that does not exist and
+// returns how long to hold the response. Reaching BanAfter misses in a
+// window bans the client and starts a fresh window.
+func (g *Guard) Miss(ctx context.Context, client string) time.Duration {
+	g.misses.Add(1)
+	now := g.opts.Clock.Now()
+
+	g.mu.Lock()
+	g.prune(now)
+	t, ok := g.clients[client]
+	if !ok || now.Sub(t.start) >= g.opts.Window {
+		t = &tally{start: now}
+		g.clients[client] = t
+	}
+	t.misses++
+	misses := t.misses
+	banned := misses >= g.opts.BanAfter
+	if banned {
+		delete(g.clients, client)
+	}
+	g.mu.Unlock()
+
+	if banned {
+		g.bans.Add(1)
+		logging.Warnf("🚫 Banned %s for %s after %d unknown codes", client, g.opts.BanDuration, misses)
+		if err := g.opts.Bans.Ban(ctx, client, now.Add(g.opts.BanDuration)); err != nil {
+			logging.Errorf("❌ Failed to ban %s: %v", client, err)
+		}
+	}
+
+	over := misses - g.opts.Threshold
+	if over <= 0 {
+		return 0
+	}
+	g.delayed.Add(1)
+	return min(time.Duration(over)*g.opts.Delay, g.opts.MaxDelay)
+}
+
+// prune drops clients whose window has ended; g.mu must be held.
+func (g *Guard) prune(now time.Time) {
+	if now.Sub(g.lastPrune) < g.opts.Window {
+		return
+	}
+	g.lastPrune = now
+	for client, t := range g.clients {
+		if now.Sub(t.start) >= g.opts.Window {
+			delete(g.clients, client)
+		}
+	}
+}
+
+func (g *Guard) Stats() Stats {
+	g.mu.Lock()
+	tracked := len(g.clients)
+	g.mu.Unlock()
+	return Stats{
+		Misses:  g.misses.Load(),
+		Delayed: g.delayed.Load(),
+		Bans:    g.bans.Load(),
+		Blocked: g.blocked.Load(),
+		Tracked: tracked,
+	}
+}
+
+// MemoryBans is a BanStore for a single instance.
+type MemoryBans struct {
+	clock clock.Clock
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func NewMemoryBans() *MemoryBans {
+	return &MemoryBans{clock: clock.System, until: map[string]time.Time{}}
+}
+
+// Ban also forgets bans that have ended, so the map only holds live ones.
+func (m *MemoryBans) Ban(_ context.Context, client string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	for c, u := range m.until {
+		if !u.After(now) {
+			delete(m.until, c)
+		}
+	}
+	m.until[client] = until
+	return nil
+}
+
+func (m *MemoryBans) BannedUntil(_ context.Context, client string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.until[client], nil
+}
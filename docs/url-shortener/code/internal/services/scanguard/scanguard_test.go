@@ -0,0 +1,102 @@
+package scanguard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"urlshortener/internal/clock/clocktest"
+)
+
+func newTestGuard(opts Options) (*Guard, *clocktest.Clock) {
+	clk := clocktest.New(time.Now())
+	opts.Clock = clk
+	return New(opts), clk
+}
+
+func TestProgressiveDelay(t *testing.T) {
+	ctx := context.Background()
+	g, _ := newTestGuard(Options{Threshold: 3, Delay: 100 * time.Millisecond, MaxDelay: 250 * time.Millisecond, BanAfter: 50})
+
+	var got []time.Duration
+	for range 6 {
+		got = append(got, g.Miss(ctx, "ip:192.0.2.1"))
+	}
+	want := []time.Duration{0, 0, 0, 100 * time.Millisecond, 200 * time.Millisecond, 250 * time.Millisecond}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("miss %d: expected delay %s, got %s (all: %v)", i+1, want[i], got[i], got)
+		}
+	}
+	if d := g.Miss(ctx, "ip:192.0.2.2"); d != 0 {
+		t.Fatalf("expected another client to start undelayed, got %s", d)
+	}
+
+	s := g.Stats()
+	if s.Misses != 7 || s.Delayed != 3 || s.Bans != 0 || s.Tracked != 2 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
+
+func TestWindowResets(t *testing.T) {
+	ctx := context.Background()
+	g, clk := newTestGuard(Options{Window: time.Minute, Threshold: 2, BanAfter: 3})
+
+	g.Miss(ctx, "ip:192.0.2.1")
+	g.Miss(ctx, "ip:192.0.2.1")
+	clk.Advance(time.Minute)
+	if d := g.Miss(ctx, "ip:192.0.2.1"); d != 0 {
+		t.Fatalf("expected a fresh window after Window, got delay %s", d)
+	}
+	if wait := g.Banned(ctx, "ip:192.0.2.1"); wait != 0 {
+		t.Fatalf("expected misses from an old window not to ban, got %s", wait)
+	}
+
+	clk.Advance(2 * time.Minute)
+	g.Miss(ctx, "ip:192.0.2.9")
+	if s := g.Stats(); s.Tracked != 1 {
+		t.Fatalf("expected stale clients to be pruned, got %+v", s)
+	}
+}
+
+func TestBanAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	g, clk := newTestGuard(Options{Threshold: 2, BanAfter: 4, BanDuration: 10 * time.Minute})
+
+	for range 4 {
+		g.Miss(ctx, "ip:192.0.2.1")
+	}
+	if wait := g.Banned(ctx, "ip:192.0.2.1"); wait != 10*time.Minute {
+		t.Fatalf("expected a 10m ban, got %s", wait)
+	}
+	if wait := g.Banned(ctx, "ip:192.0.2.2"); wait != 0 {
+		t.Fatalf("expected other clients not to be banned, got %s", wait)
+	}
+
+	clk.Advance(10 * time.Minute)
+	if wait := g.Banned(ctx, "ip:192.0.2.1"); wait != 0 {
+		t.Fatalf("expected the ban to end, got %s", wait)
+	}
+	// The ban started a fresh window.
+	if d := g.Miss(ctx, "ip:192.0.2.1"); d != 0 {
+		t.Fatalf("expected no delay after a ban ends, got %s", d)
+	}
+
+	s := g.Stats()
+	if s.Bans != 1 || s.Blocked != 1 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
+
+func TestSharedBanStore(t *testing.T) {
+	ctx := context.Background()
+	bans := NewMemoryBans()
+	a, _ := newTestGuard(Options{BanAfter: 2, Bans: bans})
+	b, _ := newTestGuard(Options{BanAfter: 2, Bans: bans})
+
+	a.Miss(ctx, "ip:192.0.2.1")
+	a.Miss(ctx, "ip:192.0.2.1")
+	if wait := b.Banned(ctx, "ip:192.0.2.1"); wait <= 0 {
+		t.Fatal("expected a ban on one instance to apply on another")
+	}
+}
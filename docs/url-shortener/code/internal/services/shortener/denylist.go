@@ -0,0 +1,56 @@
+package shortener
+
+import (
+	"strings"
+	"sync"
+)
+
+// denylist holds destination hosts that may not be shortened. A listed
+// domain also blocks its subdomains. It can be replaced while serving.
+type denylist struct {
+	mu    sync.RWMutex
+	hosts map[string]struct{}
+}
+
+func newDenylist(hosts []string) *denylist {
+	d := &denylist{}
+	d.set(hosts)
+	return d
+}
+
+func (d *denylist) set(hosts []string) {
+	next := make(map[string]struct{}, len(hosts))
+	for _, h := range hosts {
+		h = normaliseHost(h)
+		if h != "" {
+			next[h] = struct{}{}
+		}
+	}
+
+	d.mu.Lock()
+	d.hosts = next
+	d.mu.Unlock()
+}
+
+func (d *denylist) denied(host string) bool {
+	host = normaliseHost(host)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for host != "" {
+		if _, ok := d.hosts[host]; ok {
+			return true
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			return false
+		}
+		host = parent
+	}
+	return false
+}
+
+func normaliseHost(h string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(h)), ".")
+}
@@ -0,0 +1,136 @@
+package shortener
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"urlshortener/internal/services/audit"
+	"urlshortener/internal/services/storage"
+)
+
+const (
+	maxTitleLen       = 200
+	maxDescriptionLen = 1000
+	maxTags           = 20
+	maxTagLen         = 50
+)
+
+// tagPattern allows folder paths like "launch/q3": each level starts with a
+// letter or digit.
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*(/[a-z0-9][a-z0-9_.-]*)*$`)
+
+// NormalizeTag trims and lower-cases tag and checks it is well formed.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if len(tag) > maxTagLen || !tagPattern.MatchString(tag) {
+		return "", ErrInvalidTag
+	}
+	return tag, nil
+}
+
+// NormalizeTags normalizes every tag, then sorts them and drops duplicates so
+// equal sets compare equal. It returns nil for no tags.
+func NormalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, tag)
+	}
+	slices.Sort(out)
+	out = slices.Compact(out)
+	if len(out) > maxTags {
+		return nil, ErrTooManyTags
+	}
+	return out, nil
+}
+
+func checkDetails(title, description string) error {
+	if utf8.RuneCountInString(title) > maxTitleLen {
+		return ErrTitleTooLong
+	}
+	if utf8.RuneCountInString(description) > maxDescriptionLen {
+		return ErrDescriptionTooLong
+	}
+	return nil
+}
+
+// LinkChanges are the edits Edit makes; nil fields are left as they are. An
+// empty Tags slice clears the tags.
+type LinkChanges struct {
+	URL         *string
+	Title       *string
+	Description *string
+	Tags        *[]string
+}
+
+// Edit changes a link's destination or details. A new URL is checked like in
+// Shorten and is refused for disabled links; details can still be edited. The
+// change is audited as a retarget when the URL changed and as an edit
+// otherwise.
+func (s *Shortener) Edit(
+	ctx context.Context,
+	shortCode string,
+	changes LinkChanges,
+) (storage.Entry, error) {
+	if shortCode == "" {
+		return storage.Entry{}, ErrEmptyCode
+	}
+	if changes == (LinkChanges{}) {
+		return storage.Entry{}, ErrNoChanges
+	}
+	if changes.URL != nil {
+		if err := s.checkURL(*changes.URL); err != nil {
+			return storage.Entry{}, err
+		}
+	}
+	before, err := s.store.Find(ctx, shortCode)
+	if err != nil {
+		return storage.Entry{}, err
+	}
+
+	entry := before
+	if changes.URL != nil {
+		if before.Disabled {
+			return before, ErrDisabled
+		}
+		entry.OriginalURL = *changes.URL
+		if entry.OriginalURL != before.OriginalURL {
+			// The last health check was of the old destination.
+			entry.CheckedAt, entry.CheckStatus, entry.CheckError = time.Time{}, 0, ""
+		}
+	}
+	if changes.Title != nil {
+		entry.Title = *changes.Title
+	}
+	if changes.Description != nil {
+		entry.Description = *changes.Description
+	}
+	if err := checkDetails(entry.Title, entry.Description); err != nil {
+		return storage.Entry{}, err
+	}
+	if changes.Tags != nil {
+		if entry.Tags, err = NormalizeTags(*changes.Tags); err != nil {
+			return storage.Entry{}, err
+		}
+	}
+
+	if err := s.store.Update(ctx, entry); err != nil {
+		return storage.Entry{}, err
+	}
+	action := audit.ActionEdit
+	if entry.OriginalURL != before.OriginalURL {
+		action = audit.ActionRetarget
+	}
+	s.record(ctx, action, shortCode, &before, &entry)
+	return entry, nil
+}
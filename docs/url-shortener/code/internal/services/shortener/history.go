@@ -0,0 +1,154 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/audit"
+	"urlshortener/internal/services/storage"
+)
+
+// VersionBaseline is the action of the first version of a link created
+// before history was kept: the state its first recorded change replaced.
+const VersionBaseline = "baseline"
+
+// maxVersionAttempts bounds retries when concurrent changes to one link race
+// for the same version number.
+const maxVersionAttempts = 3
+
+// WithHistory keeps a version of each link after every change made through
+// the service in h, so changes can be reviewed and rolled back; without it
+// no history is kept.
+func WithHistory(h storage.VersionStore) Option {
+	return func(s *Shortener) { s.history = h }
+}
+
+// addVersion appends after to the link's history as the result of action. A
+// link without history yet first gets before as its baseline, so the change
+// can be undone.
+func (s *Shortener) addVersion(ctx context.Context, action string, before *storage.Entry, after storage.Entry) {
+	now := s.clock.Now().UTC()
+	if before != nil {
+		_, err := s.history.FindVersion(ctx, after.ShortCode, 1)
+		if errors.Is(err, storage.ErrNotFound) {
+			err = s.appendVersion(ctx, newVersion(*before, VersionBaseline, "", now))
+		}
+		if err != nil {
+			logging.Errorf("❌ Failed to record history of %s: %v", after.ShortCode, err)
+			return
+		}
+	}
+	if err := s.appendVersion(ctx, newVersion(after, action, audit.Actor(ctx), now)); err != nil {
+		logging.Errorf("❌ Failed to record history of %s: %v", after.ShortCode, err)
+	}
+}
+
+func (s *Shortener) appendVersion(ctx context.Context, v storage.LinkVersion) error {
+	var err error
+	for range maxVersionAttempts {
+		if _, err = s.history.AppendVersion(ctx, v); !errors.Is(err, storage.ErrConflict) {
+			return err
+		}
+	}
+	return err
+}
+
+func newVersion(e storage.Entry, action, actor string, at time.Time) storage.LinkVersion {
+	return storage.LinkVersion{
+		ShortCode:      e.ShortCode,
+		At:             at,
+		Actor:          actor,
+		Action:         action,
+		OriginalURL:    e.OriginalURL,
+		ExpiresAt:      e.ExpiresAt,
+		ActivatesAt:    e.ActivatesAt,
+		Disabled:       e.Disabled,
+		DisabledReason: e.DisabledReason,
+		Title:          e.Title,
+		Description:    e.Description,
+		Tags:           slices.Clone(e.Tags),
+	}
+}
+
+// History returns the link's versions, newest first. It is empty for links
+// that have not changed since history was turned on, and for every link
+// when it is off.
+func (s *Shortener) History(
+	ctx context.Context,
+	shortCode string,
+) ([]storage.LinkVersion, error) {
+	if shortCode == "" {
+		return nil, ErrEmptyCode
+	}
+	if _, err := s.store.Find(ctx, shortCode); err != nil {
+		return nil, err
+	}
+	if s.history == nil {
+		return []storage.LinkVersion{}, nil
+	}
+	return s.history.ListVersions(ctx, shortCode)
+}
+
+// Rollback restores the destination, expiry, go-live time, title,
+// description and tags the link had in the given version, as one more
+// change. Moderation is left alone, and the restored URL is checked like in
+// Edit, so a rollback cannot bring back a destination that has since been
+// blocked; nor can it bring back an expiry that has passed. Rolling back to
+// the current state changes nothing.
+func (s *Shortener) Rollback(
+	ctx context.Context,
+	shortCode string,
+	version int,
+) (storage.Entry, error) {
+	if shortCode == "" {
+		return storage.Entry{}, ErrEmptyCode
+	}
+	if s.history == nil {
+		return storage.Entry{}, storage.ErrNotFound
+	}
+	v, err := s.history.FindVersion(ctx, shortCode, version)
+	if err != nil {
+		return storage.Entry{}, err
+	}
+	before, err := s.store.Find(ctx, shortCode)
+	if err != nil {
+		return storage.Entry{}, err
+	}
+
+	entry := before
+	if v.OriginalURL != before.OriginalURL {
+		if before.Disabled {
+			return before, ErrDisabled
+		}
+		if err := s.checkURL(v.OriginalURL); err != nil {
+			return storage.Entry{}, err
+		}
+		entry.OriginalURL = v.OriginalURL
+		entry.CheckedAt, entry.CheckStatus, entry.CheckError = time.Time{}, 0, ""
+	}
+	if !v.ExpiresAt.Equal(before.ExpiresAt) {
+		if !v.ExpiresAt.IsZero() && !v.ExpiresAt.After(s.clock.Now()) {
+			return storage.Entry{}, ErrInvalidExpiry
+		}
+		entry.ExpiresAt = v.ExpiresAt
+	}
+	// A version's go-live time came with its expiry, so the two still fit.
+	entry.ActivatesAt = v.ActivatesAt
+	entry.Title = v.Title
+	entry.Description = v.Description
+	entry.Tags = slices.Clone(v.Tags)
+	if entry.OriginalURL == before.OriginalURL && entry.ExpiresAt.Equal(before.ExpiresAt) &&
+		entry.ActivatesAt.Equal(before.ActivatesAt) && entry.Title == before.Title &&
+		entry.Description == before.Description && slices.Equal(entry.Tags, before.Tags) {
+		return before, nil
+	}
+
+	if err := s.store.Update(ctx, entry); err != nil {
+		return storage.Entry{}, err
+	}
+	s.record(ctx, audit.ActionRollback, shortCode, &before, &entry)
+	return entry, nil
+}
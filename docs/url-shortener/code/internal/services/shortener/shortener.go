@@ -2,31 +2,107 @@ package shortener
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
-	"math/rand"
+	"io"
 	"net/url"
+	"regexp"
+	"strings"
 	"sync"
 	"time"
+	"urlshortener/internal/clock"
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/audit"
+	"urlshortener/internal/services/signing"
 	"urlshortener/internal/services/storage"
+	"urlshortener/internal/services/transfer"
+	"urlshortener/internal/services/webhook"
 )
 
 var (
-	ErrEmptyURL          = errors.New("url is required")
-	ErrInvalidURL        = errors.New("url is invalid")
-	ErrNoGenerator       = errors.New("code generator unavailable")
-	ErrEmptyCode         = errors.New("short-code is empty")
-	ErrTooManyCollisions = errors.New("too many collisions")
+	ErrEmptyURL           = errors.New("url is required")
+	ErrInvalidURL         = errors.New("url is invalid")
+	ErrNoGenerator        = errors.New("code generator unavailable")
+	ErrEmptyCode          = errors.New("short-code is empty")
+	ErrTooManyCollisions  = errors.New("too many collisions")
+	ErrInvalidAlias       = errors.New("alias must be 3-50 letters, digits, '-' or '_'")
+	ErrReservedAlias      = errors.New("alias is reserved")
+	ErrAliasTaken         = errors.New("alias is already taken")
+	ErrInvalidExpiry      = errors.New("expiry must be in the future")
+	ErrInvalidActivation  = errors.New("activation must be before expiry")
+	ErrExpired            = errors.New("short-code has expired")
+	ErrNotActive          = errors.New("short-code is not active yet")
+	ErrDeniedURL          = errors.New("url host is not allowed")
+	ErrBlockedURL         = errors.New("url host is on the abuse blocklist")
+	ErrDisabled           = errors.New("short-code has been disabled")
+	ErrTitleTooLong       = errors.New("title must be at most 200 characters")
+	ErrDescriptionTooLong = errors.New("description must be at most 1000 characters")
+	ErrInvalidTag         = errors.New("tags must be 1-50 lowercase letters, digits, '-', '_' or '.', with '/' between folder levels")
+	ErrTooManyTags        = errors.New("a link can have at most 20 tags")
+	ErrNoChanges          = errors.New("nothing to change")
+	ErrSignatureRequired  = errors.New("short-code only resolves through a signed link")
+	ErrNotSigned          = errors.New("link is not a signed link")
 )
 
+// reservedAliases collide with routes served next to /{shortCode}.
+var reservedAliases = map[string]struct{}{
+	"api":       {},
+	"dashboard": {},
+	"healthz":   {},
+	"static":    {},
+}
+
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,50}$`)
+
+// anonymousOwner is recorded as CreatedBy when the caller is unknown.
+const anonymousOwner = "anonymous"
+
+// defaultMaxRetries applies when ShortenerSettings.MaxRetries is not set.
+const defaultMaxRetries = 3
+
 type ShortenerSettings struct {
 	CodeLength int
 	MaxRetries int
+	Denylist   []string // destination hosts (and their subdomains) to refuse
 }
 
 type Shortener struct {
 	generator CodeGenerator
 	store     storage.Store
 	settings  ShortenerSettings
+	denylist  *denylist
+	blocklist *denylist // hosts from the abuse blocklist file, if any
+	audit     *audit.Log
+	history   storage.VersionStore
+	webhooks  *webhook.Service
+	keys      *signing.Keyring
+	clock     clock.Clock
+}
+
+// Option configures a Shortener at construction.
+type Option func(*Shortener)
+
+// WithClock makes the service tell the time from c instead of the wall
+// clock, for expiry, activation, signatures and history.
+func WithClock(c clock.Clock) Option {
+	return func(s *Shortener) { s.clock = c }
+}
+
+// WithAuditLog records every change made through the service to l; without
+// it nothing is audited.
+func WithAuditLog(l *audit.Log) Option {
+	return func(s *Shortener) { s.audit = l }
+}
+
+// WithKeyring signs and verifies signed links with k; without it signed
+// links cannot be created or followed.
+func WithKeyring(k *signing.Keyring) Option {
+	return func(s *Shortener) { s.keys = k }
+}
+
+// WithWebhooks announces link events through w.
+func WithWebhooks(w *webhook.Service) Option {
+	return func(s *Shortener) { s.webhooks = w }
 }
 
 type CodeGenerator interface {
@@ -34,45 +110,170 @@ type CodeGenerator interface {
 }
 
 type ShortenRequest struct {
-	URL string
+	URL       string
+	Alias     string    // optional custom short code
+	ExpiresAt time.Time // optional; zero means never
+	CreatedBy string    // optional owner; defaults to "anonymous"
+
+	// ActivatesAt, when set, is when the link starts redirecting; see
+	// ErrNotActive.
+	ActivatesAt time.Time
+
+	Title       string   // optional
+	Description string   // optional
+	Tags        []string // optional; normalized by NormalizeTags
+
+	// Signed links only resolve through a signed URL; see Sign.
+	Signed bool
 }
 
 type ShortenResponse struct {
 	ShortCode   string
 	OriginalURL string
+	ExpiresAt   time.Time
+	ActivatesAt time.Time
+	Title       string
+	Description string
+	Tags        []string
+	Signed      bool
+	Signature   signing.Signature // for Signed links, valid for the keyring's TTL
 }
 
 func NewShortener(
 	gen CodeGenerator,
 	store storage.Store,
 	settings ShortenerSettings,
+	opts ...Option,
 ) *Shortener {
-	return &Shortener{generator: gen, store: store}
+	s := &Shortener{
+		generator: gen,
+		store:     store,
+		settings:  settings,
+		denylist:  newDenylist(settings.Denylist),
+		blocklist: newDenylist(nil),
+		clock:     clock.System,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Now is the time by the service's clock, for callers that judge links
+// the way the service does, e.g. whether one has expired.
+func (s *Shortener) Now() time.Time {
+	return s.clock.Now()
+}
+
+// SetDenylist replaces the denied hosts; safe to call while serving.
+func (s *Shortener) SetDenylist(hosts []string) {
+	s.denylist.set(hosts)
+}
+
+// SetBlocklist replaces the hosts from the abuse blocklist. It is kept apart
+// from the configured denylist so reloading one does not clear the other.
+func (s *Shortener) SetBlocklist(hosts []string) {
+	s.blocklist.set(hosts)
+}
+
+// record adds a change to the audit log and, unless it removed the link, to
+// the link's history. The change has already happened, so a failure to
+// record it is logged rather than returned.
+func (s *Shortener) record(ctx context.Context, action, shortCode string, before, after *storage.Entry) {
+	if s.history != nil && after != nil {
+		s.addVersion(ctx, action, before, *after)
+	}
+	if s.audit == nil {
+		return
+	}
+	if err := s.audit.Record(ctx, action, shortCode, before, after); err != nil {
+		logging.Errorf("❌ Failed to write audit log: %v", err)
+	}
+}
+
+// notify queues a webhook event. Like record, it runs after the change and
+// only logs failures.
+func (s *Shortener) notify(ctx context.Context, event string, entry storage.Entry) {
+	if s.webhooks == nil {
+		return
+	}
+	if err := s.webhooks.Notify(ctx, event, entry); err != nil {
+		logging.Errorf("❌ Failed to queue webhook: %v", err)
+	}
+}
+
+// checkURL validates a destination and applies the deny- and blocklists.
+func (s *Shortener) checkURL(raw string) error {
+	if raw == "" {
+		return ErrEmptyURL
+	}
+	parsed, err := url.ParseRequestURI(raw)
+	if err != nil {
+		return ErrInvalidURL
+	}
+	if s.denylist.denied(parsed.Hostname()) {
+		return ErrDeniedURL
+	}
+	if s.blocklist.denied(parsed.Hostname()) {
+		return ErrBlockedURL
+	}
+	return nil
 }
 
 func (s *Shortener) Shorten(
 	ctx context.Context,
 	req ShortenRequest,
 ) (ShortenResponse, error) {
-	if req.URL == "" {
-		return ShortenResponse{}, ErrEmptyURL
+	if err := s.checkURL(req.URL); err != nil {
+		return ShortenResponse{}, err
 	}
-	if _, err := url.ParseRequestURI(req.URL); err != nil {
-		return ShortenResponse{}, ErrInvalidURL
+	now := s.clock.Now().UTC()
+	if !req.ExpiresAt.IsZero() && !req.ExpiresAt.After(now) {
+		return ShortenResponse{}, ErrInvalidExpiry
 	}
-	if s.generator == nil {
-		return ShortenResponse{}, ErrNoGenerator
+	if !req.ActivatesAt.IsZero() && !req.ExpiresAt.IsZero() && !req.ActivatesAt.Before(req.ExpiresAt) {
+		return ShortenResponse{}, ErrInvalidActivation
+	}
+	createdBy := req.CreatedBy
+	if createdBy == "" {
+		createdBy = anonymousOwner
+	}
+	if err := checkDetails(req.Title, req.Description); err != nil {
+		return ShortenResponse{}, err
+	}
+	tags, err := NormalizeTags(req.Tags)
+	if err != nil {
+		return ShortenResponse{}, err
+	}
+	if req.Signed && !s.keys.Enabled() {
+		return ShortenResponse{}, signing.ErrNoKeys
 	}
 
 	entry := storage.Entry{
 		ShortCode:   "",
 		OriginalURL: req.URL,
-		CreatedAt:   time.Now().UTC(),
-		CreatedBy:   "coming soon",
+		CreatedAt:   now,
+		CreatedBy:   createdBy,
 		HitCount:    0,
+		ExpiresAt:   req.ExpiresAt.UTC(),
+		ActivatesAt: req.ActivatesAt.UTC(),
+		Title:       req.Title,
+		Description: req.Description,
+		Tags:        tags,
+		Signed:      req.Signed,
+	}
+
+	if req.Alias != "" {
+		return s.saveAlias(ctx, entry, req.Alias)
+	}
+	if s.generator == nil {
+		return ShortenResponse{}, ErrNoGenerator
 	}
 
-	const maxAttempts = 3
+	maxAttempts := s.settings.MaxRetries
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxRetries
+	}
 	for i := range maxAttempts {
 		code, err := s.generator.Generate(ctx)
 		if err != nil {
@@ -87,20 +288,90 @@ func (s *Shortener) Shorten(
 				}
 				return ShortenResponse{}, ErrTooManyCollisions
 			}
+			// Transient backend failures get the same attempt budget.
+			if errors.Is(err, storage.ErrRetryable) && i < maxAttempts-1 {
+				continue
+			}
 			return ShortenResponse{}, err
 		} else {
 			break
 		}
 	}
-	return ShortenResponse{
+	s.record(ctx, audit.ActionCreate, entry.ShortCode, nil, &entry)
+	s.notify(ctx, webhook.EventCreated, entry)
+	return s.newShortenResponse(entry)
+}
+
+func (s *Shortener) newShortenResponse(entry storage.Entry) (ShortenResponse, error) {
+	resp := ShortenResponse{
 		ShortCode:   entry.ShortCode,
-		OriginalURL: req.URL,
-	}, nil
+		OriginalURL: entry.OriginalURL,
+		ExpiresAt:   entry.ExpiresAt,
+		ActivatesAt: entry.ActivatesAt,
+		Title:       entry.Title,
+		Description: entry.Description,
+		Tags:        entry.Tags,
+		Signed:      entry.Signed,
+	}
+	if entry.Signed {
+		sig, err := s.sign(entry, time.Time{})
+		if err != nil {
+			return ShortenResponse{}, err
+		}
+		resp.Signature = sig
+	}
+	return resp, nil
 }
 
+// checkAlias applies the rules for caller-chosen codes.
+func checkAlias(alias string) error {
+	if !aliasPattern.MatchString(alias) {
+		return ErrInvalidAlias
+	}
+	if _, ok := reservedAliases[strings.ToLower(alias)]; ok {
+		return ErrReservedAlias
+	}
+	return nil
+}
+
+// saveAlias stores entry under a caller-chosen code. There is no retry: a
+// taken alias is the caller's problem, not a collision.
+func (s *Shortener) saveAlias(
+	ctx context.Context,
+	entry storage.Entry,
+	alias string,
+) (ShortenResponse, error) {
+	if err := checkAlias(alias); err != nil {
+		return ShortenResponse{}, err
+	}
+
+	entry.ShortCode = alias
+	if err := s.store.Save(ctx, entry); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			return ShortenResponse{}, ErrAliasTaken
+		}
+		return ShortenResponse{}, err
+	}
+	s.record(ctx, audit.ActionCreate, entry.ShortCode, nil, &entry)
+	s.notify(ctx, webhook.EventCreated, entry)
+	return s.newShortenResponse(entry)
+}
+
+// Lookup resolves shortCode for a redirect and counts the hit. Signed links
+// fail with ErrSignatureRequired, revealing nothing about them; they resolve
+// through LookupSigned. Links before their activation time fail with
+// ErrNotActive and count no hit.
 func (s *Shortener) Lookup(
 	ctx context.Context,
 	shortCode string,
+) (storage.Entry, error) {
+	return s.lookup(ctx, shortCode, false)
+}
+
+func (s *Shortener) lookup(
+	ctx context.Context,
+	shortCode string,
+	verified bool,
 ) (storage.Entry, error) {
 	if shortCode == "" {
 		return storage.Entry{}, ErrEmptyCode
@@ -109,27 +380,215 @@ func (s *Shortener) Lookup(
 	if err != nil {
 		return storage.Entry{}, err
 	}
+	if entry.Signed && !verified {
+		return storage.Entry{}, ErrSignatureRequired
+	}
+	if entry.Disabled {
+		return entry, ErrDisabled
+	}
+	now := s.clock.Now()
+	if entry.Expired(now) {
+		return entry, ErrExpired
+	}
+	if entry.Pending(now) {
+		return entry, ErrNotActive
+	}
 	updated, incErr := s.store.IncrementHits(ctx, shortCode)
 	if incErr == nil {
+		s.notify(ctx, webhook.EventHits, updated)
 		return updated, nil
 	}
 	// If increment fails, surface the original entry so callers can still redirect.
 	return entry, incErr
 }
 
+// Stats returns the entry for shortCode without counting a hit.
+func (s *Shortener) Stats(
+	ctx context.Context,
+	shortCode string,
+) (storage.Entry, error) {
+	if shortCode == "" {
+		return storage.Entry{}, ErrEmptyCode
+	}
+	return s.store.Find(ctx, shortCode)
+}
+
+func (s *Shortener) List(
+	ctx context.Context,
+	opts storage.ListOptions,
+) ([]storage.Entry, error) {
+	return s.store.List(ctx, opts)
+}
+
+func (s *Shortener) Delete(
+	ctx context.Context,
+	shortCode string,
+) error {
+	if shortCode == "" {
+		return ErrEmptyCode
+	}
+	if s.audit == nil && s.webhooks == nil {
+		return s.store.Delete(ctx, shortCode)
+	}
+	before, err := s.store.Find(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Delete(ctx, shortCode); err != nil {
+		return err
+	}
+	s.record(ctx, audit.ActionDelete, shortCode, &before, nil)
+	s.notify(ctx, webhook.EventDeleted, before)
+	return nil
+}
+
+// Retarget points an existing link at a new destination. The URL is checked
+// like in Shorten; disabled links cannot be retargeted.
+func (s *Shortener) Retarget(
+	ctx context.Context,
+	shortCode string,
+	rawURL string,
+) (storage.Entry, error) {
+	return s.Edit(ctx, shortCode, LinkChanges{URL: &rawURL})
+}
+
+// Disable takes a link down: it stops redirecting until Enable is called.
+// reason is shown to visitors and should be one of the report reasons.
+func (s *Shortener) Disable(
+	ctx context.Context,
+	shortCode string,
+	reason string,
+) (storage.Entry, error) {
+	if shortCode == "" {
+		return storage.Entry{}, ErrEmptyCode
+	}
+	before, err := s.store.Find(ctx, shortCode)
+	if err != nil {
+		return storage.Entry{}, err
+	}
+	entry := before
+	entry.Disabled = true
+	entry.DisabledReason = reason
+	if err := s.store.Update(ctx, entry); err != nil {
+		return storage.Entry{}, err
+	}
+	s.record(ctx, audit.ActionDisable, shortCode, &before, &entry)
+	return entry, nil
+}
+
+// Enable puts a disabled link back into service.
+func (s *Shortener) Enable(
+	ctx context.Context,
+	shortCode string,
+) (storage.Entry, error) {
+	if shortCode == "" {
+		return storage.Entry{}, ErrEmptyCode
+	}
+	before, err := s.store.Find(ctx, shortCode)
+	if err != nil {
+		return storage.Entry{}, err
+	}
+	entry := before
+	entry.Disabled = false
+	entry.DisabledReason = ""
+	if err := s.store.Update(ctx, entry); err != nil {
+		return storage.Entry{}, err
+	}
+	s.record(ctx, audit.ActionEnable, shortCode, &before, &entry)
+	return entry, nil
+}
+
+// Export streams every link to w in the given format.
+func (s *Shortener) Export(
+	ctx context.Context,
+	w io.Writer,
+	format transfer.Format,
+) (int, error) {
+	return transfer.Export(ctx, s.store, w, format)
+}
+
+// Import loads links from r; see transfer.Import for the conflict policies.
+// Rows must pass the checks Shorten applies: codes follow the alias rules
+// and destinations the deny- and blocklists. Each link written is audited
+// and versioned as an import, and new links are announced as link.created.
+func (s *Shortener) Import(
+	ctx context.Context,
+	r io.Reader,
+	opts transfer.ImportOptions,
+) (transfer.Report, error) {
+	opts.Check = s.checkImported
+	if (s.audit == nil && s.history == nil && s.webhooks == nil) || opts.DryRun {
+		return transfer.Import(ctx, s.store, r, opts)
+	}
+	return transfer.Import(ctx, trackedImport{Store: s.store, s: s}, r, opts)
+}
+
+func (s *Shortener) checkImported(rec *transfer.Record) error {
+	if err := s.checkURL(rec.OriginalURL); err != nil {
+		return err
+	}
+	if err := checkAlias(rec.ShortCode); err != nil {
+		return err
+	}
+	if err := checkDetails(rec.Title, rec.Description); err != nil {
+		return err
+	}
+	tags, err := NormalizeTags(rec.Tags)
+	if err != nil {
+		return err
+	}
+	rec.Tags = tags
+	return nil
+}
+
+// trackedImport records the links an import creates or overwrites.
+type trackedImport struct {
+	storage.Store
+	s *Shortener
+}
+
+func (a trackedImport) Save(ctx context.Context, entry storage.Entry) error {
+	if err := a.Store.Save(ctx, entry); err != nil {
+		return err
+	}
+	a.s.record(ctx, audit.ActionImport, entry.ShortCode, nil, &entry)
+	a.s.notify(ctx, webhook.EventCreated, entry)
+	return nil
+}
+
+func (a trackedImport) Update(ctx context.Context, entry storage.Entry) error {
+	before, err := a.Store.Find(ctx, entry.ShortCode)
+	if err != nil {
+		return err
+	}
+	if err := a.Store.Update(ctx, entry); err != nil {
+		return err
+	}
+	a.s.record(ctx, audit.ActionImport, entry.ShortCode, &before, &entry)
+	return nil
+}
+
 // RandomCodeGenerator produces random alphanumeric codes of fixed length.
 type RandomCodeGenerator struct {
 	mu       sync.Mutex
-	rnd      *rand.Rand
-	alphabet []rune
+	src      io.Reader
+	alphabet []byte
 	length   int
 }
 
-var defaultAlphabet = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+var defaultAlphabet = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
 
+// NewRandomCodeGenerator draws codes from crypto/rand, so they cannot be
+// predicted from earlier ones.
 func NewRandomCodeGenerator(length int) *RandomCodeGenerator {
+	return NewCodeGenerator(length, rand.Reader)
+}
+
+// NewCodeGenerator draws codes from src, e.g. a seeded math/rand/v2 ChaCha8
+// for reproducible codes in tests.
+func NewCodeGenerator(length int, src io.Reader) *RandomCodeGenerator {
 	return &RandomCodeGenerator{
-		rnd:      rand.New(rand.NewSource(rand.Int63())),
+		src:      src,
 		alphabet: defaultAlphabet,
 		length:   length,
 	}
@@ -138,9 +597,20 @@ func NewRandomCodeGenerator(length int) *RandomCodeGenerator {
 func (g *RandomCodeGenerator) Generate(_ context.Context) (string, error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	code := make([]rune, g.length)
-	for i := range code {
-		code[i] = g.alphabet[g.rnd.Intn(len(g.alphabet))]
+	// Bytes at or above the largest multiple of the alphabet size are
+	// dropped, so every character is equally likely.
+	limit := 256 - 256%len(g.alphabet)
+	code := make([]byte, 0, g.length)
+	buf := make([]byte, g.length)
+	for len(code) < g.length {
+		if _, err := io.ReadFull(g.src, buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(code) < g.length {
+				code = append(code, g.alphabet[int(b)%len(g.alphabet)])
+			}
+		}
 	}
 	return string(code), nil
 }
@@ -2,9 +2,20 @@ package shortener
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+	"urlshortener/internal/clock/clocktest"
+	"urlshortener/internal/services/audit"
+	"urlshortener/internal/services/signing"
 	"urlshortener/internal/services/storage"
+	"urlshortener/internal/services/transfer"
+	"urlshortener/internal/services/webhook"
 )
 
 type stubGenerator struct {
@@ -26,10 +37,24 @@ func defaultTestSettings() ShortenerSettings {
 	}
 }
 
+// testNow is where every test clock starts.
+var testNow = time.Date(2030, time.March, 4, 9, 0, 0, 0, time.UTC)
+
+// newTestShortener is NewShortener on a test clock stopped at testNow.
+func newTestShortener(gen CodeGenerator, store storage.Store, settings ShortenerSettings, opts ...Option) (*Shortener, *clocktest.Clock) {
+	clk := clocktest.New(testNow)
+	return NewShortener(gen, store, settings, append([]Option{WithClock(clk)}, opts...)...), clk
+}
+
+// newSeededGenerator makes the same codes every run for a given seed.
+func newSeededGenerator(length int, seed byte) *RandomCodeGenerator {
+	return NewCodeGenerator(length, rand.NewChaCha8([32]byte{seed}))
+}
+
 func TestShortenSuccess(t *testing.T) {
 	gen := stubGenerator{code: "stub123"}
 	store := storage.NewInMemoryStore()
-	svc := NewShortener(gen, store, defaultTestSettings())
+	svc, _ := newTestShortener(gen, store, defaultTestSettings())
 
 	resp, err := svc.Shorten(context.Background(), ShortenRequest{URL: "https://example.com"})
 	if err != nil {
@@ -58,7 +83,7 @@ func TestShortenSuccess(t *testing.T) {
 }
 
 func TestShortenValidation(t *testing.T) {
-	svc := NewShortener(
+	svc, _ := newTestShortener(
 		stubGenerator{code: "unused"},
 		nil,
 		defaultTestSettings(),
@@ -76,7 +101,7 @@ func TestShortenValidation(t *testing.T) {
 }
 
 func TestShortenNoGenerator(t *testing.T) {
-	svc := NewShortener(nil, nil, defaultTestSettings())
+	svc, _ := newTestShortener(nil, nil, defaultTestSettings())
 
 	_, err := svc.Shorten(context.Background(), ShortenRequest{URL: "https://example.com"})
 	if err != ErrNoGenerator {
@@ -86,7 +111,7 @@ func TestShortenNoGenerator(t *testing.T) {
 
 func TestShortenGeneratorError(t *testing.T) {
 	want := errors.New("boom")
-	svc := NewShortener(stubGenerator{err: want}, nil, defaultTestSettings())
+	svc, _ := newTestShortener(stubGenerator{err: want}, nil, defaultTestSettings())
 
 	_, err := svc.Shorten(context.Background(), ShortenRequest{URL: "https://example.com"})
 	if err != want {
@@ -95,7 +120,7 @@ func TestShortenGeneratorError(t *testing.T) {
 }
 
 func TestRandomCodeGeneratorGenerate(t *testing.T) {
-	gen := NewRandomCodeGenerator(8)
+	gen := newSeededGenerator(8, 1)
 	ctx := context.Background()
 
 	code, err := gen.Generate(ctx)
@@ -107,24 +132,44 @@ func TestRandomCodeGeneratorGenerate(t *testing.T) {
 		t.Fatalf("expected code length 8, got %d", len(code))
 	}
 
-	allowed := make(map[rune]struct{}, len(defaultAlphabet))
-	for _, r := range defaultAlphabet {
-		allowed[r] = struct{}{}
-	}
 	for _, r := range code {
-		if _, ok := allowed[r]; !ok {
+		if !strings.ContainsRune(string(defaultAlphabet), r) {
 			t.Fatalf("code contains unexpected rune %q", r)
 		}
 	}
 }
 
+func TestCodeGeneratorSeeded(t *testing.T) {
+	ctx := context.Background()
+	a, b := newSeededGenerator(8, 7), newSeededGenerator(8, 7)
+	for range 5 {
+		codeA, errA := a.Generate(ctx)
+		codeB, errB := b.Generate(ctx)
+		if errA != nil || errB != nil {
+			t.Fatalf("Generate returned errors: %v, %v", errA, errB)
+		}
+		if codeA != codeB {
+			t.Fatalf("expected the same seed to give the same codes, got %q and %q", codeA, codeB)
+		}
+	}
+	first, _ := newSeededGenerator(8, 7).Generate(ctx)
+	if other, _ := newSeededGenerator(8, 8).Generate(ctx); other == first {
+		t.Fatalf("expected another seed to give another code, got %q twice", first)
+	}
+
+	failing := NewCodeGenerator(8, strings.NewReader("abc"))
+	if _, err := failing.Generate(ctx); err == nil {
+		t.Fatalf("expected an error when the source runs dry")
+	}
+}
+
 // ////////
 // LOOKUP
 // ////////
 func TestLookupSuccess(t *testing.T) {
 	ctx := context.Background()
 	store := storage.NewInMemoryStore()
-	svc := NewShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
+	svc, _ := newTestShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
 	_ = store.Save(ctx, storage.Entry{
 		ShortCode:   "stub123",
 		OriginalURL: "https://example.com",
@@ -145,7 +190,7 @@ func TestLookupSuccess(t *testing.T) {
 }
 func TestLookupEmptyCode(t *testing.T) {
 	want := ErrEmptyCode
-	svc := NewShortener(nil, nil, defaultTestSettings())
+	svc, _ := newTestShortener(nil, nil, defaultTestSettings())
 	ctx := context.Background()
 
 	_, err := svc.Lookup(ctx, "")
@@ -159,8 +204,8 @@ func TestLookupNotFound(t *testing.T) {
 	ctx := context.Background()
 
 	store := storage.NewInMemoryStore()
-	gen := NewRandomCodeGenerator(8)
-	svc := NewShortener(gen, store, defaultTestSettings())
+	gen := newSeededGenerator(8, 1)
+	svc, _ := newTestShortener(gen, store, defaultTestSettings())
 
 	// we need a valid code (alphabet-wise)
 	code, err := gen.Generate(ctx)
@@ -179,7 +224,7 @@ func TestLookupIncrementError(t *testing.T) {
 	// Create a stubbed store
 	stubbedStore := newFailingIncrementStore(expectedError)
 	// Create a Shortener service
-	svc := NewShortener(stubGenerator{code: "stub123"}, stubbedStore, defaultTestSettings())
+	svc, _ := newTestShortener(stubGenerator{code: "stub123"}, stubbedStore, defaultTestSettings())
 	// Save an entry in the stubbed store (ignore the error)
 	_ = stubbedStore.Save(ctx, storage.Entry{
 		ShortCode:   "stub123",
@@ -225,6 +270,34 @@ func (s *stubbedIncrementStore) IncrementHits(
 	return storage.Entry{}, s.incErr
 }
 
+func (s *stubbedIncrementStore) List(
+	ctx context.Context,
+	opts storage.ListOptions,
+) ([]storage.Entry, error) {
+	return s.store.List(ctx, opts)
+}
+
+func (s *stubbedIncrementStore) Update(
+	ctx context.Context,
+	entry storage.Entry,
+) error {
+	return s.store.Update(ctx, entry)
+}
+
+func (s *stubbedIncrementStore) Overwrite(
+	ctx context.Context,
+	entry storage.Entry,
+) error {
+	return s.store.Overwrite(ctx, entry)
+}
+
+func (s *stubbedIncrementStore) Delete(
+	ctx context.Context,
+	shortCode string,
+) error {
+	return s.store.Delete(ctx, shortCode)
+}
+
 func newFailingIncrementStore(err error) *stubbedIncrementStore {
 	return &stubbedIncrementStore{
 		store:  storage.NewInMemoryStore(),
@@ -256,7 +329,7 @@ func TestShortenRetriesOnCollision(t *testing.T) {
 		OriginalURL: "existing",
 	})
 
-	svc := NewShortener(seqGen, store, defaultTestSettings())
+	svc, _ := newTestShortener(seqGen, store, defaultTestSettings())
 
 	req := ShortenRequest{URL: "https://example.com"}
 	_, err := svc.Shorten(ctx, req)
@@ -273,7 +346,7 @@ func TestShortenTooManyCollisions(t *testing.T) {
 	store := storage.NewInMemoryStore()
 	_ = store.Save(ctx, storage.Entry{ShortCode: stubCode, OriginalURL: "existing"})
 
-	svc := NewShortener(stubGenerator{code: stubCode}, store, defaultTestSettings()) // ❌ Faulty generator
+	svc, _ := newTestShortener(stubGenerator{code: stubCode}, store, defaultTestSettings()) // ❌ Faulty generator
 	req := ShortenRequest{URL: "https://example.com"}
 	_, err := svc.Shorten(ctx, req)
 	if err != want {
@@ -288,7 +361,7 @@ func TestShortenSaveErrorBubblesUp(t *testing.T) {
 	}
 	want := stubStore.err
 
-	svc := NewShortener(stubGenerator{code: "stub123"}, stubStore, defaultTestSettings())
+	svc, _ := newTestShortener(stubGenerator{code: "stub123"}, stubStore, defaultTestSettings())
 	req := ShortenRequest{URL: "https://example.com"}
 	_, err := svc.Shorten(ctx, req)
 	if err != want {
@@ -312,3 +385,864 @@ func (otherErrorStore) Find(context.Context, string) (storage.Entry, error) {
 func (otherErrorStore) IncrementHits(context.Context, string) (storage.Entry, error) {
 	return storage.Entry{}, nil
 }
+func (otherErrorStore) List(context.Context, storage.ListOptions) ([]storage.Entry, error) {
+	return nil, nil
+}
+func (otherErrorStore) Update(context.Context, storage.Entry) error {
+	return nil
+}
+func (otherErrorStore) Overwrite(context.Context, storage.Entry) error {
+	return nil
+}
+func (otherErrorStore) Delete(context.Context, string) error {
+	return nil
+}
+
+// flakyStore fails the first `failures` saves with err, then delegates.
+type flakyStore struct {
+	*storage.InMemoryStore
+	failures int
+	err      error
+	calls    int
+}
+
+func (s *flakyStore) Save(ctx context.Context, entry storage.Entry) error {
+	s.calls++
+	if s.calls <= s.failures {
+		return s.err
+	}
+	return s.InMemoryStore.Save(ctx, entry)
+}
+
+func TestShortenRetriesOnRetryableError(t *testing.T) {
+	ctx := context.Background()
+	store := &flakyStore{
+		InMemoryStore: storage.NewInMemoryStore(),
+		failures:      2,
+		err:           fmt.Errorf("%w: connection reset", storage.ErrRetryable),
+	}
+
+	svc, _ := newTestShortener(stubGenerator{code: "stub123"}, store, defaultTestSettings())
+	resp, err := svc.Shorten(ctx, ShortenRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("expected %v after retry, got %v", nil, err)
+	}
+	if resp.ShortCode != "stub123" {
+		t.Fatalf("unexpected short code: %s", resp.ShortCode)
+	}
+}
+
+func TestShortenRetryableErrorExhaustsRetries(t *testing.T) {
+	ctx := context.Background()
+	store := &flakyStore{
+		InMemoryStore: storage.NewInMemoryStore(),
+		failures:      10,
+		err:           fmt.Errorf("%w: connection reset", storage.ErrRetryable),
+	}
+
+	svc, _ := newTestShortener(stubGenerator{code: "stub123"}, store, ShortenerSettings{CodeLength: 6, MaxRetries: 2})
+	_, err := svc.Shorten(ctx, ShortenRequest{URL: "https://example.com"})
+	if !errors.Is(err, storage.ErrRetryable) {
+		t.Fatalf("expected %v, got %v", storage.ErrRetryable, err)
+	}
+	if store.calls != 2 {
+		t.Fatalf("expected 2 save attempts, got %d", store.calls)
+	}
+}
+
+// ////////
+// ALIASES & EXPIRY
+// ////////
+func TestShortenWithAlias(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	svc, _ := newTestShortener(nil, store, defaultTestSettings()) // no generator needed
+
+	resp, err := svc.Shorten(ctx, ShortenRequest{URL: "https://example.com", Alias: "launch-q3", CreatedBy: "ops"})
+	if err != nil {
+		t.Fatalf("Shorten returned error: %v", err)
+	}
+	if resp.ShortCode != "launch-q3" {
+		t.Fatalf("expected alias as short code, got %s", resp.ShortCode)
+	}
+	stored, err := store.Find(ctx, "launch-q3")
+	if err != nil {
+		t.Fatalf("expected entry to be stored, got error: %v", err)
+	}
+	if stored.CreatedBy != "ops" {
+		t.Fatalf("expected created by ops, got %s", stored.CreatedBy)
+	}
+
+	_, err = svc.Shorten(ctx, ShortenRequest{URL: "https://other.com", Alias: "launch-q3"})
+	if !errors.Is(err, ErrAliasTaken) {
+		t.Fatalf("expected %v, got %v", ErrAliasTaken, err)
+	}
+}
+
+func TestShortenAliasValidation(t *testing.T) {
+	svc, _ := newTestShortener(nil, storage.NewInMemoryStore(), defaultTestSettings())
+	tests := map[string]error{
+		"ab":          ErrInvalidAlias,
+		"has space":   ErrInvalidAlias,
+		"slash/alias": ErrInvalidAlias,
+		"API":         ErrReservedAlias,
+		"static":      ErrReservedAlias,
+	}
+	for alias, want := range tests {
+		_, err := svc.Shorten(context.Background(), ShortenRequest{URL: "https://example.com", Alias: alias})
+		if !errors.Is(err, want) {
+			t.Fatalf("alias %q: expected %v, got %v", alias, want, err)
+		}
+	}
+}
+
+func TestShortenRejectsPastExpiry(t *testing.T) {
+	svc, clk := newTestShortener(stubGenerator{code: "stub123"}, storage.NewInMemoryStore(), defaultTestSettings())
+
+	_, err := svc.Shorten(context.Background(), ShortenRequest{
+		URL:       "https://example.com",
+		ExpiresAt: clk.Now().Add(-time.Minute),
+	})
+	if !errors.Is(err, ErrInvalidExpiry) {
+		t.Fatalf("expected %v, got %v", ErrInvalidExpiry, err)
+	}
+}
+
+func TestLookupExpired(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	svc, clk := newTestShortener(nil, store, defaultTestSettings())
+	_ = store.Save(ctx, storage.Entry{
+		ShortCode:   "old123",
+		OriginalURL: "https://example.com",
+		ExpiresAt:   clk.Now().Add(-time.Hour),
+	})
+
+	_, err := svc.Lookup(ctx, "old123")
+	if !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected %v, got %v", ErrExpired, err)
+	}
+	stored, _ := store.Find(ctx, "old123")
+	if stored.HitCount != 0 {
+		t.Fatalf("expected expired lookups not to count hits, got %d", stored.HitCount)
+	}
+}
+
+// ////////
+// STATS, LIST & DELETE
+// ////////
+func TestStatsDoesNotCountHit(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	svc, _ := newTestShortener(nil, store, defaultTestSettings())
+	_ = store.Save(ctx, storage.Entry{ShortCode: "stub123", OriginalURL: "https://example.com", HitCount: 4})
+
+	entry, err := svc.Stats(ctx, "stub123")
+	if err != nil {
+		t.Fatalf("Stats returned error: %v", err)
+	}
+	if entry.HitCount != 4 {
+		t.Fatalf("expected hit count 4, got %d", entry.HitCount)
+	}
+	entry, _ = store.Find(ctx, "stub123")
+	if entry.HitCount != 4 {
+		t.Fatalf("expected Stats not to change hit count, got %d", entry.HitCount)
+	}
+}
+
+func TestListAndDelete(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	svc, _ := newTestShortener(nil, store, defaultTestSettings())
+	_, _ = svc.Shorten(ctx, ShortenRequest{URL: "https://a.com", Alias: "aaa", CreatedBy: "alice"})
+	_, _ = svc.Shorten(ctx, ShortenRequest{URL: "https://b.com", Alias: "bbb", CreatedBy: "bob"})
+
+	entries, err := svc.List(ctx, storage.ListOptions{CreatedBy: "alice"})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ShortCode != "aaa" {
+		t.Fatalf("expected only alice's link, got %+v", entries)
+	}
+
+	if err := svc.Delete(ctx, "aaa"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if err := svc.Delete(ctx, "aaa"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+	if err := svc.Delete(ctx, ""); !errors.Is(err, ErrEmptyCode) {
+		t.Fatalf("expected %v, got %v", ErrEmptyCode, err)
+	}
+}
+
+func TestShortenDenylist(t *testing.T) {
+	settings := defaultTestSettings()
+	settings.Denylist = []string{"Evil.example", "blocked.test."}
+	svc, _ := newTestShortener(stubGenerator{code: "stub123"}, storage.NewInMemoryStore(), settings)
+
+	tests := map[string]error{
+		"https://evil.example/x":    ErrDeniedURL,
+		"https://a.b.evil.example":  ErrDeniedURL,
+		"http://BLOCKED.test:8080/": ErrDeniedURL,
+		"https://notevil.example/":  nil,
+		"https://evil.example.org/": nil,
+	}
+	for url, want := range tests {
+		_, err := svc.Shorten(context.Background(), ShortenRequest{URL: url, Alias: "alias-ok"})
+		if want == nil {
+			if errors.Is(err, ErrDeniedURL) {
+				t.Fatalf("%s: unexpectedly denied", url)
+			}
+			continue
+		}
+		if !errors.Is(err, want) {
+			t.Fatalf("%s: expected %v, got %v", url, want, err)
+		}
+	}
+
+	svc.SetDenylist(nil)
+	if _, err := svc.Shorten(context.Background(), ShortenRequest{URL: "https://evil.example/x"}); err != nil {
+		t.Fatalf("expected denylist to be cleared, got %v", err)
+	}
+}
+
+func TestShortenBlocklist(t *testing.T) {
+	settings := defaultTestSettings()
+	settings.Denylist = []string{"denied.example"}
+	svc, _ := newTestShortener(stubGenerator{code: "stub123"}, storage.NewInMemoryStore(), settings)
+	svc.SetBlocklist([]string{"phish.example"})
+
+	ctx := context.Background()
+	if _, err := svc.Shorten(ctx, ShortenRequest{URL: "https://login.phish.example/"}); !errors.Is(err, ErrBlockedURL) {
+		t.Fatalf("expected %v, got %v", ErrBlockedURL, err)
+	}
+
+	// Reloading the denylist leaves the blocklist alone, and vice versa.
+	svc.SetDenylist(nil)
+	if _, err := svc.Shorten(ctx, ShortenRequest{URL: "https://phish.example/"}); !errors.Is(err, ErrBlockedURL) {
+		t.Fatalf("expected blocklist to survive a denylist reload, got %v", err)
+	}
+	svc.SetDenylist([]string{"denied.example"})
+	svc.SetBlocklist(nil)
+	if _, err := svc.Shorten(ctx, ShortenRequest{URL: "https://denied.example/"}); !errors.Is(err, ErrDeniedURL) {
+		t.Fatalf("expected denylist to survive a blocklist reload, got %v", err)
+	}
+	if _, err := svc.Shorten(ctx, ShortenRequest{URL: "https://phish.example/"}); err != nil {
+		t.Fatalf("expected blocklist to be cleared, got %v", err)
+	}
+}
+
+func TestImportAppliesShortenRules(t *testing.T) {
+	settings := defaultTestSettings()
+	settings.Denylist = []string{"denied.example"}
+	store := storage.NewInMemoryStore()
+	svc, _ := newTestShortener(stubGenerator{code: "stub123"}, store, settings)
+	svc.SetBlocklist([]string{"phish.example"})
+
+	rows := strings.Join([]string{
+		`{"short_code":"good01","original_url":"https://example.com","tags":["Launch"]}`,
+		`{"short_code":"deny01","original_url":"https://denied.example/"}`,
+		`{"short_code":"phish1","original_url":"https://login.phish.example/"}`,
+		`{"short_code":"dashboard","original_url":"https://example.com"}`,
+		`{"short_code":"a/b","original_url":"https://example.com"}`,
+		`{"short_code":"tags01","original_url":"https://example.com","tags":["no spaces"]}`,
+	}, "\n")
+	report, err := svc.Import(context.Background(), strings.NewReader(rows), transfer.ImportOptions{Format: transfer.FormatNDJSON})
+	if err != nil {
+		t.Fatalf("Import returned error: %v", err)
+	}
+	if report.Created != 1 || report.Failed != 5 {
+		t.Fatalf("expected 1 created and 5 rejected, got %+v", report)
+	}
+	for i, want := range []error{ErrDeniedURL, ErrBlockedURL, ErrReservedAlias, ErrInvalidAlias, ErrInvalidTag} {
+		if got := report.Errors[i].Error; got != want.Error() {
+			t.Fatalf("row %d: expected %q, got %q", report.Errors[i].Row, want, got)
+		}
+	}
+	if entry, err := store.Find(context.Background(), "good01"); err != nil || !slices.Equal(entry.Tags, []string{"launch"}) {
+		t.Fatalf("expected the valid row with normalised tags, got %+v, %v", entry, err)
+	}
+}
+
+func TestDisableAndEnable(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	svc, _ := newTestShortener(nil, store, defaultTestSettings())
+	_ = store.Save(ctx, storage.Entry{ShortCode: "bad123", OriginalURL: "https://example.com"})
+
+	entry, err := svc.Disable(ctx, "bad123", "phishing")
+	if err != nil {
+		t.Fatalf("Disable returned error: %v", err)
+	}
+	if !entry.Disabled || entry.DisabledReason != "phishing" {
+		t.Fatalf("expected disabled entry, got %+v", entry)
+	}
+
+	if _, err := svc.Lookup(ctx, "bad123"); !errors.Is(err, ErrDisabled) {
+		t.Fatalf("expected %v, got %v", ErrDisabled, err)
+	}
+	stored, _ := store.Find(ctx, "bad123")
+	if stored.HitCount != 0 {
+		t.Fatalf("expected disabled lookups not to count hits, got %d", stored.HitCount)
+	}
+
+	if _, err := svc.Enable(ctx, "bad123"); err != nil {
+		t.Fatalf("Enable returned error: %v", err)
+	}
+	entry, err = svc.Lookup(ctx, "bad123")
+	if err != nil {
+		t.Fatalf("Lookup after Enable returned error: %v", err)
+	}
+	if entry.Disabled || entry.DisabledReason != "" {
+		t.Fatalf("expected enabled entry, got %+v", entry)
+	}
+
+	if _, err := svc.Disable(ctx, "missing", "spam"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+}
+
+func TestRetarget(t *testing.T) {
+	ctx := context.Background()
+	settings := defaultTestSettings()
+	settings.Denylist = []string{"evil.example"}
+	store := storage.NewInMemoryStore()
+	svc, _ := newTestShortener(nil, store, settings)
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com/old", HitCount: 3})
+
+	entry, err := svc.Retarget(ctx, "abc123", "https://example.com/new")
+	if err != nil {
+		t.Fatalf("Retarget returned error: %v", err)
+	}
+	if entry.OriginalURL != "https://example.com/new" || entry.HitCount != 3 {
+		t.Fatalf("expected new destination with hits kept, got %+v", entry)
+	}
+
+	tests := map[string]struct {
+		code, url string
+		want      error
+	}{
+		"empty code":  {"", "https://example.com", ErrEmptyCode},
+		"invalid url": {"abc123", "not a url", ErrInvalidURL},
+		"denied url":  {"abc123", "https://evil.example/", ErrDeniedURL},
+		"missing":     {"nope", "https://example.com", storage.ErrNotFound},
+	}
+	for name, tc := range tests {
+		if _, err := svc.Retarget(ctx, tc.code, tc.url); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", name, tc.want, err)
+		}
+	}
+
+	_, _ = svc.Disable(ctx, "abc123", "phishing")
+	if _, err := svc.Retarget(ctx, "abc123", "https://example.com/again"); !errors.Is(err, ErrDisabled) {
+		t.Fatalf("expected %v, got %v", ErrDisabled, err)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	svc, _ := newTestShortener(stubGenerator{code: "gen123"}, store, defaultTestSettings(), WithAuditLog(audit.New(store)))
+	ctx := audit.WithActor(context.Background(), "key:k1")
+
+	if _, err := svc.Shorten(ctx, ShortenRequest{URL: "https://example.com/a"}); err != nil {
+		t.Fatalf("Shorten returned error: %v", err)
+	}
+	if _, err := svc.Shorten(ctx, ShortenRequest{URL: "https://example.com/b", Alias: "launch"}); err != nil {
+		t.Fatalf("Shorten returned error: %v", err)
+	}
+	if _, err := svc.Retarget(ctx, "launch", "https://example.com/c"); err != nil {
+		t.Fatalf("Retarget returned error: %v", err)
+	}
+	if _, err := svc.Disable(ctx, "launch", "spam"); err != nil {
+		t.Fatalf("Disable returned error: %v", err)
+	}
+	if _, err := svc.Enable(ctx, "launch"); err != nil {
+		t.Fatalf("Enable returned error: %v", err)
+	}
+	if err := svc.Delete(ctx, "launch"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	// Failed operations change nothing and are not recorded.
+	_, _ = svc.Shorten(ctx, ShortenRequest{URL: "https://example.com/d", Alias: "gen123"})
+	_ = svc.Delete(ctx, "launch")
+
+	_, err := svc.Import(ctx, strings.NewReader(`{"short_code":"imp123","original_url":"https://example.org"}`+"\n"),
+		transfer.ImportOptions{Format: transfer.FormatNDJSON})
+	if err != nil {
+		t.Fatalf("Import returned error: %v", err)
+	}
+	_, _ = svc.Import(ctx, strings.NewReader(`{"short_code":"dry123","original_url":"https://example.org"}`+"\n"),
+		transfer.ImportOptions{Format: transfer.FormatNDJSON, DryRun: true})
+
+	events, err := store.ListAudit(ctx, storage.AuditFilter{})
+	if err != nil {
+		t.Fatalf("ListAudit returned error: %v", err)
+	}
+	var got []string
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		if e.Actor != "key:k1" {
+			t.Fatalf("expected actor key:k1, got %+v", e)
+		}
+		got = append(got, e.Action+" "+e.ShortCode)
+	}
+	want := []string{
+		"create gen123", "create launch", "retarget launch", "disable launch",
+		"enable launch", "delete launch", "import imp123",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+
+	retarget := events[len(events)-3]
+	if !strings.Contains(string(retarget.Before), "example.com/b") || !strings.Contains(string(retarget.After), "example.com/c") {
+		t.Fatalf("expected before/after destinations, got %s -> %s", retarget.Before, retarget.After)
+	}
+}
+
+func TestWebhookEvents(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	hooks := webhook.New(store, webhook.Options{Endpoints: []webhook.Endpoint{
+		{Name: "crm", URL: "http://crm.invalid", HitEvery: 2},
+	}})
+	svc, _ := newTestShortener(stubGenerator{code: "gen123"}, store, defaultTestSettings(), WithWebhooks(hooks))
+	ctx := context.Background()
+
+	if _, err := svc.Shorten(ctx, ShortenRequest{URL: "https://example.com/a"}); err != nil {
+		t.Fatalf("Shorten returned error: %v", err)
+	}
+	for range 3 {
+		if _, err := svc.Lookup(ctx, "gen123"); err != nil {
+			t.Fatalf("Lookup returned error: %v", err)
+		}
+	}
+	if err := svc.Delete(ctx, "gen123"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	_, err := svc.Import(ctx, strings.NewReader(`{"short_code":"imp123","original_url":"https://example.org"}`+"\n"),
+		transfer.ImportOptions{Format: transfer.FormatNDJSON})
+	if err != nil {
+		t.Fatalf("Import returned error: %v", err)
+	}
+
+	deliveries, err := store.ListDeliveries(ctx, storage.DeliveryListOptions{})
+	if err != nil {
+		t.Fatalf("ListDeliveries returned error: %v", err)
+	}
+	var got []string
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		var p webhook.Payload
+		if err := json.Unmarshal(deliveries[i].Payload, &p); err != nil {
+			t.Fatalf("invalid payload %s: %v", deliveries[i].Payload, err)
+		}
+		got = append(got, fmt.Sprintf("%s %s %d", p.Event, p.Link.ShortCode, p.Link.HitCount))
+	}
+	want := []string{"link.created gen123 0", "link.hits gen123 2", "link.deleted gen123 3", "link.created imp123 0"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+}
+
+func TestShortenDetails(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	svc, _ := newTestShortener(stubGenerator{code: "gen123"}, store, defaultTestSettings())
+
+	resp, err := svc.Shorten(ctx, ShortenRequest{
+		URL:   "https://example.com/q3",
+		Title: "Q3 launch",
+		Tags:  []string{" Launch/Q3 ", "marketing", "launch/q3"},
+	})
+	if err != nil {
+		t.Fatalf("Shorten returned error: %v", err)
+	}
+	if resp.Title != "Q3 launch" || strings.Join(resp.Tags, ",") != "launch/q3,marketing" {
+		t.Fatalf("expected normalized details, got %+v", resp)
+	}
+
+	tests := map[string]struct {
+		req  ShortenRequest
+		want error
+	}{
+		"long title":       {ShortenRequest{Title: strings.Repeat("é", 201)}, ErrTitleTooLong},
+		"long description": {ShortenRequest{Description: strings.Repeat("x", 1001)}, ErrDescriptionTooLong},
+		"space in tag":     {ShortenRequest{Tags: []string{"q3 launch"}}, ErrInvalidTag},
+		"empty folder":     {ShortenRequest{Tags: []string{"launch//q3"}}, ErrInvalidTag},
+		"leading slash":    {ShortenRequest{Tags: []string{"/launch"}}, ErrInvalidTag},
+		"long tag":         {ShortenRequest{Tags: []string{strings.Repeat("a", 51)}}, ErrInvalidTag},
+		"too many tags":    {ShortenRequest{Tags: strings.Split("a,b,c,d,e,f,g,h,i,j,k,l,m,n,o,p,q,r,s,t,u", ",")}, ErrTooManyTags},
+	}
+	for name, tc := range tests {
+		tc.req.URL = "https://example.com"
+		if _, err := svc.Shorten(ctx, tc.req); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", name, tc.want, err)
+		}
+	}
+}
+
+func TestEdit(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	svc, _ := newTestShortener(nil, store, defaultTestSettings(), WithAuditLog(audit.New(store)))
+	ctx := context.Background()
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com", Title: "Old", Tags: []string{"old"}})
+
+	title, tags := "New", []string{"Launch"}
+	entry, err := svc.Edit(ctx, "abc123", LinkChanges{Title: &title, Tags: &tags})
+	if err != nil {
+		t.Fatalf("Edit returned error: %v", err)
+	}
+	if entry.Title != "New" || strings.Join(entry.Tags, ",") != "launch" || entry.OriginalURL != "https://example.com" {
+		t.Fatalf("unexpected edited entry: %+v", entry)
+	}
+
+	if _, err := svc.Edit(ctx, "abc123", LinkChanges{}); !errors.Is(err, ErrNoChanges) {
+		t.Fatalf("expected %v, got %v", ErrNoChanges, err)
+	}
+	bad := []string{"no spaces"}
+	if _, err := svc.Edit(ctx, "abc123", LinkChanges{Tags: &bad}); !errors.Is(err, ErrInvalidTag) {
+		t.Fatalf("expected %v, got %v", ErrInvalidTag, err)
+	}
+
+	// Details of a disabled link can still be fixed; its destination cannot.
+	_, _ = svc.Disable(ctx, "abc123", "spam")
+	description := "Taken down"
+	if _, err := svc.Edit(ctx, "abc123", LinkChanges{Description: &description}); err != nil {
+		t.Fatalf("Edit of a disabled link's details returned error: %v", err)
+	}
+	newURL := "https://example.com/new"
+	if _, err := svc.Edit(ctx, "abc123", LinkChanges{URL: &newURL, Title: &title}); !errors.Is(err, ErrDisabled) {
+		t.Fatalf("expected %v, got %v", ErrDisabled, err)
+	}
+
+	events, _ := store.ListAudit(ctx, storage.AuditFilter{Action: audit.ActionEdit})
+	if len(events) != 2 || !strings.Contains(string(events[1].After), `"tags":["launch"]`) {
+		t.Fatalf("expected two audited edits with tags, got %+v", events)
+	}
+}
+
+func TestRetargetClearsHealthCheck(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	svc, clk := newTestShortener(nil, store, defaultTestSettings())
+	ctx := context.Background()
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com/gone"})
+	_ = store.RecordCheck(ctx, "abc123", storage.LinkCheck{At: clk.Now(), Status: 404})
+
+	title := "Still gone"
+	if entry, _ := svc.Edit(ctx, "abc123", LinkChanges{Title: &title}); !entry.Dead() {
+		t.Fatalf("expected editing details to keep the check, got %+v", entry)
+	}
+	entry, err := svc.Retarget(ctx, "abc123", "https://example.com/new")
+	if err != nil {
+		t.Fatalf("Retarget returned error: %v", err)
+	}
+	if !entry.CheckedAt.IsZero() || entry.CheckStatus != 0 || entry.Dead() {
+		t.Fatalf("expected the old destination's check to be cleared, got %+v", entry)
+	}
+	if due, _ := store.DueForCheck(ctx, clk.Now(), 0); len(due) != 1 {
+		t.Fatalf("expected the new destination to be due a check, got %+v", due)
+	}
+}
+
+func TestSignedLinks(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	unsigned, _ := newTestShortener(stubGenerator{code: "doc123"}, store, defaultTestSettings())
+	ctx := context.Background()
+
+	req := ShortenRequest{URL: "https://example.com/private", Signed: true}
+	if _, err := unsigned.Shorten(ctx, req); !errors.Is(err, signing.ErrNoKeys) {
+		t.Fatalf("expected %v without a keyring, got %v", signing.ErrNoKeys, err)
+	}
+
+	keyring := signing.NewKeyring([]signing.Key{{ID: "k1", Secret: "test-secret-0123456789"}}, time.Hour)
+	svc, clk := newTestShortener(stubGenerator{code: "doc123"}, store, defaultTestSettings(), WithKeyring(keyring))
+	resp, err := svc.Shorten(ctx, req)
+	if err != nil {
+		t.Fatalf("Shorten returned error: %v", err)
+	}
+	if !resp.Signed || resp.Signature.Sig == "" || !resp.Signature.Expires.Equal(clk.Now().Add(time.Hour)) {
+		t.Fatalf("expected a signature valid for the keyring TTL, got %+v", resp)
+	}
+
+	if entry, err := svc.Lookup(ctx, "doc123"); !errors.Is(err, ErrSignatureRequired) || entry.OriginalURL != "" {
+		t.Fatalf("expected the bare code to resolve to nothing, got %+v, %v", entry, err)
+	}
+	entry, err := svc.LookupSigned(ctx, "doc123", resp.Signature)
+	if err != nil {
+		t.Fatalf("LookupSigned returned error: %v", err)
+	}
+	if entry.OriginalURL != "https://example.com/private" || entry.HitCount != 1 {
+		t.Fatalf("expected one counted hit, got %+v", entry)
+	}
+	forged := signing.Signature{Expires: resp.Signature.Expires, Sig: "k1.forged"}
+	if _, err := svc.LookupSigned(ctx, "nope404", forged); !errors.Is(err, signing.ErrInvalidSignature) {
+		t.Fatalf("expected %v before any lookup, got %v", signing.ErrInvalidSignature, err)
+	}
+
+	// New signatures never outlive the link.
+	linkExpiry := clk.Now().Add(30 * time.Minute)
+	_ = store.Update(ctx, storage.Entry{ShortCode: "doc123", OriginalURL: "https://example.com/private", Signed: true, ExpiresAt: linkExpiry})
+	sig, err := svc.Sign(ctx, "doc123", clk.Now().Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Sign returned error: %v", err)
+	}
+	if sig.Expires.After(linkExpiry) {
+		t.Fatalf("expected the signature to end with the link at %s, got %s", linkExpiry, sig.Expires)
+	}
+	if _, err := svc.Sign(ctx, "doc123", clk.Now().Add(-time.Minute)); !errors.Is(err, ErrInvalidExpiry) {
+		t.Fatalf("expected %v, got %v", ErrInvalidExpiry, err)
+	}
+
+	_ = store.Save(ctx, storage.Entry{ShortCode: "pub123", OriginalURL: "https://example.com"})
+	if _, err := svc.Sign(ctx, "pub123", time.Time{}); !errors.Is(err, ErrNotSigned) {
+		t.Fatalf("expected %v, got %v", ErrNotSigned, err)
+	}
+}
+
+func TestHistoryAndRollback(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	svc, _ := newTestShortener(nil, store, defaultTestSettings(), WithHistory(store))
+	ctx := audit.WithActor(context.Background(), "key:k1")
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com", Title: "Old"})
+
+	if versions, err := svc.History(ctx, "abc123"); err != nil || len(versions) != 0 {
+		t.Fatalf("expected no history for an unchanged link, got %+v, %v", versions, err)
+	}
+
+	title := "New"
+	if _, err := svc.Edit(ctx, "abc123", LinkChanges{Title: &title}); err != nil {
+		t.Fatalf("Edit returned error: %v", err)
+	}
+	if _, err := svc.Retarget(ctx, "abc123", "https://example.com/new"); err != nil {
+		t.Fatalf("Retarget returned error: %v", err)
+	}
+	versions, err := svc.History(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("History returned error: %v", err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("expected baseline, edit and retarget versions, got %+v", versions)
+	}
+	baseline := versions[2]
+	if baseline.Version != 1 || baseline.Action != VersionBaseline || baseline.Actor != "" || baseline.Title != "Old" {
+		t.Fatalf("unexpected baseline version: %+v", baseline)
+	}
+	if latest := versions[0]; latest.Version != 3 || latest.Action != audit.ActionRetarget ||
+		latest.Actor != "key:k1" || latest.OriginalURL != "https://example.com/new" {
+		t.Fatalf("unexpected latest version: %+v", latest)
+	}
+
+	entry, err := svc.Rollback(ctx, "abc123", 1)
+	if err != nil {
+		t.Fatalf("Rollback returned error: %v", err)
+	}
+	if entry.OriginalURL != "https://example.com" || entry.Title != "Old" {
+		t.Fatalf("expected the baseline to be restored, got %+v", entry)
+	}
+	if versions, _ = svc.History(ctx, "abc123"); len(versions) != 4 || versions[0].Action != audit.ActionRollback {
+		t.Fatalf("expected the rollback to be recorded, got %+v", versions)
+	}
+
+	// Rolling back to the current state records nothing.
+	if _, err := svc.Rollback(ctx, "abc123", 4); err != nil {
+		t.Fatalf("Rollback to the current version returned error: %v", err)
+	}
+	if versions, _ = svc.History(ctx, "abc123"); len(versions) != 4 {
+		t.Fatalf("expected no new version, got %+v", versions)
+	}
+
+	if _, err := svc.Rollback(ctx, "abc123", 9); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+	if _, err := svc.History(ctx, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+
+	// A disabled link keeps its destination.
+	_, _ = svc.Disable(ctx, "abc123", "spam")
+	if _, err := svc.Rollback(ctx, "abc123", 3); !errors.Is(err, ErrDisabled) {
+		t.Fatalf("expected %v, got %v", ErrDisabled, err)
+	}
+}
+
+func TestRollbackRestoresExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	svc, clk := newTestShortener(nil, store, defaultTestSettings(), WithHistory(store))
+	expiresAt := clk.Now().Add(2 * time.Hour).UTC()
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com", ExpiresAt: expiresAt})
+
+	title := "New"
+	if _, err := svc.Edit(ctx, "abc123", LinkChanges{Title: &title}); err != nil {
+		t.Fatalf("Edit returned error: %v", err)
+	}
+	if v, err := store.FindVersion(ctx, "abc123", 1); err != nil || !v.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("expected the baseline to keep the expiry, got %+v, %v", v, err)
+	}
+
+	// Someone drops the expiry; the rollback brings it back.
+	entry, _ := store.Find(ctx, "abc123")
+	entry.ExpiresAt = time.Time{}
+	_ = store.Update(ctx, entry)
+	entry, err := svc.Rollback(ctx, "abc123", 1)
+	if err != nil {
+		t.Fatalf("Rollback returned error: %v", err)
+	}
+	if !entry.ExpiresAt.Equal(expiresAt) || entry.Title != "" {
+		t.Fatalf("expected the baseline's expiry and title, got %+v", entry)
+	}
+
+	// An expiry that has since passed is not brought back.
+	entry.ExpiresAt = time.Time{}
+	_ = store.Update(ctx, entry)
+	clk.Advance(3 * time.Hour)
+	if _, err := svc.Rollback(ctx, "abc123", 1); !errors.Is(err, ErrInvalidExpiry) {
+		t.Fatalf("expected %v, got %v", ErrInvalidExpiry, err)
+	}
+}
+
+func TestRollbackRestoresActivation(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	svc, clk := newTestShortener(nil, store, defaultTestSettings(), WithHistory(store))
+	launch := clk.Now().Add(time.Hour).UTC()
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com", ActivatesAt: launch})
+
+	title := "New"
+	if _, err := svc.Edit(ctx, "abc123", LinkChanges{Title: &title}); err != nil {
+		t.Fatalf("Edit returned error: %v", err)
+	}
+	if v, err := store.FindVersion(ctx, "abc123", 2); err != nil || !v.ActivatesAt.Equal(launch) {
+		t.Fatalf("expected the version to keep the go-live time, got %+v, %v", v, err)
+	}
+
+	// The link went live early by mistake; the rollback holds it back again.
+	entry, _ := store.Find(ctx, "abc123")
+	entry.ActivatesAt = time.Time{}
+	_ = store.Update(ctx, entry)
+	entry, err := svc.Rollback(ctx, "abc123", 1)
+	if err != nil {
+		t.Fatalf("Rollback returned error: %v", err)
+	}
+	if !entry.ActivatesAt.Equal(launch) {
+		t.Fatalf("expected the go-live time to be restored, got %+v", entry)
+	}
+	if _, err := svc.Lookup(ctx, "abc123"); !errors.Is(err, ErrNotActive) {
+		t.Fatalf("expected %v, got %v", ErrNotActive, err)
+	}
+}
+
+func TestActivation(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	svc, clk := newTestShortener(nil, store, defaultTestSettings())
+	launch := clk.Now().Add(30 * time.Minute)
+
+	resp, err := svc.Shorten(ctx, ShortenRequest{URL: "https://example.com/launch", Alias: "launch", ActivatesAt: launch})
+	if err != nil {
+		t.Fatalf("Shorten returned error: %v", err)
+	}
+	if !resp.ActivatesAt.Equal(launch) {
+		t.Fatalf("expected ActivatesAt %v, got %v", launch, resp.ActivatesAt)
+	}
+
+	entry, err := svc.Lookup(ctx, "launch")
+	if !errors.Is(err, ErrNotActive) {
+		t.Fatalf("expected %v, got %v", ErrNotActive, err)
+	}
+	if !entry.ActivatesAt.Equal(launch) {
+		t.Fatalf("expected the pending entry back, got %+v", entry)
+	}
+
+	clk.Set(launch.Add(-time.Second))
+	if _, err := svc.Lookup(ctx, "launch"); !errors.Is(err, ErrNotActive) {
+		t.Fatalf("expected %v a second before launch, got %v", ErrNotActive, err)
+	}
+	clk.Advance(time.Second)
+	entry, err = svc.Lookup(ctx, "launch")
+	if err != nil {
+		t.Fatalf("Lookup at launch returned error: %v", err)
+	}
+	if entry.HitCount != 1 {
+		t.Fatalf("expected only the lookup after launch to count, got %d hits", entry.HitCount)
+	}
+
+	_, err = svc.Shorten(ctx, ShortenRequest{URL: "https://example.com", ActivatesAt: clk.Now().Add(2 * time.Hour), ExpiresAt: clk.Now().Add(time.Hour)})
+	if !errors.Is(err, ErrInvalidActivation) {
+		t.Fatalf("expected %v, got %v", ErrInvalidActivation, err)
+	}
+}
+
+// benchLinks is how many links the lookup benchmarks spread their hits over.
+const benchLinks = 10000
+
+func BenchmarkShorten(b *testing.B) {
+	svc, _ := newTestShortener(newSeededGenerator(8, 1), storage.NewInMemoryStore(), defaultTestSettings())
+	ctx := context.Background()
+	req := ShortenRequest{URL: "https://example.com/some/long/path"}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := svc.Shorten(ctx, req); err != nil {
+			b.Fatalf("Shorten returned error: %v", err)
+		}
+	}
+}
+
+func BenchmarkShortenParallel(b *testing.B) {
+	svc, _ := newTestShortener(newSeededGenerator(8, 1), storage.NewInMemoryStore(), defaultTestSettings())
+	ctx := context.Background()
+	req := ShortenRequest{URL: "https://example.com/some/long/path"}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := svc.Shorten(ctx, req); err != nil {
+				b.Errorf("Shorten returned error: %v", err)
+				return
+			}
+		}
+	})
+}
+
+// newBenchLookups stores benchLinks links and returns their codes.
+func newBenchLookups(b *testing.B) (*Shortener, []string) {
+	b.Helper()
+	store := storage.NewInMemoryStore()
+	svc, _ := newTestShortener(nil, store, defaultTestSettings())
+	codes := make([]string, benchLinks)
+	for i := range codes {
+		codes[i] = fmt.Sprintf("b%05d", i)
+		if err := store.Save(context.Background(), storage.Entry{ShortCode: codes[i], OriginalURL: "https://example.com"}); err != nil {
+			b.Fatalf("Save returned error: %v", err)
+		}
+	}
+	return svc, codes
+}
+
+func BenchmarkLookup(b *testing.B) {
+	svc, codes := newBenchLookups(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		if _, err := svc.Lookup(ctx, codes[i%len(codes)]); err != nil {
+			b.Fatalf("Lookup returned error: %v", err)
+		}
+		i++
+	}
+}
+
+func BenchmarkLookupParallel(b *testing.B) {
+	svc, codes := newBenchLookups(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := rand.IntN(len(codes))
+		for pb.Next() {
+			if _, err := svc.Lookup(ctx, codes[i%len(codes)]); err != nil {
+				b.Errorf("Lookup returned error: %v", err)
+				return
+			}
+			i++
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package shortener
+
+import (
+	"context"
+	"time"
+
+	"urlshortener/internal/services/signing"
+	"urlshortener/internal/services/storage"
+)
+
+// LookupSigned is Lookup for a URL carrying sig. The signature is checked
+// before the store is touched, so forged or expired URLs cost nothing and
+// reveal nothing; a valid one resolves signed and unsigned links alike.
+func (s *Shortener) LookupSigned(
+	ctx context.Context,
+	shortCode string,
+	sig signing.Signature,
+) (storage.Entry, error) {
+	if err := s.VerifySignature(shortCode, sig); err != nil {
+		return storage.Entry{}, err
+	}
+	return s.lookup(ctx, shortCode, true)
+}
+
+// VerifySignature checks sig for shortCode without looking the link up. It
+// fails with signing.ErrInvalidSignature or signing.ErrExpired.
+func (s *Shortener) VerifySignature(shortCode string, sig signing.Signature) error {
+	return s.keys.Verify(shortCode, sig, s.clock.Now())
+}
+
+// Sign makes a new signature for a signed link, e.g. to share it again
+// after the first one expired. It expires at expires, or after the
+// keyring's TTL when zero, but never after the link itself.
+func (s *Shortener) Sign(
+	ctx context.Context,
+	shortCode string,
+	expires time.Time,
+) (signing.Signature, error) {
+	if shortCode == "" {
+		return signing.Signature{}, ErrEmptyCode
+	}
+	entry, err := s.store.Find(ctx, shortCode)
+	if err != nil {
+		return signing.Signature{}, err
+	}
+	return s.SignEntry(entry, expires)
+}
+
+// SignEntry is Sign for an entry the caller already has.
+func (s *Shortener) SignEntry(entry storage.Entry, expires time.Time) (signing.Signature, error) {
+	if !entry.Signed {
+		return signing.Signature{}, ErrNotSigned
+	}
+	if entry.Disabled {
+		return signing.Signature{}, ErrDisabled
+	}
+	return s.sign(entry, expires)
+}
+
+func (s *Shortener) sign(entry storage.Entry, expires time.Time) (signing.Signature, error) {
+	if !s.keys.Enabled() {
+		return signing.Signature{}, signing.ErrNoKeys
+	}
+	now := s.clock.Now()
+	if expires.IsZero() {
+		expires = now.Add(s.keys.TTL())
+	}
+	if !entry.ExpiresAt.IsZero() && expires.After(entry.ExpiresAt) {
+		expires = entry.ExpiresAt
+	}
+	if !expires.After(now) {
+		return signing.Signature{}, ErrInvalidExpiry
+	}
+	return s.keys.Sign(entry.ShortCode, expires)
+}
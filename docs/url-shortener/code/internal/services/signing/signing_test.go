@@ -0,0 +1,98 @@
+package signing
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	ring := NewKeyring([]Key{{ID: "k1", Secret: "first-secret-0123456789"}}, 0)
+	if ring.TTL() != 7*24*time.Hour {
+		t.Fatalf("expected the default TTL, got %s", ring.TTL())
+	}
+
+	sig, err := ring.Sign("doc123", now.Add(time.Hour+500*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Sign returned error: %v", err)
+	}
+	if !strings.HasPrefix(sig.Sig, "k1.") || !sig.Expires.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected signature: %+v", sig)
+	}
+
+	q, _ := url.ParseQuery(sig.Query())
+	parsed, ok := FromQuery(q)
+	if !ok || parsed != sig {
+		t.Fatalf("expected the query to round-trip, got %+v", parsed)
+	}
+	if err := ring.Verify("doc123", parsed, now); err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		code string
+		sig  Signature
+		want error
+	}{
+		"other code":      {"doc124", sig, ErrInvalidSignature},
+		"extended expiry": {"doc123", Signature{Expires: sig.Expires.Add(time.Hour), Sig: sig.Sig}, ErrInvalidSignature},
+		"unknown key":     {"doc123", Signature{Expires: sig.Expires, Sig: "k9" + strings.TrimPrefix(sig.Sig, "k1")}, ErrInvalidSignature},
+		"no key id":       {"doc123", Signature{Expires: sig.Expires, Sig: strings.TrimPrefix(sig.Sig, "k1.")}, ErrInvalidSignature},
+		"no expiry":       {"doc123", Signature{Sig: sig.Sig}, ErrInvalidSignature},
+	} {
+		if err := ring.Verify(tc.code, tc.sig, now); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", name, tc.want, err)
+		}
+	}
+	if err := ring.Verify("doc123", sig, now.Add(time.Hour)); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected %v, got %v", ErrExpired, err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	now := time.Now()
+	oldKey := Key{ID: "2025", Secret: "old-secret-0123456789"}
+	newKey := Key{ID: "2026", Secret: "new-secret-0123456789"}
+	ring := NewKeyring([]Key{oldKey}, time.Hour)
+	old, _ := ring.Sign("doc123", now.Add(time.Hour))
+
+	// The new key signs; the old one still verifies.
+	ring.Set([]Key{newKey, oldKey}, time.Hour)
+	fresh, _ := ring.Sign("doc123", now.Add(time.Hour))
+	if !strings.HasPrefix(fresh.Sig, "2026.") {
+		t.Fatalf("expected the first key to sign, got %+v", fresh)
+	}
+	for _, sig := range []Signature{old, fresh} {
+		if err := ring.Verify("doc123", sig, now); err != nil {
+			t.Fatalf("Verify(%s) returned error: %v", sig.Sig, err)
+		}
+	}
+
+	// Dropping the old key revokes what it signed.
+	ring.Set([]Key{newKey}, time.Hour)
+	if err := ring.Verify("doc123", old, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected %v, got %v", ErrInvalidSignature, err)
+	}
+}
+
+func TestEmptyKeyring(t *testing.T) {
+	var nilRing *Keyring
+	ring := NewKeyring(nil, 0)
+	for _, r := range []*Keyring{nilRing, ring} {
+		if r.Enabled() {
+			t.Fatal("expected a keyring without keys to be disabled")
+		}
+		if err := r.Verify("doc123", Signature{Expires: time.Now().Add(time.Hour), Sig: "k1.x"}, time.Now()); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("expected %v, got %v", ErrInvalidSignature, err)
+		}
+	}
+	if _, err := ring.Sign("doc123", time.Now().Add(time.Hour)); !errors.Is(err, ErrNoKeys) {
+		t.Fatalf("expected %v, got %v", ErrNoKeys, err)
+	}
+	if _, ok := FromQuery(url.Values{"exp": {"1"}}); ok {
+		t.Fatal("expected no signature without sig")
+	}
+}
@@ -0,0 +1,64 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"urlshortener/internal/services/storage"
+
+	"go.etcd.io/bbolt"
+)
+
+type auditRecord struct {
+	ID        string          `json:"id"`
+	At        time.Time       `json:"at"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	ShortCode string          `json:"short_code"`
+	RequestID string          `json:"request_id,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+}
+
+func (s *Store) AppendAudit(ctx context.Context, event storage.AuditEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if event.At.IsZero() {
+		event.At = time.Now().UTC()
+	}
+	raw, err := json.Marshal(auditRecord(event))
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(auditBucket)
+		if bucket.Get([]byte(event.ID)) != nil {
+			return storage.ErrConflict
+		}
+		return bucket.Put([]byte(event.ID), raw)
+	})
+}
+
+func (s *Store) ListAudit(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var events []storage.AuditEvent
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(auditBucket).ForEach(func(_, raw []byte) error {
+			var rec auditRecord
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				return err
+			}
+			events = append(events, storage.AuditEvent(rec))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return storage.ApplyAuditFilter(events, filter), nil
+}
@@ -0,0 +1,70 @@
+package bolt
+
+import (
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+var (
+	// urlsBucket holds every entry, keyed by short code.
+	urlsBucket = []byte("urls")
+	// versionsBucket holds a bucket per short code with the link's versions,
+	// keyed by big-endian version number.
+	versionsBucket = []byte("link_versions")
+	// apiKeysBucket holds API keys by ID; apiKeyHashesBucket maps hash -> ID.
+	apiKeysBucket      = []byte("api_keys")
+	apiKeyHashesBucket = []byte("api_key_hashes")
+	// sessionsBucket holds dashboard sessions by token hash.
+	sessionsBucket = []byte("sessions")
+	// reportsBucket holds abuse reports by ID.
+	reportsBucket = []byte("reports")
+	// auditBucket holds the audit trail by event ID, which sorts by time.
+	auditBucket = []byte("audit")
+	// outboxBucket holds webhook deliveries by ID.
+	outboxBucket = []byte("webhook_outbox")
+	// idempotencyBucket holds idempotency records by owner and key, joined
+	// by a NUL byte.
+	idempotencyBucket = []byte("idempotency")
+)
+
+// maxBatchDelay bounds the latency batching adds to a redirect.
+const maxBatchDelay = 2 * time.Millisecond
+
+type BoltConfig struct {
+	Path    string
+	Timeout time.Duration // how long to wait for the file lock
+}
+
+// Open opens (or creates) the database file and makes sure the buckets exist.
+func Open(cfg BoltConfig) (*bbolt.DB, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+
+	db, err := bbolt.Open(cfg.Path, 0o600, &bbolt.Options{Timeout: timeout})
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	// Hit counts are batched (see IncrementHits); a lone redirect waits at
+	// most this long for others to share its commit.
+	db.MaxBatchDelay = maxBatchDelay
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{urlsBucket, versionsBucket, apiKeysBucket, apiKeyHashesBucket, sessionsBucket, reportsBucket, auditBucket, outboxBucket, idempotencyBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("create buckets: %w", err)
+	}
+
+	return db, nil
+}
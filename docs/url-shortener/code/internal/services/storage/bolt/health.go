@@ -0,0 +1,36 @@
+package bolt
+
+import (
+	"context"
+	"time"
+	"urlshortener/internal/services/storage"
+
+	"go.etcd.io/bbolt"
+)
+
+func (s *Store) DueForCheck(ctx context.Context, before time.Time, limit int) ([]storage.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	entries, err := s.all()
+	if err != nil {
+		return nil, err
+	}
+	return storage.SelectDueForCheck(entries, before, limit), nil
+}
+
+func (s *Store) RecordCheck(ctx context.Context, shortCode string, check storage.LinkCheck) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(urlsBucket)
+		entry, err := get(bucket, shortCode)
+		if err != nil {
+			return err
+		}
+		entry.CheckedAt, entry.CheckStatus, entry.CheckError = check.At, check.Status, check.Error
+		return put(bucket, entry)
+	})
+}
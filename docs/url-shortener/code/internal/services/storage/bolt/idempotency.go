@@ -0,0 +1,159 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+	"urlshortener/internal/services/storage"
+
+	"go.etcd.io/bbolt"
+)
+
+type idempotencyRecord struct {
+	Owner       string    `json:"owner"`
+	Key         string    `json:"key"`
+	RequestHash string    `json:"request_hash"`
+	Claim       string    `json:"claim,omitempty"`
+	Status      int       `json:"status,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	Body        []byte    `json:"body,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func idempotencyID(owner, key string) []byte {
+	return []byte(owner + "\x00" + key)
+}
+
+func (s *Store) ReserveIdempotencyKey(ctx context.Context, rec storage.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(idempotencyBucket)
+		old, err := getIdempotencyRecord(bucket, rec.Owner, rec.Key)
+		if err == nil && old.ExpiresAt.After(rec.CreatedAt) {
+			return storage.ErrConflict
+		}
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		return putIdempotencyRecord(bucket, rec)
+	})
+}
+
+func (s *Store) FindIdempotencyKey(ctx context.Context, owner, key string) (storage.IdempotencyRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return storage.IdempotencyRecord{}, err
+	}
+
+	var rec storage.IdempotencyRecord
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		rec, err = getIdempotencyRecord(tx.Bucket(idempotencyBucket), owner, key)
+		return err
+	})
+	if err != nil {
+		return storage.IdempotencyRecord{}, err
+	}
+	return rec, nil
+}
+
+func (s *Store) CompleteIdempotencyKey(ctx context.Context, rec storage.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(idempotencyBucket)
+		old, err := getIdempotencyRecord(bucket, rec.Owner, rec.Key)
+		if err != nil {
+			return err
+		}
+		if old.Claim != rec.Claim {
+			return storage.ErrNotFound
+		}
+		old.Status, old.ContentType, old.Body, old.ExpiresAt = rec.Status, rec.ContentType, rec.Body, rec.ExpiresAt
+		return putIdempotencyRecord(bucket, old)
+	})
+}
+
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, owner, key, claim string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(idempotencyBucket)
+		old, err := getIdempotencyRecord(bucket, owner, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if old.Claim != claim {
+			return nil
+		}
+		return bucket.Delete(idempotencyID(owner, key))
+	})
+}
+
+func (s *Store) PurgeIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(idempotencyBucket)
+		var expired [][]byte
+		err := bucket.ForEach(func(k, raw []byte) error {
+			var rec idempotencyRecord
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				return err
+			}
+			if !rec.ExpiresAt.After(now) {
+				// Keys can't be deleted while ForEach walks the bucket.
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		n = len(expired)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func getIdempotencyRecord(bucket *bbolt.Bucket, owner, key string) (storage.IdempotencyRecord, error) {
+	raw := bucket.Get(idempotencyID(owner, key))
+	if raw == nil {
+		return storage.IdempotencyRecord{}, storage.ErrNotFound
+	}
+	var rec idempotencyRecord
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return storage.IdempotencyRecord{}, err
+	}
+	return storage.IdempotencyRecord(rec), nil
+}
+
+func putIdempotencyRecord(bucket *bbolt.Bucket, rec storage.IdempotencyRecord) error {
+	raw, err := json.Marshal(idempotencyRecord(rec))
+	if err != nil {
+		return err
+	}
+	return bucket.Put(idempotencyID(rec.Owner, rec.Key), raw)
+}
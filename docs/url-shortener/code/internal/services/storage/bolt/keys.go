@@ -0,0 +1,227 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"urlshortener/internal/services/storage"
+
+	"go.etcd.io/bbolt"
+)
+
+type keyRecord struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+	RevokedAt time.Time `json:"revoked_at,omitzero"`
+}
+
+func (s *Store) SaveAPIKey(ctx context.Context, key storage.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now().UTC()
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		keys := tx.Bucket(apiKeysBucket)
+		hashes := tx.Bucket(apiKeyHashesBucket)
+		if keys.Get([]byte(key.ID)) != nil || hashes.Get([]byte(key.Hash)) != nil {
+			return storage.ErrConflict
+		}
+		if err := putKey(keys, key); err != nil {
+			return err
+		}
+		return hashes.Put([]byte(key.Hash), []byte(key.ID))
+	})
+}
+
+func (s *Store) FindAPIKey(ctx context.Context, id string) (storage.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return storage.APIKey{}, err
+	}
+
+	var key storage.APIKey
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		key, err = getKey(tx.Bucket(apiKeysBucket), id)
+		return err
+	})
+	if err != nil {
+		return storage.APIKey{}, err
+	}
+	return key, nil
+}
+
+func (s *Store) FindAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return storage.APIKey{}, err
+	}
+
+	var key storage.APIKey
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		id := tx.Bucket(apiKeyHashesBucket).Get([]byte(hash))
+		if id == nil {
+			return storage.ErrNotFound
+		}
+		var err error
+		key, err = getKey(tx.Bucket(apiKeysBucket), string(id))
+		return err
+	})
+	if err != nil {
+		return storage.APIKey{}, err
+	}
+	return key, nil
+}
+
+func (s *Store) ListAPIKeys(ctx context.Context) ([]storage.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	keys := []storage.APIKey{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(apiKeysBucket).ForEach(func(_, raw []byte) error {
+			var rec keyRecord
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				return err
+			}
+			keys = append(keys, storage.APIKey(rec))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	storage.SortAPIKeys(keys)
+	return keys, nil
+}
+
+func (s *Store) RevokeAPIKey(ctx context.Context, id string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(apiKeysBucket)
+		key, err := getKey(bucket, id)
+		if err != nil {
+			return err
+		}
+		if key.Revoked() {
+			return nil
+		}
+		key.RevokedAt = at
+		return putKey(bucket, key)
+	})
+}
+
+type sessionRecord struct {
+	Hash      string    `json:"hash"`
+	KeyID     string    `json:"key_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (s *Store) SaveSession(ctx context.Context, session storage.Session) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(sessionRecord(session))
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(sessionsBucket)
+		if bucket.Get([]byte(session.Hash)) != nil {
+			return storage.ErrConflict
+		}
+		return bucket.Put([]byte(session.Hash), raw)
+	})
+}
+
+func (s *Store) FindSession(ctx context.Context, hash string) (storage.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return storage.Session{}, err
+	}
+
+	var rec sessionRecord
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		raw := tx.Bucket(sessionsBucket).Get([]byte(hash))
+		if raw == nil {
+			return storage.ErrNotFound
+		}
+		return json.Unmarshal(raw, &rec)
+	})
+	if err != nil {
+		return storage.Session{}, err
+	}
+	return storage.Session(rec), nil
+}
+
+func (s *Store) DeleteSession(ctx context.Context, hash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Delete([]byte(hash))
+	})
+}
+
+func (s *Store) PurgeSessions(ctx context.Context, now time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(sessionsBucket)
+		var expired [][]byte
+		err := bucket.ForEach(func(k, raw []byte) error {
+			var rec sessionRecord
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				return err
+			}
+			if !rec.ExpiresAt.After(now) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// Deleting inside ForEach would skip keys.
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		n = len(expired)
+		return nil
+	})
+	return n, err
+}
+
+func getKey(bucket *bbolt.Bucket, id string) (storage.APIKey, error) {
+	raw := bucket.Get([]byte(id))
+	if raw == nil {
+		return storage.APIKey{}, storage.ErrNotFound
+	}
+	var rec keyRecord
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return storage.APIKey{}, err
+	}
+	return storage.APIKey(rec), nil
+}
+
+func putKey(bucket *bbolt.Bucket, key storage.APIKey) error {
+	raw, err := json.Marshal(keyRecord(key))
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(key.ID), raw)
+}
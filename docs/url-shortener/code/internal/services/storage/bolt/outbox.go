@@ -0,0 +1,152 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"urlshortener/internal/services/storage"
+
+	"go.etcd.io/bbolt"
+)
+
+type deliveryRecord struct {
+	ID            string    `json:"id"`
+	Endpoint      string    `json:"endpoint"`
+	Event         string    `json:"event"`
+	Payload       []byte    `json:"payload"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error,omitempty"`
+	LastStatus    int       `json:"last_status,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	DeliveredAt   time.Time `json:"delivered_at,omitzero"`
+}
+
+func (s *Store) EnqueueDelivery(ctx context.Context, d storage.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now().UTC()
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(outboxBucket)
+		if bucket.Get([]byte(d.ID)) != nil {
+			return storage.ErrConflict
+		}
+		return putDelivery(bucket, d)
+	})
+}
+
+func (s *Store) FindDelivery(ctx context.Context, id string) (storage.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return storage.WebhookDelivery{}, err
+	}
+
+	var d storage.WebhookDelivery
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		raw := tx.Bucket(outboxBucket).Get([]byte(id))
+		if raw == nil {
+			return storage.ErrNotFound
+		}
+		var rec deliveryRecord
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			return err
+		}
+		d = storage.WebhookDelivery(rec)
+		return nil
+	})
+	if err != nil {
+		return storage.WebhookDelivery{}, err
+	}
+	return d, nil
+}
+
+func (s *Store) ClaimDueDeliveries(ctx context.Context, now, until time.Time, limit int) ([]storage.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var due []storage.WebhookDelivery
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(outboxBucket)
+		deliveries, err := readDeliveries(bucket)
+		if err != nil {
+			return err
+		}
+		due = storage.SelectDueDeliveries(deliveries, now, limit)
+		for i := range due {
+			due[i].NextAttemptAt = until
+			if err := putDelivery(bucket, due[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+func (s *Store) ListDeliveries(ctx context.Context, opts storage.DeliveryListOptions) ([]storage.WebhookDelivery, error) {
+	deliveries, err := s.allDeliveries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return storage.ApplyDeliveryListOptions(deliveries, opts), nil
+}
+
+func (s *Store) UpdateDelivery(ctx context.Context, d storage.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(outboxBucket)
+		if bucket.Get([]byte(d.ID)) == nil {
+			return storage.ErrNotFound
+		}
+		return putDelivery(bucket, d)
+	})
+}
+
+func (s *Store) allDeliveries(ctx context.Context) ([]storage.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var deliveries []storage.WebhookDelivery
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		deliveries, err = readDeliveries(tx.Bucket(outboxBucket))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func readDeliveries(bucket *bbolt.Bucket) ([]storage.WebhookDelivery, error) {
+	var deliveries []storage.WebhookDelivery
+	err := bucket.ForEach(func(_, raw []byte) error {
+		var rec deliveryRecord
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			return err
+		}
+		deliveries = append(deliveries, storage.WebhookDelivery(rec))
+		return nil
+	})
+	return deliveries, err
+}
+
+func putDelivery(bucket *bbolt.Bucket, d storage.WebhookDelivery) error {
+	raw, err := json.Marshal(deliveryRecord(d))
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(d.ID), raw)
+}
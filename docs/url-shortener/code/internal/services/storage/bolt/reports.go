@@ -0,0 +1,119 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"urlshortener/internal/services/storage"
+
+	"go.etcd.io/bbolt"
+)
+
+type reportRecord struct {
+	ID         string    `json:"id"`
+	ShortCode  string    `json:"short_code"`
+	Reason     string    `json:"reason"`
+	Details    string    `json:"details,omitempty"`
+	Reporter   string    `json:"reporter,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	ResolvedAt time.Time `json:"resolved_at,omitzero"`
+	ResolvedBy string    `json:"resolved_by,omitempty"`
+	Resolution string    `json:"resolution,omitempty"`
+}
+
+func (s *Store) SaveReport(ctx context.Context, report storage.Report) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if report.CreatedAt.IsZero() {
+		report.CreatedAt = time.Now().UTC()
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(reportsBucket)
+		if bucket.Get([]byte(report.ID)) != nil {
+			return storage.ErrConflict
+		}
+		return putReport(bucket, report)
+	})
+}
+
+func (s *Store) FindReport(ctx context.Context, id string) (storage.Report, error) {
+	if err := ctx.Err(); err != nil {
+		return storage.Report{}, err
+	}
+
+	var report storage.Report
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		report, err = getReport(tx.Bucket(reportsBucket), id)
+		return err
+	})
+	if err != nil {
+		return storage.Report{}, err
+	}
+	return report, nil
+}
+
+func (s *Store) ListReports(ctx context.Context, opts storage.ReportListOptions) ([]storage.Report, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var reports []storage.Report
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(reportsBucket).ForEach(func(_, raw []byte) error {
+			var rec reportRecord
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				return err
+			}
+			reports = append(reports, storage.Report(rec))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return storage.ApplyReportListOptions(reports, opts), nil
+}
+
+func (s *Store) ResolveReport(ctx context.Context, id, resolution, by string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(reportsBucket)
+		report, err := getReport(bucket, id)
+		if err != nil {
+			return err
+		}
+		if !report.Open() {
+			return nil
+		}
+		report.ResolvedAt = at
+		report.ResolvedBy = by
+		report.Resolution = resolution
+		return putReport(bucket, report)
+	})
+}
+
+func getReport(bucket *bbolt.Bucket, id string) (storage.Report, error) {
+	raw := bucket.Get([]byte(id))
+	if raw == nil {
+		return storage.Report{}, storage.ErrNotFound
+	}
+	var rec reportRecord
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return storage.Report{}, err
+	}
+	return storage.Report(rec), nil
+}
+
+func putReport(bucket *bbolt.Bucket, report storage.Report) error {
+	raw, err := json.Marshal(reportRecord(report))
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(report.ID), raw)
+}
@@ -0,0 +1,264 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"urlshortener/internal/services/storage"
+
+	"go.etcd.io/bbolt"
+)
+
+// Store keeps entries in a single bbolt file, so it needs no external service.
+type Store struct {
+	db *bbolt.DB
+}
+
+func NewStore(db *bbolt.DB) *Store {
+	return &Store{db: db}
+}
+
+// record is the on-disk representation of a storage.Entry.
+type record struct {
+	ShortCode   string    `json:"short_code"`
+	OriginalURL string    `json:"original_url"`
+	CreatedAt   time.Time `json:"created_at"`
+	CreatedBy   string    `json:"created_by"`
+	HitCount    int64     `json:"hit_count"`
+	ExpiresAt   time.Time `json:"expires_at,omitzero"`
+	ActivatesAt time.Time `json:"activates_at,omitzero"`
+	Signed      bool      `json:"signed,omitempty"`
+
+	Disabled       bool   `json:"disabled,omitempty"`
+	DisabledReason string `json:"disabled_reason,omitempty"`
+
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+
+	CheckedAt   time.Time `json:"checked_at,omitzero"`
+	CheckStatus int       `json:"check_status,omitempty"`
+	CheckError  string    `json:"check_error,omitempty"`
+}
+
+func toRecord(entry storage.Entry) record {
+	return record{
+		ShortCode:   entry.ShortCode,
+		OriginalURL: entry.OriginalURL,
+		CreatedAt:   entry.CreatedAt,
+		CreatedBy:   entry.CreatedBy,
+		HitCount:    entry.HitCount,
+		ExpiresAt:   entry.ExpiresAt,
+		ActivatesAt: entry.ActivatesAt,
+		Signed:      entry.Signed,
+
+		Disabled:       entry.Disabled,
+		DisabledReason: entry.DisabledReason,
+
+		Title:       entry.Title,
+		Description: entry.Description,
+		Tags:        entry.Tags,
+
+		CheckedAt:   entry.CheckedAt,
+		CheckStatus: entry.CheckStatus,
+		CheckError:  entry.CheckError,
+	}
+}
+
+func (r record) toEntry() storage.Entry {
+	return storage.Entry{
+		ShortCode:   r.ShortCode,
+		OriginalURL: r.OriginalURL,
+		CreatedAt:   r.CreatedAt,
+		CreatedBy:   r.CreatedBy,
+		HitCount:    r.HitCount,
+		ExpiresAt:   r.ExpiresAt,
+		ActivatesAt: r.ActivatesAt,
+		Signed:      r.Signed,
+
+		Disabled:       r.Disabled,
+		DisabledReason: r.DisabledReason,
+
+		Title:       r.Title,
+		Description: r.Description,
+		Tags:        r.Tags,
+
+		CheckedAt:   r.CheckedAt,
+		CheckStatus: r.CheckStatus,
+		CheckError:  r.CheckError,
+	}
+}
+
+func (s *Store) Save(ctx context.Context, entry storage.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(urlsBucket)
+		key := []byte(entry.ShortCode)
+		if bucket.Get(key) != nil {
+			return storage.ErrConflict
+		}
+		return put(bucket, entry)
+	})
+}
+
+func (s *Store) Find(ctx context.Context, shortCode string) (storage.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return storage.Entry{}, err
+	}
+
+	var entry storage.Entry
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		entry, err = get(tx.Bucket(urlsBucket), shortCode)
+		return err
+	})
+	if err != nil {
+		return storage.Entry{}, err
+	}
+	return entry, nil
+}
+
+func (s *Store) IncrementHits(ctx context.Context, shortCode string) (storage.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return storage.Entry{}, err
+	}
+
+	// Every redirect lands here, so concurrent hits share one transaction
+	// and one fsync instead of queueing on a disk sync each. A batch with a
+	// failing call is re-run one call at a time, which fn allows: it only
+	// keeps what the committed run read.
+	var entry storage.Entry
+	err := s.db.Batch(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(urlsBucket)
+		var err error
+		entry, err = get(bucket, shortCode)
+		if err != nil {
+			return err
+		}
+		entry.HitCount++
+		return put(bucket, entry)
+	})
+	if err != nil {
+		return storage.Entry{}, err
+	}
+	return entry, nil
+}
+
+func (s *Store) List(ctx context.Context, opts storage.ListOptions) ([]storage.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.all()
+	if err != nil {
+		return nil, err
+	}
+	return storage.ApplyListOptions(entries, opts), nil
+}
+
+func (s *Store) ListExpired(ctx context.Context, since, until time.Time) ([]storage.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.all()
+	if err != nil {
+		return nil, err
+	}
+	return storage.SelectExpired(entries, since, until), nil
+}
+
+func (s *Store) Update(ctx context.Context, entry storage.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(urlsBucket)
+		current, err := get(bucket, entry.ShortCode)
+		if err != nil {
+			return err
+		}
+		return put(bucket, storage.ApplyUpdate(current, entry))
+	})
+}
+
+func (s *Store) Overwrite(ctx context.Context, entry storage.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(urlsBucket)
+		current, err := get(bucket, entry.ShortCode)
+		if err != nil {
+			return err
+		}
+		if entry.CreatedAt.IsZero() {
+			entry.CreatedAt = current.CreatedAt
+		}
+		return put(bucket, entry)
+	})
+}
+
+func (s *Store) Delete(ctx context.Context, shortCode string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(urlsBucket)
+		key := []byte(shortCode)
+		if bucket.Get(key) == nil {
+			return storage.ErrNotFound
+		}
+		if versions := tx.Bucket(versionsBucket); versions.Bucket(key) != nil {
+			if err := versions.DeleteBucket(key); err != nil {
+				return err
+			}
+		}
+		return bucket.Delete(key)
+	})
+}
+
+// all loads every entry; the bucket has no secondary indexes to narrow it.
+func (s *Store) all() ([]storage.Entry, error) {
+	var entries []storage.Entry
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(urlsBucket).ForEach(func(_, raw []byte) error {
+			var rec record
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				return err
+			}
+			entries = append(entries, rec.toEntry())
+			return nil
+		})
+	})
+	return entries, err
+}
+
+func get(bucket *bbolt.Bucket, shortCode string) (storage.Entry, error) {
+	raw := bucket.Get([]byte(shortCode))
+	if raw == nil {
+		return storage.Entry{}, storage.ErrNotFound
+	}
+	var rec record
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return storage.Entry{}, err
+	}
+	return rec.toEntry(), nil
+}
+
+func put(bucket *bbolt.Bucket, entry storage.Entry) error {
+	raw, err := json.Marshal(toRecord(entry))
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(entry.ShortCode), raw)
+}
@@ -0,0 +1,99 @@
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"urlshortener/internal/services/storage"
+	"urlshortener/internal/services/storage/storagetest"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := Open(BoltConfig{Path: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return NewStore(db)
+}
+
+func TestStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		return newTestStore(t)
+	})
+}
+
+func TestKeyStore(t *testing.T) {
+	storagetest.RunKeyStore(t, func(t *testing.T) storage.KeyStore {
+		return newTestStore(t)
+	})
+}
+
+func TestReportStore(t *testing.T) {
+	storagetest.RunReportStore(t, func(t *testing.T) storage.ReportStore {
+		return newTestStore(t)
+	})
+}
+
+func TestAuditStore(t *testing.T) {
+	storagetest.RunAuditStore(t, func(t *testing.T) storage.AuditStore {
+		return newTestStore(t)
+	})
+}
+
+func TestOutboxStore(t *testing.T) {
+	storagetest.RunOutboxStore(t, func(t *testing.T) storage.OutboxStore {
+		return newTestStore(t)
+	})
+}
+
+func TestVersionStore(t *testing.T) {
+	storagetest.RunVersionStore(t, func(t *testing.T) storagetest.VersionStore {
+		return newTestStore(t)
+	})
+}
+
+func TestIdempotencyStore(t *testing.T) {
+	storagetest.RunIdempotencyStore(t, func(t *testing.T) storage.IdempotencyStore {
+		return newTestStore(t)
+	})
+}
+
+func TestHealthStore(t *testing.T) {
+	storagetest.RunHealthStore(t, func(t *testing.T) storagetest.HealthStore {
+		return newTestStore(t)
+	})
+}
+
+func TestExpiryStore(t *testing.T) {
+	storagetest.RunExpiryStore(t, func(t *testing.T) storagetest.ExpiryStore {
+		return newTestStore(t)
+	})
+}
+
+func TestPersistsAcrossReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := Open(BoltConfig{Path: path})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	_ = NewStore(db).Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com"})
+	_ = db.Close()
+
+	db, err = Open(BoltConfig{Path: path})
+	if err != nil {
+		t.Fatalf("reopen returned error: %v", err)
+	}
+	defer db.Close()
+
+	entry, err := NewStore(db).Find(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("expected entry to survive reopen, got error: %v", err)
+	}
+	if entry.OriginalURL != "https://example.com" {
+		t.Fatalf("unexpected original url: %s", entry.OriginalURL)
+	}
+}
@@ -0,0 +1,121 @@
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+	"urlshortener/internal/services/storage"
+
+	"go.etcd.io/bbolt"
+)
+
+type versionRecord struct {
+	ShortCode      string    `json:"short_code"`
+	Version        int       `json:"version"`
+	At             time.Time `json:"at"`
+	Actor          string    `json:"actor"`
+	Action         string    `json:"action"`
+	OriginalURL    string    `json:"original_url"`
+	ExpiresAt      time.Time `json:"expires_at,omitzero"`
+	ActivatesAt    time.Time `json:"activates_at,omitzero"`
+	Disabled       bool      `json:"disabled,omitempty"`
+	DisabledReason string    `json:"disabled_reason,omitempty"`
+	Title          string    `json:"title,omitempty"`
+	Description    string    `json:"description,omitempty"`
+	Tags           []string  `json:"tags,omitempty"`
+}
+
+func versionKey(version int) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(version))
+}
+
+func (s *Store) AppendVersion(ctx context.Context, v storage.LinkVersion) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(urlsBucket).Get([]byte(v.ShortCode)) == nil {
+			return storage.ErrNotFound
+		}
+		bucket, err := tx.Bucket(versionsBucket).CreateBucketIfNotExists([]byte(v.ShortCode))
+		if err != nil {
+			return err
+		}
+		// The sequence lives and dies with the link's bucket, so numbering
+		// restarts at 1 for a reused code.
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		v.Version = int(seq)
+		raw, err := json.Marshal(versionRecord(v))
+		if err != nil {
+			return err
+		}
+		return bucket.Put(versionKey(v.Version), raw)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return v.Version, nil
+}
+
+func (s *Store) ListVersions(ctx context.Context, shortCode string) ([]storage.LinkVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	versions := []storage.LinkVersion{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(versionsBucket).Bucket([]byte(shortCode))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, raw := c.Last(); k != nil; k, raw = c.Prev() {
+			var rec versionRecord
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				return err
+			}
+			versions = append(versions, storage.LinkVersion(rec))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (s *Store) FindVersion(ctx context.Context, shortCode string, version int) (storage.LinkVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return storage.LinkVersion{}, err
+	}
+	if version < 1 {
+		return storage.LinkVersion{}, storage.ErrNotFound
+	}
+
+	var v storage.LinkVersion
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(versionsBucket).Bucket([]byte(shortCode))
+		if bucket == nil {
+			return storage.ErrNotFound
+		}
+		raw := bucket.Get(versionKey(version))
+		if raw == nil {
+			return storage.ErrNotFound
+		}
+		var rec versionRecord
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			return err
+		}
+		v = storage.LinkVersion(rec)
+		return nil
+	})
+	if err != nil {
+		return storage.LinkVersion{}, err
+	}
+	return v, nil
+}
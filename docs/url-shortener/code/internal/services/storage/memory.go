@@ -2,19 +2,33 @@ package storage
 
 import (
 	"context"
+	"slices"
 	"sync"
 	"time"
 )
 
 // InMemoryStore is a simple, goroutine-safe implementation useful for tests.
 type InMemoryStore struct {
-	mu      sync.RWMutex
-	entries map[string]Entry
+	mu       sync.RWMutex
+	entries  map[string]Entry
+	keys     map[string]APIKey  // by ID
+	sessions map[string]Session // by hash
+	reports  map[string]Report  // by ID
+	audit    []AuditEvent       // in append order
+	outbox   map[string]WebhookDelivery
+	replies  map[[2]string]IdempotencyRecord // by owner and key
+	history  map[string][]LinkVersion        // by short code, oldest first
 }
 
 func NewInMemoryStore() *InMemoryStore {
 	return &InMemoryStore{
-		entries: make(map[string]Entry),
+		entries:  make(map[string]Entry),
+		history:  make(map[string][]LinkVersion),
+		keys:     make(map[string]APIKey),
+		sessions: make(map[string]Session),
+		reports:  make(map[string]Report),
+		outbox:   make(map[string]WebhookDelivery),
+		replies:  make(map[[2]string]IdempotencyRecord),
 	}
 }
 
@@ -28,6 +42,8 @@ func (s *InMemoryStore) Save(_ context.Context, entry Entry) error {
 	if entry.CreatedAt.IsZero() {
 		entry.CreatedAt = time.Now().UTC()
 	}
+	// Callers keep their slice; the stored entry must not change with it.
+	entry.Tags = slices.Clone(entry.Tags)
 	s.entries[entry.ShortCode] = entry
 	return nil
 }
@@ -55,3 +71,440 @@ func (s *InMemoryStore) IncrementHits(_ context.Context, shortCode string) (Entr
 	s.entries[shortCode] = entry
 	return entry, nil
 }
+
+func (s *InMemoryStore) List(_ context.Context, opts ListOptions) ([]Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	return ApplyListOptions(entries, opts), nil
+}
+
+func (s *InMemoryStore) ListExpired(_ context.Context, since, until time.Time) ([]Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	return SelectExpired(entries, since, until), nil
+}
+
+func (s *InMemoryStore) Update(_ context.Context, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.entries[entry.ShortCode]
+	if !ok {
+		return ErrNotFound
+	}
+	entry = ApplyUpdate(current, entry)
+	entry.Tags = slices.Clone(entry.Tags)
+	s.entries[entry.ShortCode] = entry
+	return nil
+}
+
+func (s *InMemoryStore) Overwrite(_ context.Context, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.entries[entry.ShortCode]
+	if !ok {
+		return ErrNotFound
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = current.CreatedAt
+	}
+	entry.Tags = slices.Clone(entry.Tags)
+	s.entries[entry.ShortCode] = entry
+	return nil
+}
+
+func (s *InMemoryStore) Delete(_ context.Context, shortCode string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[shortCode]; !ok {
+		return ErrNotFound
+	}
+	delete(s.entries, shortCode)
+	delete(s.history, shortCode)
+	return nil
+}
+
+func (s *InMemoryStore) AppendVersion(_ context.Context, v LinkVersion) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[v.ShortCode]; !ok {
+		return 0, ErrNotFound
+	}
+	v.Version = len(s.history[v.ShortCode]) + 1
+	v.Tags = slices.Clone(v.Tags)
+	s.history[v.ShortCode] = append(s.history[v.ShortCode], v)
+	return v.Version, nil
+}
+
+func (s *InMemoryStore) ListVersions(_ context.Context, shortCode string) ([]LinkVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := slices.Clone(s.history[shortCode])
+	slices.Reverse(versions)
+	if versions == nil {
+		versions = []LinkVersion{}
+	}
+	return versions, nil
+}
+
+func (s *InMemoryStore) FindVersion(_ context.Context, shortCode string, version int) (LinkVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := s.history[shortCode]
+	if version < 1 || version > len(versions) {
+		return LinkVersion{}, ErrNotFound
+	}
+	return versions[version-1], nil
+}
+
+func (s *InMemoryStore) SaveAPIKey(_ context.Context, key APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.keys {
+		if k.ID == key.ID || k.Hash == key.Hash {
+			return ErrConflict
+		}
+	}
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now().UTC()
+	}
+	s.keys[key.ID] = key
+	return nil
+}
+
+func (s *InMemoryStore) FindAPIKey(_ context.Context, id string) (APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return APIKey{}, ErrNotFound
+	}
+	return key, nil
+}
+
+func (s *InMemoryStore) FindAPIKeyByHash(_ context.Context, hash string) (APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.keys {
+		if k.Hash == hash {
+			return k, nil
+		}
+	}
+	return APIKey{}, ErrNotFound
+}
+
+func (s *InMemoryStore) ListAPIKeys(_ context.Context) ([]APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]APIKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+	SortAPIKeys(keys)
+	return keys, nil
+}
+
+func (s *InMemoryStore) RevokeAPIKey(_ context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if !key.Revoked() {
+		key.RevokedAt = at
+		s.keys[id] = key
+	}
+	return nil
+}
+
+func (s *InMemoryStore) SaveSession(_ context.Context, session Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[session.Hash]; ok {
+		return ErrConflict
+	}
+	s.sessions[session.Hash] = session
+	return nil
+}
+
+func (s *InMemoryStore) FindSession(_ context.Context, hash string) (Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[hash]
+	if !ok {
+		return Session{}, ErrNotFound
+	}
+	return session, nil
+}
+
+func (s *InMemoryStore) DeleteSession(_ context.Context, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, hash)
+	return nil
+}
+
+func (s *InMemoryStore) PurgeSessions(_ context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for hash, session := range s.sessions {
+		if !session.ExpiresAt.After(now) {
+			delete(s.sessions, hash)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *InMemoryStore) SaveReport(_ context.Context, report Report) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.reports[report.ID]; ok {
+		return ErrConflict
+	}
+	if report.CreatedAt.IsZero() {
+		report.CreatedAt = time.Now().UTC()
+	}
+	s.reports[report.ID] = report
+	return nil
+}
+
+func (s *InMemoryStore) FindReport(_ context.Context, id string) (Report, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	report, ok := s.reports[id]
+	if !ok {
+		return Report{}, ErrNotFound
+	}
+	return report, nil
+}
+
+func (s *InMemoryStore) ListReports(_ context.Context, opts ReportListOptions) ([]Report, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	reports := make([]Report, 0, len(s.reports))
+	for _, r := range s.reports {
+		reports = append(reports, r)
+	}
+	return ApplyReportListOptions(reports, opts), nil
+}
+
+func (s *InMemoryStore) ResolveReport(_ context.Context, id, resolution, by string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report, ok := s.reports[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if report.Open() {
+		report.ResolvedAt = at
+		report.ResolvedBy = by
+		report.Resolution = resolution
+		s.reports[id] = report
+	}
+	return nil
+}
+
+func (s *InMemoryStore) AppendAudit(_ context.Context, event AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.audit {
+		if e.ID == event.ID {
+			return ErrConflict
+		}
+	}
+	if event.At.IsZero() {
+		event.At = time.Now().UTC()
+	}
+	s.audit = append(s.audit, event)
+	return nil
+}
+
+func (s *InMemoryStore) ListAudit(_ context.Context, filter AuditFilter) ([]AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return ApplyAuditFilter(s.audit, filter), nil
+}
+
+func (s *InMemoryStore) EnqueueDelivery(_ context.Context, d WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.outbox[d.ID]; ok {
+		return ErrConflict
+	}
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now().UTC()
+	}
+	s.outbox[d.ID] = d
+	return nil
+}
+
+func (s *InMemoryStore) FindDelivery(_ context.Context, id string) (WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.outbox[id]
+	if !ok {
+		return WebhookDelivery{}, ErrNotFound
+	}
+	return d, nil
+}
+
+func (s *InMemoryStore) ClaimDueDeliveries(_ context.Context, now, until time.Time, limit int) ([]WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := SelectDueDeliveries(s.deliveries(), now, limit)
+	for i := range due {
+		due[i].NextAttemptAt = until
+		s.outbox[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (s *InMemoryStore) ListDeliveries(_ context.Context, opts DeliveryListOptions) ([]WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return ApplyDeliveryListOptions(s.deliveries(), opts), nil
+}
+
+func (s *InMemoryStore) UpdateDelivery(_ context.Context, d WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.outbox[d.ID]; !ok {
+		return ErrNotFound
+	}
+	s.outbox[d.ID] = d
+	return nil
+}
+
+// deliveries copies the outbox; callers hold s.mu.
+func (s *InMemoryStore) deliveries() []WebhookDelivery {
+	out := make([]WebhookDelivery, 0, len(s.outbox))
+	for _, d := range s.outbox {
+		out = append(out, d)
+	}
+	return out
+}
+
+func (s *InMemoryStore) DueForCheck(_ context.Context, before time.Time, limit int) ([]Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	return SelectDueForCheck(entries, before, limit), nil
+}
+
+func (s *InMemoryStore) RecordCheck(_ context.Context, shortCode string, check LinkCheck) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[shortCode]
+	if !ok {
+		return ErrNotFound
+	}
+	entry.CheckedAt, entry.CheckStatus, entry.CheckError = check.At, check.Status, check.Error
+	s.entries[shortCode] = entry
+	return nil
+}
+
+func (s *InMemoryStore) ReserveIdempotencyKey(_ context.Context, rec IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := [2]string{rec.Owner, rec.Key}
+	if old, ok := s.replies[id]; ok && old.ExpiresAt.After(rec.CreatedAt) {
+		return ErrConflict
+	}
+	rec.Body = slices.Clone(rec.Body)
+	s.replies[id] = rec
+	return nil
+}
+
+func (s *InMemoryStore) FindIdempotencyKey(_ context.Context, owner, key string) (IdempotencyRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.replies[[2]string{owner, key}]
+	if !ok {
+		return IdempotencyRecord{}, ErrNotFound
+	}
+	return rec, nil
+}
+
+func (s *InMemoryStore) CompleteIdempotencyKey(_ context.Context, rec IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := [2]string{rec.Owner, rec.Key}
+	old, ok := s.replies[id]
+	if !ok || old.Claim != rec.Claim {
+		return ErrNotFound
+	}
+	old.Status, old.ContentType, old.Body, old.ExpiresAt = rec.Status, rec.ContentType, slices.Clone(rec.Body), rec.ExpiresAt
+	s.replies[id] = old
+	return nil
+}
+
+func (s *InMemoryStore) ReleaseIdempotencyKey(_ context.Context, owner, key, claim string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := [2]string{owner, key}
+	if old, ok := s.replies[id]; ok && old.Claim == claim {
+		delete(s.replies, id)
+	}
+	return nil
+}
+
+func (s *InMemoryStore) PurgeIdempotencyKeys(_ context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, rec := range s.replies {
+		if !rec.ExpiresAt.After(now) {
+			delete(s.replies, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,61 @@
+package storage_test
+
+import (
+	"testing"
+	"urlshortener/internal/services/storage"
+	"urlshortener/internal/services/storage/storagetest"
+)
+
+func TestInMemoryStore(t *testing.T) {
+	storagetest.Run(t, func(*testing.T) storage.Store {
+		return storage.NewInMemoryStore()
+	})
+}
+
+func TestInMemoryKeyStore(t *testing.T) {
+	storagetest.RunKeyStore(t, func(*testing.T) storage.KeyStore {
+		return storage.NewInMemoryStore()
+	})
+}
+
+func TestInMemoryReportStore(t *testing.T) {
+	storagetest.RunReportStore(t, func(*testing.T) storage.ReportStore {
+		return storage.NewInMemoryStore()
+	})
+}
+
+func TestInMemoryAuditStore(t *testing.T) {
+	storagetest.RunAuditStore(t, func(*testing.T) storage.AuditStore {
+		return storage.NewInMemoryStore()
+	})
+}
+
+func TestInMemoryOutboxStore(t *testing.T) {
+	storagetest.RunOutboxStore(t, func(*testing.T) storage.OutboxStore {
+		return storage.NewInMemoryStore()
+	})
+}
+
+func TestInMemoryVersionStore(t *testing.T) {
+	storagetest.RunVersionStore(t, func(*testing.T) storagetest.VersionStore {
+		return storage.NewInMemoryStore()
+	})
+}
+
+func TestInMemoryIdempotencyStore(t *testing.T) {
+	storagetest.RunIdempotencyStore(t, func(*testing.T) storage.IdempotencyStore {
+		return storage.NewInMemoryStore()
+	})
+}
+
+func TestInMemoryHealthStore(t *testing.T) {
+	storagetest.RunHealthStore(t, func(*testing.T) storagetest.HealthStore {
+		return storage.NewInMemoryStore()
+	})
+}
+
+func TestInMemoryExpiryStore(t *testing.T) {
+	storagetest.RunExpiryStore(t, func(*testing.T) storagetest.ExpiryStore {
+		return storage.NewInMemoryStore()
+	})
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+const auditColumns = `id, at, actor, action, short_code, request_id, before, after`
+
+func scanAuditEvent(row rowScanner) (storage.AuditEvent, error) {
+	var event storage.AuditEvent
+	var before, after []byte
+	err := row.Scan(
+		&event.ID,
+		&event.At,
+		&event.Actor,
+		&event.Action,
+		&event.ShortCode,
+		&event.RequestID,
+		&before,
+		&after,
+	)
+	if err != nil {
+		return storage.AuditEvent{}, err
+	}
+	event.Before = before
+	event.After = after
+	return event, nil
+}
+
+// nullJSON stores an empty document as NULL rather than invalid JSONB.
+func nullJSON(raw []byte) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+func (s *Store) AppendAudit(ctx context.Context, event storage.AuditEvent) error {
+	query := `
+		INSERT INTO audit_log (` + auditColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	at := event.At
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+
+	_, err := s.db.ExecContext(ctx, query,
+		event.ID,
+		at,
+		event.Actor,
+		event.Action,
+		event.ShortCode,
+		event.RequestID,
+		nullJSON(event.Before),
+		nullJSON(event.After),
+	)
+	return classifyError(ctx, err)
+}
+
+func (s *Store) ListAudit(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditEvent, error) {
+	query := `
+		SELECT ` + auditColumns + `
+		FROM audit_log
+		WHERE ($1 = '' OR short_code = $1)
+			AND ($2 = '' OR actor = $2)
+			AND ($3 = '' OR action = $3)
+			AND ($4::timestamp IS NULL OR at >= $4)
+			AND ($5::timestamp IS NULL OR at < $5)
+		ORDER BY at DESC, id DESC
+		LIMIT $6 OFFSET $7
+	`
+
+	// LIMIT NULL means no limit.
+	var limit sql.NullInt64
+	if filter.Limit > 0 {
+		limit = sql.NullInt64{Int64: int64(filter.Limit), Valid: true}
+	}
+
+	rows, err := s.db.QueryContext(ctx, query,
+		filter.ShortCode,
+		filter.Actor,
+		filter.Action,
+		nullTime(filter.Since),
+		nullTime(filter.Until),
+		limit,
+		max(filter.Offset, 0),
+	)
+	if err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	defer rows.Close()
+
+	events := []storage.AuditEvent{}
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	return events, nil
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"urlshortener/internal/services/storage"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes we care about. See
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	uniqueViolation      = "23505"
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+	adminShutdown        = "57P01"
+	crashShutdown        = "57P02"
+	cannotConnectNow     = "57P03"
+
+	// Class 08 covers every "connection exception".
+	connectionExceptionClass = "08"
+)
+
+// classifyError maps driver errors onto the storage sentinels. Unique
+// violations become storage.ErrConflict; failures that are worth retrying are
+// wrapped with storage.ErrRetryable. Everything else is returned unchanged.
+func classifyError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if isPgUniqueViolation(err) {
+		return storage.ErrConflict
+	}
+	if isRetryable(ctx, err) {
+		return fmt.Errorf("%w: %w", storage.ErrRetryable, err)
+	}
+	return err
+}
+
+// Helper to detect PostgreSQL unique constraint violations
+func isPgUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// isRetryable reports whether running the statement again is both useful
+// and harmless. The server rejecting it with a transient SQLSTATE qualifies.
+// A broken connection only does when nothing reached the server: a read
+// error after sending may hide a commit, and retrying an IncrementHits then
+// counts the hit twice. Nothing is retried once the caller's deadline has
+// passed or on a timeout.
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case serializationFailure, deadlockDetected, adminShutdown, crashShutdown, cannotConnectNow:
+			return true
+		}
+		return strings.HasPrefix(pgErr.Code, connectionExceptionClass)
+	}
+
+	// A failed connect never sent the statement.
+	var connectErr *pgconn.ConnectError
+	return pgconn.SafeToRetry(err) || errors.As(err, &connectErr) || errors.Is(err, driver.ErrBadConn)
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"urlshortener/internal/services/storage"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsPgUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain error", errors.New("duplicate key value violates unique constraint"), false},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, true},
+		{"wrapped unique violation", fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505"}), true},
+		{"other sqlstate", &pgconn.PgError{Code: "23502"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPgUniqueViolation(tt.err); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		want      error
+		retryable bool
+	}{
+		{"unique violation", &pgconn.PgError{Code: "23505"}, storage.ErrConflict, false},
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, nil, true},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, nil, true},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, nil, true},
+		{"cannot connect now", &pgconn.PgError{Code: "57P03"}, nil, true},
+		{"bad conn", driver.ErrBadConn, nil, true},
+		{"network error", &net.OpError{Op: "read", Err: errors.New("connection reset")}, nil, false},
+		{"timeout", &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}, nil, false},
+		{"deadline exceeded", fmt.Errorf("query: %w", context.DeadlineExceeded), nil, false},
+		{"not null violation", &pgconn.PgError{Code: "23502"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyError(context.Background(), tt.err)
+			if tt.want != nil && !errors.Is(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			if errors.Is(got, storage.ErrRetryable) != tt.retryable {
+				t.Fatalf("expected retryable=%v, got %v", tt.retryable, got)
+			}
+			if !tt.retryable && tt.want == nil && got != tt.err {
+				t.Fatalf("expected error to pass through unchanged, got %v", got)
+			}
+		})
+	}
+
+	if classifyError(context.Background(), nil) != nil {
+		t.Fatalf("expected nil for nil error")
+	}
+
+	// The server never saw a statement whose connection failed to open.
+	if !isRetryable(context.Background(), &pgconn.ConnectError{}) {
+		t.Fatal("expected a failed connect to be retryable")
+	}
+	// Past the caller's deadline, retrying only holds the request longer.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got := classifyError(ctx, &pgconn.PgError{Code: "40001"}); errors.Is(got, storage.ErrRetryable) {
+		t.Fatalf("expected no retry once the context is done, got %v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+func (s *Store) DueForCheck(ctx context.Context, before time.Time, limit int) ([]storage.Entry, error) {
+	var rowLimit sql.NullInt64
+	if limit > 0 {
+		rowLimit = sql.NullInt64{Int64: int64(limit), Valid: true}
+	}
+	query := `
+		SELECT ` + entryColumns + `
+		FROM urls
+		WHERE NOT disabled AND (checked_at IS NULL OR checked_at < $1)
+		ORDER BY checked_at NULLS FIRST, short_code
+		LIMIT $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, before, rowLimit)
+	if err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	defer rows.Close()
+
+	entries := []storage.Entry{}
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	return entries, nil
+}
+
+func (s *Store) RecordCheck(ctx context.Context, shortCode string, check storage.LinkCheck) error {
+	query := `
+		UPDATE urls
+		SET checked_at = $2, check_status = $3, check_error = $4
+		WHERE short_code = $1
+	`
+
+	res, err := s.db.ExecContext(ctx, query, shortCode, check.At, check.Status, check.Error)
+	if err != nil {
+		return classifyError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
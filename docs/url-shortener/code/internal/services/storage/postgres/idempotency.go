@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+const idempotencyColumns = `owner, key, request_hash, claim, status, content_type, body, created_at, expires_at`
+
+func (s *Store) ReserveIdempotencyKey(ctx context.Context, rec storage.IdempotencyRecord) error {
+	// The upsert only takes over a row that has expired; a live one leaves
+	// no row affected.
+	query := `
+		INSERT INTO idempotency_keys (` + idempotencyColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (owner, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, claim = EXCLUDED.claim, status = EXCLUDED.status,
+			content_type = EXCLUDED.content_type, body = EXCLUDED.body,
+			created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+	`
+
+	res, err := s.db.ExecContext(ctx, query,
+		rec.Owner,
+		rec.Key,
+		rec.RequestHash,
+		rec.Claim,
+		rec.Status,
+		rec.ContentType,
+		rec.Body,
+		rec.CreatedAt,
+		rec.ExpiresAt,
+	)
+	if err != nil {
+		return classifyError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrConflict
+	}
+	return nil
+}
+
+func (s *Store) FindIdempotencyKey(ctx context.Context, owner, key string) (storage.IdempotencyRecord, error) {
+	query := `SELECT ` + idempotencyColumns + ` FROM idempotency_keys WHERE owner = $1 AND key = $2`
+
+	var rec storage.IdempotencyRecord
+	err := s.db.QueryRowContext(ctx, query, owner, key).Scan(
+		&rec.Owner,
+		&rec.Key,
+		&rec.RequestHash,
+		&rec.Claim,
+		&rec.Status,
+		&rec.ContentType,
+		&rec.Body,
+		&rec.CreatedAt,
+		&rec.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.IdempotencyRecord{}, storage.ErrNotFound
+		}
+		return storage.IdempotencyRecord{}, classifyError(ctx, err)
+	}
+	return rec, nil
+}
+
+func (s *Store) CompleteIdempotencyKey(ctx context.Context, rec storage.IdempotencyRecord) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status = $4, content_type = $5, body = $6, expires_at = $7
+		WHERE owner = $1 AND key = $2 AND claim = $3
+	`
+
+	res, err := s.db.ExecContext(ctx, query,
+		rec.Owner,
+		rec.Key,
+		rec.Claim,
+		rec.Status,
+		rec.ContentType,
+		rec.Body,
+		rec.ExpiresAt,
+	)
+	if err != nil {
+		return classifyError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, owner, key, claim string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE owner = $1 AND key = $2 AND claim = $3`, owner, key, claim)
+	return classifyError(ctx, err)
+}
+
+func (s *Store) PurgeIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, classifyError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+const apiKeyColumns = `id, name, hash, created_at, revoked_at`
+
+func scanAPIKey(row rowScanner) (storage.APIKey, error) {
+	var key storage.APIKey
+	var revokedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.Name, &key.Hash, &key.CreatedAt, &revokedAt); err != nil {
+		return storage.APIKey{}, err
+	}
+	key.RevokedAt = revokedAt.Time
+	return key, nil
+}
+
+func (s *Store) SaveAPIKey(ctx context.Context, key storage.APIKey) error {
+	query := `
+		INSERT INTO api_keys (id, name, hash, created_at, revoked_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	createdAt := key.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+
+	_, err := s.db.ExecContext(ctx, query, key.ID, key.Name, key.Hash, createdAt, nullTime(key.RevokedAt))
+	return classifyError(ctx, err)
+}
+
+func (s *Store) FindAPIKey(ctx context.Context, id string) (storage.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1`
+
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.APIKey{}, storage.ErrNotFound
+		}
+		return storage.APIKey{}, classifyError(ctx, err)
+	}
+	return key, nil
+}
+
+func (s *Store) FindAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE hash = $1`
+
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, query, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.APIKey{}, storage.ErrNotFound
+		}
+		return storage.APIKey{}, classifyError(ctx, err)
+	}
+	return key, nil
+}
+
+func (s *Store) ListAPIKeys(ctx context.Context) ([]storage.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at, id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	defer rows.Close()
+
+	keys := []storage.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	return keys, nil
+}
+
+func (s *Store) RevokeAPIKey(ctx context.Context, id string, at time.Time) error {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, $2)
+		WHERE id = $1
+	`
+
+	res, err := s.db.ExecContext(ctx, query, id, at)
+	if err != nil {
+		return classifyError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) SaveSession(ctx context.Context, session storage.Session) error {
+	query := `
+		INSERT INTO sessions (hash, key_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := s.db.ExecContext(ctx, query, session.Hash, session.KeyID, session.CreatedAt, session.ExpiresAt)
+	return classifyError(ctx, err)
+}
+
+func (s *Store) FindSession(ctx context.Context, hash string) (storage.Session, error) {
+	query := `SELECT hash, key_id, created_at, expires_at FROM sessions WHERE hash = $1`
+
+	var session storage.Session
+	err := s.db.QueryRowContext(ctx, query, hash).Scan(&session.Hash, &session.KeyID, &session.CreatedAt, &session.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.Session{}, storage.ErrNotFound
+		}
+		return storage.Session{}, classifyError(ctx, err)
+	}
+	return session, nil
+}
+
+func (s *Store) DeleteSession(ctx context.Context, hash string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE hash = $1`, hash)
+	return classifyError(ctx, err)
+}
+
+func (s *Store) PurgeSessions(ctx context.Context, now time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, classifyError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
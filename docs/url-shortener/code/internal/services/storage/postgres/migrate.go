@@ -0,0 +1,278 @@
+package postgres
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The SQL files keep goose's annotations, so they can still be managed with
+// the goose CLI, but the server no longer depends on it.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the pg_advisory_lock key held while migrating, so
+// replicas booting at the same time don't race each other.
+const migrationLockID int64 = 0x75726c73686f7274 // "urlshort"
+
+const createVersionTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)
+`
+
+var ErrNoMigrations = errors.New("postgres: no migrations to roll back")
+
+// Migration is one numbered SQL file split into its up and down halves.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied, and when.
+type MigrationStatus struct {
+	Migration
+	AppliedAt time.Time // zero if pending
+}
+
+func (s MigrationStatus) Applied() bool {
+	return !s.AppliedAt.IsZero()
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator returns a Migrator for the migrations embedded in this package.
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies every pending migration in order and returns the ones it ran.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var ran []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx,
+					`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+					mig.Version, mig.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migrate up %05d_%s: %w", mig.Version, mig.Name, err)
+			}
+			ran = append(ran, mig)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) (Migration, error) {
+	var rolledBack Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx,
+					`DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migrate down %05d_%s: %w", mig.Version, mig.Name, err)
+			}
+			rolledBack = mig
+			return nil
+		}
+		return ErrNoMigrations
+	})
+	return rolledBack, err
+}
+
+// Status lists every known migration alongside when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			statuses = append(statuses, MigrationStatus{
+				Migration: mig,
+				AppliedAt: applied[mig.Version],
+			})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// withLock pins a single connection, takes the advisory lock on it and makes
+// sure the version table exists before running fn.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Use a fresh context so a cancelled ctx doesn't leave the lock held.
+		_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}()
+
+	if _, err := conn.ExecContext(ctx, createVersionTable); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// loadMigrations reads NNNNN_name.sql files from dir, sorted by version.
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	names, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(names))
+	seen := make(map[int64]string)
+	for _, name := range names {
+		base := strings.TrimSuffix(path.Base(name), ".sql")
+		versionStr, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected NNNNN_name.sql", name)
+		}
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", name, err)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migration %s: version %d already used by %s", name, version, other)
+		}
+		seen[version] = name
+
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		up, down, err := splitMigration(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", name, err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: label, Up: up, Down: down})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// splitMigration separates the "-- +goose Up" and "-- +goose Down" sections.
+// StatementBegin/End markers are plain SQL comments, so they are left in.
+func splitMigration(src string) (up, down string, err error) {
+	var upBuf, downBuf strings.Builder
+	var current *strings.Builder
+
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch strings.TrimSpace(line) {
+		case "-- +goose Up":
+			current = &upBuf
+			continue
+		case "-- +goose Down":
+			current = &downBuf
+			continue
+		}
+		if current != nil {
+			current.WriteString(line)
+			current.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", err
+	}
+
+	up, down = strings.TrimSpace(upBuf.String()), strings.TrimSpace(downBuf.String())
+	if up == "" {
+		return "", "", errors.New("missing -- +goose Up section")
+	}
+	return up, down, nil
+}
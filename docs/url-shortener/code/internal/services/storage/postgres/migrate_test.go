@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrationsParse(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatalf("loadMigrations returned error: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatalf("expected embedded migrations")
+	}
+	for i, mig := range migrations {
+		if i > 0 && mig.Version <= migrations[i-1].Version {
+			t.Fatalf("migrations out of order: %d after %d", mig.Version, migrations[i-1].Version)
+		}
+		if mig.Down == "" {
+			t.Fatalf("migration %d has no down section", mig.Version)
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/00002_second.sql": {Data: []byte("-- +goose Up\nCREATE TABLE b ();\n-- +goose Down\nDROP TABLE b;\n")},
+		"m/00001_first.sql":  {Data: []byte("-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE a ();\n-- +goose StatementEnd\n\n-- +goose Down\nDROP TABLE a;\n")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("loadMigrations returned error: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(migrations))
+	}
+	first := migrations[0]
+	if first.Version != 1 || first.Name != "first" {
+		t.Fatalf("unexpected first migration: %d %s", first.Version, first.Name)
+	}
+	if !strings.Contains(first.Up, "CREATE TABLE a ();") || strings.Contains(first.Up, "DROP") {
+		t.Fatalf("unexpected up section: %q", first.Up)
+	}
+	if first.Down != "DROP TABLE a;" {
+		t.Fatalf("unexpected down section: %q", first.Down)
+	}
+}
+
+func TestLoadMigrationsRejectsBadFiles(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"no version":   {"m/first.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")}},
+		"no up":        {"m/00001_first.sql": {Data: []byte("-- +goose Down\nSELECT 1;\n")}},
+		"dup versions": {"m/00001_a.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")}, "m/1_b.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")}},
+	}
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := loadMigrations(fsys, "m"); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestMigratorUpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("NewMigrator returned error: %v", err)
+	}
+
+	// openTestDB already migrated; Up must be a no-op now.
+	ran, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up returned error: %v", err)
+	}
+	if len(ran) != 0 {
+		t.Fatalf("expected no pending migrations, ran %d", len(ran))
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status returned error: %v", err)
+	}
+	for _, st := range statuses {
+		if !st.Applied() {
+			t.Fatalf("expected migration %d to be applied", st.Version)
+		}
+	}
+
+	// Roll everything back, then re-apply so other tests keep a schema.
+	for range statuses {
+		if _, err := m.Down(ctx); err != nil {
+			t.Fatalf("Down returned error: %v", err)
+		}
+	}
+	if _, err := m.Down(ctx); !errors.Is(err, ErrNoMigrations) {
+		t.Fatalf("expected %v, got %v", ErrNoMigrations, err)
+	}
+	ran, err = m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up returned error: %v", err)
+	}
+	if len(ran) != len(statuses) {
+		t.Fatalf("expected %d migrations to run, ran %d", len(statuses), len(ran))
+	}
+}
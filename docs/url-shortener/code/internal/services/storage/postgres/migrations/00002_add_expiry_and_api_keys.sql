@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP NULL;
+CREATE INDEX IF NOT EXISTS urls_created_by_created_at_idx ON urls (created_by, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE api_keys;
+DROP INDEX IF EXISTS urls_created_by_created_at_idx;
+ALTER TABLE urls DROP COLUMN expires_at;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS disabled_reason TEXT NOT NULL DEFAULT '';
+
+-- No foreign key: reports stay when their link is deleted.
+CREATE TABLE IF NOT EXISTS reports (
+    id TEXT PRIMARY KEY,
+    short_code VARCHAR(50) NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    reporter TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP NULL,
+    resolved_by TEXT NOT NULL DEFAULT '',
+    resolution TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS reports_short_code_idx ON reports (short_code);
+CREATE INDEX IF NOT EXISTS reports_open_created_at_idx ON reports (created_at) WHERE resolved_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE reports;
+ALTER TABLE urls DROP COLUMN disabled_reason;
+ALTER TABLE urls DROP COLUMN disabled;
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+-- No foreign key: the trail outlives the links it describes.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id TEXT PRIMARY KEY,
+    at TIMESTAMP NOT NULL DEFAULT NOW(),
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    short_code VARCHAR(50) NOT NULL,
+    request_id TEXT NOT NULL DEFAULT '',
+    before JSONB NULL,
+    after JSONB NULL
+);
+CREATE INDEX IF NOT EXISTS audit_log_at_idx ON audit_log (at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_short_code_at_idx ON audit_log (short_code, at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_actor_at_idx ON audit_log (actor, at DESC);
+-- +goose StatementEnd
+
+-- The trail is append-only, even for direct SQL access.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE audit_log;
+DROP FUNCTION audit_log_append_only();
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- Search matches words anywhere in this text. array_to_string is only
+-- STABLE, so the wrapper is declared IMMUTABLE to be indexable; it is, for
+-- text[].
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION urls_search_text(title TEXT, description TEXT, original_url TEXT, short_code TEXT, tags TEXT[])
+RETURNS TEXT AS $$
+    SELECT lower(title || ' ' || description || ' ' || original_url || ' ' || short_code || ' ' || array_to_string(tags, ' '))
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS urls_search_trgm_idx
+    ON urls USING GIN (urls_search_text(title, description, original_url, short_code, tags) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS urls_tags_idx ON urls USING GIN (tags);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX urls_tags_idx;
+DROP INDEX urls_search_trgm_idx;
+DROP FUNCTION urls_search_text(TEXT, TEXT, TEXT, TEXT, TEXT[]);
+ALTER TABLE urls DROP COLUMN tags;
+ALTER TABLE urls DROP COLUMN description;
+ALTER TABLE urls DROP COLUMN title;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS checked_at TIMESTAMP NULL;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS check_status INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS check_error TEXT NOT NULL DEFAULT '';
+-- The health checker walks enabled links, least recently checked first.
+CREATE INDEX IF NOT EXISTS urls_checked_at_idx ON urls (checked_at NULLS FIRST, short_code) WHERE NOT disabled;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX urls_checked_at_idx;
+ALTER TABLE urls DROP COLUMN check_error;
+ALTER TABLE urls DROP COLUMN check_status;
+ALTER TABLE urls DROP COLUMN checked_at;
+-- +goose StatementEnd
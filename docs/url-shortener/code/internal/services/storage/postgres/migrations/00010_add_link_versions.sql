@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS link_versions (
+    short_code VARCHAR(50) NOT NULL REFERENCES urls (short_code) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    at TIMESTAMP NOT NULL DEFAULT NOW(),
+    actor TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL DEFAULT '',
+    original_url TEXT NOT NULL,
+    expires_at TIMESTAMP NULL,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    disabled_reason TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    PRIMARY KEY (short_code, version)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE link_versions;
+-- +goose StatementEnd
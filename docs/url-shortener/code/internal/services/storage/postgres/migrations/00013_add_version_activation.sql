@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE link_versions ADD COLUMN IF NOT EXISTS activates_at TIMESTAMP NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE link_versions DROP COLUMN activates_at;
+-- +goose StatementEnd
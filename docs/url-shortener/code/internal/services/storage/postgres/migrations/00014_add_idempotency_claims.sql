@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS claim TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE idempotency_keys DROP COLUMN claim;
+-- +goose StatementEnd
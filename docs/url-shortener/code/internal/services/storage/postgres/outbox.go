@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+const deliveryColumns = `id, endpoint, event, payload, status, attempts, next_attempt_at, last_error, last_status, created_at, delivered_at`
+
+// qualifiedDeliveryColumns is deliveryColumns for a query that joins
+// webhook_outbox as o.
+const qualifiedDeliveryColumns = `o.id, o.endpoint, o.event, o.payload, o.status, o.attempts, o.next_attempt_at, o.last_error, o.last_status, o.created_at, o.delivered_at`
+
+func scanDelivery(row rowScanner) (storage.WebhookDelivery, error) {
+	var d storage.WebhookDelivery
+	var deliveredAt sql.NullTime
+	err := row.Scan(
+		&d.ID,
+		&d.Endpoint,
+		&d.Event,
+		&d.Payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.LastError,
+		&d.LastStatus,
+		&d.CreatedAt,
+		&deliveredAt,
+	)
+	if err != nil {
+		return storage.WebhookDelivery{}, err
+	}
+	d.DeliveredAt = deliveredAt.Time
+	return d, nil
+}
+
+func (s *Store) EnqueueDelivery(ctx context.Context, d storage.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_outbox (` + deliveryColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	createdAt := d.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+
+	_, err := s.db.ExecContext(ctx, query,
+		d.ID,
+		d.Endpoint,
+		d.Event,
+		string(d.Payload),
+		d.Status,
+		d.Attempts,
+		d.NextAttemptAt,
+		d.LastError,
+		d.LastStatus,
+		createdAt,
+		nullTime(d.DeliveredAt),
+	)
+	return classifyError(ctx, err)
+}
+
+func (s *Store) FindDelivery(ctx context.Context, id string) (storage.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_outbox WHERE id = $1`
+
+	d, err := scanDelivery(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.WebhookDelivery{}, storage.ErrNotFound
+		}
+		return storage.WebhookDelivery{}, classifyError(ctx, err)
+	}
+	return d, nil
+}
+
+func (s *Store) ClaimDueDeliveries(ctx context.Context, now, until time.Time, limit int) ([]storage.WebhookDelivery, error) {
+	// SKIP LOCKED lets dispatchers claiming at the same moment split the due
+	// rows between them instead of waiting for, then repeating, each other.
+	query := `
+		WITH due AS (
+			SELECT id, next_attempt_at
+			FROM webhook_outbox
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_outbox o
+			SET next_attempt_at = $2
+			FROM due
+			WHERE o.id = due.id
+			RETURNING ` + qualifiedDeliveryColumns + `, due.next_attempt_at AS due_at
+		)
+		SELECT ` + deliveryColumns + `
+		FROM claimed
+		ORDER BY due_at, id
+	`
+
+	var lim sql.NullInt64
+	if limit > 0 {
+		lim = sql.NullInt64{Int64: int64(limit), Valid: true}
+	}
+	return s.queryDeliveries(ctx, query, now, until, lim)
+}
+
+func (s *Store) ListDeliveries(ctx context.Context, opts storage.DeliveryListOptions) ([]storage.WebhookDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_outbox
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	// LIMIT NULL means no limit.
+	var limit sql.NullInt64
+	if opts.Limit > 0 {
+		limit = sql.NullInt64{Int64: int64(opts.Limit), Valid: true}
+	}
+	return s.queryDeliveries(ctx, query, opts.Status, limit, max(opts.Offset, 0))
+}
+
+func (s *Store) UpdateDelivery(ctx context.Context, d storage.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_outbox
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5,
+			last_status = $6, delivered_at = $7
+		WHERE id = $1
+	`
+
+	res, err := s.db.ExecContext(ctx, query,
+		d.ID,
+		d.Status,
+		d.Attempts,
+		d.NextAttemptAt,
+		d.LastError,
+		d.LastStatus,
+		nullTime(d.DeliveredAt),
+	)
+	if err != nil {
+		return classifyError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) queryDeliveries(ctx context.Context, query string, args ...any) ([]storage.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	defer rows.Close()
+
+	deliveries := []storage.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	return deliveries, nil
+}
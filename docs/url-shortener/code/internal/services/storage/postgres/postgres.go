@@ -1,9 +1,12 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
@@ -14,21 +17,79 @@ type PostgresConfig struct {
 	Password string
 	Database string
 	SSLMode  string
+
+	// Pool tuning. Zero values keep the driver defaults.
+	MaxOpenConns     int
+	MaxIdleConns     int // ignored when UsePgxPool is set
+	ConnMaxLifetime  time.Duration
+	ConnMaxIdleTime  time.Duration
+	StatementTimeout time.Duration // server-side statement_timeout per connection
+
+	// UsePgxPool makes the store run on a native pgxpool.Pool (see OpenPool)
+	// instead of database/sql's own pool.
+	UsePgxPool bool
 }
 
-func Open(cfg PostgresConfig) (*sql.DB, error) {
-	stringifiedConfig := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+// connString renders cfg in libpq keyword/value form. Unknown keywords such
+// as statement_timeout are sent to the server as runtime parameters.
+func (cfg PostgresConfig) connString() string {
+	s := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Host,
 		cfg.Port,
 		cfg.User,
 		cfg.Password,
 		cfg.Database,
 		cfg.SSLMode)
+	if cfg.StatementTimeout > 0 {
+		s += fmt.Sprintf(" statement_timeout=%d", cfg.StatementTimeout.Milliseconds())
+	}
+	return s
+}
 
-	conn, err := sql.Open("pgx", stringifiedConfig)
+func Open(cfg PostgresConfig) (*sql.DB, error) {
+	conn, err := sql.Open("pgx", cfg.connString())
 	if err != nil {
 		return nil, fmt.Errorf("open: %w", err)
 	}
 
+	if cfg.MaxOpenConns > 0 {
+		conn.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		conn.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		conn.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		conn.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+
 	return conn, nil
 }
+
+// OpenPool opens a native pgx connection pool. Use NewStoreFromPool to build a
+// Store on top of it; closing the pool is the caller's job.
+func OpenPool(ctx context.Context, cfg PostgresConfig) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(cfg.connString())
+	if err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+
+	if cfg.MaxOpenConns > 0 {
+		poolCfg.MaxConns = int32(cfg.MaxOpenConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.ConnMaxLifetime
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.ConnMaxIdleTime
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("open pool: %w", err)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,19 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConnStringStatementTimeout(t *testing.T) {
+	cfg := PostgresConfig{Host: "localhost", Port: "5432", SSLMode: "disable"}
+	if strings.Contains(cfg.connString(), "statement_timeout") {
+		t.Fatalf("expected no statement_timeout by default, got %q", cfg.connString())
+	}
+
+	cfg.StatementTimeout = 1500 * time.Millisecond
+	if !strings.HasSuffix(cfg.connString(), " statement_timeout=1500") {
+		t.Fatalf("expected statement_timeout in milliseconds, got %q", cfg.connString())
+	}
+}
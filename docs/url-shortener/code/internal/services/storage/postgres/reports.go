@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+const reportColumns = `id, short_code, reason, details, reporter, created_at, resolved_at, resolved_by, resolution`
+
+func scanReport(row rowScanner) (storage.Report, error) {
+	var report storage.Report
+	var resolvedAt sql.NullTime
+	err := row.Scan(
+		&report.ID,
+		&report.ShortCode,
+		&report.Reason,
+		&report.Details,
+		&report.Reporter,
+		&report.CreatedAt,
+		&resolvedAt,
+		&report.ResolvedBy,
+		&report.Resolution,
+	)
+	if err != nil {
+		return storage.Report{}, err
+	}
+	report.ResolvedAt = resolvedAt.Time
+	return report, nil
+}
+
+func (s *Store) SaveReport(ctx context.Context, report storage.Report) error {
+	query := `
+		INSERT INTO reports (` + reportColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	createdAt := report.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+
+	_, err := s.db.ExecContext(ctx, query,
+		report.ID,
+		report.ShortCode,
+		report.Reason,
+		report.Details,
+		report.Reporter,
+		createdAt,
+		nullTime(report.ResolvedAt),
+		report.ResolvedBy,
+		report.Resolution,
+	)
+	return classifyError(ctx, err)
+}
+
+func (s *Store) FindReport(ctx context.Context, id string) (storage.Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM reports WHERE id = $1`
+
+	report, err := scanReport(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.Report{}, storage.ErrNotFound
+		}
+		return storage.Report{}, classifyError(ctx, err)
+	}
+	return report, nil
+}
+
+func (s *Store) ListReports(ctx context.Context, opts storage.ReportListOptions) ([]storage.Report, error) {
+	query := `
+		SELECT ` + reportColumns + `
+		FROM reports
+		WHERE ($1 = '' OR short_code = $1)
+			AND (NOT $2 OR resolved_at IS NULL)
+		ORDER BY created_at, id
+		LIMIT $3 OFFSET $4
+	`
+
+	// LIMIT NULL means no limit.
+	var limit sql.NullInt64
+	if opts.Limit > 0 {
+		limit = sql.NullInt64{Int64: int64(opts.Limit), Valid: true}
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, opts.ShortCode, opts.OpenOnly, limit, max(opts.Offset, 0))
+	if err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	defer rows.Close()
+
+	reports := []storage.Report{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	return reports, nil
+}
+
+func (s *Store) ResolveReport(ctx context.Context, id, resolution, by string, at time.Time) error {
+	// Only the first resolution sticks; the WHERE still matches resolved
+	// reports so a missing ID can be told apart from a no-op.
+	query := `
+		UPDATE reports
+		SET resolved_at = COALESCE(resolved_at, $2),
+			resolved_by = CASE WHEN resolved_at IS NULL THEN $3 ELSE resolved_by END,
+			resolution = CASE WHEN resolved_at IS NULL THEN $4 ELSE resolution END
+		WHERE id = $1
+	`
+
+	res, err := s.db.ExecContext(ctx, query, id, at, by, resolution)
+	if err != nil {
+		return classifyError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
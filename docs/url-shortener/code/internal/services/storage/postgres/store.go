@@ -4,8 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strconv"
+	"strings"
 	"time"
 	"urlshortener/internal/services/storage"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
 type Store struct {
@@ -16,10 +22,80 @@ func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
+// NewStoreFromPool runs the store on a native pgxpool.Pool. Closing the pool
+// is still up to the caller.
+func NewStoreFromPool(pool *pgxpool.Pool) *Store {
+	return &Store{db: stdlib.OpenDBFromPool(pool)}
+}
+
+// entryColumns is the column list every entry query selects, in scanEntry order.
+const entryColumns = `short_code, original_url, created_at, created_by, hit_count, expires_at, disabled, disabled_reason, title, description, tags, checked_at, check_status, check_error, signed, activates_at`
+
+// searchText is the indexed expression List searches; it must match the
+// urls_search_trgm_idx index exactly.
+const searchText = `urls_search_text(title, description, original_url, short_code, tags)`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEntry(row rowScanner) (storage.Entry, error) {
+	var entry storage.Entry
+	var expiresAt, checkedAt, activatesAt sql.NullTime
+	err := row.Scan(
+		&entry.ShortCode,
+		&entry.OriginalURL,
+		&entry.CreatedAt,
+		&entry.CreatedBy,
+		&entry.HitCount,
+		&expiresAt,
+		&entry.Disabled,
+		&entry.DisabledReason,
+		&entry.Title,
+		&entry.Description,
+		// database/sql hands arrays over as text; pgtype parses them. A Map
+		// is not safe for concurrent use, hence one per row.
+		pgtype.NewMap().SQLScanner(&entry.Tags),
+		&checkedAt,
+		&entry.CheckStatus,
+		&entry.CheckError,
+		&entry.Signed,
+		&activatesAt,
+	)
+	if err != nil {
+		return storage.Entry{}, err
+	}
+	entry.ExpiresAt = expiresAt.Time
+	entry.ActivatesAt = activatesAt.Time
+	entry.CheckedAt = checkedAt.Time
+	if len(entry.Tags) == 0 {
+		entry.Tags = nil
+	}
+	return entry, nil
+}
+
+// tags stores a nil slice as an empty array, since the column is NOT NULL.
+func tags(t []string) []string {
+	if t == nil {
+		return []string{}
+	}
+	return t
+}
+
+// escapeLike makes LIKE's wildcards in s match literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// nullTime stores zero times as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
 func (s *Store) Save(ctx context.Context, entry storage.Entry) error {
 	query := `
-		INSERT INTO urls (short_code, original_url, created_at, created_by, hit_count)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO urls (` + entryColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 	`
 
 	createdAt := entry.CreatedAt
@@ -33,15 +109,22 @@ func (s *Store) Save(ctx context.Context, entry storage.Entry) error {
 		createdAt,
 		entry.CreatedBy,
 		entry.HitCount,
+		nullTime(entry.ExpiresAt),
+		entry.Disabled,
+		entry.DisabledReason,
+		entry.Title,
+		entry.Description,
+		tags(entry.Tags),
+		nullTime(entry.CheckedAt),
+		entry.CheckStatus,
+		entry.CheckError,
+		entry.Signed,
+		nullTime(entry.ActivatesAt),
 	)
 
 	if err != nil {
-		// Check for unique constraint violation (duplicate short_code)
-		// This is PostgreSQL-specific error handling
-		if isPgUniqueViolation(err) {
-			return storage.ErrConflict
-		}
-		return err
+		// Duplicate short_code becomes storage.ErrConflict so the shortener retries.
+		return classifyError(ctx, err)
 	}
 
 	return nil
@@ -49,25 +132,17 @@ func (s *Store) Save(ctx context.Context, entry storage.Entry) error {
 
 func (s *Store) Find(ctx context.Context, shortCode string) (storage.Entry, error) {
 	query := `
-		SELECT short_code, original_url, created_at, created_by, hit_count
+		SELECT ` + entryColumns + `
 		FROM urls
 		WHERE short_code = $1
 	`
 
-	var entry storage.Entry
-	err := s.db.QueryRowContext(ctx, query, shortCode).Scan(
-		&entry.ShortCode,
-		&entry.OriginalURL,
-		&entry.CreatedAt,
-		&entry.CreatedBy,
-		&entry.HitCount,
-	)
-
+	entry, err := scanEntry(s.db.QueryRowContext(ctx, query, shortCode))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return storage.Entry{}, storage.ErrNotFound
 		}
-		return storage.Entry{}, err
+		return storage.Entry{}, classifyError(ctx, err)
 	}
 
 	return entry, nil
@@ -78,36 +153,207 @@ func (s *Store) IncrementHits(ctx context.Context, shortCode string) (storage.En
 		UPDATE urls
 		SET hit_count = hit_count + 1
 		WHERE short_code = $1
-		RETURNING short_code, original_url, created_at, created_by, hit_count
-	`
-
-	var entry storage.Entry
-	err := s.db.QueryRowContext(ctx, query, shortCode).Scan(
-		&entry.ShortCode,
-		&entry.OriginalURL,
-		&entry.CreatedAt,
-		&entry.CreatedBy,
-		&entry.HitCount,
-	)
+		RETURNING ` + entryColumns
 
+	entry, err := scanEntry(s.db.QueryRowContext(ctx, query, shortCode))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return storage.Entry{}, storage.ErrNotFound
 		}
-		return storage.Entry{}, err
+		return storage.Entry{}, classifyError(ctx, err)
 	}
 
 	return entry, nil
 }
 
-// Helper to detect PostgreSQL unique constraint violations
-func isPgUniqueViolation(err error) bool {
-	// This is a simplified check - you might want to use pgconn.PgError
-	// from github.com/jackc/pgx/v5/pgconn for more robust checking
-	return err != nil && (
-	// Check error message for unique violation hints
-	// Note: This is fragile - better to use pgconn.PgError
-	sql.ErrNoRows != err &&
-		(err.Error() == "duplicate key value violates unique constraint" ||
-			err.Error() == "UNIQUE constraint failed"))
+func (s *Store) List(ctx context.Context, opts storage.ListOptions) ([]storage.Entry, error) {
+	// LIMIT NULL means no limit.
+	var limit sql.NullInt64
+	if opts.Limit > 0 {
+		limit = sql.NullInt64{Int64: int64(opts.Limit), Valid: true}
+	}
+	args := []any{opts.CreatedBy, limit, max(opts.Offset, 0), opts.Tag, escapeLike(opts.Tag) + "/%", opts.Dead}
+
+	var query strings.Builder
+	query.WriteString(`
+		SELECT ` + entryColumns + `
+		FROM urls
+		WHERE ($1 = '' OR created_by = $1)
+		AND ($4 = '' OR tags @> ARRAY[$4] OR EXISTS (SELECT 1 FROM unnest(tags) AS t WHERE t LIKE $5))
+		AND (NOT $6 OR (checked_at IS NOT NULL AND (check_status = 0 OR check_status >= 400)))
+	`)
+	// One condition per word, so the trigram index serves each of them.
+	for _, word := range strings.Fields(strings.ToLower(opts.Query)) {
+		args = append(args, "%"+escapeLike(word)+"%")
+		query.WriteString(`AND ` + searchText + ` LIKE $` + strconv.Itoa(len(args)) + "\n")
+	}
+	query.WriteString(`
+		ORDER BY created_at DESC, short_code
+		LIMIT $2 OFFSET $3
+	`)
+
+	rows, err := s.db.QueryContext(ctx, query.String(), args...)
+	if err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	defer rows.Close()
+
+	entries := []storage.Entry{}
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classifyError(ctx, err)
+	}
+
+	return entries, nil
+}
+
+func (s *Store) ListExpired(ctx context.Context, since, until time.Time) ([]storage.Entry, error) {
+	query := `
+		SELECT ` + entryColumns + `
+		FROM urls
+		WHERE expires_at > $1 AND expires_at <= $2
+		ORDER BY expires_at, short_code
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, since, until)
+	if err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	defer rows.Close()
+
+	entries := []storage.Entry{}
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	return entries, nil
+}
+
+func (s *Store) Update(ctx context.Context, entry storage.Entry) error {
+	// hit_count and the check columns are left to IncrementHits and
+	// RecordCheck; a new destination drops the old one's check. On the right
+	// of SET, original_url is still the stored value.
+	query := `
+		UPDATE urls
+		SET original_url = $2,
+			created_at = COALESCE($3, created_at),
+			created_by = $4,
+			expires_at = $5,
+			disabled = $6,
+			disabled_reason = $7,
+			title = $8,
+			description = $9,
+			tags = $10,
+			signed = $11,
+			activates_at = $12,
+			checked_at = CASE WHEN original_url = $2 THEN checked_at END,
+			check_status = CASE WHEN original_url = $2 THEN check_status ELSE 0 END,
+			check_error = CASE WHEN original_url = $2 THEN check_error ELSE '' END
+		WHERE short_code = $1
+	`
+
+	res, err := s.db.ExecContext(ctx, query,
+		entry.ShortCode,
+		entry.OriginalURL,
+		nullTime(entry.CreatedAt),
+		entry.CreatedBy,
+		nullTime(entry.ExpiresAt),
+		entry.Disabled,
+		entry.DisabledReason,
+		entry.Title,
+		entry.Description,
+		tags(entry.Tags),
+		entry.Signed,
+		nullTime(entry.ActivatesAt),
+	)
+	if err != nil {
+		return classifyError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) Overwrite(ctx context.Context, entry storage.Entry) error {
+	query := `
+		UPDATE urls
+		SET original_url = $2,
+			created_at = COALESCE($3, created_at),
+			created_by = $4,
+			hit_count = $5,
+			expires_at = $6,
+			disabled = $7,
+			disabled_reason = $8,
+			title = $9,
+			description = $10,
+			tags = $11,
+			checked_at = $12,
+			check_status = $13,
+			check_error = $14,
+			signed = $15,
+			activates_at = $16
+		WHERE short_code = $1
+	`
+
+	res, err := s.db.ExecContext(ctx, query,
+		entry.ShortCode,
+		entry.OriginalURL,
+		nullTime(entry.CreatedAt),
+		entry.CreatedBy,
+		entry.HitCount,
+		nullTime(entry.ExpiresAt),
+		entry.Disabled,
+		entry.DisabledReason,
+		entry.Title,
+		entry.Description,
+		tags(entry.Tags),
+		nullTime(entry.CheckedAt),
+		entry.CheckStatus,
+		entry.CheckError,
+		entry.Signed,
+		nullTime(entry.ActivatesAt),
+	)
+	if err != nil {
+		return classifyError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) Delete(ctx context.Context, shortCode string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM urls WHERE short_code = $1`, shortCode)
+	if err != nil {
+		return classifyError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
 }
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"urlshortener/internal/services/storage"
+	"urlshortener/internal/services/storage/storagetest"
+)
+
+// testDSNEnv names the env var pointing at a disposable database, e.g.
+// "host=localhost port=5439 user=urlshortener password=supersecret dbname=urlshortener_test sslmode=disable".
+// The tests truncate the urls table, so never point it at real data.
+const testDSNEnv = "SHORTENER_TEST_POSTGRES_DSN"
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set; skipping postgres tests", testDSNEnv)
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `TRUNCATE urls, link_versions, api_keys, sessions, reports, audit_log, webhook_outbox, idempotency_keys`); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	return db
+}
+
+func TestStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		return NewStore(openTestDB(t))
+	})
+}
+
+func TestKeyStore(t *testing.T) {
+	storagetest.RunKeyStore(t, func(t *testing.T) storage.KeyStore {
+		return NewStore(openTestDB(t))
+	})
+}
+
+func TestReportStore(t *testing.T) {
+	storagetest.RunReportStore(t, func(t *testing.T) storage.ReportStore {
+		return NewStore(openTestDB(t))
+	})
+}
+
+func TestAuditStore(t *testing.T) {
+	storagetest.RunAuditStore(t, func(t *testing.T) storage.AuditStore {
+		return NewStore(openTestDB(t))
+	})
+}
+
+func TestOutboxStore(t *testing.T) {
+	storagetest.RunOutboxStore(t, func(t *testing.T) storage.OutboxStore {
+		return NewStore(openTestDB(t))
+	})
+}
+
+func TestVersionStore(t *testing.T) {
+	storagetest.RunVersionStore(t, func(t *testing.T) storagetest.VersionStore {
+		return NewStore(openTestDB(t))
+	})
+}
+
+func TestIdempotencyStore(t *testing.T) {
+	storagetest.RunIdempotencyStore(t, func(t *testing.T) storage.IdempotencyStore {
+		return NewStore(openTestDB(t))
+	})
+}
+
+func TestHealthStore(t *testing.T) {
+	storagetest.RunHealthStore(t, func(t *testing.T) storagetest.HealthStore {
+		return NewStore(openTestDB(t))
+	})
+}
+
+func TestExpiryStore(t *testing.T) {
+	storagetest.RunExpiryStore(t, func(t *testing.T) storagetest.ExpiryStore {
+		return NewStore(openTestDB(t))
+	})
+}
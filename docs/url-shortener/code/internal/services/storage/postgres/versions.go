@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"urlshortener/internal/services/storage"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const versionColumns = `short_code, version, at, actor, action, original_url, expires_at, activates_at, disabled, disabled_reason, title, description, tags`
+
+func scanVersion(row rowScanner) (storage.LinkVersion, error) {
+	var v storage.LinkVersion
+	var expiresAt, activatesAt sql.NullTime
+	err := row.Scan(
+		&v.ShortCode,
+		&v.Version,
+		&v.At,
+		&v.Actor,
+		&v.Action,
+		&v.OriginalURL,
+		&expiresAt,
+		&activatesAt,
+		&v.Disabled,
+		&v.DisabledReason,
+		&v.Title,
+		&v.Description,
+		pgtype.NewMap().SQLScanner(&v.Tags),
+	)
+	if err != nil {
+		return storage.LinkVersion{}, err
+	}
+	v.ExpiresAt = expiresAt.Time
+	v.ActivatesAt = activatesAt.Time
+	return v, nil
+}
+
+func (s *Store) AppendVersion(ctx context.Context, v storage.LinkVersion) (int, error) {
+	// Selecting from urls makes a missing link no rows rather than a foreign
+	// key error. Racing appends collide on the primary key: ErrConflict.
+	query := `
+		INSERT INTO link_versions (` + versionColumns + `)
+		SELECT u.short_code,
+			COALESCE((SELECT MAX(version) FROM link_versions WHERE short_code = $1), 0) + 1,
+			$2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+		FROM urls u
+		WHERE u.short_code = $1
+		RETURNING version
+	`
+
+	var version int
+	err := s.db.QueryRowContext(ctx, query,
+		v.ShortCode,
+		v.At,
+		v.Actor,
+		v.Action,
+		v.OriginalURL,
+		nullTime(v.ExpiresAt),
+		nullTime(v.ActivatesAt),
+		v.Disabled,
+		v.DisabledReason,
+		v.Title,
+		v.Description,
+		tags(v.Tags),
+	).Scan(&version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrNotFound
+		}
+		return 0, classifyError(ctx, err)
+	}
+	return version, nil
+}
+
+func (s *Store) ListVersions(ctx context.Context, shortCode string) ([]storage.LinkVersion, error) {
+	query := `
+		SELECT ` + versionColumns + `
+		FROM link_versions
+		WHERE short_code = $1
+		ORDER BY version DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, shortCode)
+	if err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	defer rows.Close()
+
+	versions := []storage.LinkVersion{}
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	return versions, nil
+}
+
+func (s *Store) FindVersion(ctx context.Context, shortCode string, version int) (storage.LinkVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM link_versions WHERE short_code = $1 AND version = $2`
+
+	v, err := scanVersion(s.db.QueryRowContext(ctx, query, shortCode, version))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.LinkVersion{}, storage.ErrNotFound
+		}
+		return storage.LinkVersion{}, classifyError(ctx, err)
+	}
+	return v, nil
+}
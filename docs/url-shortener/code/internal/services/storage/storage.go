@@ -1,8 +1,12 @@
 package storage
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"errors"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -13,16 +17,497 @@ type Entry struct {
 	CreatedAt   time.Time
 	CreatedBy   string
 	HitCount    int64
+	ExpiresAt   time.Time // zero means the link never expires
+	// ActivatesAt, when set, is when the link starts redirecting; until then
+	// visitors get a coming-soon page instead of the destination.
+	ActivatesAt time.Time
+	// Signed links only resolve through a URL carrying a valid signature, so
+	// knowing the code is not enough.
+	Signed bool
+	// Disabled links were taken down by a moderator and no longer redirect.
+	Disabled       bool
+	DisabledReason string // one of the report reasons, e.g. "phishing"
+	// Title, Description and Tags help people find links. A tag containing
+	// "/" files the link in a folder: "marketing/q3-launch" is in "marketing".
+	Title       string
+	Description string
+	Tags        []string
+	// CheckedAt, CheckStatus and CheckError are the outcome of the last
+	// destination health check; CheckedAt is zero until the first one.
+	CheckedAt   time.Time
+	CheckStatus int    // HTTP status, 0 when no response was received
+	CheckError  string // why the check failed, if it did
+}
+
+// Expired reports whether the entry has an expiry that is not after now.
+func (e Entry) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// Pending reports whether the entry has an activation time after now.
+func (e Entry) Pending(now time.Time) bool {
+	return !e.ActivatesAt.IsZero() && now.Before(e.ActivatesAt)
 }
 
 var (
 	ErrNotFound = errors.New("storage: short code not found")
 	ErrConflict = errors.New("storage: short code already exists")
+	// ErrRetryable wraps transient backend failures (lost connections,
+	// serialization failures) where repeating the operation may succeed.
+	ErrRetryable = errors.New("storage: transient failure")
 )
 
+// Dead reports whether the last health check of e's destination failed: no
+// response, or a 4xx or 5xx status.
+func (e Entry) Dead() bool {
+	return !e.CheckedAt.IsZero() && (e.CheckStatus == 0 || e.CheckStatus >= 400)
+}
+
+// HasTag reports whether e is tagged with tag or filed in a folder below it.
+func (e Entry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag || strings.HasPrefix(t, tag+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesQuery reports whether every word of query appears, ignoring case, in
+// e's title, description, destination, short code or tags.
+func (e Entry) MatchesQuery(query string) bool {
+	text := strings.ToLower(strings.Join(append([]string{e.Title, e.Description, e.OriginalURL, e.ShortCode}, e.Tags...), " "))
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// ListOptions filters and pages List results. Entries come back newest first.
+type ListOptions struct {
+	CreatedBy string // empty matches every owner
+	Query     string // see Entry.MatchesQuery; empty matches everything
+	Tag       string // see Entry.HasTag; empty matches everything
+	Dead      bool   // only links whose last health check failed
+	Limit     int    // <= 0 means no limit
+	Offset    int
+}
+
 // Store defines the persistence contract the shortener service depends on.
 type Store interface {
 	Save(ctx context.Context, entry Entry) error
 	Find(ctx context.Context, shortCode string) (Entry, error)
 	IncrementHits(ctx context.Context, shortCode string) (Entry, error)
+	List(ctx context.Context, opts ListOptions) ([]Entry, error)
+	// Update replaces the stored entry with the same ShortCode, except for
+	// what other writers keep current: the hit count stays as stored, and so
+	// does the last health check unless OriginalURL changes, which clears it.
+	// A zero CreatedAt keeps the stored one.
+	Update(ctx context.Context, entry Entry) error
+	// Overwrite replaces the stored entry with the same ShortCode outright,
+	// hit count and health check included, as an import does. A zero
+	// CreatedAt keeps the stored one.
+	Overwrite(ctx context.Context, entry Entry) error
+	Delete(ctx context.Context, shortCode string) error
+}
+
+// APIKey is a credential for the HTTP API. Only a hash of the secret is stored.
+type APIKey struct {
+	ID        string
+	Name      string
+	Hash      string
+	CreatedAt time.Time
+	RevokedAt time.Time // zero while the key is active
+}
+
+func (k APIKey) Revoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+// Session is a dashboard sign-in. The browser holds a random token; only its
+// hash is stored, next to the key it signed in with.
+type Session struct {
+	Hash      string
+	KeyID     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// KeyStore persists API keys and the dashboard sessions made with them.
+// Lookups return ErrNotFound for unknown keys; revoked keys are still
+// returned so callers can tell them apart.
+type KeyStore interface {
+	SaveAPIKey(ctx context.Context, key APIKey) error
+	FindAPIKey(ctx context.Context, id string) (APIKey, error)
+	FindAPIKeyByHash(ctx context.Context, hash string) (APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string, at time.Time) error
+
+	// SaveSession stores a new session; a hash already in use is
+	// ErrConflict.
+	SaveSession(ctx context.Context, session Session) error
+	// FindSession returns the session with hash, expired or not.
+	FindSession(ctx context.Context, hash string) (Session, error)
+	// DeleteSession removes the session, if it exists.
+	DeleteSession(ctx context.Context, hash string) error
+	// PurgeSessions deletes the sessions expired at now and reports how many
+	// there were.
+	PurgeSessions(ctx context.Context, now time.Time) (int, error)
+}
+
+// Report is an abuse report filed against a link. Reports outlive the link
+// they name so the moderation trail stays complete.
+type Report struct {
+	ID         string
+	ShortCode  string
+	Reason     string
+	Details    string
+	Reporter   string // who filed it, e.g. "ip:203.0.113.7" or "key:<id>"
+	CreatedAt  time.Time
+	ResolvedAt time.Time // zero while the report is open
+	ResolvedBy string
+	Resolution string // e.g. "disabled" or "dismissed"
+}
+
+func (r Report) Open() bool {
+	return r.ResolvedAt.IsZero()
+}
+
+// ReportListOptions filters and pages ListReports results. Reports come back
+// oldest first, so the queue is worked in the order it was filed.
+type ReportListOptions struct {
+	ShortCode string // empty matches every link
+	OpenOnly  bool
+	Limit     int // <= 0 means no limit
+	Offset    int
+}
+
+// ReportStore persists abuse reports. Resolving an already resolved report
+// is a no-op that keeps the first resolution.
+type ReportStore interface {
+	SaveReport(ctx context.Context, report Report) error
+	FindReport(ctx context.Context, id string) (Report, error)
+	ListReports(ctx context.Context, opts ReportListOptions) ([]Report, error)
+	ResolveReport(ctx context.Context, id, resolution, by string, at time.Time) error
+}
+
+// AuditEvent is one entry in the audit trail of link changes. Before and
+// After hold the link's settings as JSON; Before is nil for creations and
+// After is nil for deletions.
+type AuditEvent struct {
+	ID        string // sorts in the order events were recorded
+	At        time.Time
+	Actor     string // e.g. "key:<id>", "cli:alice" or "anonymous"
+	Action    string // e.g. "create", "retarget", "delete"
+	ShortCode string
+	RequestID string
+	Before    json.RawMessage
+	After     json.RawMessage
+}
+
+// AuditFilter selects audit events. Events come back newest first.
+type AuditFilter struct {
+	ShortCode string    // empty matches every link
+	Actor     string    // empty matches every actor
+	Action    string    // empty matches every action
+	Since     time.Time // inclusive; zero means no lower bound
+	Until     time.Time // exclusive; zero means no upper bound
+	Limit     int       // <= 0 means no limit
+	Offset    int
+}
+
+// AuditStore is an append-only audit trail: events are never changed or
+// removed once appended.
+type AuditStore interface {
+	AppendAudit(ctx context.Context, event AuditEvent) error
+	ListAudit(ctx context.Context, filter AuditFilter) ([]AuditEvent, error)
+}
+
+// Webhook delivery states. Pending deliveries are retried until they succeed
+// or run out of attempts, which leaves them failed until replayed.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event queued for one webhook endpoint: a row of the
+// outbox. Payload is the exact JSON body that gets signed and sent.
+type WebhookDelivery struct {
+	ID            string
+	Endpoint      string // the endpoint's configured name
+	Event         string // e.g. "link.created"
+	Payload       []byte
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time // when a pending delivery is next due
+	LastError     string
+	LastStatus    int // HTTP status of the last attempt, 0 if none was received
+	CreatedAt     time.Time
+	DeliveredAt   time.Time // zero until delivered
+}
+
+// DeliveryListOptions filters and pages ListDeliveries results. Deliveries
+// come back newest first.
+type DeliveryListOptions struct {
+	Status string // empty matches every status
+	Limit  int    // <= 0 means no limit
+	Offset int
+}
+
+// OutboxStore persists webhook deliveries so queued events survive restarts.
+type OutboxStore interface {
+	// EnqueueDelivery adds a delivery; a duplicate ID is ErrConflict, which
+	// lets callers enqueue idempotently.
+	EnqueueDelivery(ctx context.Context, d WebhookDelivery) error
+	FindDelivery(ctx context.Context, id string) (WebhookDelivery, error)
+	// ClaimDueDeliveries takes the pending deliveries due at or before now,
+	// the longest-waiting first, and moves their next attempt to until in
+	// the same step, so no other dispatcher takes them before then. The
+	// deliveries come back with the new NextAttemptAt.
+	ClaimDueDeliveries(ctx context.Context, now, until time.Time, limit int) ([]WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, opts DeliveryListOptions) ([]WebhookDelivery, error)
+	// UpdateDelivery stores the outcome of an attempt (or a replay).
+	UpdateDelivery(ctx context.Context, d WebhookDelivery) error
+}
+
+// LinkCheck is the outcome of one destination health check.
+type LinkCheck struct {
+	At     time.Time
+	Status int    // HTTP status, 0 when no response was received
+	Error  string // empty when the check got a response
+}
+
+// HealthStore keeps track of destination health checks.
+type HealthStore interface {
+	// DueForCheck returns links that are not disabled and were last checked
+	// before the given time, or never, least recently checked first.
+	DueForCheck(ctx context.Context, before time.Time, limit int) ([]Entry, error)
+	// RecordCheck stores the outcome of a check and leaves the rest of the
+	// link alone, so it cannot undo a concurrent edit.
+	RecordCheck(ctx context.Context, shortCode string, check LinkCheck) error
+}
+
+// ExpiryStore finds links by when they expire.
+type ExpiryStore interface {
+	// ListExpired returns the links whose expiry falls in (since, until],
+	// the earliest first.
+	ListExpired(ctx context.Context, since, until time.Time) ([]Entry, error)
+}
+
+// LinkVersion is a link's destination and settings as one change left them.
+// Versions count up from 1 per link; links from before history was kept
+// start with the state their first change replaced.
+type LinkVersion struct {
+	ShortCode      string
+	Version        int
+	At             time.Time
+	Actor          string // who made the change, e.g. "key:<id>"
+	Action         string // the audit action that made it, e.g. "retarget"
+	OriginalURL    string
+	ExpiresAt      time.Time
+	ActivatesAt    time.Time
+	Disabled       bool
+	DisabledReason string
+	Title          string
+	Description    string
+	Tags           []string
+}
+
+// VersionStore keeps the history of each link. Deleting a link deletes its
+// versions, so a code that is reused starts a new history.
+type VersionStore interface {
+	// AppendVersion adds v as the link's next version, ignoring v.Version,
+	// and returns the number it got. Two appends racing for one number may
+	// fail with ErrConflict.
+	AppendVersion(ctx context.Context, v LinkVersion) (int, error)
+	// ListVersions returns the link's versions, newest first; a link without
+	// history has none.
+	ListVersions(ctx context.Context, shortCode string) ([]LinkVersion, error)
+	FindVersion(ctx context.Context, shortCode string, version int) (LinkVersion, error)
+}
+
+// IdempotencyRecord remembers the response to a request sent with an
+// Idempotency-Key, so a retry of the request gets the same response instead
+// of repeating its effect. Status is 0 while the first request is still
+// being served.
+type IdempotencyRecord struct {
+	Owner       string // who sent the key, e.g. "key:<id>" or "ip:203.0.113.7"
+	Key         string
+	RequestHash string // fingerprint of the request, to catch a reused key
+	// Claim is a random token set when the key is reserved. Only the request
+	// holding it may complete or release the record, so one whose claim went
+	// stale cannot touch the record of a retry that took over.
+	Claim       string
+	Status      int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Completed reports whether the record holds a response.
+func (r IdempotencyRecord) Completed() bool {
+	return r.Status != 0
+}
+
+// IdempotencyStore keeps idempotency records, one per owner and key.
+type IdempotencyStore interface {
+	// ReserveIdempotencyKey stores rec unless the owner already has a record
+	// for the key that has not expired at rec.CreatedAt, which is
+	// ErrConflict. An expired record is replaced.
+	ReserveIdempotencyKey(ctx context.Context, rec IdempotencyRecord) error
+	// FindIdempotencyKey returns the record for owner and key, expired or not.
+	FindIdempotencyKey(ctx context.Context, owner, key string) (IdempotencyRecord, error)
+	// CompleteIdempotencyKey stores the response and new expiry of a
+	// reserved record. A record that is missing or has another claim than
+	// rec.Claim is ErrNotFound.
+	CompleteIdempotencyKey(ctx context.Context, rec IdempotencyRecord) error
+	// ReleaseIdempotencyKey deletes the record, so the key can be used
+	// again, if it still has the given claim.
+	ReleaseIdempotencyKey(ctx context.Context, owner, key, claim string) error
+	// PurgeIdempotencyKeys deletes the records expired at now and reports how
+	// many there were.
+	PurgeIdempotencyKeys(ctx context.Context, now time.Time) (int, error)
+}
+
+// Backend is implemented by every concrete store: entries, link history, API
+// keys, abuse reports, the audit trail, the webhook outbox, link health
+// checks and idempotency records.
+type Backend interface {
+	Store
+	ExpiryStore
+	VersionStore
+	KeyStore
+	ReportStore
+	AuditStore
+	OutboxStore
+	HealthStore
+	IdempotencyStore
+}
+
+// paginate keeps the items match accepts, sorts them by compare and returns
+// the page at offset and limit (zero for no limit). Backends without a query
+// language list everything through it.
+func paginate[T any](items []T, match func(T) bool, compare func(a, b T) int, offset, limit int) []T {
+	filtered := make([]T, 0, len(items))
+	for _, item := range items {
+		if match(item) {
+			filtered = append(filtered, item)
+		}
+	}
+	slices.SortFunc(filtered, compare)
+
+	if offset > 0 {
+		if offset >= len(filtered) {
+			return []T{}
+		}
+		filtered = filtered[offset:]
+	}
+	if limit > 0 && limit < len(filtered) {
+		filtered = filtered[:limit]
+	}
+	return filtered
+}
+
+// ApplyUpdate returns the entry Update stores over current, for backends
+// without a query language.
+func ApplyUpdate(current, next Entry) Entry {
+	if next.CreatedAt.IsZero() {
+		next.CreatedAt = current.CreatedAt
+	}
+	next.HitCount = current.HitCount
+	if next.OriginalURL == current.OriginalURL {
+		next.CheckedAt, next.CheckStatus, next.CheckError = current.CheckedAt, current.CheckStatus, current.CheckError
+	} else {
+		next.CheckedAt, next.CheckStatus, next.CheckError = time.Time{}, 0, ""
+	}
+	return next
+}
+
+// ApplyListOptions filters, sorts (newest first, then by code) and pages
+// entries in memory.
+func ApplyListOptions(entries []Entry, opts ListOptions) []Entry {
+	return paginate(entries, func(e Entry) bool {
+		return (opts.CreatedBy == "" || e.CreatedBy == opts.CreatedBy) &&
+			(opts.Tag == "" || e.HasTag(opts.Tag)) &&
+			(opts.Query == "" || e.MatchesQuery(opts.Query)) &&
+			(!opts.Dead || e.Dead())
+	}, func(a, b Entry) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), strings.Compare(a.ShortCode, b.ShortCode))
+	}, opts.Offset, opts.Limit)
+}
+
+// ApplyReportListOptions filters, sorts (oldest first, then by ID) and pages
+// reports in memory.
+func ApplyReportListOptions(reports []Report, opts ReportListOptions) []Report {
+	return paginate(reports, func(r Report) bool {
+		return (opts.ShortCode == "" || r.ShortCode == opts.ShortCode) &&
+			(!opts.OpenOnly || r.Open())
+	}, func(a, b Report) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID, b.ID))
+	}, opts.Offset, opts.Limit)
+}
+
+// ApplyAuditFilter filters, sorts (newest first, then by ID) and pages audit
+// events in memory.
+func ApplyAuditFilter(events []AuditEvent, f AuditFilter) []AuditEvent {
+	return paginate(events, func(e AuditEvent) bool {
+		return (f.ShortCode == "" || e.ShortCode == f.ShortCode) &&
+			(f.Actor == "" || e.Actor == f.Actor) &&
+			(f.Action == "" || e.Action == f.Action) &&
+			(f.Since.IsZero() || !e.At.Before(f.Since)) &&
+			(f.Until.IsZero() || e.At.Before(f.Until))
+	}, func(a, b AuditEvent) int {
+		return cmp.Or(b.At.Compare(a.At), strings.Compare(b.ID, a.ID))
+	}, f.Offset, f.Limit)
+}
+
+// ApplyDeliveryListOptions filters, sorts (newest first, then by ID) and pages
+// deliveries in memory.
+func ApplyDeliveryListOptions(deliveries []WebhookDelivery, opts DeliveryListOptions) []WebhookDelivery {
+	return paginate(deliveries, func(d WebhookDelivery) bool {
+		return opts.Status == "" || d.Status == opts.Status
+	}, func(a, b WebhookDelivery) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), strings.Compare(b.ID, a.ID))
+	}, opts.Offset, opts.Limit)
+}
+
+// SelectDueDeliveries picks the pending deliveries due at now, longest-waiting
+// first, for backends without a query language.
+func SelectDueDeliveries(deliveries []WebhookDelivery, now time.Time, limit int) []WebhookDelivery {
+	return paginate(deliveries, func(d WebhookDelivery) bool {
+		return d.Status == DeliveryPending && !d.NextAttemptAt.After(now)
+	}, func(a, b WebhookDelivery) int {
+		return cmp.Or(a.NextAttemptAt.Compare(b.NextAttemptAt), strings.Compare(a.ID, b.ID))
+	}, 0, limit)
+}
+
+// SelectExpired picks the links ListExpired returns, for backends without a
+// query language.
+func SelectExpired(entries []Entry, since, until time.Time) []Entry {
+	return paginate(entries, func(e Entry) bool {
+		return !e.ExpiresAt.IsZero() && e.ExpiresAt.After(since) && !e.ExpiresAt.After(until)
+	}, func(a, b Entry) int {
+		return cmp.Or(a.ExpiresAt.Compare(b.ExpiresAt), strings.Compare(a.ShortCode, b.ShortCode))
+	}, 0, 0)
+}
+
+// SelectDueForCheck picks the links DueForCheck returns, for backends without
+// a query language.
+func SelectDueForCheck(entries []Entry, before time.Time, limit int) []Entry {
+	return paginate(entries, func(e Entry) bool {
+		return !e.Disabled && e.CheckedAt.Before(before)
+	}, func(a, b Entry) int {
+		return cmp.Or(a.CheckedAt.Compare(b.CheckedAt), strings.Compare(a.ShortCode, b.ShortCode))
+	}, 0, limit)
+}
+
+// SortAPIKeys orders keys by creation time, oldest first.
+func SortAPIKeys(keys []APIKey) {
+	slices.SortFunc(keys, func(a, b APIKey) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
 }
@@ -0,0 +1,175 @@
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+// AuditStoreFactory returns an empty audit store, like Factory.
+type AuditStoreFactory func(t *testing.T) storage.AuditStore
+
+// RunAuditStore executes the audit trail part of the suite.
+func RunAuditStore(t *testing.T, newStore AuditStoreFactory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store storage.AuditStore)
+	}{
+		{"AppendAndList", testAppendAndListAudit},
+		{"AppendConflict", testAppendAuditConflict},
+		{"Filter", testFilterAudit},
+		{"Paging", testPageAudit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+// sameJSON compares documents, ignoring the formatting a backend may apply.
+func sameJSON(t *testing.T, a, b json.RawMessage) bool {
+	t.Helper()
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatalf("invalid json %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatalf("invalid json %s: %v", b, err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func testAppendAndListAudit(t *testing.T, store storage.AuditStore) {
+	ctx := context.Background()
+	at := time.Now().UTC().Truncate(time.Millisecond)
+	want := storage.AuditEvent{
+		ID:        "0001",
+		At:        at,
+		Actor:     "key:k1",
+		Action:    "retarget",
+		ShortCode: "abc123",
+		RequestID: "req-1",
+		Before:    json.RawMessage(`{"original_url":"https://example.com/old"}`),
+		After:     json.RawMessage(`{"original_url":"https://example.com/new"}`),
+	}
+	if err := store.AppendAudit(ctx, want); err != nil {
+		t.Fatalf("AppendAudit returned error: %v", err)
+	}
+	created := storage.AuditEvent{ID: "0000", Actor: "key:k1", Action: "create", ShortCode: "abc123",
+		At: at.Add(-time.Minute), After: json.RawMessage(`{"original_url":"https://example.com/old"}`)}
+	if err := store.AppendAudit(ctx, created); err != nil {
+		t.Fatalf("AppendAudit returned error: %v", err)
+	}
+
+	events, err := store.ListAudit(ctx, storage.AuditFilter{})
+	if err != nil {
+		t.Fatalf("ListAudit returned error: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != "0001" || events[1].ID != "0000" {
+		t.Fatalf("expected both events newest first, got %+v", events)
+	}
+	got := events[0]
+	if got.Actor != want.Actor || got.Action != want.Action || got.ShortCode != want.ShortCode || got.RequestID != want.RequestID {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if !got.At.Equal(want.At) {
+		t.Fatalf("expected time %v, got %v", want.At, got.At)
+	}
+	if !sameJSON(t, got.Before, want.Before) || !sameJSON(t, got.After, want.After) {
+		t.Fatalf("expected before/after %s %s, got %s %s", want.Before, want.After, got.Before, got.After)
+	}
+	if len(events[1].Before) != 0 {
+		t.Fatalf("expected no before state for a creation, got %s", events[1].Before)
+	}
+}
+
+func testAppendAuditConflict(t *testing.T, store storage.AuditStore) {
+	ctx := context.Background()
+	event := storage.AuditEvent{ID: "0001", Actor: "anonymous", Action: "create", ShortCode: "abc123"}
+	if err := store.AppendAudit(ctx, event); err != nil {
+		t.Fatalf("AppendAudit returned error: %v", err)
+	}
+	if err := store.AppendAudit(ctx, event); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+}
+
+func testFilterAudit(t *testing.T, store storage.AuditStore) {
+	ctx := context.Background()
+	base := time.Now().UTC().Truncate(time.Second)
+	for i, e := range []storage.AuditEvent{
+		{Actor: "key:a", Action: "create", ShortCode: "one"},
+		{Actor: "key:b", Action: "create", ShortCode: "two"},
+		{Actor: "key:a", Action: "delete", ShortCode: "one"},
+	} {
+		e.ID = string(rune('a' + i))
+		e.At = base.Add(time.Duration(i) * time.Minute)
+		if err := store.AppendAudit(ctx, e); err != nil {
+			t.Fatalf("AppendAudit returned error: %v", err)
+		}
+	}
+
+	cases := map[string]struct {
+		filter storage.AuditFilter
+		want   []string
+	}{
+		"code":   {storage.AuditFilter{ShortCode: "one"}, []string{"c", "a"}},
+		"actor":  {storage.AuditFilter{Actor: "key:b"}, []string{"b"}},
+		"action": {storage.AuditFilter{Action: "create"}, []string{"b", "a"}},
+		"since":  {storage.AuditFilter{Since: base.Add(time.Minute)}, []string{"c", "b"}},
+		"until":  {storage.AuditFilter{Until: base.Add(time.Minute)}, []string{"a"}},
+		"window": {storage.AuditFilter{Since: base.Add(time.Minute), Until: base.Add(2 * time.Minute)}, []string{"b"}},
+		"none":   {storage.AuditFilter{Actor: "key:c"}, []string{}},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			events, err := store.ListAudit(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("ListAudit returned error: %v", err)
+			}
+			got := make([]string, len(events))
+			for i, e := range events {
+				got[i] = e.ID
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("expected %v, got %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func testPageAudit(t *testing.T, store storage.AuditStore) {
+	ctx := context.Background()
+	base := time.Now().UTC().Truncate(time.Second)
+	for i := range 5 {
+		e := storage.AuditEvent{ID: string(rune('a' + i)), At: base.Add(time.Duration(i) * time.Second),
+			Actor: "anonymous", Action: "create", ShortCode: "code"}
+		if err := store.AppendAudit(ctx, e); err != nil {
+			t.Fatalf("AppendAudit returned error: %v", err)
+		}
+	}
+
+	events, err := store.ListAudit(ctx, storage.AuditFilter{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("ListAudit returned error: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != "d" || events[1].ID != "c" {
+		t.Fatalf("expected events d and c, got %+v", events)
+	}
+}
@@ -0,0 +1,75 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+// ExpiryStore is what the expiry suite needs: links, and a way to find them
+// by when they expire.
+type ExpiryStore interface {
+	storage.Store
+	storage.ExpiryStore
+}
+
+// ExpiryStoreFactory returns an empty store, like Factory.
+type ExpiryStoreFactory func(t *testing.T) ExpiryStore
+
+// RunExpiryStore executes the link expiry part of the suite.
+func RunExpiryStore(t *testing.T, newStore ExpiryStoreFactory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store ExpiryStore)
+	}{
+		{"ListExpired", testListExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func testListExpired(t *testing.T, store ExpiryStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	since := now.Add(-time.Hour)
+	for _, e := range []storage.Entry{
+		{ShortCode: "recent", ExpiresAt: now.Add(-time.Minute)},
+		{ShortCode: "edge", ExpiresAt: now},
+		{ShortCode: "earlier", ExpiresAt: now.Add(-30 * time.Minute)},
+		{ShortCode: "start", ExpiresAt: since},
+		{ShortCode: "old", ExpiresAt: now.Add(-48 * time.Hour)},
+		{ShortCode: "live", ExpiresAt: now.Add(time.Hour)},
+		{ShortCode: "never"},
+	} {
+		e.OriginalURL = "https://example.com/" + e.ShortCode
+		if err := store.Save(ctx, e); err != nil {
+			t.Fatalf("Save returned error: %v", err)
+		}
+	}
+
+	expired, err := store.ListExpired(ctx, since, now)
+	if err != nil {
+		t.Fatalf("ListExpired returned error: %v", err)
+	}
+	if got, want := codes(expired), []string{"earlier", "recent", "edge"}; !equalCodes(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if !expired[0].ExpiresAt.Equal(now.Add(-30 * time.Minute)) {
+		t.Fatalf("unexpected expiry %v", expired[0].ExpiresAt)
+	}
+
+	expired, err = store.ListExpired(ctx, now, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("ListExpired returned error: %v", err)
+	}
+	if len(expired) != 0 {
+		t.Fatalf("expected nothing expiring in the next minute, got %v", codes(expired))
+	}
+}
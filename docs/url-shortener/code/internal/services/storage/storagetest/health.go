@@ -0,0 +1,168 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+// HealthStore is what the health check suite needs: links, and a place to
+// record how their destinations responded.
+type HealthStore interface {
+	storage.Store
+	storage.HealthStore
+}
+
+// HealthStoreFactory returns an empty store, like Factory.
+type HealthStoreFactory func(t *testing.T) HealthStore
+
+// RunHealthStore executes the link health check part of the suite.
+func RunHealthStore(t *testing.T, newStore HealthStoreFactory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store HealthStore)
+	}{
+		{"RecordAndFind", testRecordCheck},
+		{"RecordMissing", testRecordCheckMissing},
+		{"UpdateKeepsCheck", testUpdateKeepsCheck},
+		{"DueForCheck", testDueForCheck},
+		{"ListDead", testListDead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func testRecordCheck(t *testing.T, store HealthStore) {
+	ctx := context.Background()
+	if err := store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com", Title: "Home", HitCount: 4}); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	at := time.Now().UTC().Truncate(time.Millisecond)
+	check := storage.LinkCheck{At: at, Status: 0, Error: "dial tcp: connection refused"}
+	if err := store.RecordCheck(ctx, "abc123", check); err != nil {
+		t.Fatalf("RecordCheck returned error: %v", err)
+	}
+	got, err := store.Find(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if !got.CheckedAt.Equal(at) || got.CheckStatus != 0 || got.CheckError != check.Error || !got.Dead() {
+		t.Fatalf("expected the failed check to be stored, got %+v", got)
+	}
+	if got.Title != "Home" || got.HitCount != 4 {
+		t.Fatalf("expected the rest of the link to be kept, got %+v", got)
+	}
+
+	// A later check replaces the earlier one.
+	if err := store.RecordCheck(ctx, "abc123", storage.LinkCheck{At: at.Add(time.Hour), Status: 200}); err != nil {
+		t.Fatalf("RecordCheck returned error: %v", err)
+	}
+	got, _ = store.Find(ctx, "abc123")
+	if got.CheckStatus != 200 || got.CheckError != "" || got.Dead() {
+		t.Fatalf("expected a healthy link, got %+v", got)
+	}
+}
+
+// testUpdateKeepsCheck records a check between an edit's Find and Update.
+func testUpdateKeepsCheck(t *testing.T, store HealthStore) {
+	ctx := context.Background()
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com"})
+
+	entry, err := store.Find(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	at := time.Now().UTC().Truncate(time.Millisecond)
+	if err := store.RecordCheck(ctx, "abc123", storage.LinkCheck{At: at, Status: 404}); err != nil {
+		t.Fatalf("RecordCheck returned error: %v", err)
+	}
+	entry.Title = "Edited"
+	if err := store.Update(ctx, entry); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	got, _ := store.Find(ctx, "abc123")
+	if !got.CheckedAt.Equal(at) || got.CheckStatus != 404 || got.Title != "Edited" {
+		t.Fatalf("expected the edit and the check to both survive, got %+v", got)
+	}
+
+	// The check was of the old destination, so a new one drops it.
+	got.OriginalURL = "https://example.com/moved"
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	got, _ = store.Find(ctx, "abc123")
+	if !got.CheckedAt.IsZero() || got.CheckStatus != 0 || got.CheckError != "" {
+		t.Fatalf("expected the check to be cleared, got %+v", got)
+	}
+}
+
+func testRecordCheckMissing(t *testing.T, store HealthStore) {
+	err := store.RecordCheck(context.Background(), "nope", storage.LinkCheck{At: time.Now(), Status: 200})
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testDueForCheck(t *testing.T, store HealthStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for _, e := range []storage.Entry{
+		{ShortCode: "fresh", CheckedAt: now.Add(-time.Minute), CheckStatus: 200},
+		{ShortCode: "stale", CheckedAt: now.Add(-2 * time.Hour), CheckStatus: 200},
+		{ShortCode: "older", CheckedAt: now.Add(-3 * time.Hour), CheckStatus: 404},
+		{ShortCode: "never"},
+		{ShortCode: "taken", Disabled: true, DisabledReason: "spam"},
+	} {
+		e.OriginalURL = "https://example.com/" + e.ShortCode
+		if err := store.Save(ctx, e); err != nil {
+			t.Fatalf("Save returned error: %v", err)
+		}
+	}
+
+	due, err := store.DueForCheck(ctx, now.Add(-time.Hour), 0)
+	if err != nil {
+		t.Fatalf("DueForCheck returned error: %v", err)
+	}
+	if got, want := codes(due), []string{"never", "older", "stale"}; !equalCodes(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	due, _ = store.DueForCheck(ctx, now.Add(-time.Hour), 2)
+	if got, want := codes(due), []string{"never", "older"}; !equalCodes(got, want) {
+		t.Fatalf("expected the first page %v, got %v", want, got)
+	}
+}
+
+func testListDead(t *testing.T, store HealthStore) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	for _, e := range []storage.Entry{
+		{ShortCode: "ok", CheckedAt: now, CheckStatus: 200},
+		{ShortCode: "gone", CheckedAt: now, CheckStatus: 404},
+		{ShortCode: "down", CheckedAt: now, CheckError: "timeout"},
+		{ShortCode: "moved", CheckedAt: now, CheckStatus: 301},
+		{ShortCode: "never"},
+	} {
+		e.OriginalURL = "https://example.com/" + e.ShortCode
+		e.CreatedAt = now
+		if err := store.Save(ctx, e); err != nil {
+			t.Fatalf("Save returned error: %v", err)
+		}
+	}
+
+	dead, err := store.List(ctx, storage.ListOptions{Dead: true})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if got, want := codes(dead), []string{"down", "gone"}; !equalCodes(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
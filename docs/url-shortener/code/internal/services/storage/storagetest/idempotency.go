@@ -0,0 +1,217 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+// IdempotencyStoreFactory returns an empty idempotency store, like Factory.
+type IdempotencyStoreFactory func(t *testing.T) storage.IdempotencyStore
+
+// RunIdempotencyStore executes the idempotency record part of the suite.
+func RunIdempotencyStore(t *testing.T, newStore IdempotencyStoreFactory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store storage.IdempotencyStore)
+	}{
+		{"ReserveAndComplete", testReserveAndCompleteIdempotencyKey},
+		{"ReserveConflict", testReserveIdempotencyKeyConflict},
+		{"ReserveExpired", testReserveExpiredIdempotencyKey},
+		{"Release", testReleaseIdempotencyKey},
+		{"Takeover", testIdempotencyKeyTakeover},
+		{"Purge", testPurgeIdempotencyKeys},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newIdempotencyRecord(owner, key string, now time.Time, ttl time.Duration) storage.IdempotencyRecord {
+	return storage.IdempotencyRecord{
+		Owner:       owner,
+		Key:         key,
+		RequestHash: "hash-" + key,
+		Claim:       "claim-" + now.Format(time.RFC3339Nano),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+}
+
+func testReserveAndCompleteIdempotencyKey(t *testing.T, store storage.IdempotencyStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	rec := newIdempotencyRecord("key:k1", "retry-1", now, time.Minute)
+	if err := store.ReserveIdempotencyKey(ctx, rec); err != nil {
+		t.Fatalf("ReserveIdempotencyKey returned error: %v", err)
+	}
+	got, err := store.FindIdempotencyKey(ctx, "key:k1", "retry-1")
+	if err != nil {
+		t.Fatalf("FindIdempotencyKey returned error: %v", err)
+	}
+	if got.Completed() || got.RequestHash != "hash-retry-1" || !got.ExpiresAt.Equal(rec.ExpiresAt) {
+		t.Fatalf("unexpected reserved record: %+v", got)
+	}
+
+	rec.Status = 200
+	rec.ContentType = "application/json"
+	rec.Body = []byte(`{"short_code":"abc123"}`)
+	rec.ExpiresAt = now.Add(24 * time.Hour)
+	if err := store.CompleteIdempotencyKey(ctx, rec); err != nil {
+		t.Fatalf("CompleteIdempotencyKey returned error: %v", err)
+	}
+	got, err = store.FindIdempotencyKey(ctx, "key:k1", "retry-1")
+	if err != nil {
+		t.Fatalf("FindIdempotencyKey returned error: %v", err)
+	}
+	if got.Status != 200 || got.ContentType != "application/json" || string(got.Body) != `{"short_code":"abc123"}` || !got.ExpiresAt.Equal(rec.ExpiresAt) || !got.CreatedAt.Equal(now) {
+		t.Fatalf("unexpected completed record: %+v", got)
+	}
+
+	if _, err := store.FindIdempotencyKey(ctx, "key:k2", "retry-1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected another owner's key to be ErrNotFound, got %v", err)
+	}
+	missing := newIdempotencyRecord("key:k1", "never-reserved", now, time.Minute)
+	missing.Status = 200
+	if err := store.CompleteIdempotencyKey(ctx, missing); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound completing an unreserved key, got %v", err)
+	}
+}
+
+func testReserveIdempotencyKeyConflict(t *testing.T, store storage.IdempotencyStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	if err := store.ReserveIdempotencyKey(ctx, newIdempotencyRecord("key:k1", "retry-1", now, time.Minute)); err != nil {
+		t.Fatalf("ReserveIdempotencyKey returned error: %v", err)
+	}
+	if err := store.ReserveIdempotencyKey(ctx, newIdempotencyRecord("key:k1", "retry-1", now.Add(time.Second), time.Minute)); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	// Keys belong to their owner.
+	if err := store.ReserveIdempotencyKey(ctx, newIdempotencyRecord("key:k2", "retry-1", now, time.Minute)); err != nil {
+		t.Fatalf("ReserveIdempotencyKey for another owner returned error: %v", err)
+	}
+}
+
+func testReserveExpiredIdempotencyKey(t *testing.T, store storage.IdempotencyStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	old := newIdempotencyRecord("key:k1", "retry-1", now.Add(-time.Hour), time.Minute)
+	old.RequestHash = "old"
+	if err := store.ReserveIdempotencyKey(ctx, old); err != nil {
+		t.Fatalf("ReserveIdempotencyKey returned error: %v", err)
+	}
+	if err := store.ReserveIdempotencyKey(ctx, newIdempotencyRecord("key:k1", "retry-1", now, time.Minute)); err != nil {
+		t.Fatalf("expected an expired record to be replaced, got %v", err)
+	}
+	got, err := store.FindIdempotencyKey(ctx, "key:k1", "retry-1")
+	if err != nil {
+		t.Fatalf("FindIdempotencyKey returned error: %v", err)
+	}
+	if got.RequestHash != "hash-retry-1" || !got.CreatedAt.Equal(now) {
+		t.Fatalf("expected the new record, got %+v", got)
+	}
+}
+
+func testReleaseIdempotencyKey(t *testing.T, store storage.IdempotencyStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	if err := store.ReserveIdempotencyKey(ctx, newIdempotencyRecord("key:k1", "retry-1", now, time.Minute)); err != nil {
+		t.Fatalf("ReserveIdempotencyKey returned error: %v", err)
+	}
+	rec := newIdempotencyRecord("key:k1", "retry-1", now, time.Minute)
+	if err := store.ReleaseIdempotencyKey(ctx, "key:k1", "retry-1", rec.Claim); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey returned error: %v", err)
+	}
+	if _, err := store.FindIdempotencyKey(ctx, "key:k1", "retry-1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected a released key to be ErrNotFound, got %v", err)
+	}
+	if err := store.ReserveIdempotencyKey(ctx, newIdempotencyRecord("key:k1", "retry-1", now, time.Minute)); err != nil {
+		t.Fatalf("expected a released key to be reservable, got %v", err)
+	}
+	if err := store.ReleaseIdempotencyKey(ctx, "key:k1", "missing", rec.Claim); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey of a missing key returned error: %v", err)
+	}
+}
+
+// testIdempotencyKeyTakeover covers a request that outlives its claim: once
+// a retry has taken the key over, the first request can neither complete nor
+// release the retry's record.
+func testIdempotencyKeyTakeover(t *testing.T, store storage.IdempotencyStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	stale := newIdempotencyRecord("key:k1", "retry-1", now.Add(-time.Hour), time.Minute)
+	if err := store.ReserveIdempotencyKey(ctx, stale); err != nil {
+		t.Fatalf("ReserveIdempotencyKey returned error: %v", err)
+	}
+	retry := newIdempotencyRecord("key:k1", "retry-1", now, time.Minute)
+	if err := store.ReserveIdempotencyKey(ctx, retry); err != nil {
+		t.Fatalf("expected the retry to take over the stale claim, got %v", err)
+	}
+
+	stale.Status = 200
+	stale.ExpiresAt = now.Add(24 * time.Hour)
+	if err := store.CompleteIdempotencyKey(ctx, stale); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound completing a stale claim, got %v", err)
+	}
+	if err := store.ReleaseIdempotencyKey(ctx, stale.Owner, stale.Key, stale.Claim); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey returned error: %v", err)
+	}
+	got, err := store.FindIdempotencyKey(ctx, "key:k1", "retry-1")
+	if err != nil {
+		t.Fatalf("expected the retry's record to survive the stale request, got %v", err)
+	}
+	if got.Claim != retry.Claim || got.Completed() || !got.CreatedAt.Equal(now) {
+		t.Fatalf("expected the retry's record untouched, got %+v", got)
+	}
+
+	retry.Status = 201
+	retry.ExpiresAt = now.Add(24 * time.Hour)
+	if err := store.CompleteIdempotencyKey(ctx, retry); err != nil {
+		t.Fatalf("CompleteIdempotencyKey returned error: %v", err)
+	}
+	if got, _ := store.FindIdempotencyKey(ctx, "key:k1", "retry-1"); got.Status != 201 {
+		t.Fatalf("expected the retry's response, got %+v", got)
+	}
+}
+
+func testPurgeIdempotencyKeys(t *testing.T, store storage.IdempotencyStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	for _, rec := range []storage.IdempotencyRecord{
+		newIdempotencyRecord("key:k1", "old-1", now.Add(-time.Hour), time.Minute),
+		newIdempotencyRecord("key:k2", "old-2", now.Add(-time.Minute), time.Minute),
+		newIdempotencyRecord("key:k1", "live", now, time.Minute),
+	} {
+		if err := store.ReserveIdempotencyKey(ctx, rec); err != nil {
+			t.Fatalf("ReserveIdempotencyKey returned error: %v", err)
+		}
+	}
+
+	n, err := store.PurgeIdempotencyKeys(ctx, now)
+	if err != nil {
+		t.Fatalf("PurgeIdempotencyKeys returned error: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 purged records, got %d", n)
+	}
+	if _, err := store.FindIdempotencyKey(ctx, "key:k1", "old-1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected the expired record to be gone, got %v", err)
+	}
+	if _, err := store.FindIdempotencyKey(ctx, "key:k1", "live"); err != nil {
+		t.Fatalf("expected the live record to stay, got %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+// KeyStoreFactory returns an empty key store, like Factory.
+type KeyStoreFactory func(t *testing.T) storage.KeyStore
+
+// RunKeyStore executes the API key part of the suite.
+func RunKeyStore(t *testing.T, newStore KeyStoreFactory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store storage.KeyStore)
+	}{
+		{"SaveAndFindByHash", testSaveAndFindAPIKey},
+		{"SaveConflict", testSaveAPIKeyConflict},
+		{"FindNotFound", testFindAPIKeyNotFound},
+		{"List", testListAPIKeys},
+		{"Revoke", testRevokeAPIKey},
+		{"FindByID", testFindAPIKeyByID},
+		{"Sessions", testSessions},
+		{"PurgeSessions", testPurgeSessions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func testSaveAndFindAPIKey(t *testing.T, store storage.KeyStore) {
+	ctx := context.Background()
+	want := storage.APIKey{ID: "k1", Name: "ci", Hash: "hash-1"}
+	if err := store.SaveAPIKey(ctx, want); err != nil {
+		t.Fatalf("SaveAPIKey returned error: %v", err)
+	}
+
+	got, err := store.FindAPIKeyByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("FindAPIKeyByHash returned error: %v", err)
+	}
+	if got.ID != want.ID || got.Name != want.Name {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Fatalf("expected CreatedAt to be set")
+	}
+	if got.Revoked() {
+		t.Fatalf("expected new key to be active")
+	}
+}
+
+func testSaveAPIKeyConflict(t *testing.T, store storage.KeyStore) {
+	ctx := context.Background()
+	_ = store.SaveAPIKey(ctx, storage.APIKey{ID: "k1", Name: "ci", Hash: "hash-1"})
+
+	err := store.SaveAPIKey(ctx, storage.APIKey{ID: "k1", Name: "other", Hash: "hash-2"})
+	if !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected %v for duplicate id, got %v", storage.ErrConflict, err)
+	}
+	err = store.SaveAPIKey(ctx, storage.APIKey{ID: "k2", Name: "other", Hash: "hash-1"})
+	if !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected %v for duplicate hash, got %v", storage.ErrConflict, err)
+	}
+}
+
+func testFindAPIKeyNotFound(t *testing.T, store storage.KeyStore) {
+	_, err := store.FindAPIKeyByHash(context.Background(), "missing")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+}
+
+func testListAPIKeys(t *testing.T, store storage.KeyStore) {
+	ctx := context.Background()
+	base := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	_ = store.SaveAPIKey(ctx, storage.APIKey{ID: "k2", Name: "second", Hash: "h2", CreatedAt: base.Add(time.Hour)})
+	_ = store.SaveAPIKey(ctx, storage.APIKey{ID: "k1", Name: "first", Hash: "h1", CreatedAt: base})
+
+	keys, err := store.ListAPIKeys(ctx)
+	if err != nil {
+		t.Fatalf("ListAPIKeys returned error: %v", err)
+	}
+	if len(keys) != 2 || keys[0].ID != "k1" || keys[1].ID != "k2" {
+		t.Fatalf("expected keys [k1 k2] oldest first, got %+v", keys)
+	}
+}
+
+func testRevokeAPIKey(t *testing.T, store storage.KeyStore) {
+	ctx := context.Background()
+	revokedAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	_ = store.SaveAPIKey(ctx, storage.APIKey{ID: "k1", Name: "ci", Hash: "hash-1"})
+
+	if err := store.RevokeAPIKey(ctx, "k1", revokedAt); err != nil {
+		t.Fatalf("RevokeAPIKey returned error: %v", err)
+	}
+	// Revoking twice keeps the first timestamp.
+	if err := store.RevokeAPIKey(ctx, "k1", revokedAt.Add(time.Hour)); err != nil {
+		t.Fatalf("second RevokeAPIKey returned error: %v", err)
+	}
+
+	got, err := store.FindAPIKeyByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("FindAPIKeyByHash returned error: %v", err)
+	}
+	if !got.RevokedAt.Equal(revokedAt) {
+		t.Fatalf("expected RevokedAt %v, got %v", revokedAt, got.RevokedAt)
+	}
+
+	if err := store.RevokeAPIKey(ctx, "missing", revokedAt); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+}
+
+func testFindAPIKeyByID(t *testing.T, store storage.KeyStore) {
+	ctx := context.Background()
+	_ = store.SaveAPIKey(ctx, storage.APIKey{ID: "k1", Name: "ci", Hash: "hash-1"})
+
+	got, err := store.FindAPIKey(ctx, "k1")
+	if err != nil {
+		t.Fatalf("FindAPIKey returned error: %v", err)
+	}
+	if got.Name != "ci" || got.Hash != "hash-1" {
+		t.Fatalf("unexpected key: %+v", got)
+	}
+	if _, err := store.FindAPIKey(ctx, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+}
+
+func testSessions(t *testing.T, store storage.KeyStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	_ = store.SaveAPIKey(ctx, storage.APIKey{ID: "k1", Name: "ci", Hash: "hash-1"})
+
+	want := storage.Session{Hash: "session-1", KeyID: "k1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	if err := store.SaveSession(ctx, want); err != nil {
+		t.Fatalf("SaveSession returned error: %v", err)
+	}
+	if err := store.SaveSession(ctx, want); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected %v for a duplicate hash, got %v", storage.ErrConflict, err)
+	}
+	got, err := store.FindSession(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("FindSession returned error: %v", err)
+	}
+	if got.KeyID != "k1" || !got.CreatedAt.Equal(want.CreatedAt) || !got.ExpiresAt.Equal(want.ExpiresAt) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	if err := store.DeleteSession(ctx, "session-1"); err != nil {
+		t.Fatalf("DeleteSession returned error: %v", err)
+	}
+	if _, err := store.FindSession(ctx, "session-1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected a deleted session to be %v, got %v", storage.ErrNotFound, err)
+	}
+	if err := store.DeleteSession(ctx, "session-1"); err != nil {
+		t.Fatalf("expected deleting a missing session to succeed, got %v", err)
+	}
+}
+
+func testPurgeSessions(t *testing.T, store storage.KeyStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	_ = store.SaveAPIKey(ctx, storage.APIKey{ID: "k1", Name: "ci", Hash: "hash-1"})
+	for hash, expires := range map[string]time.Time{
+		"old":  now.Add(-time.Minute),
+		"now":  now,
+		"live": now.Add(time.Minute),
+	} {
+		if err := store.SaveSession(ctx, storage.Session{Hash: hash, KeyID: "k1", CreatedAt: now.Add(-time.Hour), ExpiresAt: expires}); err != nil {
+			t.Fatalf("SaveSession returned error: %v", err)
+		}
+	}
+
+	n, err := store.PurgeSessions(ctx, now)
+	if err != nil {
+		t.Fatalf("PurgeSessions returned error: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 expired sessions purged, got %d", n)
+	}
+	if _, err := store.FindSession(ctx, "live"); err != nil {
+		t.Fatalf("expected the live session to stay, got %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+// OutboxStoreFactory returns an empty outbox store, like Factory.
+type OutboxStoreFactory func(t *testing.T) storage.OutboxStore
+
+// RunOutboxStore executes the webhook outbox part of the suite.
+func RunOutboxStore(t *testing.T, newStore OutboxStoreFactory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store storage.OutboxStore)
+	}{
+		{"EnqueueAndFind", testEnqueueAndFindDelivery},
+		{"EnqueueConflict", testEnqueueDeliveryConflict},
+		{"ClaimDue", testClaimDueDeliveries},
+		{"ClaimDueRace", testClaimDueDeliveriesRace},
+		{"UpdateAndList", testUpdateAndListDeliveries},
+		{"UpdateMissing", testUpdateMissingDelivery},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newDelivery(id string, next time.Time) storage.WebhookDelivery {
+	return storage.WebhookDelivery{
+		ID:            id,
+		Endpoint:      "crm",
+		Event:         "link.created",
+		Payload:       []byte(`{"event":"link.created","short_code":"abc123"}`),
+		Status:        storage.DeliveryPending,
+		NextAttemptAt: next,
+		CreatedAt:     next,
+	}
+}
+
+func testEnqueueAndFindDelivery(t *testing.T, store storage.OutboxStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	want := newDelivery("d1", now)
+	if err := store.EnqueueDelivery(ctx, want); err != nil {
+		t.Fatalf("EnqueueDelivery returned error: %v", err)
+	}
+
+	got, err := store.FindDelivery(ctx, "d1")
+	if err != nil {
+		t.Fatalf("FindDelivery returned error: %v", err)
+	}
+	if got.Endpoint != want.Endpoint || got.Event != want.Event || got.Status != storage.DeliveryPending {
+		t.Fatalf("unexpected delivery %+v", got)
+	}
+	if !sameJSON(t, got.Payload, want.Payload) {
+		t.Fatalf("expected payload %s, got %s", want.Payload, got.Payload)
+	}
+	if !got.NextAttemptAt.Equal(now) || !got.DeliveredAt.IsZero() {
+		t.Fatalf("unexpected timestamps %+v", got)
+	}
+
+	if _, err := store.FindDelivery(ctx, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testEnqueueDeliveryConflict(t *testing.T, store storage.OutboxStore) {
+	ctx := context.Background()
+	d := newDelivery("d1", time.Now().UTC())
+	if err := store.EnqueueDelivery(ctx, d); err != nil {
+		t.Fatalf("EnqueueDelivery returned error: %v", err)
+	}
+	if err := store.EnqueueDelivery(ctx, d); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected ErrConflict for a duplicate ID, got %v", err)
+	}
+}
+
+func testClaimDueDeliveries(t *testing.T, store storage.OutboxStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for _, d := range []storage.WebhookDelivery{
+		newDelivery("later", now.Add(time.Minute)),
+		newDelivery("recent", now.Add(-time.Second)),
+		newDelivery("oldest", now.Add(-time.Hour)),
+		newDelivery("exact", now),
+	} {
+		if err := store.EnqueueDelivery(ctx, d); err != nil {
+			t.Fatalf("EnqueueDelivery returned error: %v", err)
+		}
+	}
+	done := newDelivery("done", now.Add(-2*time.Hour))
+	done.Status = storage.DeliveryDelivered
+	if err := store.EnqueueDelivery(ctx, done); err != nil {
+		t.Fatalf("EnqueueDelivery returned error: %v", err)
+	}
+
+	until := now.Add(5 * time.Minute)
+	due, err := store.ClaimDueDeliveries(ctx, now, until, 2)
+	if err != nil {
+		t.Fatalf("ClaimDueDeliveries returned error: %v", err)
+	}
+	if got := deliveryIDs(due); got != "oldest,recent" {
+		t.Fatalf("expected the limit to keep the longest waiting, got %s", got)
+	}
+	if !due[0].NextAttemptAt.Equal(until) {
+		t.Fatalf("expected the claim to move the next attempt to %v, got %v", until, due[0].NextAttemptAt)
+	}
+	if got, _ := store.FindDelivery(ctx, "oldest"); !got.NextAttemptAt.Equal(until) {
+		t.Fatalf("expected the claim to be stored, got %+v", got)
+	}
+
+	due, err = store.ClaimDueDeliveries(ctx, now, until, 0)
+	if err != nil {
+		t.Fatalf("ClaimDueDeliveries returned error: %v", err)
+	}
+	if got := deliveryIDs(due); got != "exact" {
+		t.Fatalf("expected claimed deliveries to be skipped, got %s", got)
+	}
+
+	// A lease that runs out makes the deliveries due again.
+	due, err = store.ClaimDueDeliveries(ctx, until, until.Add(5*time.Minute), 0)
+	if err != nil {
+		t.Fatalf("ClaimDueDeliveries returned error: %v", err)
+	}
+	if got := deliveryIDs(due); got != "later,exact,oldest,recent" {
+		t.Fatalf("expected every pending delivery once its lease is up, got %s", got)
+	}
+}
+
+// testClaimDueDeliveriesRace has several dispatchers claim at once; each
+// delivery must go to exactly one of them.
+func testClaimDueDeliveriesRace(t *testing.T, store storage.OutboxStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	const deliveries = 40
+	for i := range deliveries {
+		if err := store.EnqueueDelivery(ctx, newDelivery(fmt.Sprintf("d%02d", i), now.Add(-time.Duration(i)*time.Second))); err != nil {
+			t.Fatalf("EnqueueDelivery returned error: %v", err)
+		}
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		claimed = map[string]int{}
+	)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				due, err := store.ClaimDueDeliveries(ctx, now, now.Add(time.Minute), 3)
+				if err != nil {
+					t.Errorf("ClaimDueDeliveries returned error: %v", err)
+					return
+				}
+				if len(due) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, d := range due {
+					claimed[d.ID]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimed) != deliveries {
+		t.Fatalf("expected all %d deliveries claimed, got %d", deliveries, len(claimed))
+	}
+	for id, n := range claimed {
+		if n != 1 {
+			t.Fatalf("expected %s to be claimed once, got %d", id, n)
+		}
+	}
+}
+
+func testUpdateAndListDeliveries(t *testing.T, store storage.OutboxStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for i, id := range []string{"d1", "d2", "d3"} {
+		if err := store.EnqueueDelivery(ctx, newDelivery(id, now.Add(time.Duration(i)*time.Second))); err != nil {
+			t.Fatalf("EnqueueDelivery returned error: %v", err)
+		}
+	}
+
+	d, _ := store.FindDelivery(ctx, "d2")
+	d.Status = storage.DeliveryFailed
+	d.Attempts = 8
+	d.LastError = "unexpected status 500"
+	d.LastStatus = 500
+	if err := store.UpdateDelivery(ctx, d); err != nil {
+		t.Fatalf("UpdateDelivery returned error: %v", err)
+	}
+	d, _ = store.FindDelivery(ctx, "d3")
+	d.Status = storage.DeliveryDelivered
+	d.Attempts = 1
+	d.DeliveredAt = now
+	if err := store.UpdateDelivery(ctx, d); err != nil {
+		t.Fatalf("UpdateDelivery returned error: %v", err)
+	}
+
+	failed, err := store.ListDeliveries(ctx, storage.DeliveryListOptions{Status: storage.DeliveryFailed})
+	if err != nil {
+		t.Fatalf("ListDeliveries returned error: %v", err)
+	}
+	if len(failed) != 1 || failed[0].ID != "d2" || failed[0].Attempts != 8 || failed[0].LastStatus != 500 ||
+		failed[0].LastError != "unexpected status 500" {
+		t.Fatalf("unexpected failed deliveries %+v", failed)
+	}
+
+	all, err := store.ListDeliveries(ctx, storage.DeliveryListOptions{})
+	if err != nil {
+		t.Fatalf("ListDeliveries returned error: %v", err)
+	}
+	if got := deliveryIDs(all); got != "d3,d2,d1" {
+		t.Fatalf("expected newest first, got %s", got)
+	}
+	if !all[0].DeliveredAt.Equal(now) {
+		t.Fatalf("expected delivered_at %v, got %v", now, all[0].DeliveredAt)
+	}
+
+	page, err := store.ListDeliveries(ctx, storage.DeliveryListOptions{Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("ListDeliveries returned error: %v", err)
+	}
+	if got := deliveryIDs(page); got != "d2" {
+		t.Fatalf("expected second page d2, got %s", got)
+	}
+}
+
+func testUpdateMissingDelivery(t *testing.T, store storage.OutboxStore) {
+	err := store.UpdateDelivery(context.Background(), newDelivery("missing", time.Now()))
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func deliveryIDs(deliveries []storage.WebhookDelivery) string {
+	ids := ""
+	for i, d := range deliveries {
+		if i > 0 {
+			ids += ","
+		}
+		ids += d.ID
+	}
+	return ids
+}
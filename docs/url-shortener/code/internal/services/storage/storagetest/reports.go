@@ -0,0 +1,140 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+// ReportStoreFactory returns an empty report store, like Factory.
+type ReportStoreFactory func(t *testing.T) storage.ReportStore
+
+// RunReportStore executes the abuse report part of the suite.
+func RunReportStore(t *testing.T, newStore ReportStoreFactory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store storage.ReportStore)
+	}{
+		{"SaveAndFind", testSaveAndFindReport},
+		{"SaveConflict", testSaveReportConflict},
+		{"FindNotFound", testFindReportNotFound},
+		{"List", testListReports},
+		{"Resolve", testResolveReport},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func testSaveAndFindReport(t *testing.T, store storage.ReportStore) {
+	ctx := context.Background()
+	want := storage.Report{ID: "r1", ShortCode: "abc123", Reason: "phishing", Details: "fake bank login", Reporter: "ip:192.0.2.1"}
+	if err := store.SaveReport(ctx, want); err != nil {
+		t.Fatalf("SaveReport returned error: %v", err)
+	}
+
+	got, err := store.FindReport(ctx, "r1")
+	if err != nil {
+		t.Fatalf("FindReport returned error: %v", err)
+	}
+	if got.ShortCode != want.ShortCode || got.Reason != want.Reason || got.Details != want.Details || got.Reporter != want.Reporter {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Fatalf("expected CreatedAt to be set")
+	}
+	if !got.Open() {
+		t.Fatalf("expected new report to be open")
+	}
+}
+
+func testSaveReportConflict(t *testing.T, store storage.ReportStore) {
+	ctx := context.Background()
+	_ = store.SaveReport(ctx, storage.Report{ID: "r1", ShortCode: "abc123", Reason: "spam"})
+
+	err := store.SaveReport(ctx, storage.Report{ID: "r1", ShortCode: "xyz789", Reason: "spam"})
+	if !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected %v, got %v", storage.ErrConflict, err)
+	}
+}
+
+func testFindReportNotFound(t *testing.T, store storage.ReportStore) {
+	_, err := store.FindReport(context.Background(), "missing")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+}
+
+func testListReports(t *testing.T, store storage.ReportStore) {
+	ctx := context.Background()
+	base := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	_ = store.SaveReport(ctx, storage.Report{ID: "r3", ShortCode: "abc123", Reason: "spam", CreatedAt: base.Add(2 * time.Hour)})
+	_ = store.SaveReport(ctx, storage.Report{ID: "r1", ShortCode: "abc123", Reason: "phishing", CreatedAt: base})
+	_ = store.SaveReport(ctx, storage.Report{ID: "r2", ShortCode: "xyz789", Reason: "malware", CreatedAt: base.Add(time.Hour)})
+	_ = store.ResolveReport(ctx, "r3", "dismissed", "key:admin", base.Add(3*time.Hour))
+
+	ids := func(reports []storage.Report) []string {
+		out := make([]string, len(reports))
+		for i, r := range reports {
+			out[i] = r.ID
+		}
+		return out
+	}
+
+	tests := []struct {
+		name string
+		opts storage.ReportListOptions
+		want []string
+	}{
+		{"all oldest first", storage.ReportListOptions{}, []string{"r1", "r2", "r3"}},
+		{"open only", storage.ReportListOptions{OpenOnly: true}, []string{"r1", "r2"}},
+		{"by code", storage.ReportListOptions{ShortCode: "abc123"}, []string{"r1", "r3"}},
+		{"open by code", storage.ReportListOptions{ShortCode: "abc123", OpenOnly: true}, []string{"r1"}},
+		{"paged", storage.ReportListOptions{Limit: 1, Offset: 1}, []string{"r2"}},
+		{"past the end", storage.ReportListOptions{Offset: 5}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, err := store.ListReports(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("ListReports returned error: %v", err)
+			}
+			if got := ids(reports); !equalCodes(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func testResolveReport(t *testing.T, store storage.ReportStore) {
+	ctx := context.Background()
+	resolvedAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	_ = store.SaveReport(ctx, storage.Report{ID: "r1", ShortCode: "abc123", Reason: "phishing"})
+
+	if err := store.ResolveReport(ctx, "r1", "disabled", "key:admin", resolvedAt); err != nil {
+		t.Fatalf("ResolveReport returned error: %v", err)
+	}
+	// Resolving twice keeps the first resolution.
+	if err := store.ResolveReport(ctx, "r1", "dismissed", "key:other", resolvedAt.Add(time.Hour)); err != nil {
+		t.Fatalf("second ResolveReport returned error: %v", err)
+	}
+
+	got, err := store.FindReport(ctx, "r1")
+	if err != nil {
+		t.Fatalf("FindReport returned error: %v", err)
+	}
+	if !got.ResolvedAt.Equal(resolvedAt) || got.ResolvedBy != "key:admin" || got.Resolution != "disabled" {
+		t.Fatalf("expected first resolution to stick, got %+v", got)
+	}
+
+	if err := store.ResolveReport(ctx, "missing", "disabled", "key:admin", resolvedAt); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+}
@@ -0,0 +1,639 @@
+// Package storagetest holds a behavioural test suite that every storage.Store
+// implementation is expected to pass.
+//
+// A backend's own _test.go file only needs to provide a factory:
+//
+//	func TestStore(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Store {
+//			return NewStore(openTestDB(t))
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+// Factory returns an empty store. It is called once per sub-test, and should
+// register any cleanup with t.Cleanup.
+type Factory func(t *testing.T) storage.Store
+
+// Run executes the whole conformance suite against stores built by newStore.
+func Run(t *testing.T, newStore Factory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store storage.Store)
+	}{
+		{"SaveAndFind", testSaveAndFind},
+		{"SaveConflict", testSaveConflict},
+		{"SaveDefaultsCreatedAt", testSaveDefaultsCreatedAt},
+		{"SaveKeepsCreatedAt", testSaveKeepsCreatedAt},
+		{"FindNotFound", testFindNotFound},
+		{"IncrementHits", testIncrementHits},
+		{"IncrementHitsNotFound", testIncrementHitsNotFound},
+		{"IncrementHitsConcurrent", testIncrementHitsConcurrent},
+		{"ExpiresAtRoundTrip", testExpiresAtRoundTrip},
+		{"DisabledRoundTrip", testDisabledRoundTrip},
+		{"DetailsRoundTrip", testDetailsRoundTrip},
+		{"SignedRoundTrip", testSignedRoundTrip},
+		{"ActivatesAtRoundTrip", testActivatesAtRoundTrip},
+		{"List", testList},
+		{"ListByOwner", testListByOwner},
+		{"ListSearch", testListSearch},
+		{"ListByTag", testListByTag},
+		{"Update", testUpdate},
+		{"UpdateKeepsHits", testUpdateKeepsHits},
+		{"UpdateNotFound", testUpdateNotFound},
+		{"Overwrite", testOverwrite},
+		{"OverwriteNotFound", testOverwriteNotFound},
+		{"Delete", testDelete},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func testSaveAndFind(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	want := storage.Entry{
+		ShortCode:   "abc123",
+		OriginalURL: "https://example.com",
+		CreatedBy:   "tester",
+		HitCount:    7,
+	}
+
+	if err := store.Save(ctx, want); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	got, err := store.Find(ctx, want.ShortCode)
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if got.ShortCode != want.ShortCode {
+		t.Fatalf("expected short code %s, got %s", want.ShortCode, got.ShortCode)
+	}
+	if got.OriginalURL != want.OriginalURL {
+		t.Fatalf("expected original url %s, got %s", want.OriginalURL, got.OriginalURL)
+	}
+	if got.CreatedBy != want.CreatedBy {
+		t.Fatalf("expected created by %s, got %s", want.CreatedBy, got.CreatedBy)
+	}
+	if got.HitCount != want.HitCount {
+		t.Fatalf("expected hit count %d, got %d", want.HitCount, got.HitCount)
+	}
+}
+
+func testSaveConflict(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+
+	first := storage.Entry{ShortCode: "dup123", OriginalURL: "https://example.com"}
+	if err := store.Save(ctx, first); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	err := store.Save(ctx, storage.Entry{ShortCode: "dup123", OriginalURL: "https://other.com"})
+	if !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected %v, got %v", storage.ErrConflict, err)
+	}
+
+	// The original entry must survive the failed save.
+	got, err := store.Find(ctx, "dup123")
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if got.OriginalURL != first.OriginalURL {
+		t.Fatalf("expected original url %s, got %s", first.OriginalURL, got.OriginalURL)
+	}
+}
+
+func testSaveDefaultsCreatedAt(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	before := time.Now().Add(-time.Minute)
+
+	err := store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	got, err := store.Find(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Fatalf("expected CreatedAt to be set")
+	}
+	if got.CreatedAt.Before(before) {
+		t.Fatalf("expected CreatedAt to be recent, got %v", got.CreatedAt)
+	}
+}
+
+func testSaveKeepsCreatedAt(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	createdAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	err := store.Save(ctx, storage.Entry{
+		ShortCode:   "abc123",
+		OriginalURL: "https://example.com",
+		CreatedAt:   createdAt,
+	})
+	if err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	got, err := store.Find(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if !got.CreatedAt.Equal(createdAt) {
+		t.Fatalf("expected CreatedAt %v, got %v", createdAt, got.CreatedAt)
+	}
+}
+
+func testFindNotFound(t *testing.T, store storage.Store) {
+	_, err := store.Find(context.Background(), "missing")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+}
+
+func testIncrementHits(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	err := store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	for want := int64(1); want <= 3; want++ {
+		got, err := store.IncrementHits(ctx, "abc123")
+		if err != nil {
+			t.Fatalf("IncrementHits returned error: %v", err)
+		}
+		if got.HitCount != want {
+			t.Fatalf("expected hit count %d, got %d", want, got.HitCount)
+		}
+		if got.OriginalURL != "https://example.com" {
+			t.Fatalf("expected IncrementHits to return the full entry, got %+v", got)
+		}
+	}
+}
+
+func testIncrementHitsNotFound(t *testing.T, store storage.Store) {
+	_, err := store.IncrementHits(context.Background(), "missing")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+}
+
+func testIncrementHitsConcurrent(t *testing.T, store storage.Store) {
+	const workers = 20
+	const perWorker = 10
+	ctx := context.Background()
+
+	err := store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for range workers {
+		wg.Go(func() {
+			for range perWorker {
+				if _, err := store.IncrementHits(ctx, "abc123"); err != nil {
+					errs <- err
+				}
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("IncrementHits returned error: %v", err)
+	}
+
+	got, err := store.Find(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if got.HitCount != workers*perWorker {
+		t.Fatalf("expected hit count %d, got %d", workers*perWorker, got.HitCount)
+	}
+}
+
+func testExpiresAtRoundTrip(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	expiresAt := time.Date(2030, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	_ = store.Save(ctx, storage.Entry{ShortCode: "never", OriginalURL: "https://example.com"})
+	_ = store.Save(ctx, storage.Entry{ShortCode: "expires", OriginalURL: "https://example.com", ExpiresAt: expiresAt})
+
+	got, err := store.Find(ctx, "never")
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if !got.ExpiresAt.IsZero() {
+		t.Fatalf("expected no expiry, got %v", got.ExpiresAt)
+	}
+
+	got, err = store.IncrementHits(ctx, "expires")
+	if err != nil {
+		t.Fatalf("IncrementHits returned error: %v", err)
+	}
+	if !got.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("expected ExpiresAt %v, got %v", expiresAt, got.ExpiresAt)
+	}
+}
+
+func testDisabledRoundTrip(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com"})
+
+	got, err := store.Find(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if got.Disabled || got.DisabledReason != "" {
+		t.Fatalf("expected new entry to be enabled, got %+v", got)
+	}
+
+	got.Disabled, got.DisabledReason = true, "phishing"
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	got, err = store.IncrementHits(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("IncrementHits returned error: %v", err)
+	}
+	if !got.Disabled || got.DisabledReason != "phishing" {
+		t.Fatalf("expected disabled entry, got %+v", got)
+	}
+}
+
+func testSignedRoundTrip(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	_ = store.Save(ctx, storage.Entry{ShortCode: "doc123", OriginalURL: "https://example.com/private", Signed: true})
+	_ = store.Save(ctx, storage.Entry{ShortCode: "pub123", OriginalURL: "https://example.com"})
+
+	got, err := store.IncrementHits(ctx, "doc123")
+	if err != nil {
+		t.Fatalf("IncrementHits returned error: %v", err)
+	}
+	if !got.Signed {
+		t.Fatalf("expected the link to stay signed, got %+v", got)
+	}
+	if got, _ := store.Find(ctx, "pub123"); got.Signed {
+		t.Fatalf("expected links to be unsigned by default, got %+v", got)
+	}
+}
+
+func testActivatesAtRoundTrip(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	activatesAt := time.Date(2030, time.March, 4, 9, 30, 0, 0, time.UTC)
+	_ = store.Save(ctx, storage.Entry{ShortCode: "launch", OriginalURL: "https://example.com", ActivatesAt: activatesAt})
+	_ = store.Save(ctx, storage.Entry{ShortCode: "live", OriginalURL: "https://example.com"})
+
+	got, err := store.Find(ctx, "launch")
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if !got.ActivatesAt.Equal(activatesAt) {
+		t.Fatalf("expected ActivatesAt %v, got %v", activatesAt, got.ActivatesAt)
+	}
+	if got, _ := store.Find(ctx, "live"); !got.ActivatesAt.IsZero() {
+		t.Fatalf("expected no activation time, got %v", got.ActivatesAt)
+	}
+
+	later := activatesAt.Add(time.Hour)
+	got.ActivatesAt = later
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if got, _ := store.IncrementHits(ctx, "launch"); !got.ActivatesAt.Equal(later) {
+		t.Fatalf("expected ActivatesAt %v after Update, got %v", later, got.ActivatesAt)
+	}
+}
+
+func testDetailsRoundTrip(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	_ = store.Save(ctx, storage.Entry{
+		ShortCode:   "abc123",
+		OriginalURL: "https://example.com",
+		Title:       "Q3 launch page",
+		Description: "Landing page for the autumn campaign",
+		Tags:        []string{"launch", "marketing/q3"},
+	})
+
+	got, err := store.Find(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if got.Title != "Q3 launch page" || got.Description != "Landing page for the autumn campaign" ||
+		!equalCodes(got.Tags, []string{"launch", "marketing/q3"}) {
+		t.Fatalf("unexpected details %+v", got)
+	}
+
+	got.Title, got.Tags = "", nil
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	got, err = store.IncrementHits(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("IncrementHits returned error: %v", err)
+	}
+	if got.Title != "" || len(got.Tags) != 0 || got.Description == "" {
+		t.Fatalf("expected title and tags to be cleared, got %+v", got)
+	}
+}
+
+func testListSearch(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	base := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	for i, e := range []storage.Entry{
+		{ShortCode: "q3page", OriginalURL: "https://example.com/autumn", Title: "Q3 Launch page"},
+		{ShortCode: "q3blog", OriginalURL: "https://blog.example.com/q3-launch", Description: "Blog post"},
+		{ShortCode: "hiring", OriginalURL: "https://jobs.example.com", Tags: []string{"launch-team"}},
+		{ShortCode: "promo1", OriginalURL: "https://example.com/100%25-off", Title: "100% off"},
+	} {
+		e.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		if err := store.Save(ctx, e); err != nil {
+			t.Fatalf("Save returned error: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"launch", []string{"hiring", "q3blog", "q3page"}},
+		{"q3 LAUNCH", []string{"q3blog", "q3page"}},
+		{"blog post", []string{"q3blog"}},
+		{"q3pa", []string{"q3page"}},
+		{"100%", []string{"promo1"}},
+		{"1_0", nil},
+		{"launch nope", nil},
+	} {
+		got, err := store.List(ctx, storage.ListOptions{Query: tc.query})
+		if err != nil {
+			t.Fatalf("List returned error: %v", err)
+		}
+		if !equalCodes(codes(got), tc.want) {
+			t.Fatalf("query %q: expected %v, got %v", tc.query, tc.want, codes(got))
+		}
+	}
+}
+
+func testListByTag(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	base := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	for i, e := range []storage.Entry{
+		{ShortCode: "m1", Tags: []string{"marketing"}, CreatedBy: "alice"},
+		{ShortCode: "m2", Tags: []string{"marketing/q3"}, CreatedBy: "alice"},
+		{ShortCode: "m3", Tags: []string{"marketing/q3/emails", "press"}, CreatedBy: "bob"},
+		{ShortCode: "x1", Tags: []string{"marketing-old"}, CreatedBy: "alice"},
+	} {
+		e.OriginalURL = "https://example.com/" + e.ShortCode
+		e.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		if err := store.Save(ctx, e); err != nil {
+			t.Fatalf("Save returned error: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		opts storage.ListOptions
+		want []string
+	}{
+		{storage.ListOptions{Tag: "marketing"}, []string{"m3", "m2", "m1"}},
+		{storage.ListOptions{Tag: "marketing/q3"}, []string{"m3", "m2"}},
+		{storage.ListOptions{Tag: "press"}, []string{"m3"}},
+		{storage.ListOptions{Tag: "marketing", CreatedBy: "alice"}, []string{"m2", "m1"}},
+		{storage.ListOptions{Tag: "marketing", Query: "m1"}, []string{"m1"}},
+		{storage.ListOptions{Tag: "market"}, nil},
+	} {
+		got, err := store.List(ctx, tc.opts)
+		if err != nil {
+			t.Fatalf("List returned error: %v", err)
+		}
+		if !equalCodes(codes(got), tc.want) {
+			t.Fatalf("%+v: expected %v, got %v", tc.opts, tc.want, codes(got))
+		}
+	}
+}
+
+func saveAt(t *testing.T, store storage.Store, code, owner string, createdAt time.Time) {
+	t.Helper()
+	err := store.Save(context.Background(), storage.Entry{
+		ShortCode:   code,
+		OriginalURL: "https://example.com/" + code,
+		CreatedBy:   owner,
+		CreatedAt:   createdAt,
+	})
+	if err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+}
+
+func codes(entries []storage.Entry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.ShortCode
+	}
+	return out
+}
+
+func equalCodes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testList(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	base := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	saveAt(t, store, "old", "alice", base)
+	saveAt(t, store, "mid", "bob", base.Add(time.Hour))
+	saveAt(t, store, "new", "alice", base.Add(2*time.Hour))
+
+	all, err := store.List(ctx, storage.ListOptions{})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if want := []string{"new", "mid", "old"}; !equalCodes(codes(all), want) {
+		t.Fatalf("expected %v, got %v", want, codes(all))
+	}
+
+	page, err := store.List(ctx, storage.ListOptions{Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if want := []string{"mid"}; !equalCodes(codes(page), want) {
+		t.Fatalf("expected %v, got %v", want, codes(page))
+	}
+
+	past, err := store.List(ctx, storage.ListOptions{Offset: 10})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(past) != 0 {
+		t.Fatalf("expected empty page, got %v", codes(past))
+	}
+}
+
+func testListByOwner(t *testing.T, store storage.Store) {
+	base := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	saveAt(t, store, "a1", "alice", base)
+	saveAt(t, store, "b1", "bob", base.Add(time.Hour))
+	saveAt(t, store, "a2", "alice", base.Add(2*time.Hour))
+
+	got, err := store.List(context.Background(), storage.ListOptions{CreatedBy: "alice"})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if want := []string{"a2", "a1"}; !equalCodes(codes(got), want) {
+		t.Fatalf("expected %v, got %v", want, codes(got))
+	}
+}
+
+func testDelete(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com"})
+
+	if err := store.Delete(ctx, "abc123"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, err := store.Find(ctx, "abc123"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v after delete, got %v", storage.ErrNotFound, err)
+	}
+	if err := store.Delete(ctx, "abc123"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+
+	// A deleted code can be reused.
+	if err := store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://other.com"}); err != nil {
+		t.Fatalf("Save after delete returned error: %v", err)
+	}
+}
+
+func testUpdate(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	createdAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com", CreatedAt: createdAt})
+
+	expiresAt := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	err := store.Update(ctx, storage.Entry{
+		ShortCode:   "abc123",
+		OriginalURL: "https://other.com",
+		CreatedBy:   "importer",
+		HitCount:    42,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	got, err := store.Find(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if got.OriginalURL != "https://other.com" || got.CreatedBy != "importer" {
+		t.Fatalf("expected updated fields, got %+v", got)
+	}
+	if got.HitCount != 0 {
+		t.Fatalf("expected Update to keep the stored hit count, got %d", got.HitCount)
+	}
+	if !got.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("expected ExpiresAt %v, got %v", expiresAt, got.ExpiresAt)
+	}
+	// A zero CreatedAt keeps the original timestamp.
+	if !got.CreatedAt.Equal(createdAt) {
+		t.Fatalf("expected CreatedAt %v to be kept, got %v", createdAt, got.CreatedAt)
+	}
+}
+
+// testUpdateKeepsHits edits a link the way the shortener does, with a Find
+// first, and counts a redirect in between.
+func testUpdateKeepsHits(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com"})
+
+	entry, err := store.Find(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if _, err := store.IncrementHits(ctx, "abc123"); err != nil {
+		t.Fatalf("IncrementHits returned error: %v", err)
+	}
+	entry.Title = "Edited"
+	if err := store.Update(ctx, entry); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	got, _ := store.Find(ctx, "abc123")
+	if got.HitCount != 1 || got.Title != "Edited" {
+		t.Fatalf("expected the edit and the hit to both survive, got %+v", got)
+	}
+}
+
+func testUpdateNotFound(t *testing.T, store storage.Store) {
+	err := store.Update(context.Background(), storage.Entry{ShortCode: "missing", OriginalURL: "https://example.com"})
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+}
+
+func testOverwrite(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	createdAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	_ = store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com", CreatedAt: createdAt, HitCount: 7})
+
+	checkedAt := time.Date(2024, time.April, 1, 8, 0, 0, 0, time.UTC)
+	err := store.Overwrite(ctx, storage.Entry{
+		ShortCode:   "abc123",
+		OriginalURL: "https://other.com",
+		CreatedBy:   "importer",
+		HitCount:    42,
+		CheckedAt:   checkedAt,
+		CheckStatus: 404,
+	})
+	if err != nil {
+		t.Fatalf("Overwrite returned error: %v", err)
+	}
+
+	got, err := store.Find(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if got.OriginalURL != "https://other.com" || got.CreatedBy != "importer" || got.HitCount != 42 {
+		t.Fatalf("expected overwritten fields, got %+v", got)
+	}
+	if !got.CheckedAt.Equal(checkedAt) || got.CheckStatus != 404 {
+		t.Fatalf("expected the imported check, got %+v", got)
+	}
+	if !got.CreatedAt.Equal(createdAt) {
+		t.Fatalf("expected CreatedAt %v to be kept, got %v", createdAt, got.CreatedAt)
+	}
+}
+
+func testOverwriteNotFound(t *testing.T, store storage.Store) {
+	err := store.Overwrite(context.Background(), storage.Entry{ShortCode: "missing", OriginalURL: "https://example.com"})
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", storage.ErrNotFound, err)
+	}
+}
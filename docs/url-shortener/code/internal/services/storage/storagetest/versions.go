@@ -0,0 +1,171 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+// VersionStore is what the link history suite needs: links, and their
+// versions.
+type VersionStore interface {
+	storage.Store
+	storage.VersionStore
+}
+
+// VersionStoreFactory returns an empty store, like Factory.
+type VersionStoreFactory func(t *testing.T) VersionStore
+
+// RunVersionStore executes the link history part of the suite.
+func RunVersionStore(t *testing.T, newStore VersionStoreFactory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store VersionStore)
+	}{
+		{"AppendAndList", testAppendAndListVersions},
+		{"AppendMissing", testAppendVersionMissing},
+		{"FindMissing", testFindVersionMissing},
+		{"DeleteCascades", testDeleteRemovesVersions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newVersion(code, url string, at time.Time) storage.LinkVersion {
+	return storage.LinkVersion{
+		ShortCode:   code,
+		At:          at,
+		Actor:       "key:k1",
+		Action:      "retarget",
+		OriginalURL: url,
+	}
+}
+
+func testAppendAndListVersions(t *testing.T, store VersionStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if err := store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com/v1"}); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	first := newVersion("abc123", "https://example.com/v1", now)
+	first.Action = "create"
+	first.ExpiresAt = now.Add(time.Hour)
+	first.ActivatesAt = now.Add(time.Minute)
+	first.Title = "Launch"
+	first.Description = "Landing page"
+	first.Tags = []string{"launch/q3", "marketing"}
+	second := newVersion("abc123", "https://example.com/v2", now.Add(time.Minute))
+	second.Disabled = true
+	second.DisabledReason = "phishing"
+	for i, v := range []storage.LinkVersion{first, second} {
+		v.Version = 42 // ignored; the store numbers versions
+		n, err := store.AppendVersion(ctx, v)
+		if err != nil {
+			t.Fatalf("AppendVersion returned error: %v", err)
+		}
+		if n != i+1 {
+			t.Fatalf("expected version %d, got %d", i+1, n)
+		}
+	}
+
+	got, err := store.FindVersion(ctx, "abc123", 1)
+	if err != nil {
+		t.Fatalf("FindVersion returned error: %v", err)
+	}
+	if !got.At.Equal(first.At) || !got.ExpiresAt.Equal(first.ExpiresAt) || !got.ActivatesAt.Equal(first.ActivatesAt) {
+		t.Fatalf("expected times %v, %v and %v, got %v, %v and %v",
+			first.At, first.ExpiresAt, first.ActivatesAt, got.At, got.ExpiresAt, got.ActivatesAt)
+	}
+	first.Version = 1
+	got.At, got.ExpiresAt, got.ActivatesAt = time.Time{}, time.Time{}, time.Time{}
+	first.At, first.ExpiresAt, first.ActivatesAt = time.Time{}, time.Time{}, time.Time{}
+	if !reflect.DeepEqual(got, first) {
+		t.Fatalf("expected %+v, got %+v", first, got)
+	}
+
+	versions, err := store.ListVersions(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("ListVersions returned error: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 2 || versions[1].Version != 1 {
+		t.Fatalf("expected versions 2 and 1, newest first, got %+v", versions)
+	}
+	if v := versions[0]; v.OriginalURL != "https://example.com/v2" || !v.Disabled || v.DisabledReason != "phishing" || !v.ExpiresAt.IsZero() || !v.ActivatesAt.IsZero() || len(v.Tags) != 0 {
+		t.Fatalf("unexpected version 2: %+v", v)
+	}
+
+	none, err := store.ListVersions(ctx, "nope404")
+	if err != nil {
+		t.Fatalf("ListVersions returned error: %v", err)
+	}
+	if none == nil || len(none) != 0 {
+		t.Fatalf("expected an empty, non-nil history, got %#v", none)
+	}
+}
+
+func testAppendVersionMissing(t *testing.T, store VersionStore) {
+	_, err := store.AppendVersion(context.Background(), newVersion("missing", "https://example.com", time.Now().UTC()))
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testFindVersionMissing(t *testing.T, store VersionStore) {
+	ctx := context.Background()
+	if err := store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com"}); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	if _, err := store.AppendVersion(ctx, newVersion("abc123", "https://example.com", time.Now().UTC())); err != nil {
+		t.Fatalf("AppendVersion returned error: %v", err)
+	}
+	for _, n := range []int{0, 2} {
+		if _, err := store.FindVersion(ctx, "abc123", n); !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("FindVersion(%d): expected ErrNotFound, got %v", n, err)
+		}
+	}
+	if _, err := store.FindVersion(ctx, "nope404", 1); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing link, got %v", err)
+	}
+}
+
+func testDeleteRemovesVersions(t *testing.T, store VersionStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if err := store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com/old"}); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	for range 2 {
+		if _, err := store.AppendVersion(ctx, newVersion("abc123", "https://example.com/old", now)); err != nil {
+			t.Fatalf("AppendVersion returned error: %v", err)
+		}
+	}
+	if err := store.Delete(ctx, "abc123"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	// A reused code starts a new history.
+	if err := store.Save(ctx, storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com/new"}); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	versions, err := store.ListVersions(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("ListVersions returned error: %v", err)
+	}
+	if len(versions) != 0 {
+		t.Fatalf("expected the deleted link's history to be gone, got %+v", versions)
+	}
+	n, err := store.AppendVersion(ctx, newVersion("abc123", "https://example.com/new", now))
+	if err != nil || n != 1 {
+		t.Fatalf("expected the new history to start at 1, got %d, %v", n, err)
+	}
+}
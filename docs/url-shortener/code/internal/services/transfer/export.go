@@ -0,0 +1,143 @@
+package transfer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+// exportPageSize bounds how many entries are held in memory at once.
+const exportPageSize = 500
+
+// Export writes every entry in store to w and returns how many it wrote.
+func Export(ctx context.Context, store storage.Store, w io.Writer, format Format) (int, error) {
+	enc, err := newEncoder(w, format)
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for offset := 0; ; offset += exportPageSize {
+		page, err := store.List(ctx, storage.ListOptions{Limit: exportPageSize, Offset: offset})
+		if err != nil {
+			return written, err
+		}
+		for _, e := range page {
+			if err := enc.encode(recordFromEntry(e)); err != nil {
+				return written, err
+			}
+			written++
+		}
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+	return written, enc.close()
+}
+
+type encoder interface {
+	encode(Record) error
+	close() error
+}
+
+func newEncoder(w io.Writer, format Format) (encoder, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return &csvEncoder{w: cw}, nil
+	case FormatJSON:
+		return &jsonArrayEncoder{w: w}, nil
+	case FormatNDJSON:
+		return &ndjsonEncoder{enc: json.NewEncoder(w)}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) encode(r Record) error {
+	return e.w.Write([]string{
+		r.ShortCode,
+		r.OriginalURL,
+		formatTime(r.CreatedAt),
+		r.CreatedBy,
+		strconv.FormatInt(r.HitCount, 10),
+		formatTime(r.ExpiresAt),
+		formatBool(r.Disabled),
+		r.DisabledReason,
+		r.Title,
+		r.Description,
+		strings.Join(r.Tags, ","),
+		formatBool(r.Signed),
+		formatTime(r.ActivatesAt),
+	})
+}
+
+func (e *csvEncoder) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonArrayEncoder streams a JSON array one element at a time.
+type jsonArrayEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonArrayEncoder) encode(r Record) error {
+	sep := ",\n  "
+	if e.count == 0 {
+		sep = "[\n  "
+	}
+	e.count++
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(raw)
+	return err
+}
+
+func (e *jsonArrayEncoder) close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder) encode(r Record) error { return e.enc.Encode(r) }
+func (e *ndjsonEncoder) close() error          { return nil }
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatBool leaves false flags empty, like zero times.
+func formatBool(b bool) string {
+	if !b {
+		return ""
+	}
+	return "true"
+}
@@ -0,0 +1,420 @@
+package transfer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"urlshortener/internal/services/storage"
+)
+
+// ConflictPolicy decides what Import does with a short code that already exists.
+type ConflictPolicy string
+
+const (
+	PolicySkip      ConflictPolicy = "skip"
+	PolicyOverwrite ConflictPolicy = "overwrite"
+	PolicyFail      ConflictPolicy = "fail"
+)
+
+var (
+	ErrUnknownPolicy = errors.New("transfer: unknown conflict policy (want skip, overwrite or fail)")
+	ErrConflict      = errors.New("transfer: short code already exists")
+)
+
+func ParsePolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(strings.ToLower(s)); p {
+	case PolicySkip, PolicyOverwrite, PolicyFail:
+		return p, nil
+	case "":
+		return PolicySkip, nil
+	}
+	return "", ErrUnknownPolicy
+}
+
+type ImportOptions struct {
+	Format Format
+	Policy ConflictPolicy
+	// DryRun validates every row and reports what would happen without
+	// writing anything.
+	DryRun bool
+	// DefaultOwner fills CreatedBy for rows that don't have one.
+	DefaultOwner string
+	// Check, if set, vets each row after the format checks, e.g. against
+	// the rules links created through the API must follow. It may
+	// normalise the record; a row it rejects is reported and skipped.
+	Check func(*Record) error
+}
+
+// maxReportedErrors caps Report.Errors so a bad file can't blow up the response.
+const maxReportedErrors = 100
+
+type RowError struct {
+	Row       int    `json:"row"`
+	ShortCode string `json:"short_code,omitempty"`
+	Error     string `json:"error"`
+}
+
+// Report summarises an import. Row numbers are 1-based data rows (the CSV
+// header is not counted).
+type Report struct {
+	DryRun      bool       `json:"dry_run"`
+	Total       int        `json:"total"`
+	Created     int        `json:"created"`
+	Overwritten int        `json:"overwritten"`
+	Skipped     int        `json:"skipped"`
+	Failed      int        `json:"failed"`
+	Errors      []RowError `json:"errors,omitempty"`
+}
+
+func (r *Report) fail(row int, code string, err error) {
+	r.Failed++
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, RowError{Row: row, ShortCode: code, Error: err.Error()})
+	}
+}
+
+// Import reads records from r and saves them into store. Invalid rows are
+// reported and skipped. With PolicyFail the first conflict stops the import
+// and is returned as ErrConflict alongside the partial report.
+func Import(ctx context.Context, store storage.Store, r io.Reader, opts ImportOptions) (Report, error) {
+	report := Report{DryRun: opts.DryRun}
+	if opts.Policy == "" {
+		opts.Policy = PolicySkip
+	}
+
+	dec, err := newDecoder(r, opts.Format)
+	if err != nil {
+		return report, err
+	}
+
+	// Codes this run has (or would have) created, so a dry run still catches
+	// duplicates inside the file.
+	seen := make(map[string]struct{})
+
+	for row := 1; ; row++ {
+		rec, err := dec.next()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		report.Total++
+		var rowErr rowError
+		if errors.As(err, &rowErr) {
+			report.fail(row, "", rowErr.err)
+			continue
+		}
+		if err != nil {
+			report.Total--
+			return report, err
+		}
+
+		if err := validate(&rec, opts.DefaultOwner); err != nil {
+			report.fail(row, rec.ShortCode, err)
+			continue
+		}
+		if opts.Check != nil {
+			if err := opts.Check(&rec); err != nil {
+				report.fail(row, rec.ShortCode, err)
+				continue
+			}
+		}
+
+		exists, err := codeExists(ctx, store, rec.ShortCode, seen)
+		if err != nil {
+			return report, err
+		}
+
+		switch {
+		case !exists:
+			if !opts.DryRun {
+				if err := store.Save(ctx, rec.entry()); err != nil {
+					return report, fmt.Errorf("row %d: %w", row, err)
+				}
+			}
+			report.Created++
+		case opts.Policy == PolicySkip:
+			report.Skipped++
+		case opts.Policy == PolicyOverwrite:
+			if !opts.DryRun {
+				if err := store.Overwrite(ctx, rec.entry()); err != nil {
+					return report, fmt.Errorf("row %d: %w", row, err)
+				}
+			}
+			report.Overwritten++
+		default:
+			report.fail(row, rec.ShortCode, ErrConflict)
+			return report, fmt.Errorf("row %d (%s): %w", row, rec.ShortCode, ErrConflict)
+		}
+		seen[rec.ShortCode] = struct{}{}
+	}
+}
+
+func codeExists(ctx context.Context, store storage.Store, code string, seen map[string]struct{}) (bool, error) {
+	if _, ok := seen[code]; ok {
+		return true, nil
+	}
+	_, err := store.Find(ctx, code)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func validate(rec *Record, defaultOwner string) error {
+	rec.ShortCode = strings.TrimSpace(rec.ShortCode)
+	rec.OriginalURL = strings.TrimSpace(rec.OriginalURL)
+	if rec.ShortCode == "" {
+		return errors.New("short code is required")
+	}
+	if rec.OriginalURL == "" {
+		return errors.New("original url is required")
+	}
+	if _, err := url.ParseRequestURI(rec.OriginalURL); err != nil {
+		return errors.New("original url is invalid")
+	}
+	if rec.HitCount < 0 {
+		return errors.New("hit count is negative")
+	}
+	if rec.CreatedBy == "" {
+		rec.CreatedBy = defaultOwner
+	}
+	rec.Tags = normaliseTags(rec.Tags)
+	return nil
+}
+
+// normaliseTags trims, lower-cases, sorts and de-duplicates tags the way the
+// shortener stores them, so imported links turn up in tag filters.
+func normaliseTags(tags []string) []string {
+	var out []string
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			out = append(out, tag)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// rowError is a problem with a single record; the decoder can carry on.
+type rowError struct{ err error }
+
+func (e rowError) Error() string { return e.err.Error() }
+
+type decoder interface {
+	// next returns io.EOF when done and rowError for a bad record.
+	next() (Record, error)
+}
+
+func newDecoder(r io.Reader, format Format) (decoder, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVDecoder(r)
+	case FormatJSON:
+		return newJSONArrayDecoder(r)
+	case FormatNDJSON:
+		return &ndjsonDecoder{dec: json.NewDecoder(r)}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+type ndjsonDecoder struct {
+	dec *json.Decoder
+}
+
+func (d *ndjsonDecoder) next() (Record, error) {
+	var rec Record
+	err := d.dec.Decode(&rec)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return Record{}, rowError{err}
+	}
+	return rec, err
+}
+
+type jsonArrayDecoder struct {
+	dec *json.Decoder
+}
+
+func newJSONArrayDecoder(r io.Reader) (*jsonArrayDecoder, error) {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("read json: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("read json: expected an array of links")
+	}
+	return &jsonArrayDecoder{dec: dec}, nil
+}
+
+func (d *jsonArrayDecoder) next() (Record, error) {
+	if !d.dec.More() {
+		return Record{}, io.EOF
+	}
+	var rec Record
+	err := d.dec.Decode(&rec)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return Record{}, rowError{err}
+	}
+	return rec, err
+}
+
+// csvColumns maps normalised header names onto Record fields. Besides our own
+// export header it understands the columns used by common hosted shorteners
+// (e.g. Bitly's "Long URL", "Total Clicks", "Date Created").
+var csvColumns = map[string]string{
+	"short_code": "short_code", "code": "short_code", "slug": "short_code",
+	"alias": "short_code", "keyword": "short_code", "back_half": "short_code",
+
+	"short_url": "short_url", "short_link": "short_url", "shortlink": "short_url",
+	"link": "short_url", "bitlink": "short_url",
+
+	"original_url": "original_url", "long_url": "original_url", "url": "original_url",
+	"destination": "original_url", "destination_url": "original_url", "target": "original_url",
+
+	"created_at": "created_at", "created": "created_at", "date_created": "created_at",
+	"creation_date": "created_at",
+
+	"created_by": "created_by", "owner": "created_by", "user": "created_by",
+
+	"hit_count": "hit_count", "hits": "hit_count", "clicks": "hit_count",
+	"total_clicks": "hit_count", "click_count": "hit_count",
+
+	"expires_at": "expires_at", "expires": "expires_at", "expiry": "expires_at",
+	"expiration_date": "expires_at",
+
+	"activates_at": "activates_at", "activates": "activates_at", "starts_at": "activates_at",
+
+	"disabled": "disabled", "disabled_reason": "disabled_reason",
+
+	"title": "title", "name": "title",
+	"description": "description", "notes": "description",
+	"tags": "tags", "labels": "tags",
+	"signed": "signed",
+}
+
+type csvDecoder struct {
+	r       *csv.Reader
+	columns map[string]int // Record field -> column index
+}
+
+func normaliseHeader(h string) string {
+	h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(h)
+}
+
+func newCSVDecoder(r io.Reader) (*csvDecoder, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("read csv: missing header row")
+		}
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, h := range header {
+		field, ok := csvColumns[normaliseHeader(h)]
+		if !ok {
+			continue // extra columns such as "QR Code" are ignored
+		}
+		if _, dup := columns[field]; !dup {
+			columns[field] = i
+		}
+	}
+	_, hasCode := columns["short_code"]
+	_, hasLink := columns["short_url"]
+	_, hasURL := columns["original_url"]
+	if (!hasCode && !hasLink) || !hasURL {
+		return nil, errors.New("read csv: header needs a short code (or short link) and an original url column")
+	}
+	return &csvDecoder{r: cr, columns: columns}, nil
+}
+
+func (d *csvDecoder) next() (Record, error) {
+	row, err := d.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Record{}, rowError{err}
+		}
+		return Record{}, err
+	}
+
+	get := func(field string) string {
+		i, ok := d.columns[field]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	rec := Record{
+		ShortCode:   get("short_code"),
+		OriginalURL: get("original_url"),
+		CreatedBy:   get("created_by"),
+		Title:       get("title"),
+		Description: get("description"),
+	}
+	if tags := get("tags"); tags != "" {
+		rec.Tags = strings.Split(tags, ",")
+	}
+	if rec.ShortCode == "" {
+		rec.ShortCode = codeFromShortURL(get("short_url"))
+	}
+	if rec.CreatedAt, err = parseTime(get("created_at")); err != nil {
+		return Record{}, rowError{err}
+	}
+	if rec.ExpiresAt, err = parseTime(get("expires_at")); err != nil {
+		return Record{}, rowError{err}
+	}
+	if rec.ActivatesAt, err = parseTime(get("activates_at")); err != nil {
+		return Record{}, rowError{err}
+	}
+	if disabled := get("disabled"); disabled != "" {
+		if rec.Disabled, err = strconv.ParseBool(disabled); err != nil {
+			return Record{}, rowError{fmt.Errorf("invalid disabled flag %q", disabled)}
+		}
+		rec.DisabledReason = get("disabled_reason")
+	}
+	if signed := get("signed"); signed != "" {
+		if rec.Signed, err = strconv.ParseBool(signed); err != nil {
+			return Record{}, rowError{fmt.Errorf("invalid signed flag %q", signed)}
+		}
+	}
+	if hits := strings.ReplaceAll(get("hit_count"), ",", ""); hits != "" {
+		if rec.HitCount, err = strconv.ParseInt(hits, 10, 64); err != nil {
+			return Record{}, rowError{fmt.Errorf("invalid hit count %q", hits)}
+		}
+	}
+	return rec, nil
+}
+
+// codeFromShortURL turns "https://bit.ly/abc123" (or "bit.ly/abc123") into "abc123".
+func codeFromShortURL(s string) string {
+	if s == "" {
+		return ""
+	}
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	code := path.Base(strings.TrimSuffix(u.Path, "/"))
+	if code == "." || code == "/" {
+		return ""
+	}
+	return code
+}
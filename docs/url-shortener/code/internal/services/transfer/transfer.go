@@ -0,0 +1,133 @@
+// Package transfer moves links in and out of a storage.Store as CSV, JSON or
+// NDJSON. Exports stream page by page; imports stream row by row, so neither
+// holds the whole data set in memory.
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatJSON   Format = "json"
+	FormatNDJSON Format = "ndjson"
+)
+
+var ErrUnknownFormat = errors.New("transfer: unknown format (want csv, json or ndjson)")
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatCSV, FormatJSON, FormatNDJSON:
+		return f, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// ContentType is the MIME type served for f.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/json"
+	}
+}
+
+// Record is the wire shape of one link in every format.
+type Record struct {
+	ShortCode   string    `json:"short_code"`
+	OriginalURL string    `json:"original_url"`
+	CreatedAt   time.Time `json:"created_at"`
+	CreatedBy   string    `json:"created_by"`
+	HitCount    int64     `json:"hit_count"`
+	ExpiresAt   time.Time `json:"expires_at,omitzero"`
+	ActivatesAt time.Time `json:"activates_at,omitzero"`
+
+	// Moderation state travels with the link so a restore keeps takedowns.
+	Disabled       bool   `json:"disabled,omitempty"`
+	DisabledReason string `json:"disabled_reason,omitempty"`
+
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+
+	// Signed links stay signed, so a restore does not expose them.
+	Signed bool `json:"signed,omitempty"`
+}
+
+func recordFromEntry(e storage.Entry) Record {
+	return Record{
+		ShortCode:   e.ShortCode,
+		OriginalURL: e.OriginalURL,
+		CreatedAt:   e.CreatedAt,
+		CreatedBy:   e.CreatedBy,
+		HitCount:    e.HitCount,
+		ExpiresAt:   e.ExpiresAt,
+		ActivatesAt: e.ActivatesAt,
+
+		Disabled:       e.Disabled,
+		DisabledReason: e.DisabledReason,
+
+		Title:       e.Title,
+		Description: e.Description,
+		Tags:        e.Tags,
+
+		Signed: e.Signed,
+	}
+}
+
+func (r Record) entry() storage.Entry {
+	return storage.Entry{
+		ShortCode:   r.ShortCode,
+		OriginalURL: r.OriginalURL,
+		CreatedAt:   r.CreatedAt,
+		CreatedBy:   r.CreatedBy,
+		HitCount:    r.HitCount,
+		ExpiresAt:   r.ExpiresAt,
+		ActivatesAt: r.ActivatesAt,
+
+		Disabled:       r.Disabled,
+		DisabledReason: r.DisabledReason,
+
+		Title:       r.Title,
+		Description: r.Description,
+		Tags:        r.Tags,
+
+		Signed: r.Signed,
+	}
+}
+
+// csvHeader is the column order Export writes.
+var csvHeader = []string{"short_code", "original_url", "created_at", "created_by", "hit_count", "expires_at", "disabled", "disabled_reason", "title", "description", "tags", "signed", "activates_at"}
+
+// timeLayouts are tried in order when reading timestamps from CSV; other
+// shorteners rarely use RFC3339.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"01/02/2006 15:04",
+	"01/02/2006",
+}
+
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised time %q", s)
+}
@@ -0,0 +1,227 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"urlshortener/internal/services/storage"
+)
+
+func seededStore(t *testing.T, n int) *storage.InMemoryStore {
+	t.Helper()
+	store := storage.NewInMemoryStore()
+	base := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	for i := range n {
+		err := store.Save(context.Background(), storage.Entry{
+			ShortCode:   "code" + string(rune('a'+i%26)) + strings.Repeat("x", i/26),
+			OriginalURL: "https://example.com/page",
+			CreatedAt:   base.Add(time.Duration(i) * time.Minute),
+			CreatedBy:   "alice",
+			HitCount:    int64(i),
+		})
+		if err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	return store
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatCSV, FormatJSON, FormatNDJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			ctx := context.Background()
+			src := seededStore(t, exportPageSize+3) // spans two pages
+			taken, _ := src.Find(ctx, "coded")
+			taken.Disabled, taken.DisabledReason = true, "phishing"
+			taken.Title, taken.Description = "Q3, launch", "Landing \"page\""
+			taken.Tags = []string{"launch/q3", "marketing"}
+			taken.Signed = true
+			taken.ActivatesAt = time.Date(2030, time.March, 4, 9, 30, 0, 0, time.UTC)
+			_ = src.Update(ctx, taken)
+
+			var buf bytes.Buffer
+			n, err := Export(ctx, src, &buf, format)
+			if err != nil {
+				t.Fatalf("Export returned error: %v", err)
+			}
+			if n != exportPageSize+3 {
+				t.Fatalf("expected %d exported, got %d", exportPageSize+3, n)
+			}
+
+			dst := storage.NewInMemoryStore()
+			report, err := Import(ctx, dst, &buf, ImportOptions{Format: format})
+			if err != nil {
+				t.Fatalf("Import returned error: %v (report %+v)", err, report)
+			}
+			if report.Created != n || report.Failed != 0 {
+				t.Fatalf("expected %d created and no failures, got %+v", n, report)
+			}
+
+			want, _ := src.Find(ctx, "codec")
+			got, err := dst.Find(ctx, "codec")
+			if err != nil {
+				t.Fatalf("Find returned error: %v", err)
+			}
+			if got.HitCount != want.HitCount || got.CreatedBy != want.CreatedBy || !got.CreatedAt.Equal(want.CreatedAt) {
+				t.Fatalf("expected %+v, got %+v", want, got)
+			}
+			if got.Disabled || got.Signed || !got.ActivatesAt.IsZero() {
+				t.Fatalf("expected %s to stay enabled, unsigned and live", got.ShortCode)
+			}
+			if got, _ := dst.Find(ctx, "coded"); !got.Disabled || got.DisabledReason != "phishing" {
+				t.Fatalf("expected takedown to survive the round trip, got %+v", got)
+			}
+			if got, _ := dst.Find(ctx, "coded"); got.Title != taken.Title || got.Description != taken.Description ||
+				!slices.Equal(got.Tags, taken.Tags) || !got.Signed || !got.ActivatesAt.Equal(taken.ActivatesAt) {
+				t.Fatalf("expected details to survive the round trip, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestExportEmptyJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := Export(context.Background(), storage.NewInMemoryStore(), &buf, FormatJSON); err != nil {
+		t.Fatalf("Export returned error: %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Fatalf("expected empty array, got %q", buf.String())
+	}
+}
+
+func TestImportConflictPolicies(t *testing.T) {
+	ctx := context.Background()
+	input := `{"short_code":"taken","original_url":"https://new.example.com"}
+{"short_code":"fresh","original_url":"https://fresh.example.com"}
+`
+	newStore := func() *storage.InMemoryStore {
+		store := storage.NewInMemoryStore()
+		_ = store.Save(ctx, storage.Entry{ShortCode: "taken", OriginalURL: "https://old.example.com"})
+		return store
+	}
+
+	t.Run("skip", func(t *testing.T) {
+		store := newStore()
+		report, err := Import(ctx, store, strings.NewReader(input), ImportOptions{Format: FormatNDJSON, Policy: PolicySkip})
+		if err != nil {
+			t.Fatalf("Import returned error: %v", err)
+		}
+		if report.Created != 1 || report.Skipped != 1 {
+			t.Fatalf("unexpected report: %+v", report)
+		}
+		got, _ := store.Find(ctx, "taken")
+		if got.OriginalURL != "https://old.example.com" {
+			t.Fatalf("expected existing link to be kept, got %s", got.OriginalURL)
+		}
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		store := newStore()
+		report, err := Import(ctx, store, strings.NewReader(input), ImportOptions{Format: FormatNDJSON, Policy: PolicyOverwrite})
+		if err != nil {
+			t.Fatalf("Import returned error: %v", err)
+		}
+		if report.Created != 1 || report.Overwritten != 1 {
+			t.Fatalf("unexpected report: %+v", report)
+		}
+		got, _ := store.Find(ctx, "taken")
+		if got.OriginalURL != "https://new.example.com" {
+			t.Fatalf("expected link to be overwritten, got %s", got.OriginalURL)
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		store := newStore()
+		report, err := Import(ctx, store, strings.NewReader(input), ImportOptions{Format: FormatNDJSON, Policy: PolicyFail})
+		if !errors.Is(err, ErrConflict) {
+			t.Fatalf("expected %v, got %v", ErrConflict, err)
+		}
+		if report.Created != 0 || report.Failed != 1 {
+			t.Fatalf("unexpected report: %+v", report)
+		}
+		if _, err := store.Find(ctx, "fresh"); !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("expected import to stop at the conflict, got %v", err)
+		}
+	})
+}
+
+func TestImportDryRun(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	input := `[
+  {"short_code":"one","original_url":"https://example.com"},
+  {"short_code":"one","original_url":"https://example.com/dup"},
+  {"short_code":"bad","original_url":"not a url"}
+]`
+
+	report, err := Import(ctx, store, strings.NewReader(input), ImportOptions{Format: FormatJSON, DryRun: true})
+	if err != nil {
+		t.Fatalf("Import returned error: %v", err)
+	}
+	if !report.DryRun || report.Total != 3 || report.Created != 1 || report.Skipped != 1 || report.Failed != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Row != 3 {
+		t.Fatalf("expected row 3 to be reported, got %+v", report.Errors)
+	}
+	if _, err := store.Find(ctx, "one"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected dry run not to write, got %v", err)
+	}
+}
+
+func TestImportExternalCSV(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	input := "\ufeffTitle,Short Link,Long URL,Date Created,Total Clicks,Tags\n" +
+		"Launch,https://bit.ly/q3launch,https://example.com/q3,2023-07-01 09:30:00,\"1,204\",\"Marketing, launch\"\n" +
+		"Broken,bit.ly/oops,https://example.com/oops,yesterday,3,\n"
+
+	report, err := Import(ctx, store, strings.NewReader(input), ImportOptions{
+		Format:       FormatCSV,
+		DefaultOwner: "import",
+	})
+	if err != nil {
+		t.Fatalf("Import returned error: %v", err)
+	}
+	if report.Created != 1 || report.Failed != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	got, err := store.Find(ctx, "q3launch")
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if got.OriginalURL != "https://example.com/q3" || got.HitCount != 1204 || got.CreatedBy != "import" ||
+		got.Title != "Launch" || !slices.Equal(got.Tags, []string{"launch", "marketing"}) {
+		t.Fatalf("unexpected entry: %+v", got)
+	}
+	if want := time.Date(2023, time.July, 1, 9, 30, 0, 0, time.UTC); !got.CreatedAt.Equal(want) {
+		t.Fatalf("expected CreatedAt %v, got %v", want, got.CreatedAt)
+	}
+}
+
+func TestImportCSVRequiresColumns(t *testing.T) {
+	_, err := Import(context.Background(), storage.NewInMemoryStore(), strings.NewReader("title,clicks\nx,1\n"), ImportOptions{Format: FormatCSV})
+	if err == nil {
+		t.Fatalf("expected error for missing columns")
+	}
+}
+
+func TestParseFormatAndPolicy(t *testing.T) {
+	if _, err := ParseFormat("xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("expected %v, got %v", ErrUnknownFormat, err)
+	}
+	if f, err := ParseFormat("NDJSON"); err != nil || f != FormatNDJSON {
+		t.Fatalf("expected ndjson, got %v %v", f, err)
+	}
+	if p, err := ParsePolicy(""); err != nil || p != PolicySkip {
+		t.Fatalf("expected default skip policy, got %v %v", p, err)
+	}
+	if _, err := ParsePolicy("merge"); !errors.Is(err, ErrUnknownPolicy) {
+		t.Fatalf("expected %v, got %v", ErrUnknownPolicy, err)
+	}
+}
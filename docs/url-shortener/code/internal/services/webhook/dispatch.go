@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/storage"
+)
+
+// batchSize bounds how many deliveries one RunOnce attempts.
+const batchSize = 100
+
+// maxErrorLen bounds the receiver's response kept in LastError.
+const maxErrorLen = 200
+
+// Run delivers due webhooks until ctx is done. It checks the outbox every
+// PollInterval and as soon as new events are queued.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.poll)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := s.RunOnce(ctx)
+			if err != nil {
+				logging.Errorf("❌ Failed to dispatch webhooks: %v", err)
+			}
+			// A full batch means more may be waiting.
+			if err != nil || n < batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// RunOnce claims the deliveries that are due now for Lease, attempts them and
+// reports how many it attempted. Servers sharing an outbox never claim the
+// same delivery at once, so none is sent twice unless its lease runs out.
+func (s *Service) RunOnce(ctx context.Context) (int, error) {
+	now := s.clock.Now().UTC()
+	until := now.Add(s.lease)
+	due, err := s.outbox.ClaimDueDeliveries(ctx, now, until, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, d := range due {
+		// Once the lease is up the rest are due again and may have been
+		// claimed by another server; leave them to it.
+		if !s.clock.Now().Before(until) {
+			return i, nil
+		}
+		if err := s.attempt(ctx, d); err != nil {
+			return 0, err
+		}
+	}
+	return len(due), nil
+}
+
+// attempt sends d once and stores the outcome.
+func (s *Service) attempt(ctx context.Context, d storage.WebhookDelivery) error {
+	var status int
+	var err error
+	if e, ok := s.endpoint(d.Endpoint); ok {
+		status, err = s.send(ctx, e, d)
+	} else {
+		err = fmt.Errorf("endpoint %q is not configured", d.Endpoint)
+	}
+
+	now := s.clock.Now().UTC()
+	d.Attempts++
+	d.LastStatus = status
+	switch {
+	case err == nil:
+		d.Status = storage.DeliveryDelivered
+		d.DeliveredAt = now
+		d.LastError = ""
+		logging.Debugf("📨 Delivered %s to %s", d.Event, d.Endpoint)
+	case d.Attempts >= s.maxAttempts:
+		d.Status = storage.DeliveryFailed
+		d.LastError = err.Error()
+		logging.Warnf("⚠️  Webhook %s to %s failed after %d attempts: %v", d.ID, d.Endpoint, d.Attempts, err)
+	default:
+		d.NextAttemptAt = now.Add(s.backoff(d.Attempts))
+		d.LastError = err.Error()
+		logging.Debugf("📨 Webhook %s to %s failed, retrying at %s: %v", d.ID, d.Endpoint, d.NextAttemptAt.Format(time.RFC3339), err)
+	}
+	return s.outbox.UpdateDelivery(ctx, d)
+}
+
+// send posts the delivery's payload and returns the response status. Any
+// status outside 2xx is an error.
+func (s *Service) send(ctx context.Context, e Endpoint, d storage.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "urlshortener-webhooks")
+	req.Header.Set(IDHeader, d.ID)
+	req.Header.Set(EventHeader, d.Event)
+	req.Header.Set(SignatureHeader, Sign(e.Secret, s.clock.Now(), d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLen))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if len(body) > 0 {
+			return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+		}
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
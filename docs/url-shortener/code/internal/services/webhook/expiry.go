@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"urlshortener/internal/logging"
+	"urlshortener/internal/services/storage"
+)
+
+// expiryLookback is how far back the first sweep after a start looks, so
+// links that expired while the server was down are still announced.
+const expiryLookback = 24 * time.Hour
+
+// SweepExpired queues link.expired for the links in links whose expiry falls
+// in (since, now] and reports how many deliveries were new. The event ID is
+// derived from the link and its expiry, so overlapping sweeps, or several
+// servers sweeping one store, announce each expiry only once.
+func (s *Service) SweepExpired(ctx context.Context, links storage.ExpiryStore, since, now time.Time) (int, error) {
+	if !s.anyWants(EventExpired, storage.Entry{}) {
+		return 0, nil
+	}
+	entries, err := links.ListExpired(ctx, since, now)
+	if err != nil {
+		return 0, err
+	}
+	queued := 0
+	for _, e := range entries {
+		id := fmt.Sprintf("exp-%s-%x", e.ShortCode, e.ExpiresAt.Unix())
+		n, err := s.enqueue(ctx, id, EventExpired, e.ExpiresAt.UTC(), e)
+		queued += n
+		if err != nil {
+			return queued, err
+		}
+	}
+	return queued, nil
+}
+
+// WatchExpiry sweeps links for expirations every interval until ctx is done.
+// It returns at once; nothing happens if no endpoint wants link.expired.
+func (s *Service) WatchExpiry(ctx context.Context, links storage.ExpiryStore, interval time.Duration) {
+	if interval <= 0 || !s.anyWants(EventExpired, storage.Entry{}) {
+		return
+	}
+	go func() {
+		since := s.clock.Now().UTC().Add(-expiryLookback)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			now := s.clock.Now().UTC()
+			n, err := s.SweepExpired(ctx, links, since, now)
+			if err != nil {
+				logging.Errorf("❌ Failed to sweep expired links: %v", err)
+			} else {
+				since = now
+			}
+			if n > 0 {
+				logging.Infof("⏰ Queued %d link.expired webhooks", n)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,302 @@
+// Package webhook notifies external systems about link events. Events are
+// written to a persistent outbox first and delivered by a background
+// dispatcher, so a slow or unreachable receiver never holds up a request and
+// queued events survive restarts.
+//
+// Every request is a JSON POST signed with the endpoint's secret (see Sign).
+// Failed attempts are retried with exponential backoff; deliveries that run
+// out of attempts are kept as failed until replayed. Servers sharing an
+// outbox lease the deliveries they send, so each is normally sent by one of
+// them. Delivery is still at least once: receivers should de-duplicate on
+// the X-Webhook-ID header.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"urlshortener/internal/clock"
+	"urlshortener/internal/services/storage"
+)
+
+// Events a webhook can subscribe to.
+const (
+	EventCreated = "link.created"
+	EventDeleted = "link.deleted"
+	EventExpired = "link.expired"
+	EventHits    = "link.hits"
+)
+
+// Events lists every event, for validating subscriptions.
+var Events = []string{EventCreated, EventDeleted, EventExpired, EventHits}
+
+// ErrNotFailed is returned when replaying a delivery that has not failed.
+var ErrNotFailed = errors.New("only failed deliveries can be replayed")
+
+// Defaults for the zero values in Options.
+const (
+	defaultMaxAttempts  = 8
+	defaultPollInterval = 5 * time.Second
+	defaultTimeout      = 10 * time.Second
+	defaultLease        = 5 * time.Minute
+)
+
+// Endpoint is one receiver of webhooks.
+type Endpoint struct {
+	Name   string // stored with each delivery; keep it stable across restarts
+	URL    string
+	Secret string
+	// Events the endpoint subscribes to; empty means all of them.
+	Events []string
+	// HitEvery sends link.hits each time a link's hit count reaches a
+	// multiple of it. Zero turns link.hits off for the endpoint.
+	HitEvery int64
+}
+
+func (e Endpoint) wants(event string, entry storage.Entry) bool {
+	if len(e.Events) > 0 && !slices.Contains(e.Events, event) {
+		return false
+	}
+	if event == EventHits {
+		return e.HitEvery > 0 && entry.HitCount > 0 && entry.HitCount%e.HitEvery == 0
+	}
+	return true
+}
+
+type Options struct {
+	Endpoints    []Endpoint
+	MaxAttempts  int           // attempts before a delivery fails; default 8
+	PollInterval time.Duration // how often the dispatcher looks for due retries; default 5s
+	// Lease is how long a dispatcher holds the deliveries it picks before
+	// another server may take them, in case it died sending; default 5m.
+	Lease time.Duration
+	// Backoff returns the wait after the given number of failed attempts.
+	// The default doubles from 5s up to an hour.
+	Backoff func(attempts int) time.Duration
+	Client  *http.Client // default has a 10s timeout
+	Clock   clock.Clock  // default clock.System
+}
+
+// Service queues link events for the configured endpoints and delivers them.
+type Service struct {
+	outbox      storage.OutboxStore
+	endpoints   []Endpoint
+	client      *http.Client
+	maxAttempts int
+	poll        time.Duration
+	lease       time.Duration
+	backoff     func(attempts int) time.Duration
+	clock       clock.Clock
+	wake        chan struct{}
+}
+
+func New(outbox storage.OutboxStore, opts Options) *Service {
+	s := &Service{
+		outbox:      outbox,
+		endpoints:   opts.Endpoints,
+		client:      opts.Client,
+		maxAttempts: opts.MaxAttempts,
+		poll:        opts.PollInterval,
+		lease:       opts.Lease,
+		backoff:     opts.Backoff,
+		clock:       opts.Clock,
+		wake:        make(chan struct{}, 1),
+	}
+	if s.client == nil {
+		s.client = &http.Client{Timeout: defaultTimeout}
+	}
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = defaultMaxAttempts
+	}
+	if s.poll <= 0 {
+		s.poll = defaultPollInterval
+	}
+	if s.lease <= 0 {
+		s.lease = defaultLease
+	}
+	if s.backoff == nil {
+		s.backoff = ExponentialBackoff(5*time.Second, time.Hour)
+	}
+	if s.clock == nil {
+		s.clock = clock.System
+	}
+	return s
+}
+
+// ExponentialBackoff waits base after the first failure and doubles the wait
+// after each further one, up to max.
+func ExponentialBackoff(base, max time.Duration) func(attempts int) time.Duration {
+	return func(attempts int) time.Duration {
+		wait := base
+		for i := 1; i < attempts && wait < max; i++ {
+			wait *= 2
+		}
+		return min(wait, max)
+	}
+}
+
+// endpoint returns the configured endpoint called name.
+func (s *Service) endpoint(name string) (Endpoint, bool) {
+	for _, e := range s.endpoints {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Endpoint{}, false
+}
+
+// Link is the link as described in a webhook payload.
+type Link struct {
+	ShortCode   string    `json:"short_code"`
+	OriginalURL string    `json:"original_url"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at,omitzero"`
+	ActivatesAt time.Time `json:"activates_at,omitzero"`
+	HitCount    int64     `json:"hit_count"`
+}
+
+// Payload is the JSON body of every webhook. ID names the event and is the
+// same for every endpoint it is sent to.
+type Payload struct {
+	ID         string    `json:"id"`
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Link       Link      `json:"link"`
+}
+
+// Notify queues event about entry for every endpoint subscribed to it. The
+// event is only persisted here; the dispatcher sends it.
+func (s *Service) Notify(ctx context.Context, event string, entry storage.Entry) error {
+	if !s.anyWants(event, entry) {
+		return nil
+	}
+	at := s.clock.Now().UTC()
+	id, err := newEventID(at)
+	if err != nil {
+		return err
+	}
+	_, err = s.enqueue(ctx, id, event, at, entry)
+	return err
+}
+
+func (s *Service) anyWants(event string, entry storage.Entry) bool {
+	for _, e := range s.endpoints {
+		if e.wants(event, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue writes one delivery per subscribed endpoint and reports how many
+// were new. Deliveries already in the outbox are left alone, which makes
+// enqueueing an event with a fixed ID idempotent.
+func (s *Service) enqueue(ctx context.Context, eventID, event string, at time.Time, entry storage.Entry) (int, error) {
+	body, err := json.Marshal(Payload{
+		ID:         eventID,
+		Event:      event,
+		OccurredAt: at,
+		Link: Link{
+			ShortCode:   entry.ShortCode,
+			OriginalURL: entry.OriginalURL,
+			CreatedBy:   entry.CreatedBy,
+			CreatedAt:   entry.CreatedAt,
+			ExpiresAt:   entry.ExpiresAt,
+			ActivatesAt: entry.ActivatesAt,
+			HitCount:    entry.HitCount,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, e := range s.endpoints {
+		if !e.wants(event, entry) {
+			continue
+		}
+		err := s.outbox.EnqueueDelivery(ctx, storage.WebhookDelivery{
+			ID:            eventID + "." + e.Name,
+			Endpoint:      e.Name,
+			Event:         event,
+			Payload:       body,
+			Status:        storage.DeliveryPending,
+			NextAttemptAt: at,
+			CreatedAt:     at,
+		})
+		if errors.Is(err, storage.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return queued, fmt.Errorf("webhook %s for %s: %w", event, e.Name, err)
+		}
+		queued++
+	}
+	if queued > 0 {
+		s.kick()
+	}
+	return queued, nil
+}
+
+// kick wakes the dispatcher without waiting for its next poll.
+func (s *Service) kick() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Deliveries lists the outbox, newest first.
+func (s *Service) Deliveries(ctx context.Context, opts storage.DeliveryListOptions) ([]storage.WebhookDelivery, error) {
+	return s.outbox.ListDeliveries(ctx, opts)
+}
+
+// Replay queues a failed delivery again with a fresh set of attempts.
+func (s *Service) Replay(ctx context.Context, id string) (storage.WebhookDelivery, error) {
+	d, err := s.outbox.FindDelivery(ctx, id)
+	if err != nil {
+		return storage.WebhookDelivery{}, err
+	}
+	if d.Status != storage.DeliveryFailed {
+		return d, ErrNotFailed
+	}
+	d.Status = storage.DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = s.clock.Now().UTC()
+	if err := s.outbox.UpdateDelivery(ctx, d); err != nil {
+		return storage.WebhookDelivery{}, err
+	}
+	s.kick()
+	return d, nil
+}
+
+// ReplayFailed replays every failed delivery and reports how many there were.
+func (s *Service) ReplayFailed(ctx context.Context) (int, error) {
+	failed, err := s.outbox.ListDeliveries(ctx, storage.DeliveryListOptions{Status: storage.DeliveryFailed})
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range failed {
+		if _, err := s.Replay(ctx, d.ID); err != nil && !errors.Is(err, ErrNotFailed) {
+			return 0, err
+		}
+	}
+	return len(failed), nil
+}
+
+// newEventID is the time in hex followed by random bits, like audit event IDs.
+func newEventID(at time.Time) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%016x%s", at.UnixNano(), hex.EncodeToString(b)), nil
+}
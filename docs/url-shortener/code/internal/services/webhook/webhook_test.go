@@ -0,0 +1,385 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"urlshortener/internal/clock/clocktest"
+	"urlshortener/internal/services/storage"
+)
+
+// receiver is a webhook endpoint that records verified requests and fails
+// while failing is set.
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	failing  bool
+	payloads []Payload
+	ids      []string
+}
+
+func newReceiver(t *testing.T, secret string) (*receiver, *httptest.Server) {
+	t.Helper()
+	rcv := &receiver{secret: secret}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify(rcv.secret, r.Header.Get(SignatureHeader), body, time.Now(), time.Minute); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		rcv.mu.Lock()
+		defer rcv.mu.Unlock()
+		if rcv.failing {
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		var p Payload
+		if err := json.Unmarshal(body, &p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get(EventHeader) != p.Event {
+			http.Error(w, "event header mismatch", http.StatusBadRequest)
+			return
+		}
+		rcv.payloads = append(rcv.payloads, p)
+		rcv.ids = append(rcv.ids, r.Header.Get(IDHeader))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return rcv, srv
+}
+
+func (r *receiver) setFailing(failing bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failing = failing
+}
+
+func (r *receiver) received() []Payload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Payload(nil), r.payloads...)
+}
+
+// noBackoff makes every retry due at once.
+func noBackoff(int) time.Duration { return 0 }
+
+func TestNotifyAndDeliver(t *testing.T) {
+	ctx := context.Background()
+	rcv, srv := newReceiver(t, "s3cret")
+	outbox := storage.NewInMemoryStore()
+	svc := New(outbox, Options{Endpoints: []Endpoint{{Name: "crm", URL: srv.URL, Secret: "s3cret"}}})
+
+	entry := storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com", CreatedBy: "key:k1"}
+	if err := svc.Notify(ctx, EventCreated, entry); err != nil {
+		t.Fatalf("Notify returned error: %v", err)
+	}
+	if len(rcv.received()) != 0 {
+		t.Fatalf("expected nothing to be sent before the dispatcher runs")
+	}
+
+	n, err := svc.RunOnce(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("expected one attempt, got %d, %v", n, err)
+	}
+	got := rcv.received()
+	if len(got) != 1 || got[0].Event != EventCreated || got[0].Link.ShortCode != "abc123" || got[0].Link.CreatedBy != "key:k1" {
+		t.Fatalf("unexpected payloads %+v", got)
+	}
+
+	deliveries, _ := svc.Deliveries(ctx, storage.DeliveryListOptions{})
+	if len(deliveries) != 1 || deliveries[0].Status != storage.DeliveryDelivered || deliveries[0].Attempts != 1 ||
+		deliveries[0].LastStatus != http.StatusNoContent || deliveries[0].DeliveredAt.IsZero() {
+		t.Fatalf("unexpected deliveries %+v", deliveries)
+	}
+	if rcv.ids[0] != deliveries[0].ID {
+		t.Fatalf("expected delivery ID header %s, got %s", deliveries[0].ID, rcv.ids[0])
+	}
+
+	if n, _ := svc.RunOnce(ctx); n != 0 {
+		t.Fatalf("expected delivered webhooks not to be sent again, got %d attempts", n)
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	outbox := storage.NewInMemoryStore()
+	svc := New(outbox, Options{Endpoints: []Endpoint{
+		{Name: "all", URL: "http://all.invalid"},
+		{Name: "deletes", URL: "http://deletes.invalid", Events: []string{EventDeleted}},
+		{Name: "hits", URL: "http://hits.invalid", Events: []string{EventHits}, HitEvery: 10},
+	}})
+
+	entry := storage.Entry{ShortCode: "abc123", OriginalURL: "https://example.com"}
+	_ = svc.Notify(ctx, EventCreated, entry)
+	_ = svc.Notify(ctx, EventDeleted, entry)
+	for hits := int64(1); hits <= 25; hits++ {
+		entry.HitCount = hits
+		_ = svc.Notify(ctx, EventHits, entry)
+	}
+
+	count := map[string]int{}
+	deliveries, _ := outbox.ListDeliveries(ctx, storage.DeliveryListOptions{})
+	for _, d := range deliveries {
+		count[d.Endpoint+" "+d.Event]++
+	}
+	want := map[string]int{
+		"all link.created":     1,
+		"all link.deleted":     1,
+		"deletes link.deleted": 1,
+		"hits link.hits":       2, // at 10 and 20 hits
+	}
+	if len(count) != len(want) {
+		t.Fatalf("expected %v, got %v", want, count)
+	}
+	for k, n := range want {
+		if count[k] != n {
+			t.Fatalf("expected %v, got %v", want, count)
+		}
+	}
+}
+
+func TestRetryFailAndReplay(t *testing.T) {
+	ctx := context.Background()
+	rcv, srv := newReceiver(t, "s3cret")
+	rcv.setFailing(true)
+	outbox := storage.NewInMemoryStore()
+	svc := New(outbox, Options{
+		Endpoints:   []Endpoint{{Name: "crm", URL: srv.URL, Secret: "s3cret"}},
+		MaxAttempts: 3,
+		Backoff:     noBackoff,
+	})
+	_ = svc.Notify(ctx, EventDeleted, storage.Entry{ShortCode: "abc123"})
+
+	for i := 1; i <= 3; i++ {
+		if n, err := svc.RunOnce(ctx); n != 1 || err != nil {
+			t.Fatalf("attempt %d: expected one attempt, got %d, %v", i, n, err)
+		}
+	}
+	if n, _ := svc.RunOnce(ctx); n != 0 {
+		t.Fatalf("expected no attempts after the last one failed, got %d", n)
+	}
+	failed, _ := svc.Deliveries(ctx, storage.DeliveryListOptions{Status: storage.DeliveryFailed})
+	if len(failed) != 1 || failed[0].Attempts != 3 || failed[0].LastStatus != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected failed deliveries %+v", failed)
+	}
+	if failed[0].LastError != "unexpected status 503: down for maintenance" {
+		t.Fatalf("unexpected last error %q", failed[0].LastError)
+	}
+
+	rcv.setFailing(false)
+	d, err := svc.Replay(ctx, failed[0].ID)
+	if err != nil || d.Status != storage.DeliveryPending || d.Attempts != 0 {
+		t.Fatalf("unexpected replay result %+v, %v", d, err)
+	}
+	if _, err := svc.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce returned error: %v", err)
+	}
+	if len(rcv.received()) != 1 {
+		t.Fatalf("expected the replayed delivery to arrive, got %d", len(rcv.received()))
+	}
+	if _, err := svc.Replay(ctx, failed[0].ID); !errors.Is(err, ErrNotFailed) {
+		t.Fatalf("expected ErrNotFailed for a delivered webhook, got %v", err)
+	}
+	if _, err := svc.Replay(ctx, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRetryWaitsForBackoff(t *testing.T) {
+	ctx := context.Background()
+	rcv, srv := newReceiver(t, "s3cret")
+	rcv.setFailing(true)
+	outbox := storage.NewInMemoryStore()
+	clk := clocktest.New(time.Now().UTC())
+	svc := New(outbox, Options{Endpoints: []Endpoint{{Name: "crm", URL: srv.URL, Secret: "s3cret"}}, Clock: clk})
+	_ = svc.Notify(ctx, EventCreated, storage.Entry{ShortCode: "abc123"})
+
+	_, _ = svc.RunOnce(ctx)
+	if n, _ := svc.RunOnce(ctx); n != 0 {
+		t.Fatalf("expected the retry to wait, got %d attempts", n)
+	}
+	pending, _ := svc.Deliveries(ctx, storage.DeliveryListOptions{Status: storage.DeliveryPending})
+	if len(pending) != 1 || !pending[0].NextAttemptAt.Equal(clk.Now().Add(5*time.Second)) {
+		t.Fatalf("expected the retry 5s out, got %+v", pending)
+	}
+
+	rcv.setFailing(false)
+	clk.Advance(5 * time.Second)
+	if n, err := svc.RunOnce(ctx); n != 1 || err != nil {
+		t.Fatalf("expected the retry once its backoff passed, got %d, %v", n, err)
+	}
+	if len(rcv.received()) != 1 {
+		t.Fatalf("expected the retry to arrive, got %d", len(rcv.received()))
+	}
+}
+
+func TestReplayFailed(t *testing.T) {
+	ctx := context.Background()
+	outbox := storage.NewInMemoryStore()
+	svc := New(outbox, Options{
+		// Nothing listens on a closed server, so every attempt fails.
+		Endpoints:   []Endpoint{{Name: "gone", URL: closedServerURL(t)}},
+		MaxAttempts: 1,
+	})
+	_ = svc.Notify(ctx, EventCreated, storage.Entry{ShortCode: "a1"})
+	_ = svc.Notify(ctx, EventCreated, storage.Entry{ShortCode: "b2"})
+	_, _ = svc.RunOnce(ctx)
+
+	n, err := svc.ReplayFailed(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 replays, got %d, %v", n, err)
+	}
+	pending, _ := svc.Deliveries(ctx, storage.DeliveryListOptions{Status: storage.DeliveryPending})
+	if len(pending) != 2 {
+		t.Fatalf("expected both deliveries pending again, got %+v", pending)
+	}
+}
+
+func closedServerURL(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
+
+func TestUnconfiguredEndpointFails(t *testing.T) {
+	ctx := context.Background()
+	outbox := storage.NewInMemoryStore()
+	_ = outbox.EnqueueDelivery(ctx, storage.WebhookDelivery{
+		ID: "old.removed", Endpoint: "removed", Event: EventCreated, Payload: []byte(`{}`),
+		Status: storage.DeliveryPending, NextAttemptAt: time.Now().Add(-time.Second),
+	})
+	svc := New(outbox, Options{MaxAttempts: 1})
+	if _, err := svc.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce returned error: %v", err)
+	}
+	d, _ := outbox.FindDelivery(ctx, "old.removed")
+	if d.Status != storage.DeliveryFailed || d.LastError != `endpoint "removed" is not configured` {
+		t.Fatalf("unexpected delivery %+v", d)
+	}
+}
+
+func TestRunOnceLeasesDeliveries(t *testing.T) {
+	ctx := context.Background()
+	outbox := storage.NewInMemoryStore()
+	clk := clocktest.New(time.Now().UTC())
+	var (
+		peer     *Service
+		received []string
+		peerSaw  = -1
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get(IDHeader))
+		if peerSaw < 0 {
+			// Another server polls while the first send is in flight, and
+			// the send then takes the whole lease.
+			peerSaw, _ = peer.RunOnce(ctx)
+			clk.Advance(time.Minute)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	opts := Options{Endpoints: []Endpoint{{Name: "crm", URL: srv.URL}}, Lease: time.Minute, Clock: clk}
+	svc := New(outbox, opts)
+	peer = New(outbox, opts)
+	_ = svc.Notify(ctx, EventCreated, storage.Entry{ShortCode: "abc123"})
+	_ = svc.Notify(ctx, EventDeleted, storage.Entry{ShortCode: "abc123"})
+
+	if n, err := svc.RunOnce(ctx); n != 1 || err != nil {
+		t.Fatalf("expected the dispatcher to stop when its lease ran out, got %d, %v", n, err)
+	}
+	if peerSaw != 0 {
+		t.Fatalf("expected the other server to find nothing while the deliveries were leased, got %d", peerSaw)
+	}
+	if n, err := peer.RunOnce(ctx); n != 1 || err != nil {
+		t.Fatalf("expected the other server to take over the rest, got %d, %v", n, err)
+	}
+	if len(received) != 2 || received[0] == received[1] {
+		t.Fatalf("expected each delivery sent once, got %v", received)
+	}
+}
+
+func TestSweepExpired(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStore()
+	now := time.Now().UTC()
+	_ = store.Save(ctx, storage.Entry{ShortCode: "gone01", OriginalURL: "https://example.com", ExpiresAt: now.Add(-time.Minute)})
+	_ = store.Save(ctx, storage.Entry{ShortCode: "old001", OriginalURL: "https://example.com", ExpiresAt: now.Add(-48 * time.Hour)})
+	_ = store.Save(ctx, storage.Entry{ShortCode: "live01", OriginalURL: "https://example.com", ExpiresAt: now.Add(time.Hour)})
+	_ = store.Save(ctx, storage.Entry{ShortCode: "never1", OriginalURL: "https://example.com"})
+	svc := New(store, Options{Endpoints: []Endpoint{{Name: "crm", URL: "http://crm.invalid", Events: []string{EventExpired}}}})
+
+	n, err := svc.SweepExpired(ctx, store, now.Add(-time.Hour), now)
+	if err != nil || n != 1 {
+		t.Fatalf("expected one expiry queued, got %d, %v", n, err)
+	}
+	// Sweeping the same window again is a no-op.
+	if n, _ := svc.SweepExpired(ctx, store, now.Add(-time.Hour), now); n != 0 {
+		t.Fatalf("expected the expiry to be queued once, got %d more", n)
+	}
+	deliveries, _ := store.ListDeliveries(ctx, storage.DeliveryListOptions{})
+	var p Payload
+	if len(deliveries) != 1 || json.Unmarshal(deliveries[0].Payload, &p) != nil || p.Link.ShortCode != "gone01" || p.Event != EventExpired {
+		t.Fatalf("unexpected deliveries %+v", deliveries)
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"event":"link.created"}`)
+	at := time.Unix(1700000000, 0)
+	header := Sign("s3cret", at, body)
+
+	if err := Verify("s3cret", header, body, at.Add(time.Second), time.Minute); err != nil {
+		t.Fatalf("expected a valid signature, got %v", err)
+	}
+	if err := Verify("other", header, body, at, time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected the wrong secret to fail, got %v", err)
+	}
+	if err := Verify("s3cret", header, []byte(`{"event":"link.deleted"}`), at, time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected a tampered body to fail, got %v", err)
+	}
+	if err := Verify("s3cret", header, body, at.Add(time.Hour), time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected a stale signature to fail, got %v", err)
+	}
+	if err := Verify("s3cret", "garbage", body, at, 0); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected a malformed header to fail, got %v", err)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(5*time.Second, time.Minute)
+	for attempts, want := range map[int]time.Duration{1: 5 * time.Second, 2: 10 * time.Second, 4: 40 * time.Second, 5: time.Minute, 30: time.Minute} {
+		if got := backoff(attempts); got != want {
+			t.Fatalf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestRunDeliversWithoutWaitingForPoll(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rcv, srv := newReceiver(t, "s3cret")
+	svc := New(storage.NewInMemoryStore(), Options{
+		Endpoints:    []Endpoint{{Name: "crm", URL: srv.URL, Secret: "s3cret"}},
+		PollInterval: time.Hour,
+	})
+	go svc.Run(ctx)
+
+	_ = svc.Notify(ctx, EventCreated, storage.Entry{ShortCode: "abc123"})
+	deadline := time.Now().Add(2 * time.Second)
+	for len(rcv.received()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the webhook to be delivered promptly")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,146 @@
+// Package tlscert serves HTTPS from a certificate and key on disk, picking up
+// renewed files without a restart, for deployments with no proxy in front.
+package tlscert
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"urlshortener/internal/logging"
+)
+
+// Reloader holds the certificate loaded from a cert and key file pair.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time // the newer of the two files' at the last load
+}
+
+// Load reads the PEM certificate and key; only this first load can fail.
+func Load(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads the files if either changed since the last load, and
+// reports whether it did. On error the previous certificate stays in use.
+func (r *Reloader) Reload() (bool, error) {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	unchanged := r.cert != nil && modTime.Equal(r.modTime)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("tls certificate: %w", err)
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mu.Unlock()
+	return true, nil
+}
+
+func (r *Reloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("tls certificate: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// GetCertificate is for tls.Config; every handshake gets the latest
+// certificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Watch calls Reload every interval until ctx is done. Errors are logged,
+// e.g. while a renewal has written the certificate but not yet the key, and
+// retried on the next tick.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		reloaded, err := r.Reload()
+		if err != nil {
+			logging.Warnf("⚠️  TLS certificate not reloaded: %v", err)
+			continue
+		}
+		if reloaded {
+			logging.Infof("🔐 Reloaded TLS certificate from %s", r.certFile)
+		}
+	}
+}
+
+// Server returns a server for addr that speaks HTTP/2 and HTTP/1.1 over TLS
+// with the reloader's certificate. Start it with ListenAndServeTLS("", "").
+func (r *Reloader) Server(addr string, handler http.Handler) *http.Server {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	return &http.Server{
+		Addr:      addr,
+		Handler:   handler,
+		Protocols: protocols,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: r.GetCertificate,
+		},
+	}
+}
+
+// RedirectHTTPS sends every request to the same host and path over HTTPS
+// on the port of httpsAddr. 308 keeps the method and body of API calls.
+func RedirectHTTPS(httpsAddr string) http.Handler {
+	_, port, err := net.SplitHostPort(httpsAddr)
+	if err != nil || port == "" {
+		port = "443"
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		target := net.JoinHostPort(host, port)
+		if port == "443" {
+			target = strings.TrimSuffix(target, ":443")
+		}
+		http.Redirect(w, r, "https://"+target+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,190 @@
+package tlscert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSigned writes a fresh self-signed certificate for localhost and
+// its key to dir, stamped with modTime, and returns the parsed certificate.
+func writeSelfSigned(t *testing.T, dir string, serial int64, modTime time.Time) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	files := map[string][]byte{
+		"cert.pem": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		"key.pem":  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("failed to stamp %s: %v", name, err)
+		}
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+// clientFor trusts only cert and prefers HTTP/2, like a browser would.
+func clientFor(cert *x509.Certificate) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots, ServerName: "localhost"},
+		ForceAttemptHTTP2: true,
+	}}
+}
+
+func TestServerReloadsCertificate(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+	first := writeSelfSigned(t, dir, 1, start)
+	certs, err := Load(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	srv := certs.Server("127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go func() { _ = srv.ServeTLS(ln, "", "") }()
+	t.Cleanup(func() { _ = srv.Close() })
+	url := "https://" + ln.Addr().String() + "/"
+
+	resp, err := clientFor(first).Get(url)
+	if err != nil {
+		t.Fatalf("GET returned error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("expected HTTP/2, got %s", resp.Proto)
+	}
+	if got := resp.TLS.PeerCertificates[0].SerialNumber.Int64(); got != 1 {
+		t.Fatalf("expected the first certificate, got serial %d", got)
+	}
+
+	if reloaded, err := certs.Reload(); err != nil || reloaded {
+		t.Fatalf("expected unchanged files not to be reloaded, got %t, %v", reloaded, err)
+	}
+	second := writeSelfSigned(t, dir, 2, start.Add(time.Second))
+	if reloaded, err := certs.Reload(); err != nil || !reloaded {
+		t.Fatalf("expected the renewed files to be reloaded, got %t, %v", reloaded, err)
+	}
+	resp, err = clientFor(second).Get(url)
+	if err != nil {
+		t.Fatalf("GET after renewal returned error: %v", err)
+	}
+	resp.Body.Close()
+	if got := resp.TLS.PeerCertificates[0].SerialNumber.Int64(); got != 2 {
+		t.Fatalf("expected the renewed certificate, got serial %d", got)
+	}
+}
+
+func TestReloadKeepsCertificateOnError(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+	writeSelfSigned(t, dir, 1, start)
+	certs, err := Load(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	// A renewal caught halfway: the key no longer matches.
+	keyPath := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(keyPath, []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	if _, err := certs.Reload(); err == nil {
+		t.Fatal("expected a broken key to be reported")
+	}
+	cert, _ := certs.GetCertificate(nil)
+	if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err != nil || leaf.SerialNumber.Int64() != 1 {
+		t.Fatalf("expected the previous certificate to stay, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		certs.Watch(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	writeSelfSigned(t, dir, 3, start.Add(time.Second))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		cert, _ := certs.GetCertificate(nil)
+		if leaf, _ := x509.ParseCertificate(cert.Certificate[0]); leaf.SerialNumber.Int64() == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected Watch to pick up the fixed files")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if _, err := Load(filepath.Join(dir, "missing.pem"), keyPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected a missing file to fail Load, got %v", err)
+	}
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	for _, tc := range []struct {
+		httpsAddr, host, target, want string
+	}{
+		{":443", "sho.rt", "/abc123?x=1", "https://sho.rt/abc123?x=1"},
+		{":8443", "sho.rt:8080", "/api/shorten", "https://sho.rt:8443/api/shorten"},
+		{":8443", "[::1]:8080", "/", "https://[::1]:8443/"},
+		{":443", "[::1]", "/", "https://[::1]/"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.target, nil)
+		req.Host = tc.host
+		rec := httptest.NewRecorder()
+		RedirectHTTPS(tc.httpsAddr).ServeHTTP(rec, req)
+		if rec.Code != http.StatusPermanentRedirect || rec.Header().Get("Location") != tc.want {
+			t.Fatalf("%s%s: expected 308 to %s, got %d %s", tc.host, tc.target, tc.want, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+}
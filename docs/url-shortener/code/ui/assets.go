@@ -0,0 +1,186 @@
+package ui
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// hashLen is how many hex digits of the content hash go into asset names.
+const hashLen = 12
+
+// immutableCache is sent for hashed names: the bytes behind them never change.
+const immutableCache = "public, max-age=31536000, immutable"
+
+// asset is one static file with its precompressed variants. gz and br are nil
+// when compressing would not make the file smaller.
+type asset struct {
+	name        string
+	hashed      string
+	contentType string
+	hash        string
+	raw, gz, br []byte
+}
+
+// assetSet serves embedded assets under both their plain and hashed names.
+// Hashed names are cached for a year; plain names (still needed by static
+// HTML such as docs.html) are revalidated by ETag on every use.
+type assetSet struct {
+	byPath map[string]*asset
+	hashed map[string]string
+}
+
+func newAssetSet(static fs.FS) (*assetSet, error) {
+	set := &assetSet{byPath: map[string]*asset{}, hashed: map[string]string{}}
+	err := fs.WalkDir(static, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		raw, err := fs.ReadFile(static, name)
+		if err != nil {
+			return err
+		}
+		a, err := newAsset(name, raw)
+		if err != nil {
+			return err
+		}
+		set.byPath[a.name] = a
+		set.byPath[a.hashed] = a
+		set.hashed[a.name] = a.hashed
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+func newAsset(name string, raw []byte) (*asset, error) {
+	sum := sha256.Sum256(raw)
+	hash := hex.EncodeToString(sum[:])[:hashLen]
+
+	ext := path.Ext(name)
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = http.DetectContentType(raw)
+	}
+
+	a := &asset{
+		name:        name,
+		hashed:      strings.TrimSuffix(name, ext) + "." + hash + ext,
+		contentType: contentType,
+		hash:        hash,
+		raw:         raw,
+	}
+	if !compressible(contentType) {
+		return a, nil
+	}
+
+	var gz bytes.Buffer
+	gw, err := gzip.NewWriterLevel(&gz, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gw.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	if gz.Len() < len(raw) {
+		a.gz = gz.Bytes()
+	}
+
+	var br bytes.Buffer
+	bw := brotli.NewWriterLevel(&br, brotli.BestCompression)
+	if _, err := bw.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := bw.Close(); err != nil {
+		return nil, err
+	}
+	if br.Len() < len(raw) {
+		a.br = br.Bytes()
+	}
+	return a, nil
+}
+
+// compressible reports whether a content type is text-like; images and fonts
+// are already compressed.
+func compressible(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/javascript",
+		mediaType == "application/json",
+		mediaType == "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// hashedName maps a static/ file to its content-hashed name. Unknown names
+// are returned unchanged and will 404 when fetched.
+func (s *assetSet) hashedName(name string) string {
+	if hashed, ok := s.hashed[name]; ok {
+		return hashed
+	}
+	return name
+}
+
+func (s *assetSet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	a, ok := s.byPath[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", a.contentType)
+	header.Set("Vary", "Accept-Encoding")
+	if name == a.hashed {
+		header.Set("Cache-Control", immutableCache)
+	} else {
+		header.Set("Cache-Control", "no-cache")
+	}
+
+	// Each encoding is its own representation, so it gets its own ETag.
+	body, etag := a.raw, `"`+a.hash+`"`
+	accept := r.Header.Get("Accept-Encoding")
+	switch {
+	case a.br != nil && acceptsEncoding(accept, "br"):
+		body, etag = a.br, `"`+a.hash+`-br"`
+		header.Set("Content-Encoding", "br")
+	case a.gz != nil && acceptsEncoding(accept, "gzip"):
+		body, etag = a.gz, `"`+a.hash+`-gz"`
+		header.Set("Content-Encoding", "gzip")
+	}
+	header.Set("ETag", etag)
+
+	// ServeContent answers If-None-Match with 304 and handles HEAD and ranges.
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(body))
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows enc,
+// honouring explicit "q=0" refusals.
+func acceptsEncoding(header, enc string) bool {
+	for _, part := range strings.Split(header, ",") {
+		token, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(token), enc) {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
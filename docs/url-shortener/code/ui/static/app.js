@@ -0,0 +1,68 @@
+// Progressive enhancement for the server-rendered pages: copy buttons,
+// delete confirmations and the shorten form on the home page.
+
+async function copyText(button) {
+  const text = button.dataset.copy;
+  try {
+    await navigator.clipboard.writeText(text);
+    const label = button.textContent;
+    button.textContent = "Copied!";
+    setTimeout(() => { button.textContent = label; }, 1500);
+  } catch {
+    window.prompt("Copy this link:", text);
+  }
+}
+
+document.addEventListener("click", (event) => {
+  const button = event.target.closest("button[data-copy]");
+  if (button) copyText(button);
+});
+
+document.addEventListener("submit", (event) => {
+  const message = event.target.dataset.confirm;
+  if (message && !window.confirm(message)) event.preventDefault();
+});
+
+const form = document.getElementById("shorten-form");
+if (form) {
+  const result = document.getElementById("result");
+  const link = document.getElementById("result-link");
+  const copy = document.getElementById("result-copy");
+  const errorBox = document.getElementById("result-error");
+
+  form.addEventListener("submit", async (event) => {
+    event.preventDefault();
+    result.hidden = true;
+    errorBox.hidden = true;
+
+    const url = document.getElementById("url-input").value.trim();
+    if (!url) {
+      errorBox.textContent = "Please enter a URL.";
+      errorBox.hidden = false;
+      return;
+    }
+
+    try {
+      const response = await fetch("/api/shorten", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ url }),
+      });
+      if (!response.ok) {
+        errorBox.textContent = `Error: ${(await response.text()).trim()}`;
+        errorBox.hidden = false;
+        return;
+      }
+
+      const data = await response.json();
+      const shortURL = `${window.location.origin}/${data.short_code}`;
+      link.href = shortURL;
+      link.textContent = shortURL;
+      copy.dataset.copy = shortURL;
+      result.hidden = false;
+    } catch (error) {
+      errorBox.textContent = `Request failed: ${error.message}`;
+      errorBox.hidden = false;
+    }
+  });
+}
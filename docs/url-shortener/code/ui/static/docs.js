@@ -0,0 +1,142 @@
+// Renders /api/openapi.json as a browsable list of operations, with a small
+// "try it" form per operation. No build step or third-party code.
+const specURL = "/api/openapi.json";
+
+function el(tag, attrs = {}, ...children) {
+  const node = document.createElement(tag);
+  for (const [key, value] of Object.entries(attrs)) {
+    if (key === "class") node.className = value;
+    else node.setAttribute(key, value);
+  }
+  for (const child of children.flat()) {
+    if (child == null) continue;
+    node.append(child instanceof Node ? child : String(child));
+  }
+  return node;
+}
+
+function resolve(spec, obj) {
+  if (!obj || !obj.$ref) return obj;
+  const path = obj.$ref.replace(/^#\//, "").split("/");
+  return path.reduce((node, key) => node[key], spec);
+}
+
+function schemaLabel(schema) {
+  if (!schema) return "";
+  if (schema.$ref) return schema.$ref.split("/").pop();
+  if (schema.type === "array") return `${schemaLabel(schema.items)}[]`;
+  if (schema.enum) return `${schema.type} (${schema.enum.join(" | ")})`;
+  return schema.format ? `${schema.type} (${schema.format})` : schema.type || "any";
+}
+
+function parametersTable(spec, params) {
+  if (!params.length) return null;
+  return el("table", {},
+    el("tr", {}, el("th", {}, "Name"), el("th", {}, "In"), el("th", {}, "Type"), el("th", {}, "Description")),
+    params.map((param) => el("tr", {},
+      el("td", {}, param.name, param.required ? " *" : ""),
+      el("td", {}, param.in),
+      el("td", {}, schemaLabel(param.schema)),
+      el("td", {}, param.description || ""))));
+}
+
+function responsesTable(spec, responses) {
+  return el("table", {},
+    el("tr", {}, el("th", {}, "Status"), el("th", {}, "Description"), el("th", {}, "Body")),
+    Object.entries(responses).map(([status, ref]) => {
+      const response = resolve(spec, ref);
+      const bodies = Object.entries(response.content || {})
+        .map(([type, media]) => `${type}: ${schemaLabel(media.schema)}`);
+      return el("tr", {}, el("td", {}, status), el("td", {}, response.description || ""), el("td", {}, bodies.join(", ")));
+    }));
+}
+
+function tryIt(path, method, op) {
+  const params = op.parameters || [];
+  const inputs = {};
+  const form = el("form", {},
+    params.map((param) => {
+      inputs[param.name] = el("input", { name: param.name, placeholder: param.in });
+      return el("label", {}, `${param.name} `, inputs[param.name], " ");
+    }));
+  const bodyTypes = Object.keys(op.requestBody?.content || {});
+  const body = bodyTypes.length ? el("textarea", { placeholder: bodyTypes[0] }) : null;
+  const output = el("pre", {});
+  form.append(body, el("button", { type: "submit" }, "Send"), output);
+
+  form.addEventListener("submit", async (event) => {
+    event.preventDefault();
+    let url = path;
+    const query = new URLSearchParams();
+    for (const param of params) {
+      const value = inputs[param.name].value;
+      if (!value) continue;
+      if (param.in === "path") url = url.replace(`{${param.name}}`, encodeURIComponent(value));
+      if (param.in === "query") query.set(param.name, value);
+    }
+    if ([...query].length) url += `?${query}`;
+
+    const headers = {};
+    const key = document.getElementById("api-key").value.trim();
+    if (key) headers.Authorization = `Bearer ${key}`;
+    if (body) headers["Content-Type"] = bodyTypes[0];
+
+    try {
+      const response = await fetch(url, { method, headers, body: body ? body.value : undefined, redirect: "manual" });
+      const text = await response.text();
+      output.textContent = `${response.status} ${response.statusText}\n\n${text}`;
+    } catch (error) {
+      output.textContent = `Request failed: ${error.message}`;
+    }
+  });
+  return form;
+}
+
+function renderOperation(spec, path, method, op) {
+  const secured = (op.security || spec.security || []).some((req) => Object.keys(req).length > 0);
+  const optional = (op.security || []).some((req) => Object.keys(req).length === 0);
+  const requestBody = op.requestBody
+    ? el("p", {}, "Body: ", Object.entries(op.requestBody.content)
+      .map(([type, media]) => `${type}: ${schemaLabel(media.schema)}`).join(", "))
+    : null;
+
+  return el("details", {},
+    el("summary", {},
+      el("span", { class: `method ${method}` }, method.toUpperCase()),
+      path, " ", op.summary ? `— ${op.summary}` : "",
+      secured ? el("span", { class: "lock" }, optional ? " (API key optional)" : " (API key)") : null),
+    el("div", {},
+      op.description ? el("p", {}, op.description) : null,
+      parametersTable(spec, (op.parameters || []).map((p) => resolve(spec, p))),
+      requestBody,
+      el("h4", {}, "Responses"),
+      responsesTable(spec, op.responses),
+      el("h4", {}, "Try it"),
+      tryIt(path, method, { ...op, parameters: (op.parameters || []).map((p) => resolve(spec, p)) })));
+}
+
+async function main() {
+  const response = await fetch(specURL);
+  const spec = await response.json();
+
+  document.getElementById("title").textContent = `${spec.info.title} ${spec.info.version}`;
+  document.getElementById("description").textContent = spec.info.description || "";
+
+  const operations = document.getElementById("operations");
+  for (const [path, item] of Object.entries(spec.paths)) {
+    for (const [method, op] of Object.entries(item)) {
+      operations.append(renderOperation(spec, path, method, op));
+    }
+  }
+
+  const schemas = document.getElementById("schemas");
+  for (const [name, schema] of Object.entries(spec.components?.schemas || {})) {
+    schemas.append(el("details", {}, el("summary", {}, name), el("div", {},
+      schema.description ? el("p", {}, schema.description) : null,
+      el("pre", {}, JSON.stringify(schema, null, 2)))));
+  }
+}
+
+main().catch((error) => {
+  document.getElementById("operations").textContent = `Failed to load ${specURL}: ${error.message}`;
+});
@@ -0,0 +1,161 @@
+// Package ui holds the web UI: server-rendered page templates and the static
+// assets they load. Both are embedded, so the server does not depend on the
+// directory it is started from; see Dev for editing them live.
+package ui
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+//go:embed static templates
+var files embed.FS
+
+// UI renders pages and serves the assets they reference.
+type UI struct {
+	fsys   fs.FS // holds static/ and templates/
+	dev    bool
+	assets *assetSet                     // nil in dev mode
+	pages  map[string]*template.Template // nil in dev mode
+}
+
+var (
+	embeddedOnce sync.Once
+	embeddedUI   *UI
+	embeddedErr  error
+)
+
+// Embedded returns the UI built into the binary. Assets are hashed and
+// compressed once, on first use.
+func Embedded() (*UI, error) {
+	embeddedOnce.Do(func() {
+		embeddedUI, embeddedErr = build(files)
+	})
+	return embeddedUI, embeddedErr
+}
+
+// Dev serves templates and assets straight from dir (the ui/ directory of a
+// checkout), re-reading them on every request so edits show up on reload.
+// Nothing is cached or compressed.
+func Dev(dir string) (*UI, error) {
+	if _, err := os.Stat(path.Join(dir, "templates", "layout.html")); err != nil {
+		return nil, fmt.Errorf("ui dev dir: %w", err)
+	}
+	return &UI{fsys: os.DirFS(dir), dev: true}, nil
+}
+
+func build(fsys fs.FS) (*UI, error) {
+	static, err := fs.Sub(fsys, "static")
+	if err != nil {
+		return nil, err
+	}
+	assets, err := newAssetSet(static)
+	if err != nil {
+		return nil, err
+	}
+	u := &UI{fsys: fsys, assets: assets}
+	if u.pages, err = u.parsePages(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// AssetPath is the URL for a file in static/. Outside dev mode it carries a
+// content hash, so it can be cached forever and still change on deploy.
+func (u *UI) AssetPath(name string) string {
+	if u.dev {
+		return "/static/" + name
+	}
+	return "/static/" + u.assets.hashedName(name)
+}
+
+// Static serves static/; mount it with the /static/ prefix stripped.
+func (u *UI) Static() http.Handler {
+	if u.dev {
+		static, _ := fs.Sub(u.fsys, "static")
+		files := http.FileServerFS(static)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-store")
+			files.ServeHTTP(w, r)
+		})
+	}
+	return u.assets
+}
+
+// Page returns the template set for one page in templates/; render it with
+// ExecuteTemplate(w, "layout", data).
+func (u *UI) Page(name string) (*template.Template, error) {
+	if !u.dev {
+		page, ok := u.pages[name]
+		if !ok {
+			return nil, fmt.Errorf("ui: no page %q", name)
+		}
+		return page, nil
+	}
+	return u.parsePage("templates/" + name)
+}
+
+// parsePages parses every page in templates/ together with layout.html. Each
+// page defines "title" and "content".
+func (u *UI) parsePages() (map[string]*template.Template, error) {
+	names, err := fs.Glob(u.fsys, "templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	pages := map[string]*template.Template{}
+	for _, name := range names {
+		if path.Base(name) == "layout.html" {
+			continue
+		}
+		page, err := u.parsePage(name)
+		if err != nil {
+			return nil, err
+		}
+		pages[path.Base(name)] = page
+	}
+	return pages, nil
+}
+
+func (u *UI) parsePage(name string) (*template.Template, error) {
+	funcs := template.FuncMap{"asset": u.AssetPath}
+	for k, v := range formatFuncs {
+		funcs[k] = v
+	}
+	page, err := template.New(path.Base(name)).Funcs(funcs).ParseFS(u.fsys, "templates/layout.html", name)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path.Base(name), err)
+	}
+	return page, nil
+}
+
+var formatFuncs = template.FuncMap{
+	// when renders a timestamp for tables; zero times render as "never".
+	"when": func(t time.Time) string {
+		if t.IsZero() {
+			return "never"
+		}
+		return t.UTC().Format("2006-01-02 15:04 UTC")
+	},
+	// ago renders how long ago t was, to the largest whole unit.
+	"ago": func(t time.Time) string {
+		d := time.Since(t)
+		switch {
+		case d < time.Minute:
+			return "just now"
+		case d < time.Hour:
+			return fmt.Sprintf("%d min ago", int(d.Minutes()))
+		case d < 48*time.Hour:
+			return fmt.Sprintf("%d h ago", int(d.Hours()))
+		default:
+			return fmt.Sprintf("%d days ago", int(d.Hours()/24))
+		}
+	},
+}
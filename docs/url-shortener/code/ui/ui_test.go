@@ -0,0 +1,179 @@
+package ui
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func embedded(t *testing.T) *UI {
+	t.Helper()
+	u, err := Embedded()
+	if err != nil {
+		t.Fatalf("Embedded returned error: %v", err)
+	}
+	return u
+}
+
+func fetch(t *testing.T, h http.Handler, target string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPagesParse(t *testing.T) {
+	u := embedded(t)
+	for _, name := range []string{"index.html", "dashboard.html", "link.html", "disabled.html", "preview.html", "soon.html"} {
+		page, err := u.Page(name)
+		if err != nil {
+			t.Fatalf("Page(%s) returned error: %v", name, err)
+		}
+		if page.Lookup("layout") == nil || page.Lookup("content") == nil {
+			t.Fatalf("%s is missing the layout or content template", name)
+		}
+	}
+	if _, err := u.Page("missing.html"); err == nil {
+		t.Fatal("expected error for unknown page")
+	}
+}
+
+func TestAssetPathIsHashed(t *testing.T) {
+	u := embedded(t)
+	path := u.AssetPath("app.js")
+	if !regexp.MustCompile(`^/static/app\.[0-9a-f]{12}\.js$`).MatchString(path) {
+		t.Fatalf("unexpected asset path %s", path)
+	}
+}
+
+func TestStaticCaching(t *testing.T) {
+	u := embedded(t)
+	static := u.Static()
+	hashed := strings.TrimPrefix(u.AssetPath("app.js"), "/static/")
+
+	rec := fetch(t, static, "/"+hashed, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for hashed name, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != immutableCache {
+		t.Fatalf("expected immutable caching for hashed name, got %q", got)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/javascript") {
+		t.Fatalf("unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+
+	rec = fetch(t, static, "/app.js", nil)
+	if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Fatalf("expected revalidation for plain name, got %q", got)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+
+	rec = fetch(t, static, "/app.js", map[string]string{"If-None-Match": etag})
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for matching ETag, got %d", rec.Code)
+	}
+
+	if rec := fetch(t, static, "/nope.js", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown asset, got %d", rec.Code)
+	}
+}
+
+func TestStaticPrecompressed(t *testing.T) {
+	u := embedded(t)
+	static := u.Static()
+	plain := fetch(t, static, "/app.js", nil).Body.String()
+
+	rec := fetch(t, static, "/app.js", map[string]string{"Accept-Encoding": "gzip, deflate, br"})
+	if rec.Header().Get("Content-Encoding") != "br" {
+		t.Fatalf("expected brotli, got %q", rec.Header().Get("Content-Encoding"))
+	}
+	body, err := io.ReadAll(brotli.NewReader(rec.Body))
+	if err != nil || string(body) != plain {
+		t.Fatalf("brotli body does not round-trip (err %v)", err)
+	}
+
+	rec = fetch(t, static, "/app.js", map[string]string{"Accept-Encoding": "gzip, br;q=0"})
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip when br is refused, got %q", rec.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	body, err = io.ReadAll(zr)
+	if err != nil || string(body) != plain {
+		t.Fatalf("gzip body does not round-trip (err %v)", err)
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected Vary: Accept-Encoding, got %q", rec.Header().Get("Vary"))
+	}
+}
+
+func TestDevServesFromDisk(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"templates/layout.html": `{{define "layout"}}<link href="{{asset "app.css"}}">{{template "content" .}}{{end}}`,
+		"templates/index.html":  `{{define "title"}}t{{end}}{{define "content"}}v1{{end}}`,
+		"static/app.css":        `body {}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u, err := Dev(dir)
+	if err != nil {
+		t.Fatalf("Dev returned error: %v", err)
+	}
+	if got := u.AssetPath("app.css"); got != "/static/app.css" {
+		t.Fatalf("expected unhashed path in dev mode, got %s", got)
+	}
+
+	render := func() string {
+		page, err := u.Page("index.html")
+		if err != nil {
+			t.Fatalf("Page returned error: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := page.ExecuteTemplate(&buf, "layout", nil); err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		return buf.String()
+	}
+	if got := render(); !strings.Contains(got, "v1") {
+		t.Fatalf("unexpected render %q", got)
+	}
+	_ = os.WriteFile(filepath.Join(dir, "templates/index.html"), []byte(`{{define "title"}}t{{end}}{{define "content"}}v2{{end}}`), 0o644)
+	if got := render(); !strings.Contains(got, "v2") {
+		t.Fatalf("expected edited template to be picked up, got %q", got)
+	}
+
+	rec := fetch(t, u.Static(), "/app.css", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("expected uncached asset from disk, got %d %q", rec.Code, rec.Header().Get("Cache-Control"))
+	}
+
+	if _, err := Dev(t.TempDir()); err == nil {
+		t.Fatal("expected error for a directory without templates")
+	}
+}